@@ -1,107 +1,434 @@
 package main
 
 import (
-	"database/sql"
+	"context"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 
-	"github.com/golang-migrate/migrate/v4"
-	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
-	_ "github.com/lib/pq"
+	"github.com/linemk/avito-shop/internal/app"
 	"github.com/linemk/avito-shop/internal/config"
+	"github.com/linemk/avito-shop/internal/lib/logger"
+	"github.com/linemk/avito-shop/internal/migrator"
+	"github.com/linemk/avito-shop/internal/seed"
 )
 
-// buildMigrateDSN собирает строку подключения (DSN) из отдельных параметров
-func buildMigrateDSN(dbCfg config.DatabaseConfig, migrationTable string, dbPassword string) string {
-	return fmt.Sprintf(
-		"postgres://%s:%s@%s:%d/%s?sslmode=disable&x-migrations-table=%s",
-		dbCfg.User, dbPassword, dbCfg.Host, dbCfg.Port, dbCfg.Name, migrationTable,
-	)
+// Коды выхода, на которые может опираться CI
+const (
+	exitOK    = 0 // команда выполнена
+	exitError = 1 // ошибка подключения, чтения миграций или выполнения
+	exitUsage = 2 // неверные аргументы
+	exitDirty = 3 // схема в состоянии dirty, нужен force
+)
+
+const usageText = `Usage: migrator [flags] <command> [args] (flags may also follow the command)
+
+Commands:
+  up [N]       apply all pending migrations or the next N (default command)
+  down [N]     roll back the last N migrations (default 1)
+  goto V       migrate up or down to version V
+  version      print the current schema version
+  status       list applied and pending migrations
+  force V      set version V without running migrations and clear the dirty flag (-1 = none)
+  create NAME  create empty up/down files for a new migration
+  seed FILE... load merch, users and opening balances from YAML/JSON fixtures (idempotent)
+
+Flags:
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout))
 }
 
-// buildQueryDSN собирает DSN для обычных SQL запросов
-func buildQueryDSN(dbCfg config.DatabaseConfig, dbPassword string) string {
-	return fmt.Sprintf(
-		"postgres://%s:%s@%s:%d/%s?sslmode=disable",
-		dbCfg.User, dbPassword, dbCfg.Host, dbCfg.Port, dbCfg.Name,
-	)
+// run разбирает аргументы, выполняет команду и возвращает код выхода
+func run(args []string, stdout io.Writer) int {
+	fs := flag.NewFlagSet("migrator", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to config file (default $CONFIG_PATH)")
+	migrationsPath := fs.String("migrations-path", "", "read migrations from this directory instead of the embedded ones (create: default migrations.path from config)")
+	dryRun := fs.Bool("dry-run", false, "print what would be done without changing anything")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usageText)
+		fs.PrintDefaults()
+	}
+
+	// флаги можно указывать и до команды, и после неё: migrator up 2 -dry-run
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	// команда по умолчанию — up, как и раньше запускался мигратор в docker-compose
+	command, commandArgs := "up", []string(nil)
+	if len(positional) > 0 {
+		command, commandArgs = positional[0], positional[1:]
+	}
+
+	switch command {
+	case "create":
+		return runCreate(stdout, *configPath, *migrationsPath, commandArgs, *dryRun)
+	case "seed":
+		return runSeed(stdout, *configPath, commandArgs, *dryRun)
+	}
+
+	cmd, err := parseCommand(command, commandArgs)
+	if err != nil {
+		log.Println(err)
+		fs.Usage()
+		return exitUsage
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		log.Println(err)
+		return exitError
+	}
+
+	dbPassword := GetEnv("DB_PASSWORD", "")
+	if dbPassword == "" {
+		log.Println("DB_PASSWORD environment variable is required")
+		return exitError
+	}
+
+	dsn := migrator.BuildDSN(cfg.Database, migrator.DefaultMigrationsTable, dbPassword)
+	log.Printf("Using DSN for migrate: %s", migrator.RedactDSN(dsn))
+
+	// по умолчанию используются миграции, встроенные в бинарник
+	var mg *migrator.Migrator
+	if *migrationsPath != "" {
+		mg, err = migrator.New("file://"+*migrationsPath, dsn)
+	} else {
+		mg, err = migrator.NewEmbedded(dsn)
+	}
+	if err != nil {
+		return fail(err, dbPassword)
+	}
+	defer mg.Close()
+
+	if err := cmd.exec(stdout, mg, *dryRun); err != nil {
+		return fail(err, dbPassword)
+	}
+	return exitOK
 }
 
-func main() {
-	var migrationsPathFlag string
-	flag.StringVar(&migrationsPathFlag, "migrations-path", "", "path to migration files")
-	flag.Parse()
+// parseArgs разбирает флаги вперемешку с командой и её аргументами и возвращает позиционные
+// аргументы по порядку. fs.Parse останавливается на первом позиционном аргументе, поэтому разбор
+// продолжается с остатка, пока аргументы не кончатся. Отрицательные числа (force -1) — аргументы,
+// а не флаги; всё после "--" тоже считается аргументами.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for len(args) > 0 {
+		arg := args[0]
+		if arg == "--" {
+			return append(positional, args[1:]...), nil
+		}
+		if _, err := strconv.Atoi(arg); err == nil || !strings.HasPrefix(arg, "-") || arg == "-" {
+			positional = append(positional, arg)
+			args = args[1:]
+			continue
+		}
 
-	cfg := config.MustLoad()
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		// fs.Parse сам съедает "--", стоящий сразу после флагов
+		if args[len(args)-len(rest)-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		args = rest
+	}
+	return positional, nil
+}
 
-	migrationsPath := cfg.Migrations.Path
-	if migrationsPathFlag != "" {
-		migrationsPath = migrationsPathFlag
+// command — разобранная команда с аргументами
+type command struct {
+	name    string
+	n       int  // up/down: количество шагов
+	version uint // goto
+	force   int  // force
+}
+
+func parseCommand(name string, args []string) (command, error) {
+	cmd := command{name: name}
+	var err error
+
+	switch name {
+	case "up":
+		if len(args) > 1 {
+			return cmd, fmt.Errorf("up: expected at most one argument")
+		}
+		if len(args) == 1 {
+			cmd.n, err = parsePositive(args[0])
+		}
+	case "down":
+		if len(args) > 1 {
+			return cmd, fmt.Errorf("down: expected at most one argument")
+		}
+		cmd.n = 1
+		if len(args) == 1 {
+			cmd.n, err = parsePositive(args[0])
+		}
+	case "goto":
+		if len(args) != 1 {
+			return cmd, fmt.Errorf("goto: expected version")
+		}
+		var v uint64
+		v, err = strconv.ParseUint(args[0], 10, 64)
+		cmd.version = uint(v)
+	case "force":
+		if len(args) != 1 {
+			return cmd, fmt.Errorf("force: expected version")
+		}
+		cmd.force, err = strconv.Atoi(args[0])
+		if err == nil && cmd.force < -1 {
+			err = fmt.Errorf("version must be >= -1")
+		}
+	case "version", "status":
+		if len(args) != 0 {
+			return cmd, fmt.Errorf("%s: unexpected arguments", name)
+		}
+	default:
+		return cmd, fmt.Errorf("unknown command %q", name)
 	}
 
-	dbPassword := ""
-	if dbPassword = GetEnv("DB_PASSWORD", ""); dbPassword == "" {
-		log.Fatal("DB_PASSWORD environment variable is required")
+	if err != nil {
+		return cmd, fmt.Errorf("%s: invalid argument: %w", name, err)
 	}
+	return cmd, nil
+}
 
-	migrationTableName := "migrations"
+func parsePositive(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	if n <= 0 {
+		return 0, fmt.Errorf("must be positive")
+	}
+	return n, nil
+}
+
+// exec выполняет команду; в режиме dry-run только печатает план
+func (c command) exec(stdout io.Writer, mg *migrator.Migrator, dryRun bool) error {
+	switch c.name {
+	case "version":
+		version, dirty, err := mg.Version()
+		if err != nil {
+			return err
+		}
+		printVersion(stdout, version, dirty)
+		if dirty {
+			return migrator.ErrDirty
+		}
+		return nil
+
+	case "status":
+		status, err := mg.Status()
+		if err != nil {
+			return err
+		}
+		printVersion(stdout, status.Version, status.Dirty)
+		for _, m := range status.Migrations {
+			mark := " "
+			if m.Applied {
+				mark = "x"
+			}
+			fmt.Fprintf(stdout, "[%s] %d_%s\n", mark, m.Version, m.Name)
+		}
+		if status.Dirty {
+			return migrator.ErrDirty
+		}
+		return nil
 
-	dsnForMigrate := buildMigrateDSN(cfg.Database, migrationTableName, dbPassword)
-	log.Printf("Using DSN for migrate: %s", dsnForMigrate)
+	case "force":
+		if dryRun {
+			fmt.Fprintf(stdout, "dry-run: would force version %d\n", c.force)
+			return nil
+		}
+		if err := mg.Force(c.force); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "Version forced to %d\n", c.force)
+		return nil
+	}
 
-	// Создаем объект мигратора
-	m, err := migrate.New(
-		"file://"+migrationsPath,
-		dsnForMigrate,
+	var (
+		steps []migrator.Step
+		apply func() error
+		err   error
 	)
+	switch c.name {
+	case "up":
+		steps, err = mg.PlanUp(c.n)
+		apply = func() error { return mg.Up(c.n) }
+	case "down":
+		steps, err = mg.PlanDown(c.n)
+		apply = func() error { return mg.Down(c.n) }
+	case "goto":
+		steps, err = mg.PlanGoto(c.version)
+		apply = func() error { return mg.Goto(c.version) }
+	}
 	if err != nil {
-		log.Fatalf("failed to create migrate instance: %v", err)
+		return err
+	}
+
+	if len(steps) == 0 {
+		fmt.Fprintln(stdout, "No migrations to apply")
+		return nil
+	}
+	prefix := ""
+	if dryRun {
+		prefix = "dry-run: would "
+	}
+	for _, s := range steps {
+		fmt.Fprintf(stdout, "%sapply %d_%s (%s)\n", prefix, s.Version, s.Name, s.Direction)
+	}
+	if dryRun {
+		return nil
+	}
+
+	if err := apply(); err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, "Migrations applied successfully")
+	return nil
+}
+
+func printVersion(stdout io.Writer, version uint, dirty bool) {
+	switch {
+	case version == 0 && !dirty:
+		fmt.Fprintln(stdout, "version: none (no migrations applied)")
+	case dirty:
+		fmt.Fprintf(stdout, "version: %d (dirty)\n", version)
+	default:
+		fmt.Fprintf(stdout, "version: %d\n", version)
+	}
+}
+
+// runCreate создаёт файлы новой миграции; конфиг нужен, только если не указан -migrations-path
+func runCreate(stdout io.Writer, configPath, migrationsPath string, args []string, dryRun bool) int {
+	if len(args) != 1 {
+		log.Println("create: expected migration name")
+		return exitUsage
 	}
 
-	if err := m.Up(); err != nil {
-		if errors.Is(err, migrate.ErrNoChange) {
-			fmt.Println("No migrations to apply")
-		} else {
-			log.Fatalf("migration failed: %v", err)
+	if migrationsPath == "" {
+		cfg, err := loadConfig(configPath)
+		if err != nil {
+			log.Println(err)
+			return exitError
 		}
-	} else {
-		log.Println("Migrations applied successfully")
+		migrationsPath = cfg.Migrations.Path
 	}
 
-	dsnForQuery := buildQueryDSN(cfg.Database, dbPassword)
+	create := migrator.Create
+	prefix := "Created"
+	if dryRun {
+		create = migrator.MigrationFiles
+		prefix = "dry-run: would create"
+	}
 
-	db, err := sql.Open("postgres", dsnForQuery)
+	files, err := create(migrationsPath, args[0])
 	if err != nil {
-		log.Fatalf("failed to open database: %v", err)
+		log.Println(err)
+		return exitError
 	}
-	defer db.Close()
+	for _, f := range files {
+		fmt.Fprintf(stdout, "%s %s\n", prefix, f)
+	}
+	return exitOK
+}
 
-	rows, err := db.Query(`
-		SELECT table_name 
-		FROM information_schema.tables 
-		WHERE table_schema = 'public'
-		ORDER BY table_name
-	`)
-	if err != nil {
-		log.Fatalf("failed to query tables: %v", err)
+// runSeed загружает фикстуры; в режиме dry-run только проверяет их и печатает план
+func runSeed(stdout io.Writer, configPath string, files []string, dryRun bool) int {
+	if len(files) == 0 {
+		log.Println("seed: expected at least one fixture file")
+		return exitUsage
 	}
-	defer rows.Close()
 
-	fmt.Println("Current tables in the database:")
-	for rows.Next() {
-		var tableName string
-		if err := rows.Scan(&tableName); err != nil {
-			log.Fatalf("failed to scan row: %v", err)
+	fixtures := make([]*seed.Fixture, 0, len(files))
+	for _, path := range files {
+		f, err := seed.Load(path)
+		if err != nil {
+			log.Println(err)
+			return exitError
 		}
-		fmt.Println(" -", tableName)
+		fixtures = append(fixtures, f)
+	}
+
+	if dryRun {
+		for _, f := range fixtures {
+			for _, m := range f.Merch {
+				fmt.Fprintf(stdout, "dry-run: would upsert merch %s (price %d)\n", m.Name, m.Price)
+			}
+			for _, u := range f.Users {
+				role := ""
+				if u.Admin {
+					role = ", admin"
+				}
+				fmt.Fprintf(stdout, "dry-run: would upsert user %s (opening balance %d%s)\n", u.Username, u.OpeningBalance(), role)
+			}
+		}
+		return exitOK
+	}
+
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		log.Println(err)
+		return exitError
+	}
+	dbPassword := GetEnv("DB_PASSWORD", "")
+
+	// подключение и проверка схемы — те же, что при старте сервера
+	appLog := logger.SetupLogger(cfg.Env)
+	application, err := app.NewApp(appLog, cfg)
+	if err != nil {
+		return fail(err, dbPassword)
+	}
+	defer application.Close()
+
+	st := application.Storage
+	seeder := seed.NewSeeder(appLog, st.TxManager, st.Users, st.Merch)
+	res, err := seeder.Apply(context.Background(), fixtures...)
+	if err != nil {
+		return fail(err, dbPassword)
 	}
-	if err := rows.Err(); err != nil {
-		log.Fatalf("error reading rows: %v", err)
+
+	fmt.Fprintf(stdout, "Seeded: merch upserted %d, users created %d, users existing %d\n",
+		res.MerchUpserted, res.UsersCreated, res.UsersExisting)
+	return exitOK
+}
+
+func loadConfig(path string) (*config.Config, error) {
+	if path == "" {
+		path = os.Getenv("CONFIG_PATH")
+	}
+	if path == "" {
+		return nil, fmt.Errorf("CONFIG_PATH not exists")
+	}
+	return config.Load(path)
+}
+
+// fail печатает ошибку без пароля и возвращает код выхода
+func fail(err error, dbPassword string) int {
+	msg := err.Error()
+	if dbPassword != "" {
+		msg = strings.ReplaceAll(msg, dbPassword, "xxxxx")
+	}
+	log.Println(msg)
+
+	if errors.Is(err, migrator.ErrDirty) {
+		return exitDirty
 	}
+	return exitError
 }
 
 func GetEnv(key, defaultValue string) string {
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		positional []string
+		dryRun     bool
+		config     string
+	}{
+		{name: "empty", args: nil},
+		{name: "flags first", args: []string{"-dry-run", "up", "2"}, positional: []string{"up", "2"}, dryRun: true},
+		{name: "flags after command", args: []string{"up", "-dry-run", "2"}, positional: []string{"up", "2"}, dryRun: true},
+		{name: "flags after arguments", args: []string{"up", "2", "-dry-run"}, positional: []string{"up", "2"}, dryRun: true},
+		{
+			name:       "flags between arguments",
+			args:       []string{"seed", "a.yaml", "-config", "local.yaml", "b.yaml", "-dry-run"},
+			positional: []string{"seed", "a.yaml", "b.yaml"},
+			dryRun:     true,
+			config:     "local.yaml",
+		},
+		{name: "negative number", args: []string{"force", "-1", "-dry-run"}, positional: []string{"force", "-1"}, dryRun: true},
+		{name: "terminator", args: []string{"create", "--", "-dry-run"}, positional: []string{"create", "-dry-run"}},
+		{name: "terminator after flag", args: []string{"create", "-dry-run", "--", "-x"}, positional: []string{"create", "-x"}, dryRun: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("migrator", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			dryRun := fs.Bool("dry-run", false, "")
+			config := fs.String("config", "", "")
+
+			positional, err := parseArgs(fs, tt.args)
+			require.NoError(t, err)
+			assert.Equal(t, tt.positional, positional)
+			assert.Equal(t, tt.dryRun, *dryRun)
+			assert.Equal(t, tt.config, *config)
+		})
+	}
+}
+
+func TestParseArgs_UnknownFlag(t *testing.T) {
+	fs := flag.NewFlagSet("migrator", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Bool("dry-run", false, "")
+
+	_, err := parseArgs(fs, []string{"up", "2", "-verbose"})
+	assert.Error(t, err)
+}
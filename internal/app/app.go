@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log/slog"
@@ -8,21 +9,143 @@ import (
 
 	_ "github.com/lib/pq"
 	"github.com/linemk/avito-shop/internal/config"
+	"github.com/linemk/avito-shop/internal/migrator"
+	"github.com/linemk/avito-shop/internal/storage"
+	"github.com/linemk/avito-shop/internal/storage/blob"
+	"github.com/linemk/avito-shop/internal/storage/memory"
 )
 
 type App struct {
-	Config *config.Config
-	Logger *slog.Logger
-	DB     *sql.DB
+	Config  *config.Config
+	Logger  *slog.Logger
+	DB      *sql.DB // nil, если используется хранилище в памяти
+	Storage Storage
+}
+
+// Storage — набор репозиториев, менеджер транзакций и хранилище изображений выбранного хранилища.
+type Storage struct {
+	TxManager        storage.TxManager
+	Users            storage.UserStorage
+	Merch            storage.MerchStorage
+	Orders           storage.OrderStorage
+	CoinTransactions storage.CoinTransactionStorage
+	CoinLots         storage.CoinLotStorage
+	CoinRequests     storage.CoinRequestStorage
+	Promotions       storage.PromotionStorage
+	Wishlists        storage.WishlistStorage
+	Notifications    storage.NotificationStorage
+	JobRuns          storage.JobRunStorage
+	// Locker — блокировки задач планировщика между репликами
+	Locker storage.Locker
+	// Images — изображения товаров
+	Images blob.Store
 }
 
 // NewApp создаёт новый экземпляр App
 func NewApp(log *slog.Logger, cfg *config.Config) (*App, error) {
+	app := &App{
+		Config: cfg,
+		Logger: log,
+	}
+
+	switch cfg.Storage {
+	case config.StorageMemory:
+		log.Warn("using in-memory storage, data will be lost on restart")
+		app.Storage = NewMemoryStorage(memory.New())
+	case config.StoragePostgres, "":
+		db, err := openPostgres(cfg)
+		if err != nil {
+			return nil, err
+		}
+		if err := ensureSchema(log, cfg, db); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+		images, err := blob.NewLocalStore(cfg.Images.Dir)
+		if err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+		app.DB = db
+		app.Storage = NewPostgresStorage(db, images)
+	default:
+		return nil, fmt.Errorf("unknown storage type %q", cfg.Storage)
+	}
+
+	return app, nil
+}
+
+// Close освобождает ресурсы приложения.
+func (a *App) Close() error {
+	if a.DB == nil {
+		return nil
+	}
+	return a.DB.Close()
+}
+
+// NewPostgresStorage собирает репозитории поверх PostgreSQL; изображения хранятся в images.
+func NewPostgresStorage(db *sql.DB, images blob.Store) Storage {
+	return Storage{
+		TxManager:        storage.NewTxManager(db),
+		Users:            storage.NewUserRepository(db),
+		Merch:            storage.NewMerchRepository(db),
+		Orders:           storage.NewOrderRepository(db),
+		CoinTransactions: storage.NewCoinTransactionRepository(db),
+		CoinLots:         storage.NewCoinLotRepository(db),
+		CoinRequests:     storage.NewCoinRequestRepository(db),
+		Promotions:       storage.NewPromotionRepository(db),
+		Wishlists:        storage.NewWishlistRepository(db),
+		Notifications:    storage.NewNotificationRepository(db),
+		JobRuns:          storage.NewJobRunRepository(db),
+		Locker:           storage.NewAdvisoryLocker(db),
+		Images:           images,
+	}
+}
+
+// NewMemoryStorage собирает репозитории и хранилище изображений в памяти и заполняет каталог
+// мерча по умолчанию.
+func NewMemoryStorage(db *memory.DB) Storage {
+	for _, m := range memory.DefaultCatalog {
+		db.AddCatalogItem(m)
+	}
+	return Storage{
+		TxManager:        memory.NewTxManager(db),
+		Users:            memory.NewUserRepository(db),
+		Merch:            memory.NewMerchRepository(db),
+		Orders:           memory.NewOrderRepository(db),
+		CoinTransactions: memory.NewCoinTransactionRepository(db),
+		CoinLots:         memory.NewCoinLotRepository(db),
+		CoinRequests:     memory.NewCoinRequestRepository(db),
+		Promotions:       memory.NewPromotionRepository(db),
+		Wishlists:        memory.NewWishlistRepository(db),
+		Notifications:    memory.NewNotificationRepository(db),
+		JobRuns:          memory.NewJobRunRepository(db),
+		Locker:           memory.NewLocker(),
+		Images:           blob.NewMemoryStore(),
+	}
+}
+
+// ensureSchema применяет миграции (если включено auto_migrate) и отказывается запускать
+// сервер на схеме в состоянии dirty, на отстающей или неизвестной версии.
+func ensureSchema(log *slog.Logger, cfg *config.Config, db *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Migrations.LockTimeout)
+	defer cancel()
+
+	if err := migrator.EnsureSchema(ctx, log, db, cfg.Migrations.AutoMigrate); err != nil {
+		return fmt.Errorf("database schema check failed: %w", err)
+	}
+	return nil
+}
 
+// openPostgres подключается к PostgreSQL и проверяет соединение.
+func openPostgres(cfg *config.Config) (*sql.DB, error) {
 	dbPassword := os.Getenv("DB_PASSWORD")
 	if dbPassword == "" {
 		return nil, fmt.Errorf("DB_PASSWORD environment variable is not set")
 	}
+	if cfg.Database.User == "" || cfg.Database.Name == "" {
+		return nil, fmt.Errorf("database user and name must be set for postgres storage")
+	}
 	// реализуем подключение к БД через DSN
 	dsn := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable",
 		cfg.Database.User,
@@ -38,14 +161,9 @@ func NewApp(log *slog.Logger, cfg *config.Config) (*App, error) {
 	}
 
 	if err := db.Ping(); err != nil {
+		_ = db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	app := &App{
-		Config: cfg,
-		Logger: log,
-		DB:     db,
-	}
-
-	return app, nil
+	return db, nil
 }
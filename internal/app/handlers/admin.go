@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/linemk/avito-shop/internal/jwt-new/jwtmiddleware"
+	"github.com/linemk/avito-shop/internal/service"
+)
+
+// maxGrantBodySize — ограничение на размер тела массового начисления.
+const maxGrantBodySize = 4 << 20
+
+// AdjustBalanceRequest — запрос на начисление или списание монет администратором.
+type AdjustBalanceRequest struct {
+	Username string `json:"username" validate:"required,email"`
+	Amount   int    `json:"amount" validate:"required,gt=0"`
+	Reason   string `json:"reason" validate:"required"`
+}
+
+// AdjustBalanceResponse — новый баланс пользователя.
+type AdjustBalanceResponse struct {
+	Username string `json:"username"`
+	Balance  int    `json:"balance"`
+}
+
+// AdminCreditHandler обрабатывает запрос POST /api/admin/credit.
+func AdminCreditHandler(log *slog.Logger, adminService service.AdminService) http.HandlerFunc {
+	return adjustBalanceHandler(log, adminService, "handlers.AdminCreditHandler", 1)
+}
+
+// AdminDebitHandler обрабатывает запрос POST /api/admin/debit.
+func AdminDebitHandler(log *slog.Logger, adminService service.AdminService) http.HandlerFunc {
+	return adjustBalanceHandler(log, adminService, "handlers.AdminDebitHandler", -1)
+}
+
+// adjustBalanceHandler — общий обработчик начисления (sign = 1) и списания (sign = -1).
+func adjustBalanceHandler(log *slog.Logger, adminService service.AdminService, op string, sign int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := log.With(slog.String("op", op))
+
+		var req AdjustBalanceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("invalid request: decoding error", slog.Any("error", err))
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+		if err := validate.Struct(req); err != nil {
+			logger.Error("invalid request: validation error", slog.Any("error", err))
+			http.Error(w, "validation error", http.StatusBadRequest)
+			return
+		}
+
+		adminID, ok := jwtmiddleware.FromContext(r.Context())
+		if !ok {
+			logger.Error("userID not found in context")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		balance, err := adminService.AdjustBalance(r.Context(), adminID, req.Username, sign*req.Amount, req.Reason)
+		if err != nil {
+			logger.Error("failed to adjust balance", slog.Any("error", err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, AdjustBalanceResponse{Username: req.Username, Balance: balance})
+	}
+}
+
+// AdminGrantHandler обрабатывает запрос POST /api/admin/grants.
+// Тело — JSON (service.BulkGrant) или CSV (Content-Type: text/csv) со строками
+// "username,amount" и параметрами id, reason, mode в query.
+// Если пакетное начисление остановилось на ошибке, возвращается 422 с частичным результатом.
+func AdminGrantHandler(log *slog.Logger, adminService service.AdminService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.AdminGrantHandler"
+		logger := log.With(slog.String("op", op))
+
+		body := http.MaxBytesReader(w, r.Body, maxGrantBodySize)
+		var (
+			grant service.BulkGrant
+			err   error
+		)
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType == "text/csv" {
+			q := r.URL.Query()
+			grant = service.BulkGrant{ID: q.Get("id"), Reason: q.Get("reason"), Mode: q.Get("mode")}
+			grant.Items, err = parseGrantCSV(body)
+		} else {
+			err = json.NewDecoder(body).Decode(&grant)
+		}
+		if err != nil {
+			logger.Error("invalid request: decoding error", slog.Any("error", err))
+			http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		adminID, ok := jwtmiddleware.FromContext(r.Context())
+		if !ok {
+			logger.Error("userID not found in context")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		res, err := adminService.BulkGrant(r.Context(), adminID, grant)
+		if errors.Is(err, service.ErrGrantIncomplete) && res != nil {
+			logger.Error("grant incomplete", slog.Any("error", err))
+			writeJSON(w, logger, http.StatusUnprocessableEntity, res)
+			return
+		}
+		if err != nil {
+			logger.Error("failed to grant coins", slog.Any("error", err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, res)
+	}
+}
+
+// parseGrantCSV читает строки "username,amount"; строка заголовка (username,amount) пропускается.
+func parseGrantCSV(r io.Reader) ([]service.GrantItem, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+
+	var items []service.GrantItem
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return items, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line == 1 && strings.EqualFold(record[0], "username") {
+			continue
+		}
+		amount, err := strconv.Atoi(strings.TrimSpace(record[1]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid amount %q", line, record[1])
+		}
+		items = append(items, service.GrantItem{Username: strings.TrimSpace(record[0]), Amount: amount})
+	}
+}
+
+// writeJSON пишет ответ в формате JSON с заданным статусом.
+func writeJSON(w http.ResponseWriter, logger *slog.Logger, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Error("failed to encode response", slog.Any("error", err))
+	}
+}
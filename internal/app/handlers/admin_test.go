@@ -0,0 +1,119 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/linemk/avito-shop/internal/app/handlers"
+	"github.com/linemk/avito-shop/internal/jwt-new/jwtmiddleware"
+	"github.com/linemk/avito-shop/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAdminService запоминает последний вызов и возвращает заданный результат.
+type fakeAdminService struct {
+	amount int
+	grant  service.BulkGrant
+	res    *service.BulkGrantResult
+	err    error
+}
+
+func (f *fakeAdminService) AdjustBalance(ctx context.Context, adminID int64, username string, amount int, reason string) (int, error) {
+	f.amount = amount
+	return 100 + amount, f.err
+}
+
+func (f *fakeAdminService) BulkGrant(ctx context.Context, adminID int64, grant service.BulkGrant) (*service.BulkGrantResult, error) {
+	f.grant = grant
+	return f.res, f.err
+}
+
+func adminRequest(method, target, contentType, body string) *http.Request {
+	req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", contentType)
+	return req.WithContext(context.WithValue(req.Context(), jwtmiddleware.UserIDKey, int64(1)))
+}
+
+func TestAdminDebitHandler_NegatesAmount(t *testing.T) {
+	fakeSvc := &fakeAdminService{}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	req := adminRequest("POST", "/api/admin/debit", "application/json",
+		`{"username": "user@example.com", "amount": 30, "reason": "correction"}`)
+	rr := httptest.NewRecorder()
+	handlers.AdminDebitHandler(logger, fakeSvc).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, -30, fakeSvc.amount)
+	var resp handlers.AdjustBalanceResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	assert.Equal(t, 70, resp.Balance)
+}
+
+func TestAdminCreditHandler_ReasonRequired(t *testing.T) {
+	fakeSvc := &fakeAdminService{}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	req := adminRequest("POST", "/api/admin/credit", "application/json",
+		`{"username": "user@example.com", "amount": 30}`)
+	rr := httptest.NewRecorder()
+	handlers.AdminCreditHandler(logger, fakeSvc).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestAdminGrantHandler_CSV(t *testing.T) {
+	fakeSvc := &fakeAdminService{res: &service.BulkGrantResult{ID: "q3", Applied: 2, Completed: true}}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	req := adminRequest("POST", "/api/admin/grants?id=q3&reason=bonus&mode=batched", "text/csv",
+		"username,amount\nalice@example.com,100\nbob@example.com, 200\n")
+	rr := httptest.NewRecorder()
+	handlers.AdminGrantHandler(logger, fakeSvc).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, service.BulkGrant{
+		ID:     "q3",
+		Reason: "bonus",
+		Mode:   service.GrantModeBatched,
+		Items: []service.GrantItem{
+			{Username: "alice@example.com", Amount: 100},
+			{Username: "bob@example.com", Amount: 200},
+		},
+	}, fakeSvc.grant)
+}
+
+func TestAdminGrantHandler_IncompleteReturnsPartialResult(t *testing.T) {
+	partial := &service.BulkGrantResult{ID: "q3", Mode: service.GrantModeBatched, Applied: 100, Error: "user x: not found"}
+	fakeSvc := &fakeAdminService{res: partial, err: fmt.Errorf("op: %w", service.ErrGrantIncomplete)}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	req := adminRequest("POST", "/api/admin/grants", "application/json",
+		`{"id": "q3", "reason": "bonus", "mode": "batched", "items": [{"username": "a@example.com", "amount": 1}]}`)
+	rr := httptest.NewRecorder()
+	handlers.AdminGrantHandler(logger, fakeSvc).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	var resp service.BulkGrantResult
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	assert.Equal(t, *partial, resp)
+}
+
+func TestAdminGrantHandler_InvalidCSV(t *testing.T) {
+	fakeSvc := &fakeAdminService{}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	req := adminRequest("POST", "/api/admin/grants?id=q3&reason=bonus", "text/csv", "alice@example.com,lots\n")
+	rr := httptest.NewRecorder()
+	handlers.AdminGrantHandler(logger, fakeSvc).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 
@@ -15,7 +16,8 @@ type BuyResponse struct {
 	Message string `json:"message"`
 }
 
-// BuyHandler обрабатывает запрос GET /api/buy/{item}
+// BuyHandler обрабатывает запрос GET /api/buy/{item}?variant=&code= — variant нужен товару с вариантами,
+// code необязателен.
 func BuyHandler(log *slog.Logger, buyService service.BuyService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.BuyHandler"
@@ -38,9 +40,9 @@ func BuyHandler(log *slog.Logger, buyService service.BuyService) http.HandlerFun
 		}
 
 		// Вызываем бизнес-логику для покупки
-		if err := buyService.Buy(r.Context(), userID, item); err != nil {
+		if err := buyService.Buy(r.Context(), userID, item, r.URL.Query().Get("variant"), r.URL.Query().Get("code")); err != nil {
 			logger.Error("failed to complete purchase", slog.Any("error", err))
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			http.Error(w, err.Error(), purchaseErrorStatus(err))
 			return
 		}
 
@@ -54,3 +56,58 @@ func BuyHandler(log *slog.Logger, buyService service.BuyService) http.HandlerFun
 		}
 	}
 }
+
+// GiftRequest — покупка товара Item (варианта Variant) в подарок пользователю ToUser.
+type GiftRequest struct {
+	ToUser  string `json:"toUser" validate:"required,email"`
+	Item    string `json:"item" validate:"required"`
+	Variant string `json:"variant,omitempty"`
+}
+
+// GiftHandler обрабатывает запрос POST /api/gift.
+func GiftHandler(log *slog.Logger, buyService service.BuyService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.GiftHandler"
+		logger := log.With(slog.String("op", op))
+
+		var req GiftRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("invalid request: decoding error", slog.Any("error", err))
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+		if err := validate.Struct(req); err != nil {
+			logger.Error("invalid request: validation error", slog.Any("error", err))
+			http.Error(w, "validation error", http.StatusBadRequest)
+			return
+		}
+
+		userID, ok := jwtmiddleware.FromContext(r.Context())
+		if !ok {
+			logger.Error("userID not found in context")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if err := buyService.Gift(r.Context(), userID, req.Item, req.Variant, req.ToUser); err != nil {
+			logger.Error("failed to complete gift", slog.Any("error", err))
+			http.Error(w, err.Error(), purchaseErrorStatus(err))
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, BuyResponse{Message: "Gift sent successfully"})
+	}
+}
+
+// purchaseErrorStatus отличает закончившийся товар (409) и исчерпанный лимит покупок или промокода (422)
+// от остальных ошибок покупки: нехватки монет, неизвестного товара, варианта, получателя или промокода (400).
+func purchaseErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrSoldOut):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrPurchaseLimit), errors.Is(err, service.ErrPromoCodeExhausted):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
@@ -29,20 +30,42 @@ func (f *fakeAuthService) Login(ctx context.Context, username, password string)
 }
 
 type fakeInfoService struct {
-	resp *service.InfoResponse
-	err  error
+	resp    *service.InfoResponse
+	entries []service.TransactionEntry
+	err     error
+	// query и limit последнего вызова SearchHistory
+	query string
+	limit int
 }
 
 func (f *fakeInfoService) GetInfo(ctx context.Context, userID int64) (*service.InfoResponse, error) {
 	return f.resp, f.err
 }
 
+func (f *fakeInfoService) SearchHistory(ctx context.Context, userID int64, query string, limit int) ([]service.TransactionEntry, error) {
+	f.query, f.limit = query, limit
+	return f.entries, f.err
+}
+
+// fakeSendCoinService — фиктивная реализация интерфейса SendCoinService
+type fakeSendCoinService struct {
+	err error
+}
+
+func (f *fakeSendCoinService) SendCoin(ctx context.Context, fromUserID int64, toUser string, amount int, comment string) error {
+	return f.err
+}
+
 // fakeBuyService — фиктивная реализация интерфейса BuyService
 type fakeBuyService struct {
 	err error
 }
 
-func (f *fakeBuyService) Buy(ctx context.Context, userID int64, item string) error {
+func (f *fakeBuyService) Buy(ctx context.Context, userID int64, item, variant, code string) error {
+	return f.err
+}
+
+func (f *fakeBuyService) Gift(ctx context.Context, userID int64, item, variant, toUser string) error {
 	return f.err
 }
 
@@ -195,6 +218,53 @@ func TestInfoHandler_ServiceError(t *testing.T) {
 	assert.Equal(t, http.StatusInternalServerError, rr.Code, "Expected status 500 when service returns error")
 }
 
+func TestHistoryHandler_PassesQuery(t *testing.T) {
+	fakeSvc := &fakeInfoService{entries: []service.TransactionEntry{
+		{ID: 1, Type: "transfer_received", Amount: 50, FromUser: "userA", Comment: "thanks for the review"},
+	}}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	handler := handlers.HistoryHandler(logger, fakeSvc)
+
+	req := httptest.NewRequest("GET", "/api/history?q=review&limit=10", nil)
+	req = req.WithContext(context.WithValue(req.Context(), jwtmiddleware.UserIDKey, int64(1)))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "review", fakeSvc.query)
+	assert.Equal(t, 10, fakeSvc.limit)
+	var resp handlers.HistoryResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	assert.Len(t, resp.Transactions, 1)
+	assert.Equal(t, "thanks for the review", resp.Transactions[0].Comment)
+}
+
+func TestHistoryHandler_InvalidLimit(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	handler := handlers.HistoryHandler(logger, &fakeInfoService{})
+
+	req := httptest.NewRequest("GET", "/api/history?limit=abc", nil)
+	req = req.WithContext(context.WithValue(req.Context(), jwtmiddleware.UserIDKey, int64(1)))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestSendCoinHandler_LimitExceeded(t *testing.T) {
+	fakeSvc := &fakeSendCoinService{err: fmt.Errorf("op: %w: amount exceeds 100 per transfer", service.ErrTransferLimit)}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	handler := handlers.SendCoinHandler(logger, fakeSvc)
+
+	req := httptest.NewRequest("POST", "/api/sendCoin", bytes.NewBufferString(`{"toUser": "bob@example.com", "amount": 500}`))
+	req = req.WithContext(context.WithValue(req.Context(), jwtmiddleware.UserIDKey, int64(1)))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Contains(t, rr.Body.String(), "transfer limit exceeded")
+}
+
 // TestBuyHandler_Success проверяет успешный сценарий покупки товара.
 func TestBuyHandler_Success(t *testing.T) {
 	fakeSvc := &fakeBuyService{err: nil}
@@ -283,3 +353,57 @@ func TestBuyHandler_ServiceError(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code, "Expected Bad Request when service returns an error")
 }
+
+func TestGiftHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		err    error
+		status int
+	}{
+		{name: "success", body: `{"toUser": "bob@example.com", "item": "cup"}`, status: http.StatusOK},
+		{name: "missing item", body: `{"toUser": "bob@example.com"}`, status: http.StatusBadRequest},
+		{name: "bad recipient", body: `{"toUser": "bob", "item": "cup"}`, status: http.StatusBadRequest},
+		{name: "service error", body: `{"toUser": "bob@example.com", "item": "cup"}`, err: service.ErrInvalidGift, status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+			handler := handlers.GiftHandler(logger, &fakeBuyService{err: tt.err})
+
+			req := httptest.NewRequest("POST", "/api/gift", bytes.NewBufferString(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), jwtmiddleware.UserIDKey, int64(1)))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.status, rr.Code)
+		})
+	}
+}
+
+func TestBuyHandler_ErrorStatus(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{err: service.ErrInsufficientFunds, status: http.StatusBadRequest},
+		{err: service.ErrSoldOut, status: http.StatusConflict},
+		{err: fmt.Errorf("op: %w: 1 per person", service.ErrPurchaseLimit), status: http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+			handler := handlers.BuyHandler(logger, &fakeBuyService{err: tt.err})
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("item", "pink-hoody")
+			req := httptest.NewRequest("GET", "/api/buy/pink-hoody", nil)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			req = req.WithContext(context.WithValue(req.Context(), jwtmiddleware.UserIDKey, int64(1)))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.status, rr.Code)
+		})
+	}
+}
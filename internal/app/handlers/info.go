@@ -4,34 +4,12 @@ import (
 	"encoding/json"
 	"log/slog"
 	"net/http"
+	"strconv"
 
 	"github.com/linemk/avito-shop/internal/jwt-new/jwtmiddleware"
 	"github.com/linemk/avito-shop/internal/service"
 )
 
-// InfoResponse — структура ответа, соответствующая OpenAPI.
-type InfoResponse struct {
-	Coins       int             `json:"coins"`
-	Inventory   []InventoryItem `json:"inventory"`
-	CoinHistory CoinHistory     `json:"coinHistory"`
-}
-
-type InventoryItem struct {
-	Type     string `json:"type"`
-	Quantity int    `json:"quantity"`
-}
-
-type CoinHistory struct {
-	Received []HistoryEntry `json:"received"`
-	Sent     []HistoryEntry `json:"sent"`
-}
-
-type HistoryEntry struct {
-	FromUser string `json:"fromUser,omitempty"`
-	ToUser   string `json:"toUser,omitempty"`
-	Amount   int    `json:"amount"`
-}
-
 // InfoHandler обрабатывает запрос GET /api/info.
 // Он извлекает идентификатор пользователя из контекста (установленный JWT‑middleware),
 // затем вызывает сервис InfoService для получения информации о балансе, инвентаре и истории транзакций
@@ -64,3 +42,45 @@ func InfoHandler(log *slog.Logger, infoService service.InfoService) http.Handler
 		}
 	}
 }
+
+// HistoryResponse — результат поиска по истории операций.
+type HistoryResponse struct {
+	Transactions []service.TransactionEntry `json:"transactions"`
+}
+
+// HistoryHandler обрабатывает запрос GET /api/history?q=ревью&limit=20 —
+// поиск по комментариям в истории операций пользователя.
+func HistoryHandler(log *slog.Logger, infoService service.InfoService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.HistoryHandler"
+		logger := log.With(slog.String("op", op))
+
+		userID, ok := jwtmiddleware.FromContext(r.Context())
+		if !ok {
+			logger.Error("userID not found in context")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		q := r.URL.Query()
+		limit := 0
+		if v := q.Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				logger.Error("invalid request: bad limit", slog.String("limit", v))
+				http.Error(w, "invalid limit", http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+
+		entries, err := infoService.SearchHistory(r.Context(), userID, q.Get("q"), limit)
+		if err != nil {
+			logger.Error("failed to search history", slog.Any("error", err))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, HistoryResponse{Transactions: entries})
+	}
+}
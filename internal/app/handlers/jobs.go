@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/service"
+)
+
+// JobRunsResponse — история запусков фоновых задач.
+type JobRunsResponse struct {
+	Runs []models.JobRun `json:"runs"`
+}
+
+// AdminJobRunsHandler обрабатывает запрос GET /api/admin/jobs/runs?job=allowance&limit=20.
+func AdminJobRunsHandler(log *slog.Logger, jobService service.JobService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.AdminJobRunsHandler"
+		logger := log.With(slog.String("op", op))
+
+		q := r.URL.Query()
+		limit := 0
+		if v := q.Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				logger.Error("invalid request: bad limit", slog.String("limit", v))
+				http.Error(w, "invalid limit", http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+
+		runs, err := jobService.Runs(r.Context(), q.Get("job"), limit)
+		if err != nil {
+			logger.Error("failed to list job runs", slog.Any("error", err))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, JobRunsResponse{Runs: runs})
+	}
+}
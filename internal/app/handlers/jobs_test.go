@@ -0,0 +1,46 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/linemk/avito-shop/internal/app/handlers"
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeJobService запоминает параметры запроса истории.
+type fakeJobService struct {
+	job   string
+	limit int
+}
+
+func (f *fakeJobService) Runs(ctx context.Context, job string, limit int) ([]models.JobRun, error) {
+	f.job, f.limit = job, limit
+	return []models.JobRun{{ID: 1, Job: job, Status: models.JobRunSucceeded}}, nil
+}
+
+func TestAdminJobRunsHandler(t *testing.T) {
+	fakeSvc := &fakeJobService{}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	rr := httptest.NewRecorder()
+	handlers.AdminJobRunsHandler(logger, fakeSvc).ServeHTTP(rr, httptest.NewRequest("GET", "/api/admin/jobs/runs?job=allowance&limit=5", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "allowance", fakeSvc.job)
+	assert.Equal(t, 5, fakeSvc.limit)
+	var resp handlers.JobRunsResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	assert.Len(t, resp.Runs, 1)
+
+	rr = httptest.NewRecorder()
+	handlers.AdminJobRunsHandler(logger, fakeSvc).ServeHTTP(rr, httptest.NewRequest("GET", "/api/admin/jobs/runs?limit=-1", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,390 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/linemk/avito-shop/internal/jwt-new/jwtmiddleware"
+	"github.com/linemk/avito-shop/internal/service"
+	"github.com/linemk/avito-shop/internal/storage"
+)
+
+// CatalogResponse — каталог мерча с остатками.
+type CatalogResponse struct {
+	Items []service.CatalogItem `json:"items"`
+}
+
+// CatalogHandler обрабатывает запрос GET /api/merch. Необязательные параметры отбора:
+// category, tag, q (поиск по названию, описанию и тегам), minPrice и maxPrice.
+func CatalogHandler(log *slog.Logger, merchService service.MerchService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.CatalogHandler"
+		logger := log.With(slog.String("op", op))
+
+		query := r.URL.Query()
+		filter := service.CatalogFilter{
+			Category: query.Get("category"),
+			Tag:      query.Get("tag"),
+			Query:    query.Get("q"),
+		}
+		var err error
+		if filter.MinPrice, err = parsePriceParam(query.Get("minPrice")); err != nil {
+			logger.Error("invalid request: bad minPrice", slog.String("minPrice", query.Get("minPrice")))
+			http.Error(w, "invalid minPrice", http.StatusBadRequest)
+			return
+		}
+		if filter.MaxPrice, err = parsePriceParam(query.Get("maxPrice")); err != nil {
+			logger.Error("invalid request: bad maxPrice", slog.String("maxPrice", query.Get("maxPrice")))
+			http.Error(w, "invalid maxPrice", http.StatusBadRequest)
+			return
+		}
+
+		items, err := merchService.Catalog(r.Context(), filter)
+		if err != nil {
+			logger.Error("failed to get catalog", slog.Any("error", err))
+			if errors.Is(err, service.ErrInvalidCatalogFilter) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, CatalogResponse{Items: items})
+	}
+}
+
+// RestockRequest — пополнение склада на Quantity единиц.
+type RestockRequest struct {
+	Quantity int `json:"quantity" validate:"required,gt=0"`
+}
+
+// SetStockRequest — точный остаток товара; null снимает ограничение количества.
+type SetStockRequest struct {
+	Stock *int `json:"stock"`
+}
+
+// AdminRestockHandler обрабатывает запрос POST /api/admin/merch/{item}/restock.
+func AdminRestockHandler(log *slog.Logger, merchService service.MerchService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.AdminRestockHandler"
+		logger := log.With(slog.String("op", op))
+
+		var req RestockRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("invalid request: decoding error", slog.Any("error", err))
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+		if err := validate.Struct(req); err != nil {
+			logger.Error("invalid request: validation error", slog.Any("error", err))
+			http.Error(w, "validation error", http.StatusBadRequest)
+			return
+		}
+
+		item, err := merchService.Restock(r.Context(), chi.URLParam(r, "item"), req.Quantity)
+		if err != nil {
+			logger.Error("failed to restock merch", slog.Any("error", err))
+			http.Error(w, err.Error(), merchErrorStatus(err))
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, item)
+	}
+}
+
+// AdminSetStockHandler обрабатывает запрос PUT /api/admin/merch/{item}/stock.
+func AdminSetStockHandler(log *slog.Logger, merchService service.MerchService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.AdminSetStockHandler"
+		logger := log.With(slog.String("op", op))
+
+		var req SetStockRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("invalid request: decoding error", slog.Any("error", err))
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+
+		item, err := merchService.SetStock(r.Context(), chi.URLParam(r, "item"), req.Stock)
+		if err != nil {
+			logger.Error("failed to set merch stock", slog.Any("error", err))
+			http.Error(w, err.Error(), merchErrorStatus(err))
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, item)
+	}
+}
+
+// SetPurchaseLimitRequest — лимит покупок товара одним сотрудником за период
+// (month, quarter, year; пусто — за всё время). Limit = 0 снимает ограничение.
+type SetPurchaseLimitRequest struct {
+	Limit  int    `json:"limit" validate:"gte=0"`
+	Period string `json:"period,omitempty"`
+}
+
+// AdminSetPurchaseLimitHandler обрабатывает запрос PUT /api/admin/merch/{item}/limit.
+func AdminSetPurchaseLimitHandler(log *slog.Logger, merchService service.MerchService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.AdminSetPurchaseLimitHandler"
+		logger := log.With(slog.String("op", op))
+
+		var req SetPurchaseLimitRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("invalid request: decoding error", slog.Any("error", err))
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+		if err := validate.Struct(req); err != nil {
+			logger.Error("invalid request: validation error", slog.Any("error", err))
+			http.Error(w, "validation error", http.StatusBadRequest)
+			return
+		}
+
+		var limit *service.PurchaseLimit
+		if req.Limit > 0 {
+			limit = &service.PurchaseLimit{Limit: req.Limit, Period: req.Period}
+		}
+		item, err := merchService.SetPurchaseLimit(r.Context(), chi.URLParam(r, "item"), limit)
+		if err != nil {
+			logger.Error("failed to set purchase limit", slog.Any("error", err))
+			http.Error(w, err.Error(), merchErrorStatus(err))
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, item)
+	}
+}
+
+// PriceHistoryHandler обрабатывает запрос GET /api/merch/{item}/prices.
+func PriceHistoryHandler(log *slog.Logger, merchService service.MerchService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.PriceHistoryHandler"
+		logger := log.With(slog.String("op", op))
+
+		history, err := merchService.PriceHistory(r.Context(), chi.URLParam(r, "item"))
+		if err != nil {
+			logger.Error("failed to get price history", slog.Any("error", err))
+			if errors.Is(err, storage.ErrMerchNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, history)
+	}
+}
+
+// SetPriceRequest — новая цена товара. Без effectiveFrom (или с прошедшим временем) цена
+// меняется сразу, иначе изменение планируется, например на начало распродажи.
+type SetPriceRequest struct {
+	Price         int        `json:"price" validate:"required,gt=0"`
+	EffectiveFrom *time.Time `json:"effectiveFrom,omitempty"`
+	Comment       string     `json:"comment,omitempty"`
+}
+
+// AdminSetPriceHandler обрабатывает запрос POST /api/admin/merch/{item}/prices.
+func AdminSetPriceHandler(log *slog.Logger, merchService service.MerchService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.AdminSetPriceHandler"
+		logger := log.With(slog.String("op", op))
+
+		var req SetPriceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("invalid request: decoding error", slog.Any("error", err))
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+		if err := validate.Struct(req); err != nil {
+			logger.Error("invalid request: validation error", slog.Any("error", err))
+			http.Error(w, "validation error", http.StatusBadRequest)
+			return
+		}
+
+		adminID, ok := jwtmiddleware.FromContext(r.Context())
+		if !ok {
+			logger.Error("userID not found in context")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var effectiveFrom time.Time
+		if req.EffectiveFrom != nil {
+			effectiveFrom = *req.EffectiveFrom
+		}
+		entry, err := merchService.SetPrice(r.Context(), adminID, chi.URLParam(r, "item"), req.Price, effectiveFrom, req.Comment)
+		if err != nil {
+			logger.Error("failed to set merch price", slog.Any("error", err))
+			http.Error(w, err.Error(), merchErrorStatus(err))
+			return
+		}
+
+		writeJSON(w, logger, http.StatusCreated, entry)
+	}
+}
+
+// PriceChangeResponse — результат отмены запланированного изменения цены.
+type PriceChangeResponse struct {
+	Message string `json:"message"`
+}
+
+// AdminCancelPriceChangeHandler обрабатывает запрос DELETE /api/admin/merch/{item}/prices/{id}.
+func AdminCancelPriceChangeHandler(log *slog.Logger, merchService service.MerchService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.AdminCancelPriceChangeHandler"
+		logger := log.With(slog.String("op", op))
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil || id <= 0 {
+			logger.Error("invalid request: bad id", slog.String("id", chi.URLParam(r, "id")))
+			http.Error(w, "invalid price change id", http.StatusBadRequest)
+			return
+		}
+
+		if err := merchService.CancelPriceChange(r.Context(), chi.URLParam(r, "item"), id); err != nil {
+			logger.Error("failed to cancel price change", slog.Any("error", err))
+			http.Error(w, err.Error(), merchErrorStatus(err))
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, PriceChangeResponse{Message: "Price change cancelled"})
+	}
+}
+
+// CreateVariantRequest — новый вариант товара. Price и Stock необязательны: без них действуют
+// цена и остаток товара.
+type CreateVariantRequest struct {
+	SKU   string `json:"sku" validate:"required"`
+	Size  string `json:"size,omitempty"`
+	Color string `json:"color,omitempty"`
+	Price *int   `json:"price,omitempty" validate:"omitempty,gt=0"`
+	Stock *int   `json:"stock,omitempty" validate:"omitempty,gte=0"`
+}
+
+// UpdateVariantRequest — собственные цена и остаток варианта; null — действуют цена и остаток товара.
+type UpdateVariantRequest struct {
+	Price *int `json:"price" validate:"omitempty,gt=0"`
+	Stock *int `json:"stock" validate:"omitempty,gte=0"`
+}
+
+// VariantResponse — результат снятия варианта с продажи.
+type VariantResponse struct {
+	Message string `json:"message"`
+}
+
+// AdminCreateVariantHandler обрабатывает запрос POST /api/admin/merch/{item}/variants.
+func AdminCreateVariantHandler(log *slog.Logger, merchService service.MerchService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.AdminCreateVariantHandler"
+		logger := log.With(slog.String("op", op))
+
+		var req CreateVariantRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("invalid request: decoding error", slog.Any("error", err))
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+		if err := validate.Struct(req); err != nil {
+			logger.Error("invalid request: validation error", slog.Any("error", err))
+			http.Error(w, "validation error", http.StatusBadRequest)
+			return
+		}
+
+		variant, err := merchService.CreateVariant(r.Context(), chi.URLParam(r, "item"), service.VariantInput{
+			SKU:   req.SKU,
+			Size:  req.Size,
+			Color: req.Color,
+			Price: req.Price,
+			Stock: req.Stock,
+		})
+		if err != nil {
+			logger.Error("failed to create merch variant", slog.Any("error", err))
+			http.Error(w, err.Error(), merchErrorStatus(err))
+			return
+		}
+
+		writeJSON(w, logger, http.StatusCreated, variant)
+	}
+}
+
+// AdminUpdateVariantHandler обрабатывает запрос PUT /api/admin/merch/{item}/variants/{sku}.
+func AdminUpdateVariantHandler(log *slog.Logger, merchService service.MerchService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.AdminUpdateVariantHandler"
+		logger := log.With(slog.String("op", op))
+
+		var req UpdateVariantRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("invalid request: decoding error", slog.Any("error", err))
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+		if err := validate.Struct(req); err != nil {
+			logger.Error("invalid request: validation error", slog.Any("error", err))
+			http.Error(w, "validation error", http.StatusBadRequest)
+			return
+		}
+
+		variant, err := merchService.UpdateVariant(r.Context(), chi.URLParam(r, "item"), chi.URLParam(r, "sku"), req.Price, req.Stock)
+		if err != nil {
+			logger.Error("failed to update merch variant", slog.Any("error", err))
+			http.Error(w, err.Error(), merchErrorStatus(err))
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, variant)
+	}
+}
+
+// AdminDeleteVariantHandler обрабатывает запрос DELETE /api/admin/merch/{item}/variants/{sku}.
+func AdminDeleteVariantHandler(log *slog.Logger, merchService service.MerchService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.AdminDeleteVariantHandler"
+		logger := log.With(slog.String("op", op))
+
+		if err := merchService.DeleteVariant(r.Context(), chi.URLParam(r, "item"), chi.URLParam(r, "sku")); err != nil {
+			logger.Error("failed to delete merch variant", slog.Any("error", err))
+			http.Error(w, err.Error(), merchErrorStatus(err))
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, VariantResponse{Message: "Variant removed from sale"})
+	}
+}
+
+// merchErrorStatus выбирает HTTP-статус для ошибок управления остатками, ценами, вариантами и изображениями.
+func merchErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrMerchNotFound), errors.Is(err, storage.ErrPriceChangeNotFound),
+		errors.Is(err, storage.ErrVariantNotFound), errors.Is(err, service.ErrImageNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrPriceChangeApplied), errors.Is(err, storage.ErrVariantExists):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrImageTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, service.ErrInvalidImage):
+		return http.StatusUnsupportedMediaType
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// parsePriceParam разбирает необязательный параметр цены; пустая строка — без ограничения.
+func parsePriceParam(value string) (*int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	price, err := strconv.Atoi(value)
+	if err != nil || price < 0 {
+		return nil, errors.New("price must be a non-negative integer")
+	}
+	return &price, nil
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/linemk/avito-shop/internal/service"
+)
+
+// CategoriesResponse — категории каталога.
+type CategoriesResponse struct {
+	Categories []service.CatalogCategory `json:"categories"`
+}
+
+// SetMerchDetailsRequest — карточка товара. Пустые поля очищают категорию, описание и теги.
+type SetMerchDetailsRequest struct {
+	Category    string   `json:"category"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+}
+
+// CategoriesHandler обрабатывает запрос GET /api/merch/categories.
+func CategoriesHandler(log *slog.Logger, merchService service.MerchService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.CategoriesHandler"
+		logger := log.With(slog.String("op", op))
+
+		categories, err := merchService.Categories(r.Context())
+		if err != nil {
+			logger.Error("failed to list categories", slog.Any("error", err))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, CategoriesResponse{Categories: categories})
+	}
+}
+
+// MerchImageHandler обрабатывает запрос GET /api/merch/{item}/image.
+func MerchImageHandler(log *slog.Logger, merchService service.MerchService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.MerchImageHandler"
+		logger := log.With(slog.String("op", op))
+
+		image, contentType, err := merchService.Image(r.Context(), chi.URLParam(r, "item"))
+		if err != nil {
+			logger.Error("failed to open merch image", slog.Any("error", err))
+			status := merchErrorStatus(err)
+			if status == http.StatusBadRequest {
+				status = http.StatusInternalServerError
+			}
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		defer image.Close()
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.Copy(w, image); err != nil {
+			logger.Error("failed to write merch image", slog.Any("error", err))
+		}
+	}
+}
+
+// AdminSetMerchDetailsHandler обрабатывает запрос PUT /api/admin/merch/{item}/details.
+func AdminSetMerchDetailsHandler(log *slog.Logger, merchService service.MerchService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.AdminSetMerchDetailsHandler"
+		logger := log.With(slog.String("op", op))
+
+		var req SetMerchDetailsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("invalid request: decoding error", slog.Any("error", err))
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+
+		item, err := merchService.SetDetails(r.Context(), chi.URLParam(r, "item"), service.MerchDetails{
+			Category:    req.Category,
+			Description: req.Description,
+			Tags:        req.Tags,
+		})
+		if err != nil {
+			logger.Error("failed to set merch details", slog.Any("error", err))
+			http.Error(w, err.Error(), merchErrorStatus(err))
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, item)
+	}
+}
+
+// AdminSetMerchImageHandler обрабатывает запрос PUT /api/admin/merch/{item}/image.
+// Тело запроса — сам файл изображения (PNG, JPEG, GIF или WebP), тип определяется по содержимому.
+// Размер ограничивает сервис: тело дочитывается не дальше лимита.
+func AdminSetMerchImageHandler(log *slog.Logger, merchService service.MerchService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.AdminSetMerchImageHandler"
+		logger := log.With(slog.String("op", op))
+
+		item, err := merchService.SetImage(r.Context(), chi.URLParam(r, "item"), r.Body)
+		if err != nil {
+			logger.Error("failed to set merch image", slog.Any("error", err))
+			http.Error(w, err.Error(), merchErrorStatus(err))
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, item)
+	}
+}
+
+// AdminDeleteMerchImageHandler обрабатывает запрос DELETE /api/admin/merch/{item}/image.
+func AdminDeleteMerchImageHandler(log *slog.Logger, merchService service.MerchService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.AdminDeleteMerchImageHandler"
+		logger := log.With(slog.String("op", op))
+
+		item, err := merchService.DeleteImage(r.Context(), chi.URLParam(r, "item"))
+		if err != nil {
+			logger.Error("failed to delete merch image", slog.Any("error", err))
+			http.Error(w, err.Error(), merchErrorStatus(err))
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, item)
+	}
+}
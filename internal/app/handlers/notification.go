@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/linemk/avito-shop/internal/jwt-new/jwtmiddleware"
+	"github.com/linemk/avito-shop/internal/service"
+	"github.com/linemk/avito-shop/internal/storage"
+)
+
+// NotificationReadResponse — результат отметки уведомления прочитанным.
+type NotificationReadResponse struct {
+	Unread int `json:"unread"`
+}
+
+// NotificationsReadAllResponse — результат отметки всех уведомлений прочитанными.
+type NotificationsReadAllResponse struct {
+	Marked int `json:"marked"`
+}
+
+// ListNotificationsHandler обрабатывает запрос GET /api/notifications?unread=true&limit=20.
+func ListNotificationsHandler(log *slog.Logger, notificationService service.NotificationService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.ListNotificationsHandler"
+		logger := log.With(slog.String("op", op))
+
+		userID, ok := jwtmiddleware.FromContext(r.Context())
+		if !ok {
+			logger.Error("userID not found in context")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		q := r.URL.Query()
+		unreadOnly := false
+		if v := q.Get("unread"); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				logger.Error("invalid request: bad unread", slog.String("unread", v))
+				http.Error(w, "invalid unread", http.StatusBadRequest)
+				return
+			}
+			unreadOnly = b
+		}
+		limit := 0
+		if v := q.Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				logger.Error("invalid request: bad limit", slog.String("limit", v))
+				http.Error(w, "invalid limit", http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+
+		inbox, err := notificationService.Inbox(r.Context(), userID, unreadOnly, limit)
+		if err != nil {
+			logger.Error("failed to get notifications", slog.Any("error", err))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, inbox)
+	}
+}
+
+// MarkNotificationReadHandler обрабатывает запрос POST /api/notifications/{id}/read.
+func MarkNotificationReadHandler(log *slog.Logger, notificationService service.NotificationService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.MarkNotificationReadHandler"
+		logger := log.With(slog.String("op", op))
+
+		userID, ok := jwtmiddleware.FromContext(r.Context())
+		if !ok {
+			logger.Error("userID not found in context")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil || id <= 0 {
+			logger.Error("invalid request: bad id", slog.String("id", chi.URLParam(r, "id")))
+			http.Error(w, "invalid notification id", http.StatusBadRequest)
+			return
+		}
+
+		unread, err := notificationService.MarkRead(r.Context(), userID, id)
+		if err != nil {
+			logger.Error("failed to mark notification read", slog.Any("error", err))
+			if errors.Is(err, storage.ErrNotificationNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, NotificationReadResponse{Unread: unread})
+	}
+}
+
+// MarkAllNotificationsReadHandler обрабатывает запрос POST /api/notifications/read-all.
+func MarkAllNotificationsReadHandler(log *slog.Logger, notificationService service.NotificationService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.MarkAllNotificationsReadHandler"
+		logger := log.With(slog.String("op", op))
+
+		userID, ok := jwtmiddleware.FromContext(r.Context())
+		if !ok {
+			logger.Error("userID not found in context")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		marked, err := notificationService.MarkAllRead(r.Context(), userID)
+		if err != nil {
+			logger.Error("failed to mark notifications read", slog.Any("error", err))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, NotificationsReadAllResponse{Marked: marked})
+	}
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/linemk/avito-shop/internal/jwt-new/jwtmiddleware"
+	"github.com/linemk/avito-shop/internal/service"
+	"github.com/linemk/avito-shop/internal/storage"
+)
+
+// OrdersResponse — список заказов.
+type OrdersResponse struct {
+	Orders []service.OrderView `json:"orders"`
+}
+
+// OrderStatusRequest — перевод заказа в новый статус с необязательным комментарием.
+type OrderStatusRequest struct {
+	Status  string `json:"status" validate:"required"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// ListOrdersHandler обрабатывает запрос GET /api/orders?status=.
+func ListOrdersHandler(log *slog.Logger, orderService service.OrderService) http.HandlerFunc {
+	return listOrdersHandler(log, orderService, "handlers.ListOrdersHandler", false)
+}
+
+// AdminListOrdersHandler обрабатывает запрос GET /api/admin/orders?status= — заказы всех сотрудников.
+func AdminListOrdersHandler(log *slog.Logger, orderService service.OrderService) http.HandlerFunc {
+	return listOrdersHandler(log, orderService, "handlers.AdminListOrdersHandler", true)
+}
+
+// listOrdersHandler — общий обработчик списка заказов; all = true — заказы всех пользователей.
+func listOrdersHandler(log *slog.Logger, orderService service.OrderService, op string, all bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := log.With(slog.String("op", op))
+
+		userID, ok := jwtmiddleware.FromContext(r.Context())
+		if !ok {
+			logger.Error("userID not found in context")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if all {
+			userID = 0
+		}
+
+		orders, err := orderService.List(r.Context(), userID, r.URL.Query().Get("status"))
+		if err != nil {
+			logger.Error("failed to list orders", slog.Any("error", err))
+			http.Error(w, err.Error(), orderErrorStatus(err))
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, OrdersResponse{Orders: orders})
+	}
+}
+
+// GetOrderHandler обрабатывает запрос GET /api/orders/{id}.
+func GetOrderHandler(log *slog.Logger, orderService service.OrderService) http.HandlerFunc {
+	return getOrderHandler(log, "handlers.GetOrderHandler", orderService.Get)
+}
+
+// AdminGetOrderHandler обрабатывает запрос GET /api/admin/orders/{id}.
+func AdminGetOrderHandler(log *slog.Logger, orderService service.OrderService) http.HandlerFunc {
+	return getOrderHandler(log, "handlers.AdminGetOrderHandler", func(ctx context.Context, _, orderID int64) (*service.OrderView, error) {
+		return orderService.Get(ctx, 0, orderID)
+	})
+}
+
+// getOrderHandler — общий обработчик просмотра заказа с историей статусов.
+func getOrderHandler(log *slog.Logger, op string, get func(ctx context.Context, userID, orderID int64) (*service.OrderView, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := log.With(slog.String("op", op))
+
+		orderID, ok := orderIDParam(w, r, logger)
+		if !ok {
+			return
+		}
+		userID, ok := jwtmiddleware.FromContext(r.Context())
+		if !ok {
+			logger.Error("userID not found in context")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		order, err := get(r.Context(), userID, orderID)
+		if err != nil {
+			logger.Error("failed to get order", slog.Any("error", err))
+			http.Error(w, err.Error(), orderErrorStatus(err))
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, order)
+	}
+}
+
+// CancelOrderHandler обрабатывает запрос POST /api/orders/{id}/cancel.
+func CancelOrderHandler(log *slog.Logger, orderService service.OrderService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.CancelOrderHandler"
+		logger := log.With(slog.String("op", op))
+
+		orderID, ok := orderIDParam(w, r, logger)
+		if !ok {
+			return
+		}
+		userID, ok := jwtmiddleware.FromContext(r.Context())
+		if !ok {
+			logger.Error("userID not found in context")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		order, err := orderService.Cancel(r.Context(), userID, orderID)
+		if err != nil {
+			logger.Error("failed to cancel order", slog.Any("error", err))
+			http.Error(w, err.Error(), orderErrorStatus(err))
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, order)
+	}
+}
+
+// AdminOrderStatusHandler обрабатывает запрос POST /api/admin/orders/{id}/status.
+func AdminOrderStatusHandler(log *slog.Logger, orderService service.OrderService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.AdminOrderStatusHandler"
+		logger := log.With(slog.String("op", op))
+
+		orderID, ok := orderIDParam(w, r, logger)
+		if !ok {
+			return
+		}
+
+		var req OrderStatusRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("invalid request: decoding error", slog.Any("error", err))
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+		if err := validate.Struct(req); err != nil {
+			logger.Error("invalid request: validation error", slog.Any("error", err))
+			http.Error(w, "validation error", http.StatusBadRequest)
+			return
+		}
+
+		adminID, ok := jwtmiddleware.FromContext(r.Context())
+		if !ok {
+			logger.Error("userID not found in context")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		order, err := orderService.Transition(r.Context(), adminID, orderID, req.Status, req.Comment)
+		if err != nil {
+			logger.Error("failed to change order status", slog.Any("error", err))
+			http.Error(w, err.Error(), orderErrorStatus(err))
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, order)
+	}
+}
+
+// orderIDParam разбирает id заказа из пути; при ошибке отвечает 400.
+func orderIDParam(w http.ResponseWriter, r *http.Request, logger *slog.Logger) (int64, bool) {
+	orderID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || orderID <= 0 {
+		logger.Error("invalid request: bad id", slog.String("id", chi.URLParam(r, "id")))
+		http.Error(w, "invalid order id", http.StatusBadRequest)
+		return 0, false
+	}
+	return orderID, true
+}
+
+// orderErrorStatus выбирает HTTP-статус для ошибки сервиса заказов.
+func orderErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrOrderNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrOrderTransition), errors.Is(err, service.ErrCancelNotAllowed):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrInvalidOrderStatus), errors.Is(err, service.ErrInvalidComment):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/linemk/avito-shop/internal/jwt-new/jwtmiddleware"
+	"github.com/linemk/avito-shop/internal/service"
+	"github.com/linemk/avito-shop/internal/storage"
+)
+
+// CreatePromotionRequest — новая акция. Без Code акция применяется автоматически,
+// Item и User ограничивают её товаром и покупателем.
+type CreatePromotionRequest struct {
+	Name           string     `json:"name" validate:"required"`
+	Code           string     `json:"code,omitempty"`
+	DiscountType   string     `json:"discountType" validate:"required,oneof=percent fixed"`
+	DiscountValue  int        `json:"discountValue" validate:"required,gt=0"`
+	Item           string     `json:"item,omitempty"`
+	User           string     `json:"user,omitempty" validate:"omitempty,email"`
+	StartsAt       *time.Time `json:"startsAt,omitempty"`
+	EndsAt         *time.Time `json:"endsAt,omitempty"`
+	MaxUses        int        `json:"maxUses" validate:"gte=0"`
+	MaxUsesPerUser int        `json:"maxUsesPerUser" validate:"gte=0"`
+}
+
+// PromotionsResponse — список акций.
+type PromotionsResponse struct {
+	Promotions []service.PromotionView `json:"promotions"`
+}
+
+// AdminCreatePromotionHandler обрабатывает запрос POST /api/admin/promotions.
+func AdminCreatePromotionHandler(log *slog.Logger, promotionService service.PromotionService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.AdminCreatePromotionHandler"
+		logger := log.With(slog.String("op", op))
+
+		var req CreatePromotionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("invalid request: decoding error", slog.Any("error", err))
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+		if err := validate.Struct(req); err != nil {
+			logger.Error("invalid request: validation error", slog.Any("error", err))
+			http.Error(w, "validation error", http.StatusBadRequest)
+			return
+		}
+
+		adminID, ok := jwtmiddleware.FromContext(r.Context())
+		if !ok {
+			logger.Error("userID not found in context")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		promo, err := promotionService.Create(r.Context(), adminID, service.PromotionInput{
+			Name:           req.Name,
+			Code:           req.Code,
+			DiscountType:   req.DiscountType,
+			DiscountValue:  req.DiscountValue,
+			Item:           req.Item,
+			User:           req.User,
+			StartsAt:       req.StartsAt,
+			EndsAt:         req.EndsAt,
+			MaxUses:        req.MaxUses,
+			MaxUsesPerUser: req.MaxUsesPerUser,
+		})
+		if err != nil {
+			logger.Error("failed to create promotion", slog.Any("error", err))
+			http.Error(w, err.Error(), promotionErrorStatus(err))
+			return
+		}
+
+		writeJSON(w, logger, http.StatusCreated, promo)
+	}
+}
+
+// AdminListPromotionsHandler обрабатывает запрос GET /api/admin/promotions.
+func AdminListPromotionsHandler(log *slog.Logger, promotionService service.PromotionService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.AdminListPromotionsHandler"
+		logger := log.With(slog.String("op", op))
+
+		promos, err := promotionService.List(r.Context())
+		if err != nil {
+			logger.Error("failed to list promotions", slog.Any("error", err))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, PromotionsResponse{Promotions: promos})
+	}
+}
+
+// AdminDeactivatePromotionHandler обрабатывает запрос POST /api/admin/promotions/{id}/deactivate.
+func AdminDeactivatePromotionHandler(log *slog.Logger, promotionService service.PromotionService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.AdminDeactivatePromotionHandler"
+		logger := log.With(slog.String("op", op))
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil || id <= 0 {
+			logger.Error("invalid request: bad id", slog.String("id", chi.URLParam(r, "id")))
+			http.Error(w, "invalid promotion id", http.StatusBadRequest)
+			return
+		}
+
+		promo, err := promotionService.Deactivate(r.Context(), id)
+		if err != nil {
+			logger.Error("failed to deactivate promotion", slog.Any("error", err))
+			http.Error(w, err.Error(), promotionErrorStatus(err))
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, promo)
+	}
+}
+
+// promotionErrorStatus выбирает HTTP-статус для ошибки сервиса акций.
+func promotionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrPromotionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrPromoCodeExists):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrInvalidPromotion):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
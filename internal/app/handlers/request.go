@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/linemk/avito-shop/internal/jwt-new/jwtmiddleware"
+	"github.com/linemk/avito-shop/internal/service"
+	"github.com/linemk/avito-shop/internal/storage"
+)
+
+// CreateCoinRequestRequest — запрос монет у коллеги fromUser.
+type CreateCoinRequestRequest struct {
+	FromUser string `json:"fromUser" validate:"required,email"`
+	Amount   int    `json:"amount" validate:"required,gt=0"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// CreateCoinRequestHandler обрабатывает запрос POST /api/requests.
+func CreateCoinRequestHandler(log *slog.Logger, requestService service.CoinRequestService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.CreateCoinRequestHandler"
+		logger := log.With(slog.String("op", op))
+
+		var req CreateCoinRequestRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("invalid request: decoding error", slog.Any("error", err))
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+		if err := validate.Struct(req); err != nil {
+			logger.Error("invalid request: validation error", slog.Any("error", err))
+			http.Error(w, "validation error", http.StatusBadRequest)
+			return
+		}
+
+		userID, ok := jwtmiddleware.FromContext(r.Context())
+		if !ok {
+			logger.Error("userID not found in context")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		view, err := requestService.Create(r.Context(), userID, req.FromUser, req.Amount, req.Comment)
+		if err != nil {
+			logger.Error("failed to create coin request", slog.Any("error", err))
+			http.Error(w, err.Error(), coinRequestErrorStatus(err))
+			return
+		}
+
+		writeJSON(w, logger, http.StatusCreated, view)
+	}
+}
+
+// ListCoinRequestsHandler обрабатывает запрос GET /api/requests.
+func ListCoinRequestsHandler(log *slog.Logger, requestService service.CoinRequestService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.ListCoinRequestsHandler"
+		logger := log.With(slog.String("op", op))
+
+		userID, ok := jwtmiddleware.FromContext(r.Context())
+		if !ok {
+			logger.Error("userID not found in context")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		requests, err := requestService.List(r.Context(), userID)
+		if err != nil {
+			logger.Error("failed to list coin requests", slog.Any("error", err))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, requests)
+	}
+}
+
+// AcceptCoinRequestHandler обрабатывает запрос POST /api/requests/{id}/accept.
+func AcceptCoinRequestHandler(log *slog.Logger, requestService service.CoinRequestService) http.HandlerFunc {
+	return resolveCoinRequestHandler(log, "handlers.AcceptCoinRequestHandler", requestService.Accept, "Coin request accepted")
+}
+
+// DeclineCoinRequestHandler обрабатывает запрос POST /api/requests/{id}/decline.
+func DeclineCoinRequestHandler(log *slog.Logger, requestService service.CoinRequestService) http.HandlerFunc {
+	return resolveCoinRequestHandler(log, "handlers.DeclineCoinRequestHandler", requestService.Decline, "Coin request declined")
+}
+
+// CoinRequestResponse — результат ответа на запрос монет.
+type CoinRequestResponse struct {
+	Message string `json:"message"`
+}
+
+// resolveCoinRequestHandler — общий обработчик принятия и отклонения запроса.
+func resolveCoinRequestHandler(log *slog.Logger, op string, resolve func(ctx context.Context, payerID, requestID int64) error, message string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := log.With(slog.String("op", op))
+
+		requestID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil || requestID <= 0 {
+			logger.Error("invalid request: bad id", slog.String("id", chi.URLParam(r, "id")))
+			http.Error(w, "invalid request id", http.StatusBadRequest)
+			return
+		}
+
+		userID, ok := jwtmiddleware.FromContext(r.Context())
+		if !ok {
+			logger.Error("userID not found in context")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if err := resolve(r.Context(), userID, requestID); err != nil {
+			logger.Error("failed to resolve coin request", slog.Any("error", err))
+			http.Error(w, err.Error(), coinRequestErrorStatus(err))
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, CoinRequestResponse{Message: message})
+	}
+}
+
+// coinRequestErrorStatus выбирает HTTP-статус для ошибки сервиса запросов монет.
+func coinRequestErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrCoinRequestNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrCoinRequestResolved):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrTransferLimit):
+		return http.StatusUnprocessableEntity
+	default:
+		// как и в SendCoinHandler: недостаточно средств, неизвестный пользователь и т.п.
+		return http.StatusBadRequest
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 
@@ -13,6 +14,8 @@ import (
 type SendCoinRequest struct {
 	ToUser string `json:"toUser" validate:"required,email"`
 	Amount int    `json:"amount" validate:"required,gt=0"`
+	// Comment — необязательный комментарий к переводу (до service.MaxTransferCommentLength символов)
+	Comment string `json:"comment,omitempty"`
 }
 
 // SendCoinResponse представляет ответ при успешном переводе.
@@ -44,9 +47,13 @@ func SendCoinHandler(log *slog.Logger, sendCoinService service.SendCoinService)
 		}
 
 		// Вызываем бизнес-логику для перевода монет
-		if err := sendCoinService.SendCoin(r.Context(), userID, req.ToUser, req.Amount); err != nil {
+		if err := sendCoinService.SendCoin(r.Context(), userID, req.ToUser, req.Amount, req.Comment); err != nil {
 			logger.Error("failed to send coin", slog.Any("error", err))
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			status := http.StatusBadRequest
+			if errors.Is(err, service.ErrTransferLimit) {
+				status = http.StatusUnprocessableEntity
+			}
+			http.Error(w, err.Error(), status)
 			return
 		}
 
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/linemk/avito-shop/internal/jwt-new/jwtmiddleware"
+	"github.com/linemk/avito-shop/internal/service"
+	"github.com/linemk/avito-shop/internal/storage"
+)
+
+// AddToWishlistRequest — товар для списка желаний.
+type AddToWishlistRequest struct {
+	Item string `json:"item" validate:"required"`
+}
+
+// WishlistResponse — список желаний пользователя.
+type WishlistResponse struct {
+	Items []service.WishlistItem `json:"items"`
+}
+
+// WishlistMessageResponse — результат удаления товара из списка желаний.
+type WishlistMessageResponse struct {
+	Message string `json:"message"`
+}
+
+// ListWishlistHandler обрабатывает запрос GET /api/wishlist.
+func ListWishlistHandler(log *slog.Logger, wishlistService service.WishlistService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.ListWishlistHandler"
+		logger := log.With(slog.String("op", op))
+
+		userID, ok := jwtmiddleware.FromContext(r.Context())
+		if !ok {
+			logger.Error("userID not found in context")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		items, err := wishlistService.List(r.Context(), userID)
+		if err != nil {
+			logger.Error("failed to list wishlist", slog.Any("error", err))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, WishlistResponse{Items: items})
+	}
+}
+
+// AddToWishlistHandler обрабатывает запрос POST /api/wishlist.
+func AddToWishlistHandler(log *slog.Logger, wishlistService service.WishlistService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.AddToWishlistHandler"
+		logger := log.With(slog.String("op", op))
+
+		var req AddToWishlistRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("invalid request: decoding error", slog.Any("error", err))
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+		if err := validate.Struct(req); err != nil {
+			logger.Error("invalid request: validation error", slog.Any("error", err))
+			http.Error(w, "validation error", http.StatusBadRequest)
+			return
+		}
+
+		userID, ok := jwtmiddleware.FromContext(r.Context())
+		if !ok {
+			logger.Error("userID not found in context")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		item, err := wishlistService.Add(r.Context(), userID, req.Item)
+		if err != nil {
+			logger.Error("failed to add wishlist item", slog.Any("error", err))
+			http.Error(w, err.Error(), wishlistErrorStatus(err))
+			return
+		}
+
+		writeJSON(w, logger, http.StatusCreated, item)
+	}
+}
+
+// RemoveFromWishlistHandler обрабатывает запрос DELETE /api/wishlist/{item}.
+func RemoveFromWishlistHandler(log *slog.Logger, wishlistService service.WishlistService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.RemoveFromWishlistHandler"
+		logger := log.With(slog.String("op", op))
+
+		userID, ok := jwtmiddleware.FromContext(r.Context())
+		if !ok {
+			logger.Error("userID not found in context")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if err := wishlistService.Remove(r.Context(), userID, chi.URLParam(r, "item")); err != nil {
+			logger.Error("failed to remove wishlist item", slog.Any("error", err))
+			http.Error(w, err.Error(), wishlistErrorStatus(err))
+			return
+		}
+
+		writeJSON(w, logger, http.StatusOK, WishlistMessageResponse{Message: "Item removed from wishlist"})
+	}
+}
+
+// wishlistErrorStatus выбирает HTTP-статус для ошибки сервиса списков желаний.
+func wishlistErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrMerchNotFound), errors.Is(err, storage.ErrWishlistItemNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrWishlistItemExists):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrWishlistFull):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
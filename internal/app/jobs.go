@@ -0,0 +1,79 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/config"
+	"github.com/linemk/avito-shop/internal/scheduler"
+	"github.com/linemk/avito-shop/internal/service"
+)
+
+// Имена фоновых задач (в истории запусков и в блокировках)
+const (
+	JobAllowance         = "allowance"
+	JobCoinExpiration    = "coin_expiration"
+	JobRequestExpiration = "coin_request_expiration"
+)
+
+// NewScheduler создаёт планировщик и регистрирует задачи, включённые в конфиге.
+// Если планировщик выключен, задач в нём нет и Run просто ждёт остановки.
+func NewScheduler(log *slog.Logger, cfg *config.Config, st Storage) (*scheduler.Scheduler, error) {
+	location, err := time.LoadLocation(cfg.Scheduler.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scheduler timezone: %w", err)
+	}
+	sched := scheduler.New(log, st.Locker, st.JobRuns, location)
+	if !cfg.Scheduler.Enabled {
+		log.Info("scheduler is disabled")
+		return sched, nil
+	}
+
+	ledger := newLedger(cfg, st)
+
+	allowance := cfg.Scheduler.Allowance
+	if allowance.Schedule != "" {
+		if allowance.Amount <= 0 {
+			return nil, fmt.Errorf("scheduler.allowance.amount must be positive")
+		}
+		svc := service.NewAllowanceService(log, st.TxManager, st.Users, ledger,
+			allowance.Amount, allowance.Reason, allowance.BatchSize)
+		err := sched.Add(JobAllowance, allowance.Schedule, func(ctx context.Context, scheduledAt time.Time) error {
+			// период выплаты — момент запуска по расписанию: повтор запуска не начислит дважды
+			_, err := svc.CreditAll(ctx, scheduledAt.UTC().Format(time.RFC3339))
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	expiration := cfg.Scheduler.Expiration
+	if len(cfg.CoinExpiration.TTL) > 0 && expiration.Schedule != "" {
+		svc := service.NewExpirationService(log, st.TxManager, st.CoinLots, ledger, expiration.BatchSize)
+		err := sched.Add(JobCoinExpiration, expiration.Schedule, func(ctx context.Context, scheduledAt time.Time) error {
+			// сгорают партии со сроком до момента запуска по расписанию
+			_, err := svc.ExpireDue(ctx, scheduledAt)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if schedule := cfg.Scheduler.RequestExpiration.Schedule; schedule != "" {
+		// истечение запросов не выполняет переводов, SendCoinService задаче не нужен
+		svc := service.NewCoinRequestService(log, st.TxManager, st.Users, st.CoinRequests, nil, cfg.CoinRequests.TTL)
+		err := sched.Add(JobRequestExpiration, schedule, func(ctx context.Context, scheduledAt time.Time) error {
+			_, err := svc.ExpireStale(ctx, scheduledAt)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sched, nil
+}
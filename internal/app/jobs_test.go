@@ -0,0 +1,82 @@
+package app_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/app"
+	"github.com/linemk/avito-shop/internal/app/handlers"
+	"github.com/linemk/avito-shop/internal/config"
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewScheduler_AllowanceJob(t *testing.T) {
+	os.Setenv("JWT_SECRET", "testsecret")
+	defer os.Unsetenv("JWT_SECRET")
+
+	cfg := newTestConfig()
+	cfg.Admin.Users = []string{"hr@example.com"}
+	cfg.Scheduler = config.SchedulerConfig{
+		Enabled:   true,
+		Timezone:  "UTC",
+		Allowance: config.AllowanceJobConfig{Schedule: "@monthly", Amount: 100, Reason: "monthly allowance"},
+	}
+	st := app.NewMemoryStorage(memory.New())
+	provisionAdmin(t, st, "hr@example.com")
+	server := httptest.NewServer(app.NewRouter(newTestLogger(), cfg, st))
+	defer server.Close()
+
+	adminToken := login(t, server.URL, "hr@example.com")
+	user, err := st.Users.GetUserByEmail(context.Background(), "hr@example.com")
+	require.NoError(t, err)
+
+	sched, err := app.NewScheduler(newTestLogger(), cfg, st)
+	require.NoError(t, err)
+	scheduledAt := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, sched.RunJob(context.Background(), app.JobAllowance, scheduledAt))
+	// повторный запуск за тот же момент не выполняется
+	require.NoError(t, sched.RunJob(context.Background(), app.JobAllowance, scheduledAt))
+
+	user, err = st.Users.GetUserByID(context.Background(), user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1100, user.CoinBalance)
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/api/admin/jobs/runs?job=allowance", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var history handlers.JobRunsResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&history))
+	require.Len(t, history.Runs, 1)
+	assert.Equal(t, models.JobRunSucceeded, history.Runs[0].Status)
+	assert.True(t, scheduledAt.Equal(history.Runs[0].ScheduledAt))
+}
+
+func TestNewScheduler_InvalidConfig(t *testing.T) {
+	st := app.NewMemoryStorage(memory.New())
+
+	cfg := newTestConfig()
+	cfg.Scheduler = config.SchedulerConfig{Enabled: true, Timezone: "Mars/Olympus"}
+	_, err := app.NewScheduler(newTestLogger(), cfg, st)
+	assert.Error(t, err)
+
+	cfg.Scheduler = config.SchedulerConfig{Enabled: true, Allowance: config.AllowanceJobConfig{Schedule: "monthly", Amount: 100}}
+	_, err = app.NewScheduler(newTestLogger(), cfg, st)
+	assert.Error(t, err)
+
+	cfg.Scheduler = config.SchedulerConfig{Enabled: true, Allowance: config.AllowanceJobConfig{Schedule: "@monthly"}}
+	_, err = app.NewScheduler(newTestLogger(), cfg, st)
+	assert.Error(t, err)
+}
@@ -0,0 +1,162 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/linemk/avito-shop/internal/app/handlers"
+	"github.com/linemk/avito-shop/internal/config"
+	"github.com/linemk/avito-shop/internal/jwt-new/jwtmiddleware"
+	"github.com/linemk/avito-shop/internal/lib/logger/handlers/urllog"
+	"github.com/linemk/avito-shop/internal/service"
+	"github.com/linemk/avito-shop/internal/storage"
+)
+
+// NewRouter собирает сервисы поверх переданного хранилища и регистрирует все маршруты API.
+func NewRouter(log *slog.Logger, cfg *config.Config, st Storage) http.Handler {
+	router := chi.NewRouter()
+	// настройка middleware
+	router.Use(middleware.RequestID)
+	router.Use(urllog.CustomLoggerMiddleware(log))
+	router.Use(middleware.Recoverer)
+	router.Use(middleware.URLFormat)
+
+	ledger := newLedger(cfg, st)
+	notifier := service.NewNotifier(st.Notifications)
+	authService := service.NewAuthService(log, st.TxManager, st.Users, ledger,
+		time.Duration(cfg.JWT.TokenTTL)*time.Minute, welcomeBonus(cfg.WelcomeBonus), cfg.Admin.Users)
+	buyService := service.NewBuyService(log, st.TxManager, st.Users, st.Merch, st.Orders, st.CoinTransactions, st.Promotions, ledger)
+	sendCoinService := service.NewSendCoinService(log, st.TxManager, st.Users, st.CoinTransactions, ledger, notifier, transferLimits(cfg.TransferLimits))
+	requestService := service.NewCoinRequestService(log, st.TxManager, st.Users, st.CoinRequests, sendCoinService, cfg.CoinRequests.TTL)
+	merchService := service.NewMerchService(log, st.TxManager, st.Users, st.Merch, st.Images, cfg.Images.MaxSize, newWishlistNotifier(st))
+	infoService := service.NewInfoService(log, st.Users, st.Orders, st.CoinTransactions, st.CoinLots, cfg.CoinExpiration.ExpiringWindow)
+	adminService := service.NewAdminService(log, st.TxManager, st.Users, ledger, notifier, cfg.Admin.GrantBatchSize)
+	jobService := service.NewJobService(log, st.JobRuns)
+	orderService := service.NewOrderService(log, st.TxManager, st.Users, st.Merch, st.Orders, ledger, notifier,
+		service.NewLogEventPublisher(log), cfg.Orders.CancelWindow)
+	promotionService := service.NewPromotionService(log, st.TxManager, st.Users, st.Merch, st.Orders, st.Promotions)
+	wishlistService := service.NewWishlistService(log, st.TxManager, st.Users, st.Merch, st.Wishlists)
+	notificationService := service.NewNotificationService(log, st.TxManager, st.Notifications)
+
+	// эндпоинт для аутентификации
+	router.Post("/api/auth", handlers.AuthHandler(log, authService))
+	// изображения товаров открыты без токена: браузер загружает их тегом <img> без заголовка Authorization
+	router.Get("/api/merch/{item}/image", handlers.MerchImageHandler(log, merchService))
+
+	router.Group(func(r chi.Router) {
+		jwtMW := jwtmiddleware.NewJWTMiddleware()
+		r.Use(jwtMW)
+		// эндпоинт для инфо
+		r.Get("/api/info", handlers.InfoHandler(log, infoService))
+		// эндпоинт для поиска по комментариям в истории операций
+		r.Get("/api/history", handlers.HistoryHandler(log, infoService))
+		// эндпоинт для отправки монет другому пользователю
+		r.Post("/api/sendCoin", handlers.SendCoinHandler(log, sendCoinService))
+		// запросы монет у коллег: плательщик принимает или отклоняет запрос
+		r.Route("/api/requests", func(r chi.Router) {
+			r.Post("/", handlers.CreateCoinRequestHandler(log, requestService))
+			r.Get("/", handlers.ListCoinRequestsHandler(log, requestService))
+			r.Post("/{id}/accept", handlers.AcceptCoinRequestHandler(log, requestService))
+			r.Post("/{id}/decline", handlers.DeclineCoinRequestHandler(log, requestService))
+		})
+		// каталог мерча с ценами, остатками и вариантами (?category=, ?tag=, ?q=, ?minPrice=, ?maxPrice= — отбор)
+		r.Get("/api/merch", handlers.CatalogHandler(log, merchService))
+		r.Get("/api/merch/categories", handlers.CategoriesHandler(log, merchService))
+		r.Get("/api/merch/{item}/prices", handlers.PriceHistoryHandler(log, merchService))
+		// эндпоинт для покупки мерча (параметр в path — название товара, ?variant= — артикул варианта, ?code= — промокод)
+		r.Get("/api/buy/{item}", handlers.BuyHandler(log, buyService))
+		// эндпоинт для покупки мерча в подарок коллеге
+		r.Post("/api/gift", handlers.GiftHandler(log, buyService))
+		// заказы пользователя со статусами и историей
+		r.Get("/api/orders", handlers.ListOrdersHandler(log, orderService))
+		r.Get("/api/orders/{id}", handlers.GetOrderHandler(log, orderService))
+		r.Post("/api/orders/{id}/cancel", handlers.CancelOrderHandler(log, orderService))
+		// список желаний: уведомления, когда хватает монет или товар снова в продаже
+		r.Get("/api/wishlist", handlers.ListWishlistHandler(log, wishlistService))
+		r.Post("/api/wishlist", handlers.AddToWishlistHandler(log, wishlistService))
+		r.Delete("/api/wishlist/{item}", handlers.RemoveFromWishlistHandler(log, wishlistService))
+		// входящие уведомления: переводы, статусы заказов, начисления
+		r.Get("/api/notifications", handlers.ListNotificationsHandler(log, notificationService))
+		r.Post("/api/notifications/read-all", handlers.MarkAllNotificationsReadHandler(log, notificationService))
+		r.Post("/api/notifications/{id}/read", handlers.MarkNotificationReadHandler(log, notificationService))
+
+		// эндпоинты администратора (права — флаг is_admin, который выдают фикстуры)
+		r.Route("/api/admin", func(r chi.Router) {
+			r.Use(jwtmiddleware.RequireAdmin(isAdmin(st.Users)))
+			r.Post("/credit", handlers.AdminCreditHandler(log, adminService))
+			r.Post("/debit", handlers.AdminDebitHandler(log, adminService))
+			r.Post("/grants", handlers.AdminGrantHandler(log, adminService))
+			r.Get("/jobs/runs", handlers.AdminJobRunsHandler(log, jobService))
+			r.Post("/merch/{item}/restock", handlers.AdminRestockHandler(log, merchService))
+			r.Put("/merch/{item}/stock", handlers.AdminSetStockHandler(log, merchService))
+			r.Put("/merch/{item}/limit", handlers.AdminSetPurchaseLimitHandler(log, merchService))
+			r.Put("/merch/{item}/details", handlers.AdminSetMerchDetailsHandler(log, merchService))
+			r.Put("/merch/{item}/image", handlers.AdminSetMerchImageHandler(log, merchService))
+			r.Delete("/merch/{item}/image", handlers.AdminDeleteMerchImageHandler(log, merchService))
+			r.Post("/merch/{item}/prices", handlers.AdminSetPriceHandler(log, merchService))
+			r.Delete("/merch/{item}/prices/{id}", handlers.AdminCancelPriceChangeHandler(log, merchService))
+			r.Post("/merch/{item}/variants", handlers.AdminCreateVariantHandler(log, merchService))
+			r.Put("/merch/{item}/variants/{sku}", handlers.AdminUpdateVariantHandler(log, merchService))
+			r.Delete("/merch/{item}/variants/{sku}", handlers.AdminDeleteVariantHandler(log, merchService))
+			r.Get("/orders", handlers.AdminListOrdersHandler(log, orderService))
+			r.Get("/orders/{id}", handlers.AdminGetOrderHandler(log, orderService))
+			r.Post("/orders/{id}/status", handlers.AdminOrderStatusHandler(log, orderService))
+			r.Get("/promotions", handlers.AdminListPromotionsHandler(log, promotionService))
+			r.Post("/promotions", handlers.AdminCreatePromotionHandler(log, promotionService))
+			r.Post("/promotions/{id}/deactivate", handlers.AdminDeactivatePromotionHandler(log, promotionService))
+		})
+	})
+
+	return router
+}
+
+// isAdmin проверяет права администратора по флагу пользователя в хранилище.
+func isAdmin(users storage.UserStorage) jwtmiddleware.AdminChecker {
+	return func(ctx context.Context, userID int64) (bool, error) {
+		user, err := users.GetUserByID(ctx, userID)
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return user.IsAdmin, nil
+	}
+}
+
+// newLedger создаёт Ledger хранилища st со сроками жизни монет из конфига.
+func newLedger(cfg *config.Config, st Storage) *service.Ledger {
+	return service.NewLedger(st.Users, st.CoinTransactions, st.CoinLots, service.CoinExpiration{TTL: cfg.CoinExpiration.TTL},
+		newWishlistNotifier(st))
+}
+
+// newWishlistNotifier создаёт уведомления о товарах из списков желаний хранилища st.
+func newWishlistNotifier(st Storage) *service.WishlistNotifier {
+	return service.NewWishlistNotifier(st.Wishlists, service.NewNotifier(st.Notifications))
+}
+
+// welcomeBonus переводит настройки стартового бонуса из конфига в правила сервиса.
+func welcomeBonus(cfg config.WelcomeBonusConfig) service.WelcomeBonus {
+	bonus := service.WelcomeBonus{Amount: cfg.Amount, ExpiresAt: cfg.ExpiresAt}
+	for _, d := range cfg.Domains {
+		bonus.Domains = append(bonus.Domains, service.DomainBonus{Domain: d.Domain, Amount: d.Amount, ExpiresAt: d.ExpiresAt})
+	}
+	return bonus
+}
+
+// transferLimits переводит лимиты переводов из конфига в правила сервиса.
+func transferLimits(cfg config.TransferLimitsConfig) service.TransferLimits {
+	return service.TransferLimits{
+		MaxAmount:      cfg.MaxAmount,
+		DailyOutgoing:  cfg.DailyOutgoing,
+		DailyIncoming:  cfg.DailyIncoming,
+		MinAccountAge:  cfg.MinAccountAge,
+		VelocityCount:  cfg.Velocity.MaxTransfers,
+		VelocityWindow: cfg.Velocity.Window,
+	}
+}
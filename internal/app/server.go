@@ -0,0 +1,77 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/config"
+)
+
+// Server — HTTP-сервер приложения с корректным (graceful) завершением.
+type Server struct {
+	log             *slog.Logger
+	httpServer      *http.Server
+	shutdownTimeout time.Duration
+}
+
+// NewServer создаёт сервер с роутером из NewRouter и таймаутами из конфига.
+func NewServer(log *slog.Logger, cfg *config.Config, st Storage) *Server {
+	return &Server{
+		log: log,
+		httpServer: &http.Server{
+			Addr:         cfg.HTTPServer.Address,
+			Handler:      NewRouter(log, cfg, st),
+			ReadTimeout:  cfg.HTTPServer.Timeout,
+			WriteTimeout: cfg.HTTPServer.Timeout,
+			IdleTimeout:  cfg.HTTPServer.IdleTimeout,
+		},
+		shutdownTimeout: cfg.HTTPServer.ShutdownTimeout,
+	}
+}
+
+// Handler возвращает роутер сервера, например для httptest.
+func (s *Server) Handler() http.Handler {
+	return s.httpServer.Handler
+}
+
+// Run слушает адрес из конфига и обслуживает запросы до отмены ctx.
+func (s *Server) Run(ctx context.Context) error {
+	l, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.httpServer.Addr, err)
+	}
+	return s.Serve(ctx, l)
+}
+
+// Serve обслуживает запросы на переданном listener до отмены ctx,
+// после чего завершает сервер, ожидая активные запросы не дольше shutdownTimeout.
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	errCh := make(chan error, 1)
+	go func() {
+		s.log.Info("starting server", slog.String("address", l.Addr().String()))
+		if err := s.httpServer.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
+		}
+		close(errCh)
+	}()
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("server error: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+
+	if err := s.httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("server shutdown failed: %w", err)
+	}
+	s.log.Info("server gracefully stopped")
+	return nil
+}
@@ -0,0 +1,696 @@
+package app_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/app"
+	"github.com/linemk/avito-shop/internal/config"
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/seed"
+	"github.com/linemk/avito-shop/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestConfig() *config.Config {
+	return &config.Config{
+		HTTPServer: config.HTTPServerConfig{
+			Address:         "127.0.0.1:0",
+			Timeout:         4 * time.Second,
+			IdleTimeout:     time.Minute,
+			ShutdownTimeout: time.Second,
+		},
+		JWT:          config.JWTConfig{TokenTTL: 60},
+		WelcomeBonus: config.WelcomeBonusConfig{Amount: 1000},
+		Images:       config.ImagesConfig{MaxSize: 1024},
+	}
+}
+
+func newTestLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stdout, nil))
+}
+
+func TestNewRouter_BuyFlow(t *testing.T) {
+	os.Setenv("JWT_SECRET", "testsecret")
+	defer os.Unsetenv("JWT_SECRET")
+
+	router := app.NewRouter(newTestLogger(), newTestConfig(), app.NewMemoryStorage(memory.New()))
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	// Аутентификация создаёт пользователя и выдаёт токен.
+	resp, err := http.Post(server.URL+"/api/auth", "application/json",
+		bytes.NewBufferString(`{"username": "router@example.com", "password": "password123"}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var auth struct {
+		Token string `json:"token"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&auth))
+
+	// Покупка из каталога по умолчанию.
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/api/buy/cup", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+auth.Token)
+	buyResp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer buyResp.Body.Close()
+	assert.Equal(t, http.StatusOK, buyResp.StatusCode)
+
+	// Баланс и инвентарь видны в /api/info.
+	req, err = http.NewRequest(http.MethodGet, server.URL+"/api/info", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+auth.Token)
+	infoResp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer infoResp.Body.Close()
+	require.Equal(t, http.StatusOK, infoResp.StatusCode)
+	var info struct {
+		Coins int `json:"coins"`
+	}
+	require.NoError(t, json.NewDecoder(infoResp.Body).Decode(&info))
+	assert.Equal(t, 980, info.Coins)
+}
+
+func TestNewRouter_ProtectedRoutesRequireToken(t *testing.T) {
+	os.Setenv("JWT_SECRET", "testsecret")
+	defer os.Unsetenv("JWT_SECRET")
+
+	router := app.NewRouter(newTestLogger(), newTestConfig(), app.NewMemoryStorage(memory.New()))
+
+	for _, path := range []string{"/api/info", "/api/buy/cup"} {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusUnauthorized, rr.Code, path)
+	}
+}
+
+func TestServer_ServeStopsOnContextCancel(t *testing.T) {
+	os.Setenv("JWT_SECRET", "testsecret")
+	defer os.Unsetenv("JWT_SECRET")
+
+	srv := app.NewServer(newTestLogger(), newTestConfig(), app.NewMemoryStorage(memory.New()))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ctx, l) }()
+
+	// Сервер отвечает, пока контекст не отменён.
+	resp, err := http.Get("http://" + l.Addr().String() + "/api/info")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop after context cancel")
+	}
+}
+
+// login выполняет /api/auth и возвращает токен.
+func login(t *testing.T, baseURL, email string) string {
+	t.Helper()
+	resp, err := http.Post(baseURL+"/api/auth", "application/json",
+		bytes.NewBufferString(`{"username": "`+email+`", "password": "password123"}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var auth struct {
+		Token string `json:"token"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&auth))
+	return auth.Token
+}
+
+// provisionAdmin заводит администратора email с паролем, который использует login, так же,
+// как это делает migrator seed.
+func provisionAdmin(t *testing.T, st app.Storage, email string) {
+	t.Helper()
+	seeder := seed.NewSeeder(newTestLogger(), st.TxManager, st.Users, st.Merch)
+	_, err := seeder.Apply(context.Background(), &seed.Fixture{
+		Users: []seed.UserFixture{{Username: email, Password: "password123", Admin: true}},
+	})
+	require.NoError(t, err)
+}
+
+// doRequest выполняет запрос с токеном; тело ответа закрывается по завершении теста.
+func doRequest(t *testing.T, method, url, token, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, bytes.NewBufferString(body))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestNewRouter_AdminRoutesRequireAdmin(t *testing.T) {
+	os.Setenv("JWT_SECRET", "testsecret")
+	defer os.Unsetenv("JWT_SECRET")
+
+	cfg := newTestConfig()
+	cfg.Admin.Users = []string{"hr@example.com", "boss@example.com"}
+	st := app.NewMemoryStorage(memory.New())
+	provisionAdmin(t, st, "hr@example.com")
+	server := httptest.NewServer(app.NewRouter(newTestLogger(), cfg, st))
+	defer server.Close()
+
+	userToken := login(t, server.URL, "user@example.com")
+	adminToken := login(t, server.URL, "hr@example.com")
+
+	// Зарезервированный email администратора не регистрируется первым вошедшим
+	resp, err := http.Post(server.URL+"/api/auth", "application/json",
+		bytes.NewBufferString(`{"username": "boss@example.com", "password": "password123"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	credit := func(token string) int {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/api/admin/credit",
+			bytes.NewBufferString(`{"username": "user@example.com", "amount": 50, "reason": "bonus"}`))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusForbidden, credit(userToken))
+	assert.Equal(t, http.StatusOK, credit(adminToken))
+}
+
+func TestNewRouter_CoinRequestFlow(t *testing.T) {
+	os.Setenv("JWT_SECRET", "testsecret")
+	defer os.Unsetenv("JWT_SECRET")
+
+	server := httptest.NewServer(app.NewRouter(newTestLogger(), newTestConfig(), app.NewMemoryStorage(memory.New())))
+	defer server.Close()
+
+	aliceToken := login(t, server.URL, "alice@example.com")
+	bobToken := login(t, server.URL, "bob@example.com")
+
+	do := func(method, path, token, body string) *http.Response {
+		return doRequest(t, method, server.URL+path, token, body)
+	}
+
+	resp := do(http.MethodPost, "/api/requests", aliceToken, `{"fromUser": "bob@example.com", "amount": 150, "comment": "обед"}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var created struct {
+		ID int64 `json:"id"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+
+	resp = do(http.MethodGet, "/api/requests", bobToken, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var list struct {
+		Incoming []struct {
+			ID       int64  `json:"id"`
+			FromUser string `json:"fromUser"`
+			Status   string `json:"status"`
+		} `json:"incoming"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	require.Len(t, list.Incoming, 1)
+	assert.Equal(t, "alice@example.com", list.Incoming[0].FromUser)
+
+	path := "/api/requests/" + strconv.FormatInt(created.ID, 10)
+	// Принять запрос может только плательщик
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPost, path+"/accept", aliceToken, "").StatusCode)
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, path+"/accept", bobToken, "").StatusCode)
+	assert.Equal(t, http.StatusConflict, do(http.MethodPost, path+"/decline", bobToken, "").StatusCode)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/api/requests/abc/accept", bobToken, "").StatusCode)
+
+	resp = do(http.MethodGet, "/api/info", aliceToken, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var info struct {
+		Coins int `json:"coins"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&info))
+	assert.Equal(t, 1150, info.Coins)
+}
+
+func TestNewRouter_MerchStock(t *testing.T) {
+	os.Setenv("JWT_SECRET", "testsecret")
+	defer os.Unsetenv("JWT_SECRET")
+
+	cfg := newTestConfig()
+	cfg.Admin.Users = []string{"hr@example.com"}
+	st := app.NewMemoryStorage(memory.New())
+	provisionAdmin(t, st, "hr@example.com")
+	server := httptest.NewServer(app.NewRouter(newTestLogger(), cfg, st))
+	defer server.Close()
+
+	userToken := login(t, server.URL, "user@example.com")
+	adminToken := login(t, server.URL, "hr@example.com")
+
+	assert.Equal(t, http.StatusForbidden, doRequest(t, http.MethodPut, server.URL+"/api/admin/merch/cup/stock", userToken, `{"stock": 1}`).StatusCode)
+	assert.Equal(t, http.StatusOK, doRequest(t, http.MethodPut, server.URL+"/api/admin/merch/cup/stock", adminToken, `{"stock": 1}`).StatusCode)
+	assert.Equal(t, http.StatusNotFound, doRequest(t, http.MethodPost, server.URL+"/api/admin/merch/unknown/restock", adminToken, `{"quantity": 1}`).StatusCode)
+
+	assert.Equal(t, http.StatusOK, doRequest(t, http.MethodGet, server.URL+"/api/buy/cup", userToken, "").StatusCode)
+	assert.Equal(t, http.StatusConflict, doRequest(t, http.MethodGet, server.URL+"/api/buy/cup", userToken, "").StatusCode)
+
+	resp := doRequest(t, http.MethodGet, server.URL+"/api/merch", userToken, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var catalog struct {
+		Items []struct {
+			Name    string `json:"name"`
+			Stock   *int   `json:"stock"`
+			SoldOut bool   `json:"soldOut"`
+		} `json:"items"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&catalog))
+	require.NotEmpty(t, catalog.Items)
+	for _, item := range catalog.Items {
+		if item.Name == "cup" {
+			assert.True(t, item.SoldOut)
+		} else {
+			assert.Nil(t, item.Stock, item.Name)
+		}
+	}
+
+	assert.Equal(t, http.StatusOK, doRequest(t, http.MethodPost, server.URL+"/api/admin/merch/cup/restock", adminToken, `{"quantity": 3}`).StatusCode)
+	assert.Equal(t, http.StatusOK, doRequest(t, http.MethodGet, server.URL+"/api/buy/cup", userToken, "").StatusCode)
+}
+
+func TestNewRouter_OrderStatuses(t *testing.T) {
+	os.Setenv("JWT_SECRET", "testsecret")
+	defer os.Unsetenv("JWT_SECRET")
+
+	cfg := newTestConfig()
+	cfg.Admin.Users = []string{"hr@example.com"}
+	st := app.NewMemoryStorage(memory.New())
+	provisionAdmin(t, st, "hr@example.com")
+	server := httptest.NewServer(app.NewRouter(newTestLogger(), cfg, st))
+	defer server.Close()
+
+	userToken := login(t, server.URL, "user@example.com")
+	otherToken := login(t, server.URL, "other@example.com")
+	adminToken := login(t, server.URL, "hr@example.com")
+
+	assert.Equal(t, http.StatusOK, doRequest(t, http.MethodGet, server.URL+"/api/buy/cup", userToken, "").StatusCode)
+
+	type order struct {
+		ID      int64  `json:"id"`
+		Item    string `json:"item"`
+		Status  string `json:"status"`
+		History []struct {
+			To string `json:"to"`
+		} `json:"history"`
+	}
+	resp := doRequest(t, http.MethodGet, server.URL+"/api/orders", userToken, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var list struct {
+		Orders []order `json:"orders"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	require.Len(t, list.Orders, 1)
+	assert.Equal(t, "cup", list.Orders[0].Item)
+	assert.Equal(t, "placed", list.Orders[0].Status)
+	orderURL := "/orders/" + strconv.FormatInt(list.Orders[0].ID, 10)
+
+	assert.Equal(t, http.StatusBadRequest, doRequest(t, http.MethodGet, server.URL+"/api/orders?status=lost", userToken, "").StatusCode)
+	assert.Equal(t, http.StatusNotFound, doRequest(t, http.MethodGet, server.URL+"/api"+orderURL, otherToken, "").StatusCode)
+	assert.Equal(t, http.StatusForbidden, doRequest(t, http.MethodPost, server.URL+"/api/admin"+orderURL+"/status", userToken, `{"status": "delivered"}`).StatusCode)
+	assert.Equal(t, http.StatusOK, doRequest(t, http.MethodPost, server.URL+"/api/admin"+orderURL+"/status", adminToken, `{"status": "delivered"}`).StatusCode)
+	assert.Equal(t, http.StatusConflict, doRequest(t, http.MethodPost, server.URL+"/api/admin"+orderURL+"/status", adminToken, `{"status": "cancelled"}`).StatusCode)
+
+	resp = doRequest(t, http.MethodGet, server.URL+"/api"+orderURL, userToken, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var got order
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+	assert.Equal(t, "delivered", got.Status)
+	require.Len(t, got.History, 2)
+	assert.Equal(t, "delivered", got.History[1].To)
+
+	resp = doRequest(t, http.MethodGet, server.URL+"/api/admin/orders?status=delivered", adminToken, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	assert.Len(t, list.Orders, 1)
+
+	// Выданный заказ не отменяется, невыданный — отменяется с возвратом монет
+	assert.Equal(t, http.StatusConflict, doRequest(t, http.MethodPost, server.URL+"/api"+orderURL+"/cancel", userToken, "").StatusCode)
+	assert.Equal(t, http.StatusOK, doRequest(t, http.MethodGet, server.URL+"/api/buy/cup", userToken, "").StatusCode)
+	resp = doRequest(t, http.MethodGet, server.URL+"/api/orders?status=placed", userToken, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	require.Len(t, list.Orders, 1)
+	cancelURL := server.URL + "/api/orders/" + strconv.FormatInt(list.Orders[0].ID, 10) + "/cancel"
+	assert.Equal(t, http.StatusNotFound, doRequest(t, http.MethodPost, cancelURL, otherToken, "").StatusCode)
+	assert.Equal(t, http.StatusOK, doRequest(t, http.MethodPost, cancelURL, userToken, "").StatusCode)
+}
+
+func TestNewRouter_MerchPrices(t *testing.T) {
+	os.Setenv("JWT_SECRET", "testsecret")
+	defer os.Unsetenv("JWT_SECRET")
+
+	cfg := newTestConfig()
+	cfg.Admin.Users = []string{"hr@example.com"}
+	st := app.NewMemoryStorage(memory.New())
+	provisionAdmin(t, st, "hr@example.com")
+	server := httptest.NewServer(app.NewRouter(newTestLogger(), cfg, st))
+	defer server.Close()
+
+	userToken := login(t, server.URL, "user@example.com")
+	adminToken := login(t, server.URL, "hr@example.com")
+
+	assert.Equal(t, http.StatusForbidden, doRequest(t, http.MethodPost, server.URL+"/api/admin/merch/cup/prices", userToken, `{"price": 15}`).StatusCode)
+	assert.Equal(t, http.StatusCreated, doRequest(t, http.MethodPost, server.URL+"/api/admin/merch/cup/prices", adminToken, `{"price": 15}`).StatusCode)
+	saleAt := time.Now().Add(48 * time.Hour).UTC().Format(time.RFC3339)
+	resp := doRequest(t, http.MethodPost, server.URL+"/api/admin/merch/cup/prices", adminToken, `{"price": 5, "effectiveFrom": "`+saleAt+`", "comment": "sale"}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var scheduled struct {
+		ID int64 `json:"id"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&scheduled))
+
+	resp = doRequest(t, http.MethodGet, server.URL+"/api/merch/cup/prices", userToken, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var history struct {
+		Price     int                   `json:"price"`
+		History   []struct{ Price int } `json:"history"`
+		Scheduled []struct{ Price int } `json:"scheduled"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&history))
+	assert.Equal(t, 15, history.Price)
+	assert.Len(t, history.History, 1)
+	assert.Len(t, history.Scheduled, 1)
+	assert.Equal(t, http.StatusNotFound, doRequest(t, http.MethodGet, server.URL+"/api/merch/unknown/prices", userToken, "").StatusCode)
+
+	// С покупки списывается действующая цена
+	assert.Equal(t, http.StatusOK, doRequest(t, http.MethodGet, server.URL+"/api/buy/cup", userToken, "").StatusCode)
+	resp = doRequest(t, http.MethodGet, server.URL+"/api/info", userToken, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var info struct {
+		Coins int `json:"coins"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&info))
+	assert.Equal(t, 985, info.Coins)
+
+	assert.Equal(t, http.StatusOK, doRequest(t, http.MethodDelete, server.URL+"/api/admin/merch/cup/prices/"+strconv.FormatInt(scheduled.ID, 10), adminToken, "").StatusCode)
+	assert.Equal(t, http.StatusNotFound, doRequest(t, http.MethodDelete, server.URL+"/api/admin/merch/cup/prices/"+strconv.FormatInt(scheduled.ID, 10), adminToken, "").StatusCode)
+}
+
+func TestNewRouter_Promotions(t *testing.T) {
+	os.Setenv("JWT_SECRET", "testsecret")
+	defer os.Unsetenv("JWT_SECRET")
+
+	cfg := newTestConfig()
+	cfg.Admin.Users = []string{"hr@example.com"}
+	st := app.NewMemoryStorage(memory.New())
+	provisionAdmin(t, st, "hr@example.com")
+	server := httptest.NewServer(app.NewRouter(newTestLogger(), cfg, st))
+	defer server.Close()
+
+	userToken := login(t, server.URL, "user@example.com")
+	adminToken := login(t, server.URL, "hr@example.com")
+
+	body := `{"name": "Неделя чашек", "code": "cup5", "discountType": "fixed", "discountValue": 5, "item": "cup", "maxUsesPerUser": 1}`
+	assert.Equal(t, http.StatusForbidden, doRequest(t, http.MethodPost, server.URL+"/api/admin/promotions", userToken, body).StatusCode)
+	resp := doRequest(t, http.MethodPost, server.URL+"/api/admin/promotions", adminToken, body)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var promo struct {
+		ID   int64  `json:"id"`
+		Code string `json:"code"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&promo))
+	assert.Equal(t, "CUP5", promo.Code)
+	assert.Equal(t, http.StatusConflict, doRequest(t, http.MethodPost, server.URL+"/api/admin/promotions", adminToken, body).StatusCode)
+	assert.Equal(t, http.StatusBadRequest, doRequest(t, http.MethodPost, server.URL+"/api/admin/promotions", adminToken,
+		`{"name": "x", "discountType": "percent", "discountValue": 150}`).StatusCode)
+
+	// Код применяется один раз, неизвестный код отклоняется
+	assert.Equal(t, http.StatusBadRequest, doRequest(t, http.MethodGet, server.URL+"/api/buy/cup?code=nope", userToken, "").StatusCode)
+	assert.Equal(t, http.StatusOK, doRequest(t, http.MethodGet, server.URL+"/api/buy/cup?code=cup5", userToken, "").StatusCode)
+	assert.Equal(t, http.StatusUnprocessableEntity, doRequest(t, http.MethodGet, server.URL+"/api/buy/cup?code=cup5", userToken, "").StatusCode)
+
+	resp = doRequest(t, http.MethodGet, server.URL+"/api/orders", userToken, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var orders struct {
+		Orders []struct {
+			TotalPrice int    `json:"totalPrice"`
+			Discount   int    `json:"discount"`
+			Promotion  string `json:"promotion"`
+		} `json:"orders"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&orders))
+	require.Len(t, orders.Orders, 1)
+	assert.Equal(t, 15, orders.Orders[0].TotalPrice)
+	assert.Equal(t, 5, orders.Orders[0].Discount)
+	assert.Equal(t, "Неделя чашек", orders.Orders[0].Promotion)
+
+	assert.Equal(t, http.StatusOK, doRequest(t, http.MethodPost, server.URL+"/api/admin/promotions/"+strconv.FormatInt(promo.ID, 10)+"/deactivate", adminToken, "").StatusCode)
+	assert.Equal(t, http.StatusNotFound, doRequest(t, http.MethodPost, server.URL+"/api/admin/promotions/999/deactivate", adminToken, "").StatusCode)
+	resp = doRequest(t, http.MethodGet, server.URL+"/api/admin/promotions", adminToken, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var list struct {
+		Promotions []struct {
+			Item   string `json:"item"`
+			Uses   int    `json:"uses"`
+			Active bool   `json:"active"`
+		} `json:"promotions"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	require.Len(t, list.Promotions, 1)
+	assert.Equal(t, "cup", list.Promotions[0].Item)
+	assert.Equal(t, 1, list.Promotions[0].Uses)
+	assert.False(t, list.Promotions[0].Active)
+}
+
+func TestNewRouter_MerchVariants(t *testing.T) {
+	os.Setenv("JWT_SECRET", "testsecret")
+	defer os.Unsetenv("JWT_SECRET")
+
+	cfg := newTestConfig()
+	cfg.Admin.Users = []string{"hr@example.com"}
+	st := app.NewMemoryStorage(memory.New())
+	provisionAdmin(t, st, "hr@example.com")
+	server := httptest.NewServer(app.NewRouter(newTestLogger(), cfg, st))
+	defer server.Close()
+
+	userToken := login(t, server.URL, "user@example.com")
+	adminToken := login(t, server.URL, "hr@example.com")
+
+	body := `{"sku": "HD-L-GRY", "size": "L", "color": "grey", "stock": 5}`
+	assert.Equal(t, http.StatusForbidden, doRequest(t, http.MethodPost, server.URL+"/api/admin/merch/hoody/variants", userToken, body).StatusCode)
+	assert.Equal(t, http.StatusCreated, doRequest(t, http.MethodPost, server.URL+"/api/admin/merch/hoody/variants", adminToken, body).StatusCode)
+	assert.Equal(t, http.StatusConflict, doRequest(t, http.MethodPost, server.URL+"/api/admin/merch/hoody/variants", adminToken, body).StatusCode)
+	assert.Equal(t, http.StatusNotFound, doRequest(t, http.MethodPost, server.URL+"/api/admin/merch/unknown/variants", adminToken, `{"sku": "X"}`).StatusCode)
+	assert.Equal(t, http.StatusOK, doRequest(t, http.MethodPut, server.URL+"/api/admin/merch/hoody/variants/HD-L-GRY", adminToken, `{"price": 250, "stock": 5}`).StatusCode)
+
+	assert.Equal(t, http.StatusBadRequest, doRequest(t, http.MethodGet, server.URL+"/api/buy/hoody", userToken, "").StatusCode)
+	assert.Equal(t, http.StatusOK, doRequest(t, http.MethodGet, server.URL+"/api/buy/hoody?variant=HD-L-GRY", userToken, "").StatusCode)
+
+	resp := doRequest(t, http.MethodGet, server.URL+"/api/info", userToken, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var info struct {
+		Coins     int `json:"coins"`
+		Inventory []struct {
+			Type     string `json:"type"`
+			Variant  string `json:"variant"`
+			Size     string `json:"size"`
+			Color    string `json:"color"`
+			Quantity int    `json:"quantity"`
+		} `json:"inventory"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&info))
+	assert.Equal(t, 750, info.Coins)
+	require.Len(t, info.Inventory, 1)
+	assert.Equal(t, "HD-L-GRY", info.Inventory[0].Variant)
+	assert.Equal(t, "grey", info.Inventory[0].Color)
+
+	assert.Equal(t, http.StatusOK, doRequest(t, http.MethodDelete, server.URL+"/api/admin/merch/hoody/variants/HD-L-GRY", adminToken, "").StatusCode)
+	assert.Equal(t, http.StatusNotFound, doRequest(t, http.MethodDelete, server.URL+"/api/admin/merch/hoody/variants/HD-L-GRY", adminToken, "").StatusCode)
+	assert.Equal(t, http.StatusOK, doRequest(t, http.MethodGet, server.URL+"/api/buy/hoody", userToken, "").StatusCode)
+}
+
+func TestNewRouter_MerchDetailsAndImages(t *testing.T) {
+	os.Setenv("JWT_SECRET", "testsecret")
+	defer os.Unsetenv("JWT_SECRET")
+
+	cfg := newTestConfig()
+	cfg.Admin.Users = []string{"hr@example.com"}
+	st := app.NewMemoryStorage(memory.New())
+	provisionAdmin(t, st, "hr@example.com")
+	server := httptest.NewServer(app.NewRouter(newTestLogger(), cfg, st))
+	defer server.Close()
+
+	userToken := login(t, server.URL, "user@example.com")
+	adminToken := login(t, server.URL, "hr@example.com")
+
+	catalog := func(query string) []string {
+		t.Helper()
+		resp := doRequest(t, http.MethodGet, server.URL+"/api/merch"+query, userToken, "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var body struct {
+			Items []struct {
+				Name string `json:"name"`
+			} `json:"items"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		names := make([]string, 0, len(body.Items))
+		for _, item := range body.Items {
+			names = append(names, item.Name)
+		}
+		return names
+	}
+
+	// Каталог по умолчанию разложен по категориям
+	assert.Equal(t, []string{"book", "pen"}, catalog("?category=stationery"))
+	assert.Equal(t, []string{"hoody", "pink-hoody"}, catalog("?category=clothing&minPrice=100"))
+	assert.Equal(t, http.StatusBadRequest, doRequest(t, http.MethodGet, server.URL+"/api/merch?minPrice=abc", userToken, "").StatusCode)
+	assert.Equal(t, http.StatusBadRequest, doRequest(t, http.MethodGet, server.URL+"/api/merch?minPrice=50&maxPrice=10", userToken, "").StatusCode)
+	assert.Equal(t, http.StatusOK, doRequest(t, http.MethodGet, server.URL+"/api/merch/categories", userToken, "").StatusCode)
+
+	details := `{"category": "accessories", "description": "Ceramic mug", "tags": ["kitchen"]}`
+	assert.Equal(t, http.StatusForbidden, doRequest(t, http.MethodPut, server.URL+"/api/admin/merch/cup/details", userToken, details).StatusCode)
+	assert.Equal(t, http.StatusOK, doRequest(t, http.MethodPut, server.URL+"/api/admin/merch/cup/details", adminToken, details).StatusCode)
+	assert.Equal(t, []string{"cup"}, catalog("?q=mug"))
+	assert.Equal(t, []string{"cup"}, catalog("?tag=kitchen"))
+
+	png := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+	assert.Equal(t, http.StatusNotFound, doRequest(t, http.MethodGet, server.URL+"/api/merch/cup/image", "", "").StatusCode)
+	assert.Equal(t, http.StatusUnsupportedMediaType, doRequest(t, http.MethodPut, server.URL+"/api/admin/merch/cup/image", adminToken, "<svg/>").StatusCode)
+	assert.Equal(t, http.StatusRequestEntityTooLarge,
+		doRequest(t, http.MethodPut, server.URL+"/api/admin/merch/cup/image", adminToken, png+strings.Repeat("x", 1024)).StatusCode)
+
+	resp := doRequest(t, http.MethodPut, server.URL+"/api/admin/merch/cup/image", adminToken, png)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var item struct {
+		Image string `json:"image"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&item))
+	assert.Equal(t, "/api/merch/cup/image", item.Image)
+
+	// Изображение доступно без токена
+	resp = doRequest(t, http.MethodGet, server.URL+item.Image, "", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, png, string(data))
+
+	assert.Equal(t, http.StatusOK, doRequest(t, http.MethodDelete, server.URL+"/api/admin/merch/cup/image", adminToken, "").StatusCode)
+	assert.Equal(t, http.StatusNotFound, doRequest(t, http.MethodGet, server.URL+"/api/merch/cup/image", "", "").StatusCode)
+}
+
+func TestNewRouter_Wishlist(t *testing.T) {
+	os.Setenv("JWT_SECRET", "testsecret")
+	defer os.Unsetenv("JWT_SECRET")
+
+	server := httptest.NewServer(app.NewRouter(newTestLogger(), newTestConfig(), app.NewMemoryStorage(memory.New())))
+	defer server.Close()
+
+	token := login(t, server.URL, "user@example.com")
+
+	assert.Equal(t, http.StatusCreated, doRequest(t, http.MethodPost, server.URL+"/api/wishlist", token, `{"item": "pink-hoody"}`).StatusCode)
+	assert.Equal(t, http.StatusConflict, doRequest(t, http.MethodPost, server.URL+"/api/wishlist", token, `{"item": "pink-hoody"}`).StatusCode)
+	assert.Equal(t, http.StatusNotFound, doRequest(t, http.MethodPost, server.URL+"/api/wishlist", token, `{"item": "unknown"}`).StatusCode)
+	assert.Equal(t, http.StatusBadRequest, doRequest(t, http.MethodPost, server.URL+"/api/wishlist", token, `{}`).StatusCode)
+
+	resp := doRequest(t, http.MethodGet, server.URL+"/api/wishlist", token, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var body struct {
+		Items []struct {
+			Item       string `json:"item"`
+			Price      int    `json:"price"`
+			Affordable bool   `json:"affordable"`
+		} `json:"items"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	require.Len(t, body.Items, 1)
+	assert.Equal(t, "pink-hoody", body.Items[0].Item)
+	assert.True(t, body.Items[0].Affordable)
+
+	assert.Equal(t, http.StatusOK, doRequest(t, http.MethodDelete, server.URL+"/api/wishlist/pink-hoody", token, "").StatusCode)
+	assert.Equal(t, http.StatusNotFound, doRequest(t, http.MethodDelete, server.URL+"/api/wishlist/pink-hoody", token, "").StatusCode)
+}
+
+func TestNewRouter_Notifications(t *testing.T) {
+	os.Setenv("JWT_SECRET", "testsecret")
+	defer os.Unsetenv("JWT_SECRET")
+
+	server := httptest.NewServer(app.NewRouter(newTestLogger(), newTestConfig(), app.NewMemoryStorage(memory.New())))
+	defer server.Close()
+
+	alice := login(t, server.URL, "alice@example.com")
+	bob := login(t, server.URL, "bob@example.com")
+
+	for _, amount := range []int{10, 20} {
+		body := fmt.Sprintf(`{"toUser": "bob@example.com", "amount": %d, "comment": "спасибо"}`, amount)
+		require.Equal(t, http.StatusOK, doRequest(t, http.MethodPost, server.URL+"/api/sendCoin", alice, body).StatusCode)
+	}
+
+	type inbox struct {
+		Unread        int `json:"unread"`
+		Notifications []struct {
+			ID   int64          `json:"id"`
+			Type string         `json:"type"`
+			Data map[string]any `json:"data"`
+			Read bool           `json:"read"`
+		} `json:"notifications"`
+	}
+	getInbox := func(query string) inbox {
+		t.Helper()
+		resp := doRequest(t, http.MethodGet, server.URL+"/api/notifications"+query, bob, "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var res inbox
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+		return res
+	}
+
+	res := getInbox("?limit=1")
+	assert.Equal(t, 2, res.Unread)
+	require.Len(t, res.Notifications, 1)
+	assert.Equal(t, models.NotificationCoinsReceived, res.Notifications[0].Type)
+	assert.Equal(t, "alice@example.com", res.Notifications[0].Data["from"])
+	assert.Equal(t, float64(20), res.Notifications[0].Data["amount"])
+
+	readURL := fmt.Sprintf("%s/api/notifications/%d/read", server.URL, res.Notifications[0].ID)
+	// Чужое уведомление отправителю не найдётся
+	assert.Equal(t, http.StatusNotFound, doRequest(t, http.MethodPost, readURL, alice, "").StatusCode)
+	resp := doRequest(t, http.MethodPost, readURL, bob, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var read struct {
+		Unread int `json:"unread"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&read))
+	assert.Equal(t, 1, read.Unread)
+
+	res = getInbox("?unread=true")
+	assert.Equal(t, 1, res.Unread)
+	require.Len(t, res.Notifications, 1)
+	assert.False(t, res.Notifications[0].Read)
+
+	assert.Equal(t, http.StatusOK, doRequest(t, http.MethodPost, server.URL+"/api/notifications/read-all", bob, "").StatusCode)
+	res = getInbox("")
+	assert.Zero(t, res.Unread)
+	assert.Len(t, res.Notifications, 2)
+
+	assert.Equal(t, http.StatusBadRequest, doRequest(t, http.MethodGet, server.URL+"/api/notifications?limit=0", bob, "").StatusCode)
+	assert.Equal(t, http.StatusBadRequest, doRequest(t, http.MethodGet, server.URL+"/api/notifications?unread=maybe", bob, "").StatusCode)
+	assert.Equal(t, http.StatusBadRequest, doRequest(t, http.MethodPost, server.URL+"/api/notifications/abc/read", bob, "").StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, doRequest(t, http.MethodGet, server.URL+"/api/notifications", "", "").StatusCode)
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	"time"
@@ -9,12 +10,33 @@ import (
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
+// Варианты хранилища данных
+const (
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+)
+
 type Config struct {
-	Env        string           `yaml:"env" env-default:"development"` // environment
+	Env        string           `yaml:"env" env-default:"development"`  // environment
+	Storage    string           `yaml:"storage" env-default:"postgres"` // postgres или memory
 	HTTPServer HTTPServerConfig `yaml:"http_server"`
 	Database   DatabaseConfig   `yaml:"database"`
 	JWT        JWTConfig        `yaml:"jwt"`
 	Migrations MigrationsConfig `yaml:"migrations"`
+	Admin      AdminConfig      `yaml:"admin"`
+	// WelcomeBonus стартовый бонус при регистрации
+	WelcomeBonus WelcomeBonusConfig `yaml:"welcome_bonus"`
+	Scheduler    SchedulerConfig    `yaml:"scheduler"`
+	// CoinExpiration сгорание начисленных монет
+	CoinExpiration CoinExpirationConfig `yaml:"coin_expiration"`
+	// TransferLimits ограничения переводов между сотрудниками
+	TransferLimits TransferLimitsConfig `yaml:"transfer_limits"`
+	// CoinRequests запросы монет у коллег
+	CoinRequests CoinRequestsConfig `yaml:"coin_requests"`
+	// Orders заказы мерча
+	Orders OrdersConfig `yaml:"orders"`
+	// Images изображения товаров
+	Images ImagesConfig `yaml:"images"`
 }
 
 // HTTPServerConfig структура http сервера
@@ -22,15 +44,18 @@ type HTTPServerConfig struct {
 	Address     string        `yaml:"address" env-default:"localhost:8080"`
 	Timeout     time.Duration `yaml:"timeout" env-default:"4s"`
 	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"60s"`
+	// ShutdownTimeout — сколько ждать завершения активных запросов при остановке
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env-default:"5s"`
 }
 
 // DatabaseConfig структура по работе с БД
+// User, Password и Name обязательны только для storage: postgres, проверяются при подключении
 type DatabaseConfig struct {
 	Host     string `yaml:"host" env-default:"localhost"`
 	Port     int    `yaml:"port" env-default:"5432"`
-	User     string `yaml:"user" env-required:"true"`
-	Password string `yaml:"-" env:"DB_PASSWORD" env-required:"true"`
-	Name     string `yaml:"name" env-required:"true"`
+	User     string `yaml:"user"`
+	Password string `yaml:"-" env:"DB_PASSWORD"`
+	Name     string `yaml:"name"`
 }
 
 // JWTConfig настройка jwt
@@ -39,8 +64,111 @@ type JWTConfig struct {
 	TokenTTL int    `yaml:"token_ttl" env-default:"60"`
 }
 
+// MigrationsConfig настройки миграций. Сами миграции встроены в бинарники,
+// Path нужен только для создания новых файлов и запуска мигратора с диска.
 type MigrationsConfig struct {
 	Path string `yaml:"path" env-default:"./migrations"`
+	// AutoMigrate — применять встроенные миграции при старте сервера
+	AutoMigrate bool `yaml:"auto_migrate" env:"AUTO_MIGRATE" env-default:"false"`
+	// LockTimeout — сколько ждать advisory lock, пока миграции применяет другая реплика
+	LockTimeout time.Duration `yaml:"lock_timeout" env-default:"1m"`
+}
+
+// AdminConfig email администраторов, которые не регистрируются автоматически при входе
+// (права выдаёт фикстура с admin: true), и настройки массовых начислений
+type AdminConfig struct {
+	Users          []string `yaml:"users" env:"ADMIN_USERS" env-separator:","`
+	GrantBatchSize int      `yaml:"grant_batch_size" env-default:"100"`
+}
+
+// WelcomeBonusConfig стартовый бонус и его правила для отдельных email-доменов.
+// expires_at — момент (например, 2025-12-31T23:59:59Z), после которого правило перестаёт действовать
+type WelcomeBonusConfig struct {
+	Amount    int                 `yaml:"amount" env:"WELCOME_BONUS" env-default:"1000"`
+	ExpiresAt time.Time           `yaml:"expires_at"`
+	Domains   []DomainBonusConfig `yaml:"domains"`
+}
+
+// DomainBonusConfig стартовый бонус для email-домена (заменяет бонус по умолчанию)
+type DomainBonusConfig struct {
+	Domain    string    `yaml:"domain"`
+	Amount    int       `yaml:"amount"`
+	ExpiresAt time.Time `yaml:"expires_at"`
+}
+
+// SchedulerConfig фоновые задачи по расписанию. Расписание — cron из пяти полей
+// ("минута час день_месяца месяц день_недели") или сокращение (@daily, @monthly)
+type SchedulerConfig struct {
+	Enabled bool `yaml:"enabled" env:"SCHEDULER_ENABLED" env-default:"true"`
+	// Timezone — часовой пояс, в котором вычисляются расписания (например, Europe/Moscow)
+	Timezone  string             `yaml:"timezone" env-default:"UTC"`
+	Allowance AllowanceJobConfig `yaml:"allowance"`
+	// Expiration задача сгорания монет, работает, только если задан coin_expiration.ttl
+	Expiration ExpirationJobConfig `yaml:"expiration"`
+	// RequestExpiration задача, переводящая просроченные запросы монет в expired
+	RequestExpiration RequestExpirationJobConfig `yaml:"request_expiration"`
+}
+
+// AllowanceJobConfig регулярное начисление всем активным сотрудникам; пустое расписание — выключено
+type AllowanceJobConfig struct {
+	Schedule  string `yaml:"schedule" env:"ALLOWANCE_SCHEDULE"`
+	Amount    int    `yaml:"amount" env:"ALLOWANCE_AMOUNT"`
+	Reason    string `yaml:"reason" env-default:"monthly allowance"`
+	BatchSize int    `yaml:"batch_size" env-default:"500"`
+}
+
+// ExpirationJobConfig расписание задачи сгорания монет
+type ExpirationJobConfig struct {
+	Schedule  string `yaml:"schedule" env:"EXPIRATION_SCHEDULE" env-default:"@hourly"`
+	BatchSize int    `yaml:"batch_size" env-default:"500"`
+}
+
+// RequestExpirationJobConfig расписание задачи истечения запросов монет; пустое — выключена
+// (просроченный запрос всё равно нельзя принять, он лишь остаётся pending в БД)
+type RequestExpirationJobConfig struct {
+	Schedule string `yaml:"schedule" env:"REQUEST_EXPIRATION_SCHEDULE" env-default:"@hourly"`
+}
+
+// CoinExpirationConfig срок жизни монет по типу начисления (welcome_bonus, grant, transfer_received,
+// allowance, adjustment), например {grant: 8760h}. Тип без срока не сгорает, пустой ttl — сгорание выключено
+type CoinExpirationConfig struct {
+	TTL map[string]time.Duration `yaml:"ttl"`
+	// ExpiringWindow — насколько вперёд /api/info показывает сгорающие монеты (0 — все)
+	ExpiringWindow time.Duration `yaml:"expiring_window" env-default:"720h"`
+}
+
+// TransferLimitsConfig лимиты переводов; 0 — правило выключено. Суточные лимиты считаются
+// за последние 24 часа
+type TransferLimitsConfig struct {
+	MaxAmount     int `yaml:"max_amount" env:"TRANSFER_MAX_AMOUNT"`
+	DailyOutgoing int `yaml:"daily_outgoing" env:"TRANSFER_DAILY_OUTGOING"`
+	DailyIncoming int `yaml:"daily_incoming" env:"TRANSFER_DAILY_INCOMING"`
+	// MinAccountAge — с какого возраста аккаунта (от регистрации) разрешено отправлять переводы
+	MinAccountAge time.Duration  `yaml:"min_account_age"`
+	Velocity      VelocityConfig `yaml:"velocity"`
+}
+
+// VelocityConfig не больше MaxTransfers исходящих переводов за Window
+type VelocityConfig struct {
+	MaxTransfers int           `yaml:"max_transfers"`
+	Window       time.Duration `yaml:"window" env-default:"1m"`
+}
+
+// CoinRequestsConfig запросы монет: сколько запрос ждёт ответа плательщика
+type CoinRequestsConfig struct {
+	TTL time.Duration `yaml:"ttl" env-default:"168h"`
+}
+
+// OrdersConfig заказы: сколько времени после покупки сотрудник может сам отменить заказ
+type OrdersConfig struct {
+	CancelWindow time.Duration `yaml:"cancel_window" env:"ORDER_CANCEL_WINDOW" env-default:"24h"`
+}
+
+// ImagesConfig изображения товаров: каталог на диске (для storage: postgres; в памяти изображения
+// живут до перезапуска) и максимальный размер загружаемого файла в байтах
+type ImagesConfig struct {
+	Dir     string `yaml:"dir" env:"IMAGES_DIR" env-default:"./data/images"`
+	MaxSize int64  `yaml:"max_size" env-default:"2097152"`
 }
 
 // MustLoad - если не загружаем - паникуем
@@ -69,10 +197,19 @@ func MustLoadByPath(configPath string) *Config {
 		panic("config file not found: " + configPath)
 	}
 
-	var cfg Config
-	if err := cleanenv.ReadConfig(configPath, &cfg); err != nil {
+	cfg, err := Load(configPath)
+	if err != nil {
 		log.Fatalf("can't read config file %s", configPath)
 	}
 
-	return &cfg
+	return cfg
+}
+
+// Load читает конфиг и возвращает ошибку вместо паники (для утилит с собственными кодами выхода)
+func Load(configPath string) (*Config, error) {
+	var cfg Config
+	if err := cleanenv.ReadConfig(configPath, &cfg); err != nil {
+		return nil, fmt.Errorf("can't read config file %s: %w", configPath, err)
+	}
+	return &cfg, nil
 }
@@ -65,3 +65,134 @@ func TestMustLoadByPath_FileNotFound(t *testing.T) {
 		config.MustLoadByPath("non_existent_config.yaml")
 	})
 }
+
+func TestLoad_WelcomeBonus(t *testing.T) {
+	os.Setenv("JWT_SECRET", "mysecret")
+	defer os.Unsetenv("JWT_SECRET")
+
+	content := `
+welcome_bonus:
+  amount: 500
+  expires_at: 2030-01-01T00:00:00Z
+  domains:
+    - domain: "partner.com"
+      amount: 100
+`
+	tmpFile, err := os.CreateTemp("", "config_test_*.yaml")
+	assert.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+	_, err = tmpFile.WriteString(content)
+	assert.NoError(t, err)
+	assert.NoError(t, tmpFile.Close())
+
+	cfg, err := config.Load(tmpFile.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, 500, cfg.WelcomeBonus.Amount)
+	assert.Equal(t, time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), cfg.WelcomeBonus.ExpiresAt)
+	assert.Equal(t, []config.DomainBonusConfig{{Domain: "partner.com", Amount: 100}}, cfg.WelcomeBonus.Domains)
+}
+
+func TestLoad_WelcomeBonusDefault(t *testing.T) {
+	os.Setenv("JWT_SECRET", "mysecret")
+	defer os.Unsetenv("JWT_SECRET")
+
+	tmpFile, err := os.CreateTemp("", "config_test_*.yaml")
+	assert.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+	_, err = tmpFile.WriteString("env: \"local\"\n")
+	assert.NoError(t, err)
+	assert.NoError(t, tmpFile.Close())
+
+	cfg, err := config.Load(tmpFile.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, cfg.WelcomeBonus.Amount)
+	assert.True(t, cfg.WelcomeBonus.ExpiresAt.IsZero())
+}
+
+func TestLoad_Scheduler(t *testing.T) {
+	os.Setenv("JWT_SECRET", "mysecret")
+	defer os.Unsetenv("JWT_SECRET")
+
+	content := `
+scheduler:
+  timezone: "Europe/Moscow"
+  allowance:
+    schedule: "0 9 1 * *"
+    amount: 100
+`
+	tmpFile, err := os.CreateTemp("", "config_test_*.yaml")
+	assert.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+	_, err = tmpFile.WriteString(content)
+	assert.NoError(t, err)
+	assert.NoError(t, tmpFile.Close())
+
+	cfg, err := config.Load(tmpFile.Name())
+	assert.NoError(t, err)
+	assert.True(t, cfg.Scheduler.Enabled)
+	assert.Equal(t, "Europe/Moscow", cfg.Scheduler.Timezone)
+	assert.Equal(t, config.AllowanceJobConfig{
+		Schedule:  "0 9 1 * *",
+		Amount:    100,
+		Reason:    "monthly allowance",
+		BatchSize: 500,
+	}, cfg.Scheduler.Allowance)
+}
+
+func TestLoad_CoinExpiration(t *testing.T) {
+	os.Setenv("JWT_SECRET", "mysecret")
+	defer os.Unsetenv("JWT_SECRET")
+
+	content := `
+coin_expiration:
+  ttl:
+    welcome_bonus: 8760h
+    transfer_received: 2160h
+`
+	tmpFile, err := os.CreateTemp("", "config_test_*.yaml")
+	assert.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+	_, err = tmpFile.WriteString(content)
+	assert.NoError(t, err)
+	assert.NoError(t, tmpFile.Close())
+
+	cfg, err := config.Load(tmpFile.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]time.Duration{
+		"welcome_bonus":     8760 * time.Hour,
+		"transfer_received": 2160 * time.Hour,
+	}, cfg.CoinExpiration.TTL)
+	assert.Equal(t, 720*time.Hour, cfg.CoinExpiration.ExpiringWindow)
+	assert.Equal(t, "@hourly", cfg.Scheduler.Expiration.Schedule)
+}
+
+func TestLoad_TransferLimits(t *testing.T) {
+	os.Setenv("JWT_SECRET", "mysecret")
+	os.Setenv("TRANSFER_MAX_AMOUNT", "300")
+	defer os.Unsetenv("JWT_SECRET")
+	defer os.Unsetenv("TRANSFER_MAX_AMOUNT")
+
+	content := `
+transfer_limits:
+  max_amount: 500
+  daily_outgoing: 1000
+  min_account_age: 72h
+  velocity:
+    max_transfers: 5
+`
+	tmpFile, err := os.CreateTemp("", "config_test_*.yaml")
+	assert.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+	_, err = tmpFile.WriteString(content)
+	assert.NoError(t, err)
+	assert.NoError(t, tmpFile.Close())
+
+	cfg, err := config.Load(tmpFile.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, config.TransferLimitsConfig{
+		MaxAmount:     300, // переменная окружения важнее файла
+		DailyOutgoing: 1000,
+		MinAccountAge: 72 * time.Hour,
+		Velocity:      config.VelocityConfig{MaxTransfers: 5, Window: time.Minute},
+	}, cfg.TransferLimits)
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Статусы запуска фоновой задачи
+const (
+	JobRunRunning   = "running"
+	JobRunSucceeded = "succeeded"
+	JobRunFailed    = "failed"
+)
+
+// JobRun представляет запуск фоновой задачи планировщика. Для каждой задачи
+// и запланированного момента существует не больше одного запуска.
+type JobRun struct {
+	ID          int64      `json:"id"`
+	Job         string     `json:"job"`
+	ScheduledAt time.Time  `json:"scheduled_at"` // момент по расписанию, за который выполняется запуск
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+}
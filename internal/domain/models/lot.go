@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// CoinLot — партия начисленных монет. Списания расходуют партии от старых к новым,
+// а непотраченный остаток партии со сроком ExpiresAt сгорает.
+type CoinLot struct {
+	ID        int64      `json:"id"`
+	UserID    int64      `json:"user_id"`
+	Amount    int        `json:"amount"`    // начислено
+	Remaining int        `json:"remaining"` // ещё не потрачено
+	Source    string     `json:"source"`    // тип операции начисления, например "grant"
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -1,8 +1,51 @@
 package models
 
+import "time"
+
+// Периоды лимита покупок товара (календарные, в UTC)
+const (
+	PurchaseLimitForever = "" // за всё время
+	PurchaseLimitMonth   = "month"
+	PurchaseLimitQuarter = "quarter"
+	PurchaseLimitYear    = "year"
+)
+
 // Merch представляет товар мерча, доступный для покупки
 type Merch struct {
 	ID    int64  // Уникальный идентификатор товара
 	Name  string // Название товара (уникальное)
-	Price int    // Цена товара в монетах
+	Price int    // Действующая цена товара в монетах (с учётом истории цен)
+	Stock *int   // Остаток на складе; nil — количество не ограничено
+	// PurchaseLimit — сколько штук один сотрудник может получить за PurchaseLimitPeriod; 0 — без ограничения
+	PurchaseLimit       int
+	PurchaseLimitPeriod string
+	Category            string   // Категория каталога; пусто — без категории
+	Description         string   // Описание для витрины
+	ImageKey            string   // Ключ изображения в хранилище blob; пусто — изображения нет
+	Tags                []string // Теги для поиска, в нижнем регистре
+}
+
+// MerchVariant — вариант товара (размер и/или цвет) со своим артикулом SKU.
+// Price и Stock необязательны: nil — действуют цена и остаток самого товара.
+type MerchVariant struct {
+	ID      int64
+	MerchID int64
+	SKU     string
+	Size    string
+	Color   string
+	Price   *int
+	Stock   *int
+	Active  bool // выключенный вариант не продаётся и не показывается в каталоге
+}
+
+// MerchPriceChange — запись истории цен товара. Цена действует с EffectiveFrom до следующей записи;
+// запись с EffectiveFrom в будущем — запланированное изменение.
+type MerchPriceChange struct {
+	ID            int64
+	MerchID       int64
+	Price         int
+	EffectiveFrom time.Time
+	ChangedBy     *int64 // администратор; nil — загрузка фикстур или миграция
+	Comment       string
+	CreatedAt     time.Time
 }
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Типы уведомлений
+const (
+	// NotificationWishlistAffordable — баланса стало хватать на товар из списка желаний
+	NotificationWishlistAffordable = "wishlist.affordable"
+	// NotificationBackInStock — распроданный товар из списка желаний снова в продаже
+	NotificationBackInStock = "merch.back_in_stock"
+	// NotificationCoinsReceived — другой сотрудник перевёл пользователю монеты
+	NotificationCoinsReceived = "coins.received"
+	// NotificationCoinsGranted — администратор начислил пользователю монеты
+	NotificationCoinsGranted = "coins.granted"
+	// NotificationOrderStatusChanged — изменился статус заказа пользователя
+	NotificationOrderStatusChanged = "order.status_changed"
+)
+
+// Notification — уведомление пользователю. Data — подробности для клиента
+// (например, товар и цена), Message — готовый текст.
+type Notification struct {
+	ID        int64
+	UserID    int64
+	Type      string
+	Message   string
+	Data      map[string]any
+	ReadAt    *time.Time // nil — не прочитано
+	CreatedAt time.Time
+}
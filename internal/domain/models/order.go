@@ -2,6 +2,14 @@ package models
 
 import "time"
 
+// Статусы заказа: placed → ready_for_pickup → delivered; до выдачи заказ можно отменить
+const (
+	OrderPlaced         = "placed"
+	OrderReadyForPickup = "ready_for_pickup"
+	OrderDelivered      = "delivered"
+	OrderCancelled      = "cancelled"
+)
+
 // Order представляет заказ, созданный при покупке мерча
 type Order struct {
 	ID         int64     `json:"id"`
@@ -9,6 +17,30 @@ type Order struct {
 	MerchID    int64     `json:"merch_id"`
 	MerchName  string    `json:"merch_name"` // Имя товара; заполняется через JOIN с таблицей merch
 	Quantity   int       `json:"quantity"`
-	TotalPrice int       `json:"total_price"`
+	TotalPrice int       `json:"total_price"`         // сколько заплачено, с учётом скидки
+	GiftedBy   *int64    `json:"gifted_by,omitempty"` // кто купил заказ в подарок пользователю UserID
+	Status     string    `json:"status"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"` // время последней смены статуса
+	// Discount — скидка по акции PromotionID; PromotionName заполняется через JOIN с таблицей promotions
+	Discount      int    `json:"discount"`
+	PromotionID   *int64 `json:"promotion_id,omitempty"`
+	PromotionName string `json:"promotion_name,omitempty"`
+	// VariantID — купленный вариант товара; размер и цвет заполняются через JOIN с merch_variants.
+	// Артикул хранится в заказе и остаётся, даже если строку варианта удалили и VariantID стал nil.
+	VariantID    *int64 `json:"variant_id,omitempty"`
+	VariantSKU   string `json:"variant_sku,omitempty"`
+	VariantSize  string `json:"variant_size,omitempty"`
+	VariantColor string `json:"variant_color,omitempty"`
+}
+
+// OrderStatusChange — запись истории статусов заказа.
+type OrderStatusChange struct {
+	ID         int64     `json:"id"`
+	OrderID    int64     `json:"order_id"`
+	FromStatus string    `json:"from_status,omitempty"` // пусто для создания заказа
+	ToStatus   string    `json:"to_status"`
+	ChangedBy  *int64    `json:"changed_by,omitempty"`
+	Comment    string    `json:"comment,omitempty"`
 	CreatedAt  time.Time `json:"created_at"`
 }
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Типы скидок акции
+const (
+	DiscountPercent = "percent" // процент от цены товара
+	DiscountFixed   = "fixed"   // фиксированное число монет
+)
+
+// Promotion — акция со скидкой на покупку мерча. Акция без кода применяется автоматически,
+// с кодом — только если покупатель его указал.
+type Promotion struct {
+	ID            int64
+	Name          string
+	Code          string // пусто — акция применяется автоматически
+	DiscountType  string
+	DiscountValue int    // процент (1–100) или монеты
+	MerchID       *int64 // nil — на любой товар
+	UserID        *int64 // nil — для любого покупателя
+	StartsAt      *time.Time
+	EndsAt        *time.Time
+	// MaxUses и MaxUsesPerUser — сколько заказов можно оформить по акции всего и одному покупателю; 0 — без ограничения
+	MaxUses        int
+	MaxUsesPerUser int
+	Active         bool
+	CreatedBy      *int64
+	CreatedAt      time.Time
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Статусы запроса монет
+const (
+	CoinRequestPending  = "pending"
+	CoinRequestAccepted = "accepted"
+	CoinRequestDeclined = "declined"
+	CoinRequestExpired  = "expired"
+)
+
+// CoinRequest — запрос монет: RequesterID просит у PayerID перевести Amount монет.
+// Плательщик принимает запрос (выполняется обычный перевод) или отклоняет его;
+// не рассмотренный до ExpiresAt запрос истекает.
+type CoinRequest struct {
+	ID          int64      `json:"id"`
+	RequesterID int64      `json:"requester_id"`
+	PayerID     int64      `json:"payer_id"`
+	Amount      int        `json:"amount"`
+	Comment     string     `json:"comment,omitempty"`
+	Status      string     `json:"status"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
+}
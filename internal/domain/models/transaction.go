@@ -2,12 +2,32 @@ package models
 
 import "time"
 
+// Типы операций с монетами
+const (
+	CoinTxTransferSent     = "transfer_sent"
+	CoinTxTransferReceived = "transfer_received"
+	CoinTxGrant            = "grant"      // начисление администратором (в том числе массовое)
+	CoinTxAdjustment       = "adjustment" // ручная корректировка баланса: amount > 0 — начисление, < 0 — списание
+	CoinTxWelcomeBonus     = "welcome_bonus"
+	CoinTxAllowance        = "allowance"     // регулярное начисление всем сотрудникам по расписанию
+	CoinTxExpired          = "expired"       // сгорание непотраченного остатка партии монет (amount < 0)
+	CoinTxGiftSent         = "gift_sent"     // покупка мерча в подарок: amount — минус цена, comment — товар
+	CoinTxGiftReceived     = "gift_received" // получение мерча в подарок: amount = 0, баланс получателя не меняется
+	CoinTxRefund           = "refund"        // возврат цены отменённого заказа покупателю: comment — товар
+)
+
 // CoinTransaction представляет операцию с монетами.
+// Amount — изменение баланса пользователя: начисления положительные, списания отрицательные.
+// Исключение — переводы: transfer_sent и transfer_received хранят сумму перевода положительной,
+// направление задаёт тип.
 type CoinTransaction struct {
-	ID            int64     `json:"id"`
-	UserID        int64     `json:"user_id"`
-	Amount        int       `json:"amount"`
-	Type          string    `json:"type"` // например, "transfer_sent" или "transfer_received"
-	RelatedUserID *int64    `json:"related_user_id,omitempty"`
-	CreatedAt     time.Time `json:"created_at"`
+	ID            int64  `json:"id"`
+	UserID        int64  `json:"user_id"`
+	Amount        int    `json:"amount"`
+	Type          string `json:"type"` // например, "transfer_sent" или "transfer_received"
+	RelatedUserID *int64 `json:"related_user_id,omitempty"`
+	Comment       string `json:"comment,omitempty"` // причина операции
+	// IdempotencyKey — уникальный ключ операции; повторная запись с тем же ключом не выполняется
+	IdempotencyKey *string   `json:"-"`
+	CreatedAt      time.Time `json:"created_at"`
 }
@@ -1,9 +1,13 @@
 package models
 
+import "time"
+
 // User представляет пользователя
 type User struct {
 	ID          int64
 	Email       string
 	PassHash    []byte
 	CoinBalance int
+	IsAdmin     bool      // доступ к /api/admin; выдаётся только фикстурой при загрузке через seed
+	CreatedAt   time.Time // дата регистрации
 }
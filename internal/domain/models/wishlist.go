@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// WishlistItem — товар в списке желаний пользователя.
+type WishlistItem struct {
+	UserID    int64
+	MerchID   int64
+	CreatedAt time.Time
+}
+
+// WishlistPrice — активный товар из списка желаний с ценой, с которой он становится доступен:
+// действующей ценой товара или самого дешёвого варианта в продаже.
+type WishlistPrice struct {
+	MerchID int64
+	Name    string
+	Price   int
+	SoldOut bool // распроданы товар или все его активные варианты
+}
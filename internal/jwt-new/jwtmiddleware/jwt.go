@@ -13,7 +13,10 @@ import (
 
 type contextKey string
 
-const UserIDKey contextKey = "userID"
+const (
+	UserIDKey contextKey = "userID"
+	EmailKey  contextKey = "email"
+)
 
 // NewJWTMiddleware создаёт middleware для проверки JWT, секрет берётся из переменной окружения.
 func NewJWTMiddleware() func(http.Handler) http.Handler {
@@ -69,8 +72,11 @@ func NewJWTMiddleware() func(http.Handler) http.Handler {
 				return
 			}
 
-			// Устанавливаем userID в контекст запроса
+			// Устанавливаем userID (и email, если он есть в токене) в контекст запроса
 			ctx := context.WithValue(r.Context(), UserIDKey, int64(userID))
+			if email, ok := claims["email"].(string); ok {
+				ctx = context.WithValue(ctx, EmailKey, email)
+			}
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -81,3 +87,37 @@ func FromContext(ctx context.Context) (int64, bool) {
 	id, ok := ctx.Value(UserIDKey).(int64)
 	return id, ok
 }
+
+// EmailFromContext извлекает email пользователя из контекста.
+func EmailFromContext(ctx context.Context) (string, bool) {
+	email, ok := ctx.Value(EmailKey).(string)
+	return email, ok
+}
+
+// AdminChecker сообщает, есть ли у пользователя userID права администратора.
+type AdminChecker func(ctx context.Context, userID int64) (bool, error)
+
+// RequireAdmin пропускает только пользователей, которым isAdmin выдаёт права администратора.
+// Права проверяются по хранилищу на каждый запрос, а не по email из токена: email в токене
+// принадлежит тому, кто первым зарегистрировался под ним. Должен стоять после NewJWTMiddleware.
+func RequireAdmin(isAdmin AdminChecker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := FromContext(r.Context())
+			if !ok {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+			admin, err := isAdmin(r.Context(), userID)
+			if err != nil {
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+			if !admin {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
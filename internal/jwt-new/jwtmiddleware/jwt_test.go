@@ -2,6 +2,7 @@ package jwtmiddleware_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -110,3 +111,32 @@ func TestFromContext(t *testing.T) {
 	assert.True(t, ok, "Expected to retrieve userID from context")
 	assert.Equal(t, int64(456), userID, "Expected userID to match")
 }
+
+func TestRequireAdmin(t *testing.T) {
+	isAdmin := func(_ context.Context, userID int64) (bool, error) {
+		if userID == 3 {
+			return false, errors.New("db is down")
+		}
+		return userID == 1, nil
+	}
+	handler := jwtmiddleware.RequireAdmin(isAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	cases := []struct {
+		name   string
+		ctx    context.Context
+		status int
+	}{
+		{"admin", context.WithValue(context.Background(), jwtmiddleware.UserIDKey, int64(1)), http.StatusOK},
+		{"not admin", context.WithValue(context.Background(), jwtmiddleware.UserIDKey, int64(2)), http.StatusForbidden},
+		{"check failed", context.WithValue(context.Background(), jwtmiddleware.UserIDKey, int64(3)), http.StatusInternalServerError},
+		{"no user", context.Background(), http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest("POST", "/api/admin/credit", nil).WithContext(tc.ctx)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		assert.Equal(t, tc.status, rr.Code, tc.name)
+	}
+}
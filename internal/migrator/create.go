@@ -0,0 +1,72 @@
+package migrator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// migrationNameRe — допустимое имя новой миграции после нормализации.
+var migrationNameRe = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// MigrationFiles возвращает пути up/down файлов, которые создаст Create: следующий номер
+// после максимального в каталоге dir и нормализованное имя (нижний регистр, пробелы и дефисы -> _).
+func MigrationFiles(dir, name string) ([]string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+	if !migrationNameRe.MatchString(name) {
+		return nil, fmt.Errorf("invalid migration name %q: only letters, digits and _ are allowed", name)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations dir: %w", err)
+	}
+
+	var last uint
+	for _, e := range entries {
+		m, err := source.Parse(e.Name())
+		if err != nil {
+			continue
+		}
+		if m.Version > last {
+			last = m.Version
+		}
+	}
+
+	base := fmt.Sprintf("%d_%s", last+1, name)
+	return []string{
+		filepath.Join(dir, base+".up.sql"),
+		filepath.Join(dir, base+".down.sql"),
+	}, nil
+}
+
+// Create создаёт пустую пару up/down файлов для новой миграции и возвращает их пути.
+func Create(dir, name string) ([]string, error) {
+	files, err := MigrationFiles(dir, name)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, path := range files {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644) // #nosec G302 -- файлы миграций коммитятся в репозиторий
+		if err == nil {
+			err = f.Close()
+		}
+		if err != nil {
+			for _, created := range files[:i] {
+				_ = os.Remove(created)
+			}
+			if errors.Is(err, os.ErrExist) {
+				return nil, fmt.Errorf("migration file %s already exists", path)
+			}
+			return nil, fmt.Errorf("failed to create migration file: %w", err)
+		}
+	}
+	return files, nil
+}
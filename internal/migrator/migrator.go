@@ -0,0 +1,293 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/linemk/avito-shop/internal/config"
+	"github.com/linemk/avito-shop/migrations"
+)
+
+// DefaultMigrationsTable — таблица, в которой golang-migrate хранит версию схемы.
+const DefaultMigrationsTable = "migrations"
+
+var (
+	// ErrDirty — предыдущая миграция завершилась с ошибкой, схему нужно починить вручную и выполнить force.
+	ErrDirty = errors.New("database is dirty, fix the schema manually and run force")
+	// ErrUnknownVersion — версия отсутствует среди файлов миграций (например, схема новее бинарника).
+	ErrUnknownVersion = errors.New("version not found in migrations")
+	// ErrPendingMigrations — в схеме применены не все миграции.
+	ErrPendingMigrations = errors.New("database schema is behind, run migrations")
+)
+
+// BuildDSN собирает строку подключения (DSN) для golang-migrate из отдельных параметров
+func BuildDSN(dbCfg config.DatabaseConfig, migrationTable string, dbPassword string) string {
+	return fmt.Sprintf(
+		"postgres://%s:%s@%s:%d/%s?sslmode=disable&x-migrations-table=%s",
+		dbCfg.User, dbPassword, dbCfg.Host, dbCfg.Port, dbCfg.Name, migrationTable,
+	)
+}
+
+// RedactDSN скрывает пароль в DSN, чтобы строку подключения можно было писать в логи.
+func RedactDSN(dsn string) string {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "<invalid dsn>"
+	}
+	return u.Redacted()
+}
+
+// Migration — файл миграции из источника.
+type Migration struct {
+	Version uint
+	Name    string
+}
+
+// Migrator — обёртка над golang-migrate с планированием шагов (для dry-run) и статусом.
+type Migrator struct {
+	m   *migrate.Migrate
+	src source.Driver
+}
+
+// New открывает источник миграций (например, file://./migrations) и подключается к БД.
+func New(sourceURL, databaseURL string) (*Migrator, error) {
+	src, err := source.Open(sourceURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open migrations source: %w", err)
+	}
+	return newWithSource(src, func(src source.Driver) (*migrate.Migrate, error) {
+		return migrate.NewWithSourceInstance("source", src, databaseURL)
+	})
+}
+
+// NewEmbedded использует миграции, встроенные в бинарник, и подключается к БД по databaseURL.
+func NewEmbedded(databaseURL string) (*Migrator, error) {
+	src, err := embeddedSource()
+	if err != nil {
+		return nil, err
+	}
+	return newWithSource(src, func(src source.Driver) (*migrate.Migrate, error) {
+		return migrate.NewWithSourceInstance("embed", src, databaseURL)
+	})
+}
+
+// NewEmbeddedWithDB использует встроенные миграции и отдельное соединение из пула db.
+// Close возвращает соединение в пул, сам пул не закрывается.
+func NewEmbeddedWithDB(ctx context.Context, db *sql.DB) (*Migrator, error) {
+	src, err := embeddedSource()
+	if err != nil {
+		return nil, err
+	}
+	return newWithSource(src, func(src source.Driver) (*migrate.Migrate, error) {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		driver, err := postgres.WithConnection(ctx, conn, &postgres.Config{MigrationsTable: DefaultMigrationsTable})
+		if err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		return migrate.NewWithInstance("embed", src, "postgres", driver)
+	})
+}
+
+func embeddedSource() (source.Driver, error) {
+	src, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open embedded migrations: %w", err)
+	}
+	return src, nil
+}
+
+// newWithSource создаёт экземпляр golang-migrate через open и закрывает источник при ошибке.
+func newWithSource(src source.Driver, open func(source.Driver) (*migrate.Migrate, error)) (*Migrator, error) {
+	m, err := open(src)
+	if err != nil {
+		_ = src.Close()
+		return nil, fmt.Errorf("failed to create migrate instance: %w", err)
+	}
+	return &Migrator{m: m, src: src}, nil
+}
+
+// Close закрывает источник миграций и подключение к БД.
+func (mg *Migrator) Close() error {
+	srcErr, dbErr := mg.m.Close()
+	if srcErr != nil {
+		return srcErr
+	}
+	return dbErr
+}
+
+// Version возвращает текущую версию схемы и признак dirty. 0 — миграции ещё не применялись.
+func (mg *Migrator) Version() (uint, bool, error) {
+	version, dirty, err := mg.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, dirty, nil
+}
+
+// Migrations возвращает все миграции источника по возрастанию версии.
+func (mg *Migrator) Migrations() ([]Migration, error) {
+	var migrations []Migration
+
+	version, err := mg.src.First()
+	for err == nil {
+		name, nameErr := mg.name(version)
+		if nameErr != nil {
+			return nil, nameErr
+		}
+		migrations = append(migrations, Migration{Version: version, Name: name})
+		version, err = mg.src.Next(version)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	return migrations, nil
+}
+
+// name возвращает идентификатор миграции из имени файла (1_init.up.sql -> init).
+func (mg *Migrator) name(version uint) (string, error) {
+	r, name, err := mg.src.ReadUp(version)
+	if errors.Is(err, fs.ErrNotExist) {
+		r, name, err = mg.src.ReadDown(version)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read migration %d: %w", version, err)
+	}
+	_ = r.Close()
+	return name, nil
+}
+
+// Status — состояние схемы: текущая версия и список миграций с отметкой о применении.
+type Status struct {
+	Version    uint
+	Dirty      bool
+	Migrations []MigrationStatus
+}
+
+// MigrationStatus — миграция и признак того, что она применена.
+type MigrationStatus struct {
+	Migration
+	Applied bool
+}
+
+// Status возвращает текущую версию схемы и список применённых и ожидающих миграций.
+func (mg *Migrator) Status() (Status, error) {
+	migrations, version, dirty, err := mg.state()
+	if err != nil {
+		return Status{}, err
+	}
+
+	status := Status{Version: version, Dirty: dirty}
+	for _, m := range migrations {
+		status.Migrations = append(status.Migrations, MigrationStatus{Migration: m, Applied: m.Version <= version})
+	}
+	return status, nil
+}
+
+// PlanUp возвращает миграции, которые применит Up(n).
+func (mg *Migrator) PlanUp(n int) ([]Step, error) {
+	migrations, version, err := mg.cleanState()
+	if err != nil {
+		return nil, err
+	}
+	return planUp(migrations, version, n)
+}
+
+// PlanDown возвращает миграции, которые откатит Down(n).
+func (mg *Migrator) PlanDown(n int) ([]Step, error) {
+	migrations, version, err := mg.cleanState()
+	if err != nil {
+		return nil, err
+	}
+	return planDown(migrations, version, n)
+}
+
+// PlanGoto возвращает миграции, которые будут выполнены при переходе на версию target.
+func (mg *Migrator) PlanGoto(target uint) ([]Step, error) {
+	migrations, version, err := mg.cleanState()
+	if err != nil {
+		return nil, err
+	}
+	return planGoto(migrations, version, target)
+}
+
+// Up применяет n следующих миграций, при n <= 0 — все неприменённые.
+func (mg *Migrator) Up(n int) error {
+	if n <= 0 {
+		return ignoreNoChange(mg.m.Up())
+	}
+	return ignoreNoChange(mg.m.Steps(n))
+}
+
+// Down откатывает n последних миграций, при n <= 0 — все.
+func (mg *Migrator) Down(n int) error {
+	if n <= 0 {
+		return ignoreNoChange(mg.m.Down())
+	}
+	return ignoreNoChange(mg.m.Steps(-n))
+}
+
+// Goto переводит схему на версию target вверх или вниз.
+func (mg *Migrator) Goto(target uint) error {
+	return ignoreNoChange(mg.m.Migrate(target))
+}
+
+// Force записывает версию без выполнения миграций и снимает признак dirty.
+// Версия -1 означает «миграции не применялись».
+func (mg *Migrator) Force(version int) error {
+	if err := mg.m.Force(version); err != nil {
+		return fmt.Errorf("failed to force version %d: %w", version, err)
+	}
+	return nil
+}
+
+// state читает миграции источника и текущую версию схемы.
+func (mg *Migrator) state() ([]Migration, uint, bool, error) {
+	migrations, err := mg.Migrations()
+	if err != nil {
+		return nil, 0, false, err
+	}
+	version, dirty, err := mg.Version()
+	if err != nil {
+		return nil, 0, false, err
+	}
+	return migrations, version, dirty, nil
+}
+
+// cleanState — state, но с ошибкой для dirty схемы и версии, которой нет среди миграций.
+func (mg *Migrator) cleanState() ([]Migration, uint, error) {
+	migrations, version, dirty, err := mg.state()
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := checkVersion(migrations, version, dirty); err != nil && !errors.Is(err, ErrPendingMigrations) {
+		return nil, 0, err
+	}
+	return migrations, version, nil
+}
+
+func ignoreNoChange(err error) error {
+	if err == nil || errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	var dirtyErr migrate.ErrDirty
+	if errors.As(err, &dirtyErr) {
+		return fmt.Errorf("%w (version %d)", ErrDirty, dirtyErr.Version)
+	}
+	return fmt.Errorf("migration failed: %w", err)
+}
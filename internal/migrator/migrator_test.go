@@ -0,0 +1,173 @@
+package migrator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testMigrations = []Migration{
+	{Version: 1, Name: "init"},
+	{Version: 2, Name: "init_merch"},
+	{Version: 5, Name: "add_comments"},
+}
+
+func versions(steps []Step) []uint {
+	var out []uint
+	for _, s := range steps {
+		out = append(out, s.Version)
+	}
+	return out
+}
+
+func TestPlanUp(t *testing.T) {
+	steps, err := planUp(testMigrations, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{1, 2, 5}, versions(steps))
+	assert.Equal(t, DirectionUp, steps[0].Direction)
+
+	steps, err = planUp(testMigrations, 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{2}, versions(steps))
+
+	steps, err = planUp(testMigrations, 5, 0)
+	require.NoError(t, err)
+	assert.Empty(t, steps)
+
+	_, err = planUp(testMigrations, 2, 2)
+	assert.Error(t, err)
+}
+
+func TestPlanDown(t *testing.T) {
+	steps, err := planDown(testMigrations, 5, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{5, 2, 1}, versions(steps))
+	assert.Equal(t, DirectionDown, steps[0].Direction)
+
+	steps, err = planDown(testMigrations, 2, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{2}, versions(steps))
+
+	steps, err = planDown(testMigrations, 0, 0)
+	require.NoError(t, err)
+	assert.Empty(t, steps)
+
+	_, err = planDown(testMigrations, 1, 2)
+	assert.Error(t, err)
+}
+
+func TestPlanGoto(t *testing.T) {
+	steps, err := planGoto(testMigrations, 1, 5)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{2, 5}, versions(steps))
+	assert.Equal(t, DirectionUp, steps[0].Direction)
+
+	steps, err = planGoto(testMigrations, 5, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{5, 2}, versions(steps))
+	assert.Equal(t, DirectionDown, steps[0].Direction)
+
+	steps, err = planGoto(testMigrations, 2, 2)
+	require.NoError(t, err)
+	assert.Empty(t, steps)
+
+	_, err = planGoto(testMigrations, 1, 3)
+	assert.ErrorIs(t, err, ErrUnknownVersion)
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"1_init.up.sql", "1_init.down.sql", "7_merch.up.sql", "README.md"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o600))
+	}
+
+	planned, err := MigrationFiles(dir, "Add Transfer-Comments")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "8_add_transfer_comments.up.sql"),
+		filepath.Join(dir, "8_add_transfer_comments.down.sql"),
+	}, planned)
+	_, err = os.Stat(planned[0])
+	assert.True(t, os.IsNotExist(err), "MigrationFiles must not create files")
+
+	created, err := Create(dir, "Add Transfer-Comments")
+	require.NoError(t, err)
+	assert.Equal(t, planned, created)
+	for _, f := range created {
+		assert.FileExists(t, f)
+	}
+
+	_, err = Create(dir, "bad;name")
+	assert.Error(t, err)
+}
+
+func TestRedactDSN(t *testing.T) {
+	dsn := "postgres://postgres:s3cr3t@db:5432/shop?sslmode=disable&x-migrations-table=migrations"
+	redacted := RedactDSN(dsn)
+	assert.NotContains(t, redacted, "s3cr3t")
+	assert.Contains(t, redacted, "postgres://postgres:xxxxx@db:5432/shop")
+}
+
+func TestEmbeddedSource(t *testing.T) {
+	src, err := embeddedSource()
+	require.NoError(t, err)
+	defer src.Close()
+
+	first, err := src.First()
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), first)
+
+	_, name, err := src.ReadUp(first)
+	require.NoError(t, err)
+	assert.Equal(t, "init", name)
+}
+
+func TestCheckVersion(t *testing.T) {
+	assert.NoError(t, checkVersion(testMigrations, 5, false))
+	assert.ErrorIs(t, checkVersion(testMigrations, 5, true), ErrDirty)
+	assert.ErrorIs(t, checkVersion(testMigrations, 9, false), ErrUnknownVersion)
+	assert.ErrorIs(t, checkVersion(testMigrations, 2, false), ErrPendingMigrations)
+	assert.ErrorIs(t, checkVersion(testMigrations, 0, false), ErrPendingMigrations)
+}
+
+func TestWithAdvisoryLock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_lock($1)")).
+		WithArgs(startupLockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).
+		WithArgs(startupLockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	called := false
+	err = withAdvisoryLock(context.Background(), db, startupLockKey, func() error {
+		called = true
+		return nil
+	})
+	require.NoError(t, err)
+	assert.True(t, called)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithAdvisoryLock_LockFailed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_lock($1)")).
+		WithArgs(startupLockKey).WillReturnError(context.DeadlineExceeded)
+
+	err = withAdvisoryLock(context.Background(), db, startupLockKey, func() error {
+		t.Fatal("fn must not be called without the lock")
+		return nil
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,86 @@
+package migrator
+
+import "fmt"
+
+// Direction — направление выполнения миграции.
+type Direction string
+
+const (
+	DirectionUp   Direction = "up"
+	DirectionDown Direction = "down"
+)
+
+// Step — шаг плана: какая миграция и в каком направлении будет выполнена.
+type Step struct {
+	Migration
+	Direction Direction
+}
+
+// planUp возвращает n первых неприменённых миграций по возрастанию версии (все при n <= 0).
+func planUp(migrations []Migration, current uint, n int) ([]Step, error) {
+	var steps []Step
+	for _, m := range migrations {
+		if m.Version > current {
+			steps = append(steps, Step{Migration: m, Direction: DirectionUp})
+		}
+	}
+	if n > 0 {
+		if n > len(steps) {
+			return nil, fmt.Errorf("requested %d migrations up, only %d pending", n, len(steps))
+		}
+		steps = steps[:n]
+	}
+	return steps, nil
+}
+
+// planDown возвращает n последних применённых миграций по убыванию версии (все при n <= 0).
+func planDown(migrations []Migration, current uint, n int) ([]Step, error) {
+	var steps []Step
+	if current != 0 {
+		for i := len(migrations) - 1; i >= 0; i-- {
+			if migrations[i].Version <= current {
+				steps = append(steps, Step{Migration: migrations[i], Direction: DirectionDown})
+			}
+		}
+	}
+	if n > 0 {
+		if n > len(steps) {
+			return nil, fmt.Errorf("requested %d migrations down, only %d applied", n, len(steps))
+		}
+		steps = steps[:n]
+	}
+	return steps, nil
+}
+
+// planGoto возвращает шаги перехода с версии current на версию target.
+func planGoto(migrations []Migration, current, target uint) ([]Step, error) {
+	if !hasVersion(migrations, target) {
+		return nil, fmt.Errorf("target version %d: %w", target, ErrUnknownVersion)
+	}
+
+	var steps []Step
+	if target >= current {
+		for _, m := range migrations {
+			if m.Version > current && m.Version <= target {
+				steps = append(steps, Step{Migration: m, Direction: DirectionUp})
+			}
+		}
+		return steps, nil
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		if migrations[i].Version > target && migrations[i].Version <= current {
+			steps = append(steps, Step{Migration: migrations[i], Direction: DirectionDown})
+		}
+	}
+	return steps, nil
+}
+
+func hasVersion(migrations []Migration, version uint) bool {
+	for _, m := range migrations {
+		if m.Version == version {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+)
+
+// startupLockKey — ключ advisory lock, под которым реплики сервера по очереди
+// применяют и проверяют миграции при старте.
+const startupLockKey int64 = 0x6176_6974_6f // "avito"
+
+// EnsureSchema вызывается при старте сервера. Под advisory lock при autoMigrate применяет
+// встроенные миграции, затем проверяет, что схема не dirty и её версия совпадает с последней
+// встроенной миграцией. Иначе возвращает ErrDirty, ErrUnknownVersion или ErrPendingMigrations.
+func EnsureSchema(ctx context.Context, log *slog.Logger, db *sql.DB, autoMigrate bool) error {
+	const op = "migrator.EnsureSchema"
+
+	err := withAdvisoryLock(ctx, db, startupLockKey, func() error {
+		mg, err := NewEmbeddedWithDB(ctx, db)
+		if err != nil {
+			return err
+		}
+		defer mg.Close()
+
+		if autoMigrate {
+			steps, err := mg.PlanUp(0)
+			if err != nil {
+				return err
+			}
+			for _, s := range steps {
+				log.Info("applying migration", slog.Uint64("version", uint64(s.Version)), slog.String("name", s.Name))
+			}
+			if err := mg.Up(0); err != nil {
+				return err
+			}
+		}
+
+		migrations, version, dirty, err := mg.state()
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(migrations, version, dirty); err != nil {
+			return err
+		}
+		log.Info("database schema is up to date", slog.Uint64("version", uint64(version)))
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// checkVersion сверяет версию схемы с последней известной миграцией.
+func checkVersion(migrations []Migration, version uint, dirty bool) error {
+	if dirty {
+		return fmt.Errorf("%w (version %d)", ErrDirty, version)
+	}
+	if version != 0 && !hasVersion(migrations, version) {
+		return fmt.Errorf("database version %d: %w", version, ErrUnknownVersion)
+	}
+	if len(migrations) > 0 && version < migrations[len(migrations)-1].Version {
+		return fmt.Errorf("%w (version %d, latest %d)", ErrPendingMigrations, version, migrations[len(migrations)-1].Version)
+	}
+	return nil
+}
+
+// withAdvisoryLock выполняет fn, удерживая сессионный pg_advisory_lock на отдельном соединении.
+// Ожидание блокировки ограничено контекстом.
+func withAdvisoryLock(ctx context.Context, db *sql.DB, key int64, fn func() error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection for advisory lock: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", key); err != nil {
+		return fmt.Errorf("failed to acquire advisory lock: %w", err)
+	}
+	defer func() {
+		_, _ = conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key)
+	}()
+
+	return fn()
+}
@@ -0,0 +1,182 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule — расписание в формате cron из пяти полей: "минута час день_месяца месяц день_недели".
+// В поле допускаются "*", числа, диапазоны "1-5", шаг "*/15" или "0-30/10", списки "1,15"
+// и имена месяцев и дней недели (jan, mon). Также поддерживаются сокращения @yearly, @monthly,
+// @weekly, @daily и @hourly. Время вычисляется в часовом поясе переданного момента.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64 // битовые маски допустимых значений
+	// domAll/dowAll — поле задано как "*": тогда день должен подходить под оба поля,
+	// иначе (как в cron) достаточно совпадения с любым из них
+	domAll, dowAll bool
+}
+
+// descriptors — сокращения для часто используемых расписаний.
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	monthNames = map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}
+	dowNames = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+)
+
+// Parse разбирает cron-выражение.
+func Parse(spec string) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if expanded, ok := descriptors[strings.ToLower(spec)]; ok {
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q: expected 5 fields, got %d", spec, len(fields))
+	}
+
+	s := &Schedule{
+		domAll: fields[2] == "*",
+		dowAll: fields[4] == "*",
+	}
+	var err error
+	if s.minute, err = parseField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: minute: %w", spec, err)
+	}
+	if s.hour, err = parseField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: hour: %w", spec, err)
+	}
+	if s.dom, err = parseField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: day of month: %w", spec, err)
+	}
+	if s.month, err = parseField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: month: %w", spec, err)
+	}
+	// 7 — тоже воскресенье
+	if s.dow, err = parseField(fields[4], 0, 7, dowNames); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: day of week: %w", spec, err)
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+	return s, nil
+}
+
+// parseField разбирает одно поле выражения в битовую маску значений из [lo, hi].
+func parseField(field string, lo, hi int, names map[string]int) (uint64, error) {
+	var mask uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rangePart, step = part[:i], n
+		}
+
+		var from, to int
+		switch {
+		case rangePart == "*":
+			from, to = lo, hi
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if from, err = parseValue(bounds[0], names); err != nil {
+				return 0, err
+			}
+			if to, err = parseValue(bounds[1], names); err != nil {
+				return 0, err
+			}
+		default:
+			v, err := parseValue(rangePart, names)
+			if err != nil {
+				return 0, err
+			}
+			from, to = v, v
+			// "5/15" — с 5 до конца диапазона с шагом 15
+			if step > 1 {
+				to = hi
+			}
+		}
+
+		if from < lo || to > hi || from > to {
+			return 0, fmt.Errorf("value %q out of range %d-%d", rangePart, lo, hi)
+		}
+		for v := from; v <= to; v += step {
+			mask |= 1 << uint(v)
+		}
+	}
+	return mask, nil
+}
+
+func parseValue(s string, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	return v, nil
+}
+
+// maxSearch — горизонт поиска следующего запуска; расписание вроде "0 0 30 2 *" не срабатывает никогда.
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+// Next возвращает первый момент строго после t, подходящий под расписание
+// (с точностью до минуты), или нулевое время, если такого нет.
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxSearch)
+
+	for t.Before(limit) {
+		if !has(s.month, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !has(s.hour, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if !has(s.minute, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches проверяет день месяца и день недели по правилам cron.
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := has(s.dom, t.Day())
+	dow := has(s.dow, int(t.Weekday()))
+	if s.domAll || s.dowAll {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func has(mask uint64, v int) bool {
+	return mask&(1<<uint(v)) != 0
+}
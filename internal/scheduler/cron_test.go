@@ -0,0 +1,72 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	from := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC) // среда
+
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2025, 1, 15, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2025, 1, 15, 10, 45, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2025, 1, 15, 13, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2025, 1, 15, 11, 0, 0, 0, time.UTC)},
+		{"0 0 1 jan *", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * mon-fri", time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2025, 1, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 * *", time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// заданы и день месяца, и день недели — достаточно любого из них
+		{"0 0 20 * fri", time.Date(2025, 1, 17, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			s, err := Parse(tt.spec)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, s.Next(from))
+		})
+	}
+}
+
+func TestSchedule_NextInLocation(t *testing.T) {
+	moscow := time.FixedZone("MSK", 3*60*60)
+	s, err := Parse("0 0 1 * *")
+	require.NoError(t, err)
+
+	next := s.Next(time.Date(2025, 1, 31, 22, 0, 0, 0, time.UTC).In(moscow))
+	assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, moscow), next)
+}
+
+func TestSchedule_NeverFires(t *testing.T) {
+	s, err := Parse("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, s.Next(time.Now()).IsZero())
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"@every 5m",
+	} {
+		_, err := Parse(spec)
+		assert.Error(t, err, spec)
+	}
+}
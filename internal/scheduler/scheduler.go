@@ -0,0 +1,287 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/storage"
+)
+
+// lockPrefix — префикс имени блокировки задачи: её держит реплика, которая выполняет задачу.
+const lockPrefix = "scheduler:"
+
+// JobFunc выполняет задачу за момент расписания scheduledAt. Повторный вызов с тем же
+// scheduledAt возможен (после падения или ошибки), поэтому задача должна быть идемпотентной.
+type JobFunc func(ctx context.Context, scheduledAt time.Time) error
+
+// Паузы перед повтором упавшего запуска: удваиваются после каждой ошибки до retryMaxDelay.
+const (
+	retryBaseDelay = time.Minute
+	retryMaxDelay  = time.Hour
+)
+
+// job — зарегистрированная задача и момент её следующего запуска.
+type job struct {
+	name     string
+	schedule *Schedule
+	run      JobFunc
+	next     time.Time // момент расписания, за который выполняется следующий запуск
+	due      time.Time // когда выполнить запуск: next или позже, если next упал и ждёт повтора
+	failures int       // ошибок подряд у запуска за next
+}
+
+// Scheduler запускает задачи по cron-расписанию. Реплик сервиса может быть несколько:
+// задачу выполняет та, что захватила её блокировку (advisory lock в PostgreSQL), а запись
+// в истории запусков (уникальная по задаче и моменту расписания) не даёт выполнить
+// один и тот же запуск дважды.
+type Scheduler struct {
+	log      *slog.Logger
+	locker   storage.Locker
+	runs     storage.JobRunStorage
+	location *time.Location
+	jobs     []*job
+	now      func() time.Time
+	// паузы перед повтором упавшего запуска
+	retryDelay    time.Duration
+	maxRetryDelay time.Duration
+}
+
+// New создаёт планировщик; расписания вычисляются в часовом поясе location.
+func New(log *slog.Logger, locker storage.Locker, runs storage.JobRunStorage, location *time.Location) *Scheduler {
+	return &Scheduler{
+		log:      log,
+		locker:   locker,
+		runs:     runs,
+		location: location,
+		now:      time.Now,
+
+		retryDelay:    retryBaseDelay,
+		maxRetryDelay: retryMaxDelay,
+	}
+}
+
+// Add регистрирует задачу name с cron-расписанием spec.
+func (s *Scheduler) Add(name, spec string, run JobFunc) error {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", name, err)
+	}
+	if schedule.Next(s.now().In(s.location)).IsZero() {
+		return fmt.Errorf("job %s: schedule %q never fires", name, spec)
+	}
+	for _, j := range s.jobs {
+		if j.name == name {
+			return fmt.Errorf("job %s: already registered", name)
+		}
+	}
+	s.jobs = append(s.jobs, &job{name: name, schedule: schedule, run: run})
+	return nil
+}
+
+// Run выполняет задачи по расписанию до отмены ctx. Задачи одного момента выполняются
+// по очереди; Run возвращается после завершения текущей задачи. Упавший запуск повторяется
+// за тот же момент расписания с растущей паузой, пока не завершится успешно.
+func (s *Scheduler) Run(ctx context.Context) {
+	const op = "scheduler.Run"
+	logger := s.log.With(slog.String("op", op))
+
+	if len(s.jobs) == 0 {
+		logger.Info("no scheduled jobs")
+		<-ctx.Done()
+		return
+	}
+
+	for _, j := range s.jobs {
+		j.next = s.firstRun(ctx, j)
+		j.due = j.next
+		logger.Info("job scheduled", slog.String("job", j.name), slog.Time("next", j.next))
+	}
+
+	for {
+		due := s.jobs[0].due
+		for _, j := range s.jobs[1:] {
+			if j.due.Before(due) {
+				due = j.due
+			}
+		}
+
+		timer := time.NewTimer(due.Sub(s.now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			logger.Info("scheduler stopped")
+			return
+		case <-timer.C:
+		}
+
+		for _, j := range s.jobs {
+			if j.due.After(s.now()) {
+				continue
+			}
+			if err := s.RunJob(ctx, j.name, j.next); err != nil {
+				if ctx.Err() != nil {
+					// остановка сервиса: запуск повторится при следующем старте (см. firstRun)
+					continue
+				}
+				j.failures++
+				j.due = s.now().Add(s.backoff(j.failures))
+				logger.Error("job failed, will retry", slog.String("job", j.name),
+					slog.Time("scheduledAt", j.next), slog.Time("retryAt", j.due), slog.Any("error", err))
+				continue
+			}
+			// пропущенные за время выполнения моменты не навёрстываются
+			j.failures = 0
+			j.next = j.schedule.Next(s.now().In(s.location))
+			j.due = j.next
+		}
+	}
+}
+
+// backoff возвращает паузу перед повтором после failures ошибок подряд.
+func (s *Scheduler) backoff(failures int) time.Duration {
+	delay := s.retryDelay
+	for i := 1; i < failures && delay < s.maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, s.maxRetryDelay)
+}
+
+// firstRun определяет первый запуск задачи по истории: незавершённый или упавший
+// последний запуск повторяется сразу, а если сервис был остановлен в момент запуска —
+// выполняется последний пропущенный. Без истории — следующий момент по расписанию.
+func (s *Scheduler) firstRun(ctx context.Context, j *job) time.Time {
+	now := s.now().In(s.location)
+
+	runs, err := s.runs.ListJobRuns(ctx, j.name, 1)
+	if err != nil {
+		s.log.Error("failed to read job history", slog.String("job", j.name), slog.Any("error", err))
+		return j.schedule.Next(now)
+	}
+	if len(runs) == 0 {
+		return j.schedule.Next(now)
+	}
+
+	last := runs[0]
+	if last.Status != models.JobRunSucceeded {
+		return last.ScheduledAt.In(s.location)
+	}
+	missed := j.schedule.Next(last.ScheduledAt.In(s.location))
+	if missed.IsZero() || missed.After(now) {
+		return j.schedule.Next(now)
+	}
+	for {
+		n := j.schedule.Next(missed)
+		if n.IsZero() || n.After(now) {
+			return missed
+		}
+		missed = n
+	}
+}
+
+// RunJob выполняет задачу name за момент расписания scheduledAt, если её блокировка свободна
+// и этот запуск ещё не выполнен успешно, и записывает результат в историю запусков.
+// Возвращает ошибку задачи или хранилища.
+func (s *Scheduler) RunJob(ctx context.Context, name string, scheduledAt time.Time) error {
+	const op = "scheduler.RunJob"
+	logger := s.log.With(slog.String("op", op), slog.String("job", name), slog.Time("scheduledAt", scheduledAt))
+
+	var j *job
+	for _, candidate := range s.jobs {
+		if candidate.name == name {
+			j = candidate
+			break
+		}
+	}
+	if j == nil {
+		return fmt.Errorf("%s: unknown job %s", op, name)
+	}
+
+	release, acquired, err := s.locker.TryLock(ctx, lockPrefix+name)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if !acquired {
+		logger.Info("job is running on another replica")
+		return nil
+	}
+	defer release()
+
+	run, err := s.startRun(ctx, name, scheduledAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if run == nil {
+		logger.Info("job run already completed")
+		return nil
+	}
+
+	logger.Info("job started")
+	jobErr := safeRun(ctx, j.run, scheduledAt)
+
+	finishedAt := s.now()
+	run.FinishedAt = &finishedAt
+	run.Status = models.JobRunSucceeded
+	run.Error = ""
+	if jobErr != nil {
+		run.Status = models.JobRunFailed
+		run.Error = jobErr.Error()
+	}
+	// результат записывается и при остановке сервиса посреди задачи
+	if err := s.runs.UpdateJobRun(context.WithoutCancel(ctx), run); err != nil {
+		logger.Error("failed to record job run", slog.Any("error", err))
+	}
+
+	if jobErr != nil {
+		return fmt.Errorf("%s: %w", op, jobErr)
+	}
+	logger.Info("job finished", slog.Duration("duration", finishedAt.Sub(run.StartedAt)))
+	return nil
+}
+
+// startRun записывает начало запуска. Если запуск за этот момент уже есть и он не завершён
+// успешно (реплика упала или задача вернула ошибку), он выполняется повторно: блокировка
+// задачи у нас, значит, никто другой его сейчас не выполняет. Для успешного запуска возвращает nil.
+func (s *Scheduler) startRun(ctx context.Context, name string, scheduledAt time.Time) (*models.JobRun, error) {
+	run := &models.JobRun{
+		Job:         name,
+		ScheduledAt: scheduledAt,
+		StartedAt:   s.now(),
+		Status:      models.JobRunRunning,
+	}
+	err := s.runs.CreateJobRun(ctx, run)
+	if err == nil {
+		return run, nil
+	}
+	if !errors.Is(err, storage.ErrJobRunExists) {
+		return nil, err
+	}
+
+	run, err = s.runs.GetJobRun(ctx, name, scheduledAt)
+	if err != nil {
+		return nil, err
+	}
+	if run.Status == models.JobRunSucceeded {
+		return nil, nil
+	}
+	run.StartedAt = s.now()
+	run.FinishedAt = nil
+	run.Status = models.JobRunRunning
+	if err := s.runs.UpdateJobRun(ctx, run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// safeRun выполняет задачу и превращает панику в ошибку, чтобы она попала в историю.
+func safeRun(ctx context.Context, run JobFunc, scheduledAt time.Time) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return run(ctx, scheduledAt)
+}
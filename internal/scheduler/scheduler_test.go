@@ -0,0 +1,230 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/storage"
+	"github.com/linemk/avito-shop/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestScheduler создаёт планировщик с фиксированным временем now; реплики
+// в тестах — планировщики с общими locker и runs.
+func newTestScheduler(locker storage.Locker, runs storage.JobRunStorage, now time.Time) *Scheduler {
+	s := New(slog.New(slog.NewTextHandler(os.Stdout, nil)), locker, runs, time.UTC)
+	s.now = func() time.Time { return now }
+	return s
+}
+
+func TestRunJob_OnlyOnceAcrossReplicas(t *testing.T) {
+	db := memory.New()
+	locker, runs := memory.NewLocker(), memory.NewJobRunRepository(db)
+	now := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	calls := 0
+	job := func(ctx context.Context, scheduledAt time.Time) error {
+		calls++
+		assert.Equal(t, now, scheduledAt)
+		return nil
+	}
+
+	replicaA := newTestScheduler(locker, runs, now)
+	replicaB := newTestScheduler(locker, runs, now)
+	require.NoError(t, replicaA.Add("allowance", "@monthly", job))
+	require.NoError(t, replicaB.Add("allowance", "@monthly", job))
+
+	require.NoError(t, replicaA.RunJob(context.Background(), "allowance", now))
+	require.NoError(t, replicaB.RunJob(context.Background(), "allowance", now))
+	assert.Equal(t, 1, calls)
+
+	history, err := runs.ListJobRuns(context.Background(), "allowance", 10)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, models.JobRunSucceeded, history[0].Status)
+	assert.NotNil(t, history[0].FinishedAt)
+}
+
+func TestRunJob_SkipsWhenLockHeld(t *testing.T) {
+	db := memory.New()
+	locker, runs := memory.NewLocker(), memory.NewJobRunRepository(db)
+	now := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	s := newTestScheduler(locker, runs, now)
+	calls := 0
+	require.NoError(t, s.Add("allowance", "@monthly", func(ctx context.Context, _ time.Time) error {
+		calls++
+		return nil
+	}))
+
+	// блокировку держит другая реплика
+	release, acquired, err := locker.TryLock(context.Background(), lockPrefix+"allowance")
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	require.NoError(t, s.RunJob(context.Background(), "allowance", now))
+	assert.Equal(t, 0, calls)
+
+	release()
+	require.NoError(t, s.RunJob(context.Background(), "allowance", now))
+	assert.Equal(t, 1, calls)
+}
+
+func TestRunJob_FailedRunIsRecordedAndRetried(t *testing.T) {
+	db := memory.New()
+	runs := memory.NewJobRunRepository(db)
+	now := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	s := newTestScheduler(memory.NewLocker(), runs, now)
+	jobErr := errors.New("db is down")
+	require.NoError(t, s.Add("allowance", "@monthly", func(ctx context.Context, _ time.Time) error {
+		return jobErr
+	}))
+
+	err := s.RunJob(context.Background(), "allowance", now)
+	assert.ErrorIs(t, err, jobErr)
+	run, err := runs.GetJobRun(context.Background(), "allowance", now)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobRunFailed, run.Status)
+	assert.Equal(t, "db is down", run.Error)
+
+	jobErr = nil
+	require.NoError(t, s.RunJob(context.Background(), "allowance", now))
+	run, err = runs.GetJobRun(context.Background(), "allowance", now)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobRunSucceeded, run.Status)
+	assert.Empty(t, run.Error)
+}
+
+func TestRunJob_Panic(t *testing.T) {
+	db := memory.New()
+	runs := memory.NewJobRunRepository(db)
+	now := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	s := newTestScheduler(memory.NewLocker(), runs, now)
+	require.NoError(t, s.Add("broken", "@daily", func(ctx context.Context, _ time.Time) error {
+		panic("boom")
+	}))
+
+	assert.Error(t, s.RunJob(context.Background(), "broken", now))
+	run, err := runs.GetJobRun(context.Background(), "broken", now)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobRunFailed, run.Status)
+	assert.Contains(t, run.Error, "boom")
+}
+
+func TestFirstRun(t *testing.T) {
+	now := time.Date(2025, 4, 10, 12, 0, 0, 0, time.UTC)
+	noop := func(ctx context.Context, _ time.Time) error { return nil }
+
+	t.Run("no history", func(t *testing.T) {
+		s := newTestScheduler(memory.NewLocker(), memory.NewJobRunRepository(memory.New()), now)
+		require.NoError(t, s.Add("allowance", "@monthly", noop))
+		assert.Equal(t, time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC), s.firstRun(context.Background(), s.jobs[0]))
+	})
+
+	t.Run("latest missed run", func(t *testing.T) {
+		runs := memory.NewJobRunRepository(memory.New())
+		require.NoError(t, runs.CreateJobRun(context.Background(), &models.JobRun{
+			Job: "allowance", ScheduledAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Status: models.JobRunSucceeded,
+		}))
+		s := newTestScheduler(memory.NewLocker(), runs, now)
+		require.NoError(t, s.Add("allowance", "@monthly", noop))
+		assert.Equal(t, time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC), s.firstRun(context.Background(), s.jobs[0]))
+	})
+
+	t.Run("unfinished run", func(t *testing.T) {
+		runs := memory.NewJobRunRepository(memory.New())
+		scheduledAt := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+		require.NoError(t, runs.CreateJobRun(context.Background(), &models.JobRun{
+			Job: "allowance", ScheduledAt: scheduledAt, Status: models.JobRunRunning,
+		}))
+		s := newTestScheduler(memory.NewLocker(), runs, now)
+		require.NoError(t, s.Add("allowance", "@monthly", noop))
+		assert.Equal(t, scheduledAt, s.firstRun(context.Background(), s.jobs[0]))
+	})
+}
+
+func TestAdd_Invalid(t *testing.T) {
+	s := newTestScheduler(memory.NewLocker(), memory.NewJobRunRepository(memory.New()), time.Now())
+	noop := func(ctx context.Context, _ time.Time) error { return nil }
+
+	assert.Error(t, s.Add("bad", "every month", noop))
+	assert.Error(t, s.Add("never", "0 0 30 2 *", noop))
+	require.NoError(t, s.Add("allowance", "@monthly", noop))
+	assert.Error(t, s.Add("allowance", "@daily", noop))
+}
+
+func TestRun_StopsOnCancel(t *testing.T) {
+	s := New(slog.New(slog.NewTextHandler(os.Stdout, nil)), memory.NewLocker(), memory.NewJobRunRepository(memory.New()), time.UTC)
+	require.NoError(t, s.Add("allowance", "@monthly", func(ctx context.Context, _ time.Time) error { return nil }))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("scheduler did not stop")
+	}
+}
+
+func TestRun_RetriesFailedRun(t *testing.T) {
+	db := memory.New()
+	runs := memory.NewJobRunRepository(db)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Прошлый запуск упал — Run повторяет его сразу и после ошибок, пока он не пройдёт
+	scheduledAt := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, runs.CreateJobRun(ctx, &models.JobRun{
+		Job: "allowance", ScheduledAt: scheduledAt, StartedAt: scheduledAt, Status: models.JobRunFailed,
+	}))
+
+	s := New(slog.New(slog.NewTextHandler(os.Stdout, nil)), memory.NewLocker(), runs, time.UTC)
+	s.retryDelay = time.Millisecond
+	var calls []time.Time
+	require.NoError(t, s.Add("allowance", "@monthly", func(ctx context.Context, at time.Time) error {
+		calls = append(calls, at)
+		if len(calls) < 3 {
+			return errors.New("user is locked")
+		}
+		cancel()
+		return nil
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Run(ctx)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("scheduler did not retry the failed run")
+	}
+
+	assert.Equal(t, []time.Time{scheduledAt, scheduledAt, scheduledAt}, calls)
+	run, err := runs.GetJobRun(context.Background(), "allowance", scheduledAt)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobRunSucceeded, run.Status)
+}
+
+func TestBackoff(t *testing.T) {
+	s := New(slog.New(slog.NewTextHandler(os.Stdout, nil)), memory.NewLocker(), memory.NewJobRunRepository(memory.New()), time.UTC)
+	assert.Equal(t, time.Minute, s.backoff(1))
+	assert.Equal(t, 4*time.Minute, s.backoff(3))
+	assert.Equal(t, time.Hour, s.backoff(10))
+	assert.Equal(t, time.Hour, s.backoff(100))
+}
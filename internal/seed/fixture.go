@@ -0,0 +1,103 @@
+package seed
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultOpeningBalance — стартовый баланс пользователя из фикстуры, если balance не указан.
+const DefaultOpeningBalance = 1000
+
+// Fixture — содержимое файла фикстур (YAML или JSON).
+type Fixture struct {
+	Merch []MerchFixture `yaml:"merch" json:"merch" validate:"dive"`
+	Users []UserFixture  `yaml:"users" json:"users" validate:"dive"`
+}
+
+// MerchFixture — товар каталога, ключ — name.
+type MerchFixture struct {
+	Name  string `yaml:"name" json:"name" validate:"required"`
+	Price int    `yaml:"price" json:"price" validate:"gt=0"`
+}
+
+// UserFixture — пользователь, ключ — username.
+// Balance — стартовый баланс, применяется только при создании пользователя.
+// Admin — права администратора; фикстура — единственный способ их выдать или отозвать.
+type UserFixture struct {
+	Username string `yaml:"username" json:"username" validate:"required,email"`
+	Password string `yaml:"password" json:"password" validate:"required,min=8"`
+	Balance  *int   `yaml:"balance" json:"balance" validate:"omitempty,gte=0"`
+	Admin    bool   `yaml:"admin" json:"admin"`
+}
+
+// OpeningBalance возвращает стартовый баланс пользователя.
+func (u UserFixture) OpeningBalance() int {
+	if u.Balance == nil {
+		return DefaultOpeningBalance
+	}
+	return *u.Balance
+}
+
+var validate = validator.New()
+
+// Load читает фикстуру из файла; формат определяется по расширению (.yaml, .yml, .json).
+// Неизвестные поля считаются ошибкой, чтобы опечатки в фикстурах не проходили молча.
+func Load(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- путь к фикстуре задаёт оператор
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+
+	var f Fixture
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&f)
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&f)
+	default:
+		return nil, fmt.Errorf("unsupported fixture format %q: use .yaml, .yml or .json", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse fixture %s: %w", path, err)
+	}
+
+	if err := f.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// Validate проверяет поля и уникальность ключей внутри фикстуры.
+func (f *Fixture) Validate() error {
+	if err := validate.Struct(f); err != nil {
+		return err
+	}
+
+	names := make(map[string]bool, len(f.Merch))
+	for _, m := range f.Merch {
+		if names[m.Name] {
+			return fmt.Errorf("duplicate merch %q", m.Name)
+		}
+		names[m.Name] = true
+	}
+
+	usernames := make(map[string]bool, len(f.Users))
+	for _, u := range f.Users {
+		if usernames[u.Username] {
+			return fmt.Errorf("duplicate user %q", u.Username)
+		}
+		usernames[u.Username] = true
+	}
+	return nil
+}
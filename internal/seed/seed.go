@@ -0,0 +1,137 @@
+package seed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/storage"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Result — сколько записей затронула загрузка фикстур.
+type Result struct {
+	MerchUpserted int
+	UsersCreated  int
+	UsersExisting int // пользователь уже был; пароль обновлён, если отличался
+}
+
+// Seeder загружает фикстуры через репозитории, поэтому работает с любым хранилищем.
+type Seeder struct {
+	log       *slog.Logger
+	txManager storage.TxManager
+	userRepo  storage.UserStorage
+	merchRepo storage.MerchStorage
+}
+
+func NewSeeder(log *slog.Logger, txManager storage.TxManager, userRepo storage.UserStorage, merchRepo storage.MerchStorage) *Seeder {
+	return &Seeder{
+		log:       log,
+		txManager: txManager,
+		userRepo:  userRepo,
+		merchRepo: merchRepo,
+	}
+}
+
+// Apply идемпотентно загружает фикстуры в одной транзакции:
+// мерч — upsert по имени (цена обновляется), пользователи — по username.
+// Существующему пользователю обновляются только пароль и права администратора, баланс не трогается:
+// стартовый баланс применяется один раз, при создании.
+func (s *Seeder) Apply(ctx context.Context, fixtures ...*Fixture) (Result, error) {
+	const op = "seed.Apply"
+	logger := s.log.With(slog.String("op", op))
+
+	var res Result
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		res = Result{}
+		for _, f := range fixtures {
+			for _, m := range f.Merch {
+				if err := s.upsertMerch(ctx, m.Name, m.Price); err != nil {
+					return fmt.Errorf("failed to upsert merch %q: %w", m.Name, err)
+				}
+				res.MerchUpserted++
+			}
+			for _, u := range f.Users {
+				created, err := s.upsertUser(ctx, u)
+				if err != nil {
+					return fmt.Errorf("failed to upsert user %q: %w", u.Username, err)
+				}
+				if created {
+					res.UsersCreated++
+				} else {
+					res.UsersExisting++
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error("failed to apply fixtures", slog.Any("error", err))
+		return Result{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	logger.Info("fixtures applied",
+		slog.Int("merch_upserted", res.MerchUpserted),
+		slog.Int("users_created", res.UsersCreated),
+		slog.Int("users_existing", res.UsersExisting),
+	)
+	return res, nil
+}
+
+// upsertMerch создаёт товар или обновляет его цену; новая действующая цена записывается в историю цен.
+func (s *Seeder) upsertMerch(ctx context.Context, name string, price int) error {
+	current, err := s.merchRepo.GetMerchByName(ctx, name)
+	if err != nil && !errors.Is(err, storage.ErrMerchNotFound) {
+		return err
+	}
+	merch, err := s.merchRepo.UpsertMerch(ctx, name, price)
+	if err != nil {
+		return err
+	}
+	if current != nil && current.Price == price {
+		return nil
+	}
+	return s.merchRepo.AddPriceChange(ctx, &models.MerchPriceChange{
+		MerchID: merch.ID,
+		Price:   price,
+		Comment: "fixtures", // EffectiveFrom не задан: цена действует сразу, время ставит база
+	})
+}
+
+// upsertUser создаёт пользователя или обновляет его пароль, если он не совпадает с фикстурой,
+// и права администратора.
+func (s *Seeder) upsertUser(ctx context.Context, u UserFixture) (bool, error) {
+	user, err := s.userRepo.GetUserByEmail(ctx, u.Username)
+	if err != nil && !errors.Is(err, storage.ErrUserNotFound) {
+		return false, err
+	}
+
+	if user != nil && user.IsAdmin != u.Admin {
+		if err := s.userRepo.SetUserAdmin(ctx, user.ID, u.Admin); err != nil {
+			return false, err
+		}
+	}
+
+	if user != nil && bcrypt.CompareHashAndPassword(user.PassHash, []byte(u.Password)) == nil {
+		return false, nil
+	}
+
+	passHash, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return false, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	if user != nil {
+		return false, s.userRepo.UpdateUserPassword(ctx, user.ID, passHash)
+	}
+
+	_, err = s.userRepo.CreateUser(ctx, &models.User{
+		Email:       u.Username,
+		PassHash:    passHash,
+		CoinBalance: u.OpeningBalance(),
+		IsAdmin:     u.Admin,
+	})
+	return err == nil, err
+}
@@ -0,0 +1,165 @@
+package seed_test
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/seed"
+	"github.com/linemk/avito-shop/internal/storage"
+	"github.com/linemk/avito-shop/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func writeFixture(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_YAMLAndJSON(t *testing.T) {
+	yamlPath := writeFixture(t, "catalog.yaml", `
+merch:
+  - name: cup
+    price: 20
+users:
+  - username: alice@example.com
+    password: password123
+    balance: 300
+`)
+	f, err := seed.Load(yamlPath)
+	require.NoError(t, err)
+	assert.Equal(t, []seed.MerchFixture{{Name: "cup", Price: 20}}, f.Merch)
+	require.Len(t, f.Users, 1)
+	assert.Equal(t, 300, f.Users[0].OpeningBalance())
+
+	jsonPath := writeFixture(t, "users.json", `{"users": [{"username": "bob@example.com", "password": "password123"}]}`)
+	f, err = seed.Load(jsonPath)
+	require.NoError(t, err)
+	require.Len(t, f.Users, 1)
+	assert.Equal(t, seed.DefaultOpeningBalance, f.Users[0].OpeningBalance())
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	cases := map[string]string{
+		"unknown.yaml":   "merch:\n  - name: cup\n    prise: 20\n",
+		"price.yaml":     "merch:\n  - name: cup\n    price: 0\n",
+		"duplicate.yaml": "merch:\n  - {name: cup, price: 20}\n  - {name: cup, price: 30}\n",
+		"email.json":     `{"users": [{"username": "not-an-email", "password": "password123"}]}`,
+		"format.toml":    "",
+	}
+	for name, content := range cases {
+		_, err := seed.Load(writeFixture(t, name, content))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestSeeder_ApplyIsIdempotent(t *testing.T) {
+	db := memory.New()
+	txManager := memory.NewTxManager(db)
+	userRepo := memory.NewUserRepository(db)
+	merchRepo := memory.NewMerchRepository(db)
+	seeder := seed.NewSeeder(slog.New(slog.NewTextHandler(os.Stdout, nil)), txManager, userRepo, merchRepo)
+	ctx := context.Background()
+
+	balance := 300
+	fixture := &seed.Fixture{
+		Merch: []seed.MerchFixture{{Name: "cup", Price: 20}},
+		Users: []seed.UserFixture{{Username: "alice@example.com", Password: "password123", Balance: &balance}},
+	}
+
+	res, err := seeder.Apply(ctx, fixture)
+	require.NoError(t, err)
+	assert.Equal(t, seed.Result{MerchUpserted: 1, UsersCreated: 1}, res)
+
+	user, err := userRepo.GetUserByEmail(ctx, "alice@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, 300, user.CoinBalance)
+
+	// Пользователь успел потратить монеты — повторная загрузка не сбрасывает баланс,
+	// но обновляет цену товара и пароль.
+	require.NoError(t, userRepo.UpdateUserBalance(ctx, user.ID, 120))
+	fixture.Merch[0].Price = 25
+	fixture.Users[0].Password = "newpassword"
+
+	res, err = seeder.Apply(ctx, fixture)
+	require.NoError(t, err)
+	assert.Equal(t, seed.Result{MerchUpserted: 1, UsersExisting: 1}, res)
+
+	user, err = userRepo.GetUserByEmail(ctx, "alice@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, 120, user.CoinBalance)
+	assert.NoError(t, bcrypt.CompareHashAndPassword(user.PassHash, []byte("newpassword")))
+
+	cup, err := merchRepo.GetMerchByName(ctx, "cup")
+	require.NoError(t, err)
+	assert.Equal(t, 25, cup.Price)
+
+	// Повторная загрузка с той же ценой не добавляет записей в историю цен
+	_, err = seeder.Apply(ctx, fixture)
+	require.NoError(t, err)
+	prices, err := merchRepo.GetPriceHistory(ctx, cup.ID)
+	require.NoError(t, err)
+	require.Len(t, prices, 2)
+	assert.Equal(t, 20, prices[0].Price)
+	assert.Equal(t, 25, prices[1].Price)
+}
+
+func TestSeeder_ApplyIsAtomic(t *testing.T) {
+	db := memory.New()
+	userRepo := memory.NewUserRepository(db)
+	merchRepo := memory.NewMerchRepository(db)
+	seeder := seed.NewSeeder(slog.New(slog.NewTextHandler(os.Stdout, nil)), memory.NewTxManager(db), userRepo, merchRepo)
+	ctx := context.Background()
+
+	// Пароль длиннее 72 байт bcrypt не примет — вся загрузка откатывается.
+	long := string(make([]byte, 100))
+	_, err := seeder.Apply(ctx, &seed.Fixture{
+		Merch: []seed.MerchFixture{{Name: "cup", Price: 20}},
+		Users: []seed.UserFixture{{Username: "alice@example.com", Password: long}},
+	})
+	require.Error(t, err)
+
+	_, err = merchRepo.GetMerchByName(ctx, "cup")
+	assert.ErrorIs(t, err, storage.ErrMerchNotFound)
+}
+
+func TestSeeder_ApplySetsAdmin(t *testing.T) {
+	db := memory.New()
+	userRepo := memory.NewUserRepository(db)
+	seeder := seed.NewSeeder(slog.New(slog.NewTextHandler(os.Stdout, nil)), memory.NewTxManager(db), userRepo, memory.NewMerchRepository(db))
+	ctx := context.Background()
+
+	// Учётная запись уже занята (например, зарегистрирована до выдачи прав) — фикстура
+	// выдаёт права и заменяет пароль на свой.
+	_, err := userRepo.CreateUser(ctx, &models.User{Email: "hr@example.com", PassHash: []byte("squatter")})
+	require.NoError(t, err)
+	fixture := &seed.Fixture{Users: []seed.UserFixture{
+		{Username: "hr@example.com", Password: "password123", Admin: true},
+		{Username: "alice@example.com", Password: "password123"},
+	}}
+	_, err = seeder.Apply(ctx, fixture)
+	require.NoError(t, err)
+
+	hr, err := userRepo.GetUserByEmail(ctx, "hr@example.com")
+	require.NoError(t, err)
+	assert.True(t, hr.IsAdmin)
+	assert.NoError(t, bcrypt.CompareHashAndPassword(hr.PassHash, []byte("password123")))
+	alice, err := userRepo.GetUserByEmail(ctx, "alice@example.com")
+	require.NoError(t, err)
+	assert.False(t, alice.IsAdmin)
+
+	// Фикстура без admin отзывает права
+	fixture.Users[0].Admin = false
+	_, err = seeder.Apply(ctx, fixture)
+	require.NoError(t, err)
+	hr, err = userRepo.GetUserByEmail(ctx, "hr@example.com")
+	require.NoError(t, err)
+	assert.False(t, hr.IsAdmin)
+}
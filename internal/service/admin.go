@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/storage"
+)
+
+// Режимы массового начисления
+const (
+	// GrantModeAtomic — все строки в одной транзакции: либо начислено всем, либо никому.
+	GrantModeAtomic = "atomic"
+	// GrantModeBatched — пачками по GrantBatchSize строк, каждая пачка в своей транзакции.
+	// При ошибке обработка останавливается; повторный запуск с тем же ID пропускает уже начисленное.
+	GrantModeBatched = "batched"
+)
+
+// MaxGrantItems — ограничение на число строк в одном массовом начислении.
+const MaxGrantItems = 10000
+
+var (
+	// ErrInvalidGrant — некорректный запрос на начисление.
+	ErrInvalidGrant = errors.New("invalid grant")
+	// ErrGrantIncomplete — пакетное начисление остановилось на ошибке, часть пачек уже применена.
+	ErrGrantIncomplete = errors.New("grant incomplete")
+)
+
+var grantIDRe = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// GrantItem — строка массового начисления.
+type GrantItem struct {
+	Username string `json:"username"`
+	Amount   int    `json:"amount"`
+}
+
+// BulkGrant — массовое начисление. ID задаёт клиент: по нему повторный запуск
+// пропускает строки, начисленные ранее.
+type BulkGrant struct {
+	ID     string      `json:"id"`
+	Reason string      `json:"reason"`
+	Mode   string      `json:"mode"`
+	Items  []GrantItem `json:"items"`
+}
+
+// BulkGrantResult — итог массового начисления.
+type BulkGrantResult struct {
+	ID        string `json:"id"`
+	Mode      string `json:"mode"`
+	Applied   int    `json:"applied"` // начислено в этом запуске
+	Skipped   int    `json:"skipped"` // уже было начислено предыдущим запуском
+	Completed bool   `json:"completed"`
+	Error     string `json:"error,omitempty"`
+}
+
+// AdminService — операции администратора с балансами сотрудников.
+type AdminService interface {
+	// AdjustBalance начисляет (amount > 0) или списывает (amount < 0) монеты с указанием причины
+	// и возвращает новый баланс. О начислении пользователь получает уведомление.
+	AdjustBalance(ctx context.Context, adminID int64, username string, amount int, reason string) (int, error)
+	// BulkGrant начисляет монеты списку сотрудников. В режиме batched при ошибке возвращает
+	// частичный результат вместе с ErrGrantIncomplete.
+	BulkGrant(ctx context.Context, adminID int64, grant BulkGrant) (*BulkGrantResult, error)
+}
+
+type adminService struct {
+	log       *slog.Logger
+	txManager storage.TxManager
+	userRepo  storage.UserStorage
+	ledger    *Ledger
+	notifier  *Notifier
+	batchSize int
+}
+
+func NewAdminService(log *slog.Logger, txManager storage.TxManager, userRepo storage.UserStorage, ledger *Ledger, notifier *Notifier, batchSize int) AdminService {
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	return &adminService{
+		log:       log,
+		txManager: txManager,
+		userRepo:  userRepo,
+		ledger:    ledger,
+		notifier:  notifier,
+		batchSize: batchSize,
+	}
+}
+
+func (s *adminService) AdjustBalance(ctx context.Context, adminID int64, username string, amount int, reason string) (int, error) {
+	const op = "service.AdminService.AdjustBalance"
+	logger := s.log.With(
+		slog.String("op", op),
+		slog.Int64("adminID", adminID),
+		slog.String("username", username),
+		slog.Int("amount", amount),
+	)
+
+	if amount == 0 {
+		return 0, fmt.Errorf("%s: amount must not be zero", op)
+	}
+	if reason == "" {
+		return 0, fmt.Errorf("%s: reason is required", op)
+	}
+
+	var balance int
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		user, err := s.userRepo.GetUserByEmail(ctx, username)
+		if err != nil {
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+
+		user, err = s.ledger.apply(ctx, &models.CoinTransaction{
+			UserID:        user.ID,
+			Amount:        amount,
+			Type:          models.CoinTxAdjustment,
+			RelatedUserID: &adminID,
+			Comment:       reason,
+		})
+		if err != nil {
+			return err
+		}
+		balance = user.CoinBalance
+		// Уведомляем только о начислениях; списание видно в истории операций
+		if amount > 0 {
+			return s.notifyGranted(ctx, user.ID, amount, reason)
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error("failed to adjust balance", slog.Any("error", err))
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	logger.Info("balance adjusted", slog.Int("balance", balance), slog.String("reason", reason))
+	return balance, nil
+}
+
+func (s *adminService) BulkGrant(ctx context.Context, adminID int64, grant BulkGrant) (*BulkGrantResult, error) {
+	const op = "service.AdminService.BulkGrant"
+	if grant.Mode == "" {
+		grant.Mode = GrantModeAtomic
+	}
+	logger := s.log.With(
+		slog.String("op", op),
+		slog.Int64("adminID", adminID),
+		slog.String("grantID", grant.ID),
+		slog.String("mode", grant.Mode),
+		slog.Int("items", len(grant.Items)),
+	)
+
+	if err := validateGrant(grant); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	batchSize := s.batchSize
+	if grant.Mode == GrantModeAtomic {
+		batchSize = len(grant.Items)
+	}
+
+	res := &BulkGrantResult{ID: grant.ID, Mode: grant.Mode}
+	for start := 0; start < len(grant.Items); start += batchSize {
+		end := min(start+batchSize, len(grant.Items))
+
+		applied, skipped, err := s.grantBatch(ctx, adminID, grant, grant.Items[start:end])
+		if err != nil {
+			logger.Error("grant batch failed", slog.Int("offset", start), slog.Any("error", err))
+			if grant.Mode == GrantModeAtomic {
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+			res.Error = err.Error()
+			return res, fmt.Errorf("%s: %w: %w", op, ErrGrantIncomplete, err)
+		}
+		res.Applied += applied
+		res.Skipped += skipped
+	}
+
+	res.Completed = true
+	logger.Info("grant completed", slog.Int("applied", res.Applied), slog.Int("skipped", res.Skipped))
+	return res, nil
+}
+
+// grantBatch начисляет пачку строк в одной транзакции.
+func (s *adminService) grantBatch(ctx context.Context, adminID int64, grant BulkGrant, items []GrantItem) (int, int, error) {
+	var applied, skipped int
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		applied, skipped = 0, 0
+		for _, item := range items {
+			user, err := s.userRepo.GetUserByEmail(ctx, item.Username)
+			if err != nil {
+				return fmt.Errorf("user %s: %w", item.Username, err)
+			}
+
+			key := grantKey(grant.ID, item.Username)
+			_, err = s.ledger.apply(ctx, &models.CoinTransaction{
+				UserID:         user.ID,
+				Amount:         item.Amount,
+				Type:           models.CoinTxGrant,
+				RelatedUserID:  &adminID,
+				Comment:        grant.Reason,
+				IdempotencyKey: &key,
+			})
+			if errors.Is(err, storage.ErrDuplicateTransaction) {
+				skipped++
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("user %s: %w", item.Username, err)
+			}
+			if err := s.notifyGranted(ctx, user.ID, item.Amount, grant.Reason); err != nil {
+				return fmt.Errorf("user %s: %w", item.Username, err)
+			}
+			applied++
+		}
+		return nil
+	})
+	return applied, skipped, err
+}
+
+// notifyGranted уведомляет пользователя о начислении монет администратором. Вызывается внутри WithinTx.
+func (s *adminService) notifyGranted(ctx context.Context, userID int64, amount int, reason string) error {
+	return s.notifier.Notify(ctx, userID, models.NotificationCoinsGranted,
+		fmt.Sprintf("You were granted %d coins: %s", amount, reason),
+		map[string]any{"amount": amount, "reason": reason})
+}
+
+// grantKey — ключ идемпотентности строки массового начисления.
+func grantKey(grantID, username string) string {
+	return "grant:" + grantID + ":" + username
+}
+
+func validateGrant(grant BulkGrant) error {
+	if !grantIDRe.MatchString(grant.ID) {
+		return fmt.Errorf("%w: id must be 1-64 characters of letters, digits, '.', '_' or '-'", ErrInvalidGrant)
+	}
+	if grant.Reason == "" {
+		return fmt.Errorf("%w: reason is required", ErrInvalidGrant)
+	}
+	if grant.Mode != GrantModeAtomic && grant.Mode != GrantModeBatched {
+		return fmt.Errorf("%w: unknown mode %q", ErrInvalidGrant, grant.Mode)
+	}
+	if len(grant.Items) == 0 || len(grant.Items) > MaxGrantItems {
+		return fmt.Errorf("%w: expected 1..%d items, got %d", ErrInvalidGrant, MaxGrantItems, len(grant.Items))
+	}
+
+	seen := make(map[string]bool, len(grant.Items))
+	for i, item := range grant.Items {
+		if item.Username == "" {
+			return fmt.Errorf("%w: item %d: username is required", ErrInvalidGrant, i+1)
+		}
+		if item.Amount <= 0 {
+			return fmt.Errorf("%w: item %d: amount must be positive", ErrInvalidGrant, i+1)
+		}
+		if seen[item.Username] {
+			return fmt.Errorf("%w: item %d: duplicate username %s", ErrInvalidGrant, i+1, item.Username)
+		}
+		seen[item.Username] = true
+	}
+	return nil
+}
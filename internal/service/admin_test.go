@@ -0,0 +1,155 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAdminService(st *testStorage, batchSize int) service.AdminService {
+	return service.NewAdminService(newTestLogger(), st.txManager, st.userRepo, st.ledger, st.notifier, batchSize)
+}
+
+func TestAdminService_AdjustBalance(t *testing.T) {
+	st := newTestStorage()
+	admin := st.createUser(t, "hr@example.com", 0)
+	user := st.createUser(t, "user@example.com", 100)
+	adminSvc := newTestAdminService(st, 10)
+	ctx := context.Background()
+
+	balance, err := adminSvc.AdjustBalance(ctx, admin.ID, user.Email, 50, "hackathon winner")
+	require.NoError(t, err)
+	assert.Equal(t, 150, balance)
+
+	balance, err = adminSvc.AdjustBalance(ctx, admin.ID, user.Email, -30, "correction")
+	require.NoError(t, err)
+	assert.Equal(t, 120, balance)
+	assert.Equal(t, 120, st.balance(t, user.ID))
+
+	// Операции записаны с причиной и администратором
+	txs, err := st.coinTxRepo.GetTransactionsByUserID(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, txs, 2)
+	assert.Equal(t, models.CoinTxAdjustment, txs[0].Type)
+	assert.Equal(t, -30, txs[0].Amount)
+	assert.Equal(t, "correction", txs[0].Comment)
+	assert.Equal(t, admin.ID, *txs[0].RelatedUserID)
+
+	// Списание больше баланса не проходит
+	_, err = adminSvc.AdjustBalance(ctx, admin.ID, user.Email, -1000, "penalty")
+	assert.ErrorIs(t, err, service.ErrInsufficientFunds)
+	assert.Equal(t, 120, st.balance(t, user.ID))
+
+	_, err = adminSvc.AdjustBalance(ctx, admin.ID, user.Email, 10, "")
+	assert.Error(t, err, "reason is required")
+}
+
+func TestAdminService_BulkGrantAtomic(t *testing.T) {
+	st := newTestStorage()
+	admin := st.createUser(t, "hr@example.com", 0)
+	alice := st.createUser(t, "alice@example.com", 0)
+	bob := st.createUser(t, "bob@example.com", 0)
+	adminSvc := newTestAdminService(st, 1)
+	ctx := context.Background()
+
+	grant := service.BulkGrant{
+		ID:     "q3-bonus",
+		Reason: "Q3 bonus",
+		Mode:   service.GrantModeAtomic,
+		Items: []service.GrantItem{
+			{Username: alice.Email, Amount: 100},
+			{Username: bob.Email, Amount: 200},
+			{Username: "ghost@example.com", Amount: 300},
+		},
+	}
+
+	// Неизвестный пользователь — не начислено никому
+	_, err := adminSvc.BulkGrant(ctx, admin.ID, grant)
+	require.Error(t, err)
+	assert.Equal(t, 0, st.balance(t, alice.ID))
+	assert.Equal(t, 0, st.balance(t, bob.ID))
+
+	grant.Items = grant.Items[:2]
+	res, err := adminSvc.BulkGrant(ctx, admin.ID, grant)
+	require.NoError(t, err)
+	assert.Equal(t, &service.BulkGrantResult{ID: "q3-bonus", Mode: service.GrantModeAtomic, Applied: 2, Completed: true}, res)
+	assert.Equal(t, 100, st.balance(t, alice.ID))
+	assert.Equal(t, 200, st.balance(t, bob.ID))
+
+	// Повтор того же начисления ничего не меняет
+	res, err = adminSvc.BulkGrant(ctx, admin.ID, grant)
+	require.NoError(t, err)
+	assert.Equal(t, 2, res.Skipped)
+	assert.Equal(t, 0, res.Applied)
+	assert.Equal(t, 100, st.balance(t, alice.ID))
+
+	txs, err := st.coinTxRepo.GetTransactionsByUserID(ctx, alice.ID)
+	require.NoError(t, err)
+	require.Len(t, txs, 1)
+	assert.Equal(t, models.CoinTxGrant, txs[0].Type)
+	assert.Equal(t, "Q3 bonus", txs[0].Comment)
+}
+
+func TestAdminService_BulkGrantBatchedResume(t *testing.T) {
+	st := newTestStorage()
+	admin := st.createUser(t, "hr@example.com", 0)
+	alice := st.createUser(t, "alice@example.com", 0)
+	bob := st.createUser(t, "bob@example.com", 0)
+	adminSvc := newTestAdminService(st, 2)
+	ctx := context.Background()
+
+	grant := service.BulkGrant{
+		ID:     "new-year",
+		Reason: "New year gift",
+		Mode:   service.GrantModeBatched,
+		Items: []service.GrantItem{
+			{Username: alice.Email, Amount: 10},
+			{Username: bob.Email, Amount: 10},
+			{Username: "carol@example.com", Amount: 10},
+		},
+	}
+
+	// Вторая пачка падает на неизвестном пользователе, первая уже применена
+	res, err := adminSvc.BulkGrant(ctx, admin.ID, grant)
+	require.ErrorIs(t, err, service.ErrGrantIncomplete)
+	require.NotNil(t, res)
+	assert.False(t, res.Completed)
+	assert.Equal(t, 2, res.Applied)
+	assert.NotEmpty(t, res.Error)
+	assert.Equal(t, 10, st.balance(t, alice.ID))
+
+	// После исправления повторный запуск доначисляет только оставшееся
+	carol := st.createUser(t, "carol@example.com", 0)
+	res, err = adminSvc.BulkGrant(ctx, admin.ID, grant)
+	require.NoError(t, err)
+	assert.True(t, res.Completed)
+	assert.Equal(t, 1, res.Applied)
+	assert.Equal(t, 2, res.Skipped)
+	assert.Equal(t, 10, st.balance(t, alice.ID))
+	assert.Equal(t, 10, st.balance(t, carol.ID))
+}
+
+func TestAdminService_BulkGrantValidation(t *testing.T) {
+	st := newTestStorage()
+	adminSvc := newTestAdminService(st, 10)
+	ctx := context.Background()
+	item := service.GrantItem{Username: "a@example.com", Amount: 1}
+
+	cases := map[string]service.BulkGrant{
+		"bad id":       {ID: "bad id!", Reason: "r", Items: []service.GrantItem{item}},
+		"no reason":    {ID: "g1", Items: []service.GrantItem{item}},
+		"bad mode":     {ID: "g1", Reason: "r", Mode: "eventually", Items: []service.GrantItem{item}},
+		"no items":     {ID: "g1", Reason: "r"},
+		"zero amount":  {ID: "g1", Reason: "r", Items: []service.GrantItem{{Username: "a@example.com"}}},
+		"duplicate":    {ID: "g1", Reason: "r", Items: []service.GrantItem{item, item}},
+		"missing user": {ID: "g1", Reason: "r", Items: []service.GrantItem{{Amount: 1}}},
+	}
+	for name, grant := range cases {
+		_, err := adminSvc.BulkGrant(ctx, 1, grant)
+		assert.ErrorIs(t, err, service.ErrInvalidGrant, name)
+	}
+}
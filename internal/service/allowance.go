@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/storage"
+)
+
+// Повторы начисления пользователю, строку которого держит параллельная покупка или перевод:
+// LockUserByID не ждёт блокировку, поэтому начисление повторяется с растущей паузой.
+const (
+	allowanceLockRetries    = 3
+	allowanceLockRetryDelay = 50 * time.Millisecond
+)
+
+// ErrAllowanceIncomplete — части пользователей выплата не начислена; повторный запуск за тот же
+// период начислит только им.
+var ErrAllowanceIncomplete = errors.New("allowance incomplete")
+
+// AllowanceResult — итог регулярного начисления.
+type AllowanceResult struct {
+	Credited int // начислено в этом запуске
+	Skipped  int // уже было начислено за этот период
+	Failed   int // не начислено из-за ошибки
+}
+
+// AllowanceService начисляет регулярную выплату всем активным сотрудникам.
+type AllowanceService interface {
+	// CreditAll начисляет выплату за период period (например, момент запуска по расписанию).
+	// Повторный вызов с тем же периодом пропускает уже получивших выплату. Если части
+	// пользователей начислить не удалось, остальные всё равно получают выплату, а CreditAll
+	// возвращает результат вместе с ErrAllowanceIncomplete.
+	CreditAll(ctx context.Context, period string) (*AllowanceResult, error)
+}
+
+type allowanceService struct {
+	log       *slog.Logger
+	txManager storage.TxManager
+	userRepo  storage.UserStorage
+	ledger    *Ledger
+	amount    int
+	reason    string
+	batchSize int
+}
+
+func NewAllowanceService(log *slog.Logger, txManager storage.TxManager, userRepo storage.UserStorage, ledger *Ledger, amount int, reason string, batchSize int) AllowanceService {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+	return &allowanceService{
+		log:       log,
+		txManager: txManager,
+		userRepo:  userRepo,
+		ledger:    ledger,
+		amount:    amount,
+		reason:    reason,
+		batchSize: batchSize,
+	}
+}
+
+// CreditAll обходит активных пользователей страницами по batchSize. Каждому пользователю выплата
+// начисляется в своей транзакции: блокировка одного пользователя параллельной операцией
+// не откатывает и не останавливает начисление остальным.
+func (s *allowanceService) CreditAll(ctx context.Context, period string) (*AllowanceResult, error) {
+	const op = "service.AllowanceService.CreditAll"
+	logger := s.log.With(slog.String("op", op), slog.String("period", period), slog.Int("amount", s.amount))
+
+	if s.amount <= 0 {
+		return nil, fmt.Errorf("%s: amount must be positive", op)
+	}
+
+	res := &AllowanceResult{}
+	var afterID int64
+	for {
+		ids, err := s.userRepo.ListActiveUserIDs(ctx, afterID, s.batchSize)
+		if err != nil {
+			return res, fmt.Errorf("%s: failed to list users: %w", op, err)
+		}
+		if len(ids) == 0 {
+			break
+		}
+
+		for _, id := range ids {
+			credited, err := s.creditUser(ctx, period, id)
+			if err != nil {
+				if ctx.Err() != nil {
+					return res, fmt.Errorf("%s: %w", op, ctx.Err())
+				}
+				logger.Warn("failed to credit allowance", slog.Int64("userID", id), slog.Any("error", err))
+				res.Failed++
+				continue
+			}
+			if credited {
+				res.Credited++
+			} else {
+				res.Skipped++
+			}
+		}
+		afterID = ids[len(ids)-1]
+	}
+
+	if res.Failed > 0 {
+		logger.Error("allowance incomplete", slog.Int("credited", res.Credited), slog.Int("skipped", res.Skipped), slog.Int("failed", res.Failed))
+		return res, fmt.Errorf("%s: %w: %d users failed", op, ErrAllowanceIncomplete, res.Failed)
+	}
+	logger.Info("allowance credited", slog.Int("credited", res.Credited), slog.Int("skipped", res.Skipped))
+	return res, nil
+}
+
+// creditUser начисляет выплату пользователю id в отдельной транзакции, повторяя попытку,
+// пока его строку держит параллельная операция. Возвращает false, если выплата за период уже была.
+func (s *allowanceService) creditUser(ctx context.Context, period string, id int64) (bool, error) {
+	key := allowanceKey(period, id)
+	for attempt := 1; ; attempt++ {
+		err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+			_, err := s.ledger.apply(ctx, &models.CoinTransaction{
+				UserID:         id,
+				Amount:         s.amount,
+				Type:           models.CoinTxAllowance,
+				Comment:        s.reason,
+				IdempotencyKey: &key,
+			})
+			return err
+		})
+		switch {
+		case err == nil:
+			return true, nil
+		case errors.Is(err, storage.ErrDuplicateTransaction):
+			return false, nil
+		case !errors.Is(err, storage.ErrUserLocked) || attempt > allowanceLockRetries:
+			return false, err
+		}
+
+		timer := time.NewTimer(time.Duration(attempt) * allowanceLockRetryDelay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// allowanceKey — ключ идемпотентности выплаты пользователю за период.
+func allowanceKey(period string, userID int64) string {
+	return "allowance:" + period + ":" + strconv.FormatInt(userID, 10)
+}
@@ -0,0 +1,106 @@
+package service_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/service"
+	"github.com/linemk/avito-shop/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllowanceService_CreditAll(t *testing.T) {
+	st := newTestStorage()
+	users := []*models.User{
+		st.createUser(t, "alice@example.com", 0),
+		st.createUser(t, "bob@example.com", 10),
+		st.createUser(t, "carol@example.com", 20),
+	}
+	// пачки по 2 пользователя: обход должен пройти все страницы
+	svc := service.NewAllowanceService(newTestLogger(), st.txManager, st.userRepo, st.ledger, 100, "monthly allowance", 2)
+	ctx := context.Background()
+
+	res, err := svc.CreditAll(ctx, "2025-02-01T00:00:00Z")
+	require.NoError(t, err)
+	assert.Equal(t, service.AllowanceResult{Credited: 3}, *res)
+	assert.Equal(t, 100, st.balance(t, users[0].ID))
+	assert.Equal(t, 110, st.balance(t, users[1].ID))
+	assert.Equal(t, 120, st.balance(t, users[2].ID))
+
+	txs, err := st.coinTxRepo.GetTransactionsByUserID(ctx, users[0].ID)
+	require.NoError(t, err)
+	require.Len(t, txs, 1)
+	assert.Equal(t, models.CoinTxAllowance, txs[0].Type)
+	assert.Equal(t, "monthly allowance", txs[0].Comment)
+
+	// Повтор за тот же период ничего не начисляет
+	res, err = svc.CreditAll(ctx, "2025-02-01T00:00:00Z")
+	require.NoError(t, err)
+	assert.Equal(t, service.AllowanceResult{Skipped: 3}, *res)
+	assert.Equal(t, 100, st.balance(t, users[0].ID))
+
+	// Следующий период начисляется снова
+	res, err = svc.CreditAll(ctx, "2025-03-01T00:00:00Z")
+	require.NoError(t, err)
+	assert.Equal(t, 3, res.Credited)
+	assert.Equal(t, 200, st.balance(t, users[0].ID))
+}
+
+func TestAllowanceService_ZeroAmount(t *testing.T) {
+	st := newTestStorage()
+	svc := service.NewAllowanceService(newTestLogger(), st.txManager, st.userRepo, st.ledger, 0, "", 10)
+
+	_, err := svc.CreditAll(context.Background(), "2025-02-01T00:00:00Z")
+	assert.Error(t, err)
+}
+
+// lockingUserRepo имитирует NOWAIT-блокировку строки параллельной покупкой: LockUserByID
+// для пользователя из locked возвращает storage.ErrUserLocked заданное число раз (-1 — всегда).
+type lockingUserRepo struct {
+	storage.UserStorage
+	mu     sync.Mutex
+	locked map[int64]int
+}
+
+func (r *lockingUserRepo) LockUserByID(ctx context.Context, id int64) (*models.User, error) {
+	r.mu.Lock()
+	n := r.locked[id]
+	if n > 0 {
+		r.locked[id] = n - 1
+	}
+	r.mu.Unlock()
+	if n != 0 {
+		return nil, fmt.Errorf("%w: user %d", storage.ErrUserLocked, id)
+	}
+	return r.UserStorage.LockUserByID(ctx, id)
+}
+
+func TestAllowanceService_LockedUsers(t *testing.T) {
+	st := newTestStorage()
+	alice := st.createUser(t, "alice@example.com", 0)
+	bob := st.createUser(t, "bob@example.com", 0)
+	carol := st.createUser(t, "carol@example.com", 0)
+	users := &lockingUserRepo{UserStorage: st.userRepo, locked: map[int64]int{bob.ID: 1, carol.ID: -1}}
+	ledger := service.NewLedger(users, st.coinTxRepo, st.lotRepo, service.CoinExpiration{}, st.wishlist)
+	svc := service.NewAllowanceService(newTestLogger(), st.txManager, users, ledger, 100, "monthly allowance", 10)
+	ctx := context.Background()
+
+	// bob освобождается при повторе, carol занята всё время: остальные всё равно получают выплату
+	res, err := svc.CreditAll(ctx, "2025-02-01T00:00:00Z")
+	assert.ErrorIs(t, err, service.ErrAllowanceIncomplete)
+	assert.Equal(t, service.AllowanceResult{Credited: 2, Failed: 1}, *res)
+	assert.Equal(t, 100, st.balance(t, alice.ID))
+	assert.Equal(t, 100, st.balance(t, bob.ID))
+	assert.Equal(t, 0, st.balance(t, carol.ID))
+
+	// Повтор запуска за тот же период начисляет только пропущенным
+	users.locked[carol.ID] = 0
+	res, err = svc.CreditAll(ctx, "2025-02-01T00:00:00Z")
+	require.NoError(t, err)
+	assert.Equal(t, service.AllowanceResult{Credited: 1, Skipped: 2}, *res)
+	assert.Equal(t, 100, st.balance(t, carol.ID))
+}
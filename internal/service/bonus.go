@@ -0,0 +1,48 @@
+package service
+
+import (
+	"strings"
+	"time"
+)
+
+// WelcomeBonus — правила стартового бонуса, который начисляется при регистрации.
+type WelcomeBonus struct {
+	// Amount — бонус по умолчанию
+	Amount int
+	// ExpiresAt — после этого момента бонус по умолчанию не начисляется (нулевое значение — бессрочно)
+	ExpiresAt time.Time
+	// Domains — отдельные правила для email-доменов; действуют вместо бонуса по умолчанию
+	Domains []DomainBonus
+}
+
+// DomainBonus — стартовый бонус для сотрудников с email в домене Domain.
+type DomainBonus struct {
+	Domain    string
+	Amount    int
+	ExpiresAt time.Time // после этого момента правило не действует и применяется бонус по умолчанию
+}
+
+// AmountFor возвращает размер стартового бонуса для email на момент now.
+// Домен сравнивается целиком и без учёта регистра: правило для example.com
+// не действует на dev.example.com.
+func (b WelcomeBonus) AmountFor(email string, now time.Time) int {
+	domain := ""
+	if at := strings.LastIndex(email, "@"); at >= 0 {
+		domain = strings.ToLower(email[at+1:])
+	}
+
+	for _, d := range b.Domains {
+		if strings.EqualFold(d.Domain, domain) && active(d.ExpiresAt, now) {
+			return d.Amount
+		}
+	}
+	if active(b.ExpiresAt, now) {
+		return b.Amount
+	}
+	return 0
+}
+
+// active сообщает, действует ли правило со сроком expiresAt на момент now.
+func active(expiresAt, now time.Time) bool {
+	return expiresAt.IsZero() || now.Before(expiresAt)
+}
@@ -0,0 +1,83 @@
+package service_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWelcomeBonus_AmountFor(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	bonus := service.WelcomeBonus{
+		Amount: 1000,
+		Domains: []service.DomainBonus{
+			{Domain: "partner.com", Amount: 100},
+			{Domain: "promo.com", Amount: 5000, ExpiresAt: now.Add(-time.Hour)},
+		},
+	}
+
+	assert.Equal(t, 1000, bonus.AmountFor("user@example.com", now))
+	assert.Equal(t, 100, bonus.AmountFor("user@Partner.COM", now))
+	assert.Equal(t, 1000, bonus.AmountFor("user@dev.partner.com", now), "subdomains do not match")
+	assert.Equal(t, 1000, bonus.AmountFor("user@promo.com", now), "expired domain policy falls back to default")
+
+	bonus.ExpiresAt = now
+	assert.Equal(t, 0, bonus.AmountFor("user@example.com", now), "default bonus expired")
+	assert.Equal(t, 100, bonus.AmountFor("user@partner.com", now))
+}
+
+func TestAuthService_Login_RecordsWelcomeBonus(t *testing.T) {
+	os.Setenv("JWT_SECRET", "testsecret")
+	defer os.Unsetenv("JWT_SECRET")
+
+	st := newTestStorage()
+	authSvc := st.newAuthService(service.WelcomeBonus{
+		Amount:  1000,
+		Domains: []service.DomainBonus{{Domain: "partner.com", Amount: 250}},
+	})
+	ctx := context.Background()
+
+	_, err := authSvc.Login(ctx, "contractor@partner.com", "password123")
+	require.NoError(t, err)
+
+	user, err := st.userRepo.GetUserByEmail(ctx, "contractor@partner.com")
+	require.NoError(t, err)
+	assert.Equal(t, 250, user.CoinBalance)
+
+	txs, err := st.coinTxRepo.GetTransactionsByUserID(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, txs, 1)
+	assert.Equal(t, models.CoinTxWelcomeBonus, txs[0].Type)
+	assert.Equal(t, 250, txs[0].Amount)
+
+	// Повторный вход бонус не начисляет
+	_, err = authSvc.Login(ctx, "contractor@partner.com", "password123")
+	require.NoError(t, err)
+	assert.Equal(t, 250, st.balance(t, user.ID))
+}
+
+func TestAuthService_Login_NoBonus(t *testing.T) {
+	os.Setenv("JWT_SECRET", "testsecret")
+	defer os.Unsetenv("JWT_SECRET")
+
+	st := newTestStorage()
+	authSvc := st.newAuthService(service.WelcomeBonus{})
+	ctx := context.Background()
+
+	_, err := authSvc.Login(ctx, "user@example.com", "password123")
+	require.NoError(t, err)
+
+	user, err := st.userRepo.GetUserByEmail(ctx, "user@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, 0, user.CoinBalance)
+
+	txs, err := st.coinTxRepo.GetTransactionsByUserID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, txs)
+}
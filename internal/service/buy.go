@@ -2,102 +2,383 @@ package service
 
 import (
 	"context"
-	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/linemk/avito-shop/internal/domain/models"
 	"github.com/linemk/avito-shop/internal/storage"
 )
 
+var (
+	// ErrInvalidGift — подарок нельзя оформить (неизвестный получатель или подарок самому себе).
+	ErrInvalidGift = errors.New("invalid gift")
+	// ErrSoldOut — товар закончился на складе.
+	ErrSoldOut = errors.New("merch is sold out")
+	// ErrPurchaseLimit — пользователь уже получил максимум этого товара за период.
+	ErrPurchaseLimit = errors.New("purchase limit reached")
+	// ErrInvalidPromoCode — промокод не найден, не действует сейчас или не подходит к товару/покупателю.
+	ErrInvalidPromoCode = errors.New("invalid promo code")
+	// ErrPromoCodeExhausted — лимит использований промокода исчерпан.
+	ErrPromoCodeExhausted = errors.New("promo code usage limit reached")
+)
+
 type BuyService interface {
-	Buy(ctx context.Context, userID int64, item string) error
+	// Buy покупает item для userID. variant — артикул варианта (обязателен, если у товара есть варианты),
+	// code — необязательный промокод.
+	Buy(ctx context.Context, userID int64, item, variant, code string) error
+	// Gift покупает item (вариант variant) за счёт userID и кладёт заказ в инвентарь пользователя toUser.
+	Gift(ctx context.Context, userID int64, item, variant, toUser string) error
 }
 
 type buyService struct {
-	log       *slog.Logger
-	userRepo  storage.UserStorage
-	merchRepo storage.MerchStorage
-	orderRepo storage.OrderStorage
-	db        *sql.DB
+	log        *slog.Logger
+	txManager  storage.TxManager
+	userRepo   storage.UserStorage
+	merchRepo  storage.MerchStorage
+	orderRepo  storage.OrderStorage
+	coinTxRepo storage.CoinTransactionStorage
+	promoRepo  storage.PromotionStorage
+	ledger     *Ledger
 }
 
-func NewBuyService(log *slog.Logger, db *sql.DB, userRepo storage.UserStorage, merchRepo storage.MerchStorage, orderRepo storage.OrderStorage) BuyService {
+func NewBuyService(log *slog.Logger, txManager storage.TxManager, userRepo storage.UserStorage, merchRepo storage.MerchStorage, orderRepo storage.OrderStorage, coinTxRepo storage.CoinTransactionStorage, promoRepo storage.PromotionStorage, ledger *Ledger) BuyService {
 	return &buyService{
-		log:       log,
-		db:        db,
-		userRepo:  userRepo,
-		merchRepo: merchRepo,
-		orderRepo: orderRepo,
+		log:        log,
+		txManager:  txManager,
+		userRepo:   userRepo,
+		merchRepo:  merchRepo,
+		orderRepo:  orderRepo,
+		coinTxRepo: coinTxRepo,
+		promoRepo:  promoRepo,
+		ledger:     ledger,
 	}
 }
 
 // Buy осуществляет покупку товара
 // Если что-то идет не так, транзакция откатывается
-func (s *buyService) Buy(ctx context.Context, userID int64, item string) error {
+func (s *buyService) Buy(ctx context.Context, userID int64, item, variant, code string) error {
 	const op = "service.BuyService.Buy"
 	logger := s.log.With(slog.String("op", op), slog.Int64("userID", userID), slog.String("item", item))
 	logger.Info("starting purchase transaction")
 
-	tx, err := s.db.BeginTx(ctx, nil)
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		_, err := s.purchase(ctx, logger, userID, userID, item, variant, code)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		return nil
+	})
 	if err != nil {
-		logger.Error("failed to begin transaction", slog.Any("error", err))
-		return fmt.Errorf("%s: failed to begin transaction: %w", op, err)
+		return err
 	}
 
-	// Получаем мерч по названию через транзакцию
-	merch, err := s.merchRepo.GetMerchByName(ctx, tx, item)
-	if err != nil {
-		if rbErr := tx.Rollback(); rbErr != nil {
-			logger.Error("transaction rollback failed", slog.Any("error", rbErr))
+	logger.Info("purchase completed successfully")
+	return nil
+}
+
+// Gift покупает товар в подарок: списание, заказ получателя и записи в истории
+// обоих пользователей выполняются в одной транзакции.
+func (s *buyService) Gift(ctx context.Context, userID int64, item, variant, toUser string) error {
+	const op = "service.BuyService.Gift"
+	logger := s.log.With(slog.String("op", op), slog.Int64("userID", userID), slog.String("item", item), slog.String("toUser", toUser))
+	logger.Info("starting gift transaction")
+
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		recipient, err := s.userRepo.GetUserByEmail(ctx, toUser)
+		if err != nil {
+			if errors.Is(err, storage.ErrUserNotFound) {
+				logger.Warn("recipient not found")
+				return fmt.Errorf("%s: %w: recipient not found", op, ErrInvalidGift)
+			}
+			logger.Error("failed to get recipient", slog.Any("error", err))
+			return fmt.Errorf("%s: failed to get recipient: %w", op, err)
 		}
+		if recipient.ID == userID {
+			return fmt.Errorf("%s: %w: cannot gift to yourself", op, ErrInvalidGift)
+		}
+
+		order, err := s.purchase(ctx, logger, userID, recipient.ID, item, variant, "")
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		// История: у покупателя — кому и что подарено (списание цены),
+		// у получателя — от кого (его баланс не меняется, поэтому сумма нулевая)
+		err = s.coinTxRepo.InsertTransaction(ctx, &models.CoinTransaction{
+			UserID:        userID,
+			Amount:        -order.TotalPrice,
+			Type:          models.CoinTxGiftSent,
+			RelatedUserID: &recipient.ID,
+			Comment:       order.MerchName,
+		})
+		if err != nil {
+			logger.Error("failed to record sender transaction", slog.Any("error", err))
+			return fmt.Errorf("%s: failed to record sender transaction: %w", op, err)
+		}
+		err = s.coinTxRepo.InsertTransaction(ctx, &models.CoinTransaction{
+			UserID:        recipient.ID,
+			Amount:        0,
+			Type:          models.CoinTxGiftReceived,
+			RelatedUserID: &userID,
+			Comment:       order.MerchName,
+		})
+		if err != nil {
+			logger.Error("failed to record recipient transaction", slog.Any("error", err))
+			return fmt.Errorf("%s: failed to record recipient transaction: %w", op, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Info("gift completed successfully")
+	return nil
+}
+
+// purchase списывает цену item (варианта sku, с учётом скидки) с buyerID и создаёт заказ
+// пользователя ownerID. Вызывается внутри WithinTx.
+func (s *buyService) purchase(ctx context.Context, logger *slog.Logger, buyerID, ownerID int64, item, sku, code string) (*models.Order, error) {
+	// Блокируем строку товара: параллельные покупки ждут, пока эта уменьшит остаток
+	merch, err := s.merchRepo.LockMerchByName(ctx, item)
+	if err != nil {
 		logger.Error("failed to get merch", slog.Any("error", err))
-		return fmt.Errorf("%s: failed to get merch: %w", op, err)
+		return nil, fmt.Errorf("failed to get merch: %w", err)
+	}
+	variant, err := s.lockVariant(ctx, logger, merch, sku)
+	if err != nil {
+		return nil, err
+	}
+	price, stock := variantPriceAndStock(merch, variant)
+	if stock != nil && *stock <= 0 {
+		logger.Warn("merch is sold out")
+		return nil, ErrSoldOut
 	}
 
 	// Получаем пользователя через транзакцию
-	user, err := s.userRepo.LockUserByIDTx(ctx, tx, userID)
+	user, err := s.userRepo.LockUserByID(ctx, buyerID)
 	if err != nil {
-		if rbErr := tx.Rollback(); rbErr != nil {
-			logger.Error("transaction rollback failed", slog.Any("error", rbErr))
-		}
 		logger.Error("failed to get user", slog.Any("error", err))
-		return fmt.Errorf("%s: failed to get user: %w", op, err)
+		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
+	if err := s.checkPurchaseLimit(ctx, logger, merch, buyerID, ownerID); err != nil {
+		return nil, err
+	}
+
+	promo, discount, err := s.selectPromotion(ctx, logger, merch, price, buyerID, code)
+	if err != nil {
+		return nil, err
+	}
+	price -= discount
+
 	// Проверяем, достаточно ли средств
-	if user.CoinBalance < merch.Price {
-		if rbErr := tx.Rollback(); rbErr != nil {
-			logger.Error("transaction rollback failed", slog.Any("error", rbErr))
-		}
-		logger.Warn("insufficient funds", slog.Int("balance", user.CoinBalance), slog.Int("price", merch.Price))
-		return fmt.Errorf("%s: insufficient funds", op)
+	if user.CoinBalance < price {
+		logger.Warn("insufficient funds", slog.Int("balance", user.CoinBalance), slog.Int("price", price))
+		return nil, ErrInsufficientFunds
 	}
 
-	// Обновляем баланс пользователя
-	newBalance := user.CoinBalance - merch.Price
-	if err := s.userRepo.UpdateUserBalance(ctx, tx, userID, newBalance); err != nil {
-		if rbErr := tx.Rollback(); rbErr != nil {
-			logger.Error("transaction rollback failed", slog.Any("error", rbErr))
-		}
+	// Списываем монеты (расходуются самые старые партии)
+	if err := s.ledger.changeBalance(ctx, user, -price, ""); err != nil {
 		logger.Error("failed to update user balance", slog.Any("error", err))
-		return fmt.Errorf("%s: failed to update user balance: %w", op, err)
+		return nil, fmt.Errorf("failed to update user balance: %w", err)
 	}
 
-	// Создаем заказ
-	if err := s.orderRepo.CreateOrder(ctx, tx, userID, merch.ID, 1, merch.Price); err != nil {
-		if rbErr := tx.Rollback(); rbErr != nil {
-			logger.Error("transaction rollback failed", slog.Any("error", rbErr))
+	// Уменьшаем остаток варианта, если он учитывается отдельно, иначе — товара
+	if variant != nil && variant.Stock != nil {
+		left := *variant.Stock - 1
+		variant.Stock = &left
+		if err := s.merchRepo.UpdateVariant(ctx, variant); err != nil {
+			logger.Error("failed to update variant stock", slog.Any("error", err))
+			return nil, fmt.Errorf("failed to update variant stock: %w", err)
+		}
+	} else if merch.Stock != nil {
+		left := *merch.Stock - 1
+		if err := s.merchRepo.UpdateMerchStock(ctx, merch.ID, &left); err != nil {
+			logger.Error("failed to update merch stock", slog.Any("error", err))
+			return nil, fmt.Errorf("failed to update merch stock: %w", err)
 		}
+	}
+
+	// Создаем заказ; подарок попадает в инвентарь получателя
+	order := &models.Order{UserID: ownerID, MerchID: merch.ID, MerchName: merch.Name, Quantity: 1, TotalPrice: price, Discount: discount, Status: models.OrderPlaced}
+	if ownerID != buyerID {
+		order.GiftedBy = &buyerID
+	}
+	if promo != nil {
+		order.PromotionID = &promo.ID
+		order.PromotionName = promo.Name
+	}
+	if variant != nil {
+		order.VariantID = &variant.ID
+		order.VariantSKU, order.VariantSize, order.VariantColor = variant.SKU, variant.Size, variant.Color
+	}
+	if err := s.orderRepo.CreateOrder(ctx, order); err != nil {
 		logger.Error("failed to create order", slog.Any("error", err))
-		return fmt.Errorf("%s: failed to create order: %w", op, err)
+		return nil, fmt.Errorf("failed to create order: %w", err)
 	}
+	// Первая запись истории статусов: заказ оформлен покупателем
+	err = s.orderRepo.AddOrderStatusChange(ctx, &models.OrderStatusChange{
+		OrderID:   order.ID,
+		ToStatus:  order.Status,
+		ChangedBy: &buyerID,
+	})
+	if err != nil {
+		logger.Error("failed to record order status", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to record order status: %w", err)
+	}
+	return order, nil
+}
 
-	// Коммит транзакции
-	if err := tx.Commit(); err != nil {
-		logger.Error("failed to commit transaction", slog.Any("error", err))
-		return fmt.Errorf("%s: failed to commit transaction: %w", op, err)
+// checkPurchaseLimit проверяет лимит товара для пользователя ownerID, который получит заказ.
+// Его строка заблокирована, поэтому параллельные покупки и подарки не превысят лимит.
+func (s *buyService) checkPurchaseLimit(ctx context.Context, logger *slog.Logger, merch *models.Merch, buyerID, ownerID int64) error {
+	if merch.PurchaseLimit <= 0 {
+		return nil
+	}
+	// покупатель уже заблокирован, получателя подарка блокируем отдельно
+	if ownerID != buyerID {
+		if _, err := s.userRepo.LockUserByID(ctx, ownerID); err != nil {
+			logger.Error("failed to lock recipient", slog.Any("error", err))
+			return fmt.Errorf("failed to lock recipient: %w", err)
+		}
 	}
 
-	logger.Info("purchase completed successfully")
+	since, err := purchaseLimitSince(merch.PurchaseLimitPeriod, time.Now())
+	if err != nil {
+		return err
+	}
+	count, err := s.orderRepo.CountUserOrders(ctx, ownerID, merch.ID, since)
+	if err != nil {
+		logger.Error("failed to count orders", slog.Any("error", err))
+		return fmt.Errorf("failed to count orders: %w", err)
+	}
+	if count >= merch.PurchaseLimit {
+		logger.Warn("purchase limit reached", slog.Int("count", count), slog.Int("limit", merch.PurchaseLimit))
+		return fmt.Errorf("%w: %s", ErrPurchaseLimit, describePurchaseLimit(merch.PurchaseLimit, merch.PurchaseLimitPeriod))
+	}
 	return nil
 }
+
+// selectPromotion выбирает акцию для покупки товара по цене price покупателем buyerID: промокод code,
+// если он указан, или автоматическую акцию — ту, что даёт большую скидку. К заказу применяется одна акция.
+// Строки акций блокируются, чтобы параллельные покупки не превысили лимиты использований.
+func (s *buyService) selectPromotion(ctx context.Context, logger *slog.Logger, merch *models.Merch, price int, buyerID int64, code string) (*models.Promotion, int, error) {
+	now := time.Now()
+	var best *models.Promotion
+	bestDiscount := 0
+
+	if code = normalizePromoCode(code); code != "" {
+		promo, err := s.promoRepo.LockPromotionByCode(ctx, code)
+		if err != nil {
+			if errors.Is(err, storage.ErrPromotionNotFound) {
+				logger.Warn("promo code not found", slog.String("code", code))
+				return nil, 0, ErrInvalidPromoCode
+			}
+			logger.Error("failed to get promotion", slog.Any("error", err))
+			return nil, 0, fmt.Errorf("failed to get promotion: %w", err)
+		}
+		if !promotionApplies(promo, merch.ID, buyerID, now) {
+			logger.Warn("promo code does not apply", slog.String("code", code))
+			return nil, 0, ErrInvalidPromoCode
+		}
+		available, err := s.promotionAvailable(ctx, promo, buyerID)
+		if err != nil {
+			logger.Error("failed to count promotion uses", slog.Any("error", err))
+			return nil, 0, fmt.Errorf("failed to count promotion uses: %w", err)
+		}
+		if !available {
+			logger.Warn("promo code exhausted", slog.String("code", code))
+			return nil, 0, ErrPromoCodeExhausted
+		}
+		best, bestDiscount = promo, promotionDiscount(promo, price)
+	}
+
+	autos, err := s.promoRepo.ListAutoPromotions(ctx, merch.ID, buyerID, now)
+	if err != nil {
+		logger.Error("failed to list promotions", slog.Any("error", err))
+		return nil, 0, fmt.Errorf("failed to list promotions: %w", err)
+	}
+	// Сначала пробуем акции с большей скидкой; исчерпанные пропускаем
+	sort.SliceStable(autos, func(i, j int) bool {
+		return promotionDiscount(&autos[i], price) > promotionDiscount(&autos[j], price)
+	})
+	for i := range autos {
+		if promotionDiscount(&autos[i], price) <= bestDiscount {
+			break
+		}
+		promo, err := s.promoRepo.LockPromotion(ctx, autos[i].ID)
+		if err != nil {
+			logger.Error("failed to lock promotion", slog.Any("error", err))
+			return nil, 0, fmt.Errorf("failed to lock promotion: %w", err)
+		}
+		// акцию могли выключить, пока мы ждали блокировку
+		if !promotionApplies(promo, merch.ID, buyerID, now) {
+			continue
+		}
+		available, err := s.promotionAvailable(ctx, promo, buyerID)
+		if err != nil {
+			logger.Error("failed to count promotion uses", slog.Any("error", err))
+			return nil, 0, fmt.Errorf("failed to count promotion uses: %w", err)
+		}
+		if available {
+			best, bestDiscount = promo, promotionDiscount(promo, price)
+			break
+		}
+	}
+	return best, bestDiscount, nil
+}
+
+// promotionAvailable проверяет общий лимит использований акции и лимит для покупателя buyerID.
+func (s *buyService) promotionAvailable(ctx context.Context, promo *models.Promotion, buyerID int64) (bool, error) {
+	if promo.MaxUses > 0 {
+		uses, err := s.orderRepo.CountPromotionOrders(ctx, promo.ID, 0)
+		if err != nil {
+			return false, err
+		}
+		if uses >= promo.MaxUses {
+			return false, nil
+		}
+	}
+	if promo.MaxUsesPerUser > 0 {
+		uses, err := s.orderRepo.CountPromotionOrders(ctx, promo.ID, buyerID)
+		if err != nil {
+			return false, err
+		}
+		if uses >= promo.MaxUsesPerUser {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// lockVariant блокирует вариант sku товара merch. Пустой sku допустим только для товара без вариантов.
+func (s *buyService) lockVariant(ctx context.Context, logger *slog.Logger, merch *models.Merch, sku string) (*models.MerchVariant, error) {
+	if sku == "" {
+		variants, err := s.merchRepo.ListVariants(ctx, merch.ID)
+		if err != nil {
+			logger.Error("failed to list merch variants", slog.Any("error", err))
+			return nil, fmt.Errorf("failed to list merch variants: %w", err)
+		}
+		if len(variants) > 0 {
+			skus := make([]string, 0, len(variants))
+			for _, v := range variants {
+				skus = append(skus, v.SKU)
+			}
+			logger.Warn("merch variant required")
+			return nil, fmt.Errorf("%w: choose one of %s", ErrVariantRequired, strings.Join(skus, ", "))
+		}
+		return nil, nil
+	}
+
+	variant, err := s.merchRepo.LockVariantBySKU(ctx, merch.ID, sku)
+	if err != nil {
+		logger.Warn("failed to get merch variant", slog.String("sku", sku), slog.Any("error", err))
+		return nil, fmt.Errorf("failed to get merch variant: %w", err)
+	}
+	return variant, nil
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Типы событий
+const (
+	// EventOrderStatusChanged — заказ перешёл в новый статус (Data: orderId, item, from, to, comment).
+	EventOrderStatusChanged = "order.status_changed"
+)
+
+// Event — доменное событие. Публикуется после фиксации транзакции, в которой произошло изменение.
+type Event struct {
+	Type string `json:"type"`
+	// UserID — пользователь, которого касается событие (например, владелец заказа)
+	UserID int64          `json:"userId"`
+	Data   map[string]any `json:"data,omitempty"`
+	At     time.Time      `json:"at"`
+}
+
+// EventPublisher доставляет события подписчикам. Ошибки доставки не влияют
+// на уже выполненную операцию, поэтому Publish ничего не возвращает.
+type EventPublisher interface {
+	Publish(ctx context.Context, event Event)
+}
+
+type logEventPublisher struct {
+	log *slog.Logger
+}
+
+// NewLogEventPublisher создаёт публикатор, который пишет события в лог.
+func NewLogEventPublisher(log *slog.Logger) EventPublisher {
+	return &logEventPublisher{log: log}
+}
+
+func (p *logEventPublisher) Publish(ctx context.Context, event Event) {
+	p.log.InfoContext(ctx, "event published",
+		slog.String("type", event.Type),
+		slog.Int64("userID", event.UserID),
+		slog.Any("data", event.Data),
+		slog.Time("at", event.At),
+	)
+}
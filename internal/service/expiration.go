@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/storage"
+)
+
+// ExpirationResult — итог сгорания монет.
+type ExpirationResult struct {
+	Lots  int // партий сожжено
+	Coins int // монет сожжено
+}
+
+// ExpirationService сжигает непотраченные остатки партий с истёкшим сроком.
+type ExpirationService interface {
+	// ExpireDue сжигает партии со сроком не позже now. Партии, которые не удалось обработать
+	// (например, пользователь заблокирован параллельной операцией), остаются до следующего запуска.
+	ExpireDue(ctx context.Context, now time.Time) (*ExpirationResult, error)
+}
+
+type expirationService struct {
+	log       *slog.Logger
+	txManager storage.TxManager
+	lotRepo   storage.CoinLotStorage
+	ledger    *Ledger
+	batchSize int
+}
+
+func NewExpirationService(log *slog.Logger, txManager storage.TxManager, lotRepo storage.CoinLotStorage, ledger *Ledger, batchSize int) ExpirationService {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+	return &expirationService{
+		log:       log,
+		txManager: txManager,
+		lotRepo:   lotRepo,
+		ledger:    ledger,
+		batchSize: batchSize,
+	}
+}
+
+// ExpireDue обрабатывает каждую партию в своей транзакции.
+func (s *expirationService) ExpireDue(ctx context.Context, now time.Time) (*ExpirationResult, error) {
+	const op = "service.ExpirationService.ExpireDue"
+	logger := s.log.With(slog.String("op", op), slog.Time("now", now))
+
+	res := &ExpirationResult{}
+	failed := 0
+	var afterID int64
+	for {
+		lots, err := s.lotRepo.ListExpiredLots(ctx, now, afterID, s.batchSize)
+		if err != nil {
+			return res, fmt.Errorf("%s: failed to list expired lots: %w", op, err)
+		}
+		if len(lots) == 0 {
+			break
+		}
+
+		for _, lot := range lots {
+			afterID = lot.ID
+			var expired int
+			err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+				var err error
+				expired, err = s.ledger.expireLot(ctx, lot.ID, now)
+				return err
+			})
+			if err != nil {
+				logger.Error("failed to expire lot", slog.Int64("lotID", lot.ID), slog.Any("error", err))
+				failed++
+				continue
+			}
+			if expired > 0 {
+				res.Lots++
+				res.Coins += expired
+			}
+		}
+	}
+
+	logger.Info("coins expired", slog.Int("lots", res.Lots), slog.Int("coins", res.Coins), slog.Int("failed", failed))
+	if failed > 0 {
+		return res, fmt.Errorf("%s: failed to expire %d lots", op, failed)
+	}
+	return res, nil
+}
@@ -0,0 +1,94 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpiration_OldestFirstAndExpireDue(t *testing.T) {
+	st := newTestStorageWithExpiration(service.CoinExpiration{TTL: map[string]time.Duration{
+		models.CoinTxAdjustment:       24 * time.Hour,
+		models.CoinTxTransferReceived: 48 * time.Hour,
+	}})
+	ctx := context.Background()
+	log := newTestLogger()
+	st.db.AddMerch("t-shirt", 80)
+
+	admin := st.createUser(t, "admin@example.com", 0)
+	// 50 монет без партии (начислены до появления партий) — не сгорают
+	alice := st.createUser(t, "alice@example.com", 50)
+	bob := st.createUser(t, "bob@example.com", 100)
+
+	adminSvc := service.NewAdminService(log, st.txManager, st.userRepo, st.ledger, st.notifier, 10)
+	_, err := adminSvc.AdjustBalance(ctx, admin.ID, "alice@example.com", 100, "bonus")
+	require.NoError(t, err)
+	sendSvc := service.NewSendCoinService(log, st.txManager, st.userRepo, st.coinTxRepo, st.ledger, st.notifier, service.TransferLimits{})
+	require.NoError(t, sendSvc.SendCoin(ctx, bob.ID, "alice@example.com", 30, ""))
+
+	// Покупка тратит сначала 50 монет без партии, затем 30 из самой старой партии
+	buySvc := service.NewBuyService(log, st.txManager, st.userRepo, st.merchRepo, st.orderRepo, st.coinTxRepo, st.promoRepo, st.ledger)
+	require.NoError(t, buySvc.Buy(ctx, alice.ID, "t-shirt", "", ""))
+	assert.Equal(t, 100, st.balance(t, alice.ID))
+
+	infoSvc := service.NewInfoService(log, st.userRepo, st.orderRepo, st.coinTxRepo, st.lotRepo, 0)
+	info, err := infoSvc.GetInfo(ctx, alice.ID)
+	require.NoError(t, err)
+	require.Len(t, info.Expiring, 2)
+	assert.Equal(t, 70, info.Expiring[0].Amount)
+	assert.Equal(t, models.CoinTxAdjustment, info.Expiring[0].Source)
+	assert.Equal(t, 30, info.Expiring[1].Amount)
+
+	expSvc := service.NewExpirationService(log, st.txManager, st.lotRepo, st.ledger, 1)
+
+	// Через сутки сгорает остаток начисления администратора
+	res, err := expSvc.ExpireDue(ctx, time.Now().Add(25*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, service.ExpirationResult{Lots: 1, Coins: 70}, *res)
+	assert.Equal(t, 30, st.balance(t, alice.ID))
+
+	txs, err := st.coinTxRepo.GetTransactionsByUserID(ctx, alice.ID)
+	require.NoError(t, err)
+	var expired []*models.CoinTransaction
+	for _, tx := range txs {
+		if tx.Type == models.CoinTxExpired {
+			expired = append(expired, tx)
+		}
+	}
+	require.Len(t, expired, 1)
+	assert.Equal(t, -70, expired[0].Amount)
+
+	// Повторный запуск за тот же момент ничего не сжигает
+	res, err = expSvc.ExpireDue(ctx, time.Now().Add(25*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, service.ExpirationResult{}, *res)
+
+	// Через двое суток сгорает перевод; баланс отправителя без партий не трогается
+	res, err = expSvc.ExpireDue(ctx, time.Now().Add(49*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, service.ExpirationResult{Lots: 1, Coins: 30}, *res)
+	assert.Equal(t, 0, st.balance(t, alice.ID))
+	assert.Equal(t, 70, st.balance(t, bob.ID))
+}
+
+func TestExpiration_DisabledKeepsCoins(t *testing.T) {
+	st := newTestStorage()
+	ctx := context.Background()
+	admin := st.createUser(t, "admin@example.com", 0)
+	alice := st.createUser(t, "alice@example.com", 0)
+
+	adminSvc := service.NewAdminService(newTestLogger(), st.txManager, st.userRepo, st.ledger, st.notifier, 10)
+	_, err := adminSvc.AdjustBalance(ctx, admin.ID, "alice@example.com", 100, "bonus")
+	require.NoError(t, err)
+
+	expSvc := service.NewExpirationService(newTestLogger(), st.txManager, st.lotRepo, st.ledger, 10)
+	res, err := expSvc.ExpireDue(ctx, time.Now().AddDate(10, 0, 0))
+	require.NoError(t, err)
+	assert.Equal(t, service.ExpirationResult{}, *res)
+	assert.Equal(t, 100, st.balance(t, alice.ID))
+}
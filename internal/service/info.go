@@ -4,13 +4,23 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/linemk/avito-shop/internal/domain/models"
 	"github.com/linemk/avito-shop/internal/storage"
 )
 
+// MaxHistoryEntries — ограничение на число операций в одном ответе поиска по истории.
+const MaxHistoryEntries = 100
+
 // InfoService определяет интерфейс для получения информации о пользователе.
 type InfoService interface {
 	GetInfo(ctx context.Context, userID int64) (*InfoResponse, error)
+	// SearchHistory возвращает последние limit операций пользователя, в комментарии которых
+	// встречается query (пустой query — все операции).
+	SearchHistory(ctx context.Context, userID int64, query string, limit int) ([]TransactionEntry, error)
 }
 
 // infoService — конкретная реализация InfoService.
@@ -19,14 +29,19 @@ type infoService struct {
 	userRepo   storage.UserStorage
 	orderRepo  storage.OrderStorage
 	coinTxRepo storage.CoinTransactionStorage
+	lotRepo    storage.CoinLotStorage
+	// expiringWindow — насколько вперёд показывать сгорающие монеты (0 — все со сроком)
+	expiringWindow time.Duration
 }
 
-func NewInfoService(log *slog.Logger, userRepo storage.UserStorage, orderRepo storage.OrderStorage, coinTxRepo storage.CoinTransactionStorage) InfoService {
+func NewInfoService(log *slog.Logger, userRepo storage.UserStorage, orderRepo storage.OrderStorage, coinTxRepo storage.CoinTransactionStorage, lotRepo storage.CoinLotStorage, expiringWindow time.Duration) InfoService {
 	return &infoService{
-		log:        log,
-		userRepo:   userRepo,
-		orderRepo:  orderRepo,
-		coinTxRepo: coinTxRepo,
+		log:            log,
+		userRepo:       userRepo,
+		orderRepo:      orderRepo,
+		coinTxRepo:     coinTxRepo,
+		lotRepo:        lotRepo,
+		expiringWindow: expiringWindow,
 	}
 }
 
@@ -35,27 +50,78 @@ type InfoResponse struct {
 	Coins       int             `json:"coins"`
 	Inventory   []InventoryItem `json:"inventory"`
 	CoinHistory CoinHistory     `json:"coinHistory"`
+	// Expiring — монеты, которые скоро сгорят, по возрастанию срока
+	Expiring []ExpiringCoins `json:"expiring,omitempty"`
+}
+
+// ExpiringCoins — непотраченный остаток партии монет и его срок.
+type ExpiringCoins struct {
+	Amount    int       `json:"amount"`
+	Source    string    `json:"source"`
+	ExpiresAt time.Time `json:"expiresAt"`
 }
 
+// InventoryItem — сколько штук товара Type есть у пользователя. Покупки разных вариантов
+// товара считаются отдельно; у товара без вариантов Variant, Size и Color пустые.
 type InventoryItem struct {
 	Type     string `json:"type"`
+	Variant  string `json:"variant,omitempty"`
+	Size     string `json:"size,omitempty"`
+	Color    string `json:"color,omitempty"`
 	Quantity int    `json:"quantity"`
 }
 
 type CoinHistory struct {
 	Received []HistoryEntry `json:"received"`
 	Sent     []HistoryEntry `json:"sent"`
+	// GiftsReceived и GiftsSent — мерч, полученный в подарок и подаренный коллегам
+	GiftsReceived []GiftEntry `json:"giftsReceived,omitempty"`
+	GiftsSent     []GiftEntry `json:"giftsSent,omitempty"`
+	// Credits и Debits — остальные начисления (приветственный бонус, выплаты по расписанию,
+	// начисления и корректировки администратора, возвраты) и списания (сгорание, корректировки)
+	Credits []BalanceEntry `json:"credits,omitempty"`
+	Debits  []BalanceEntry `json:"debits,omitempty"`
+}
+
+// BalanceEntry — начисление или списание монет без участия другого сотрудника.
+// Amount всегда положительный: направление задаёт список, в котором находится запись.
+type BalanceEntry struct {
+	Type      string    `json:"type"`
+	Amount    int       `json:"amount"`
+	Comment   string    `json:"comment,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// GiftEntry — подарок мерча: кто, кому и что. Price — сколько монет заплатил даритель;
+// в полученных подарках не заполняется, получатель за подарок не платит.
+type GiftEntry struct {
+	FromUser string `json:"fromUser,omitempty"`
+	ToUser   string `json:"toUser,omitempty"`
+	Item     string `json:"item"`
+	Price    int    `json:"price,omitempty"`
 }
 
 type HistoryEntry struct {
 	FromUser string `json:"fromUser,omitempty"`
 	ToUser   string `json:"toUser,omitempty"`
 	Amount   int    `json:"amount"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// TransactionEntry — операция с монетами в результатах поиска по истории.
+type TransactionEntry struct {
+	ID        int64     `json:"id"`
+	Type      string    `json:"type"`
+	Amount    int       `json:"amount"`
+	FromUser  string    `json:"fromUser,omitempty"`
+	ToUser    string    `json:"toUser,omitempty"`
+	Comment   string    `json:"comment,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
 }
 
-// GetInfo собирает информацию о пользователе, например, баланс, инвентарь и историю транзакций.
-// Здесь для примера мы просто возвращаем баланс из таблицы пользователей. В реальной реализации
-// необходимо обращаться к соответствующим репозиториям для инвентаря и транзакций.
+// GetInfo собирает информацию о пользователе: баланс из таблицы пользователей, инвентарь по его
+// заказам (без отменённых), историю операций с монетами и монеты, которые скоро сгорят.
+// Если историю или сгорающие монеты получить не удалось, ответ возвращается без них.
 func (s *infoService) GetInfo(ctx context.Context, userID int64) (*InfoResponse, error) {
 	const op = "service.InfoService.GetInfo"
 	s.log.Info("getting info", slog.String("op", op), slog.Int64("userID", userID))
@@ -73,32 +139,44 @@ func (s *infoService) GetInfo(ctx context.Context, userID int64) (*InfoResponse,
 		return nil, fmt.Errorf("failed to get orders: %w", err)
 	}
 
-	// Группируем заказы по типу мерча
-	inventoryMap := make(map[string]int)
+	// Группируем заказы по типу мерча и варианту; отменённые заказы в инвентарь не входят
+	inventoryMap := make(map[InventoryItem]int)
 	for _, order := range orders {
-		inventoryMap[order.MerchName] += order.Quantity
+		if order.Status == models.OrderCancelled {
+			continue
+		}
+		key := InventoryItem{Type: order.MerchName, Variant: order.VariantSKU, Size: order.VariantSize, Color: order.VariantColor}
+		inventoryMap[key] += order.Quantity
 	}
 
 	// Преобразуем результат в массив InventoryItem
 	var inventory []InventoryItem
-	for merch, quantity := range inventoryMap {
-		inventory = append(inventory, InventoryItem{
-			Type:     merch,
-			Quantity: quantity,
-		})
+	for item, quantity := range inventoryMap {
+		item.Quantity = quantity
+		inventory = append(inventory, item)
 	}
+	sort.Slice(inventory, func(i, j int) bool {
+		if inventory[i].Type != inventory[j].Type {
+			return inventory[i].Type < inventory[j].Type
+		}
+		return inventory[i].Variant < inventory[j].Variant
+	})
 
 	// Получаем историю транзакций пользователя через CoinTransactionStorage
 	transactions, err := s.coinTxRepo.GetTransactionsByUserID(ctx, userID)
 	var received []HistoryEntry
 	var sent []HistoryEntry
+	var giftsReceived []GiftEntry
+	var giftsSent []GiftEntry
+	var credits []BalanceEntry
+	var debits []BalanceEntry
 	if err != nil {
 		s.log.Error("failed to get coin transactions", slog.Any("error", err))
 		// Если ошибка получения транзакций, можно продолжить с пустой историей
 	} else {
 		for _, tx := range transactions {
 			switch tx.Type {
-			case "transfer_received":
+			case models.CoinTxTransferReceived:
 				fromName := ""
 				if tx.RelatedUserID != nil {
 					fromUser, err := s.userRepo.GetUserByID(ctx, *tx.RelatedUserID)
@@ -109,8 +187,9 @@ func (s *infoService) GetInfo(ctx context.Context, userID int64) (*InfoResponse,
 				received = append(received, HistoryEntry{
 					FromUser: fromName,
 					Amount:   tx.Amount,
+					Comment:  tx.Comment,
 				})
-			case "transfer_sent":
+			case models.CoinTxTransferSent:
 				toName := ""
 				if tx.RelatedUserID != nil {
 					toUser, err := s.userRepo.GetUserByID(ctx, *tx.RelatedUserID)
@@ -119,18 +198,118 @@ func (s *infoService) GetInfo(ctx context.Context, userID int64) (*InfoResponse,
 					}
 				}
 				sent = append(sent, HistoryEntry{
-					ToUser: toName,
-					Amount: tx.Amount,
+					ToUser:  toName,
+					Amount:  tx.Amount,
+					Comment: tx.Comment,
 				})
+			case models.CoinTxGiftReceived, models.CoinTxGiftSent:
+				// для подарков в комментарии записано название товара; у gift_sent сумма — списание цены
+				gift := GiftEntry{Item: tx.Comment, Price: -tx.Amount}
+				counterparty := ""
+				if tx.RelatedUserID != nil {
+					if related, err := s.userRepo.GetUserByID(ctx, *tx.RelatedUserID); err == nil {
+						counterparty = related.Email
+					}
+				}
+				if tx.Type == models.CoinTxGiftSent {
+					gift.ToUser = counterparty
+					giftsSent = append(giftsSent, gift)
+				} else {
+					gift.FromUser = counterparty
+					giftsReceived = append(giftsReceived, gift)
+				}
+			default:
+				entry := BalanceEntry{Type: tx.Type, Amount: tx.Amount, Comment: tx.Comment, CreatedAt: tx.CreatedAt}
+				if tx.Amount < 0 {
+					entry.Amount = -tx.Amount
+					debits = append(debits, entry)
+				} else {
+					credits = append(credits, entry)
+				}
 			}
 		}
 	}
 
-	// Для упрощения примера, инвентарь и история транзакций возвращаются пустыми.
+	expiring, err := s.expiring(ctx, userID)
+	if err != nil {
+		s.log.Error("failed to get coin lots", slog.Any("error", err))
+		// как и для истории, продолжаем без сгорающих монет
+	}
+
+	// Собираем ответ: баланс, инвентарь, история операций и сгорающие монеты
 	resp := &InfoResponse{
-		Coins:       user.CoinBalance,
-		Inventory:   inventory,
-		CoinHistory: CoinHistory{Received: received, Sent: sent}, // Здесь - транзакции
+		Coins:     user.CoinBalance,
+		Inventory: inventory,
+		CoinHistory: CoinHistory{
+			Received:      received,
+			Sent:          sent,
+			GiftsReceived: giftsReceived,
+			GiftsSent:     giftsSent,
+			Credits:       credits,
+			Debits:        debits,
+		},
+		Expiring: expiring,
 	}
 	return resp, nil
 }
+
+func (s *infoService) SearchHistory(ctx context.Context, userID int64, query string, limit int) ([]TransactionEntry, error) {
+	const op = "service.InfoService.SearchHistory"
+
+	if limit <= 0 || limit > MaxHistoryEntries {
+		limit = MaxHistoryEntries
+	}
+	transactions, err := s.coinTxRepo.SearchTransactions(ctx, userID, strings.TrimSpace(query), limit)
+	if err != nil {
+		s.log.Error("failed to search coin transactions", slog.String("op", op), slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// email участников: один пользователь обычно встречается в истории много раз
+	emails := make(map[int64]string)
+	entries := make([]TransactionEntry, 0, len(transactions))
+	for _, tx := range transactions {
+		entry := TransactionEntry{
+			ID:        tx.ID,
+			Type:      tx.Type,
+			Amount:    tx.Amount,
+			Comment:   tx.Comment,
+			CreatedAt: tx.CreatedAt,
+		}
+		if tx.RelatedUserID != nil {
+			email, ok := emails[*tx.RelatedUserID]
+			if !ok {
+				if related, err := s.userRepo.GetUserByID(ctx, *tx.RelatedUserID); err == nil {
+					email = related.Email
+				}
+				emails[*tx.RelatedUserID] = email
+			}
+			if tx.Type == models.CoinTxTransferSent || tx.Type == models.CoinTxGiftSent {
+				entry.ToUser = email
+			} else {
+				entry.FromUser = email
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// expiring возвращает партии со сроком в пределах expiringWindow, от ближайшего срока.
+func (s *infoService) expiring(ctx context.Context, userID int64) ([]ExpiringCoins, error) {
+	lots, err := s.lotRepo.GetActiveLots(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	until := time.Now().Add(s.expiringWindow)
+	var expiring []ExpiringCoins
+	for _, lot := range lots {
+		if lot.ExpiresAt == nil || (s.expiringWindow > 0 && lot.ExpiresAt.After(until)) {
+			continue
+		}
+		expiring = append(expiring, ExpiringCoins{Amount: lot.Remaining, Source: lot.Source, ExpiresAt: *lot.ExpiresAt})
+	}
+	sort.Slice(expiring, func(i, j int) bool { return expiring[i].ExpiresAt.Before(expiring[j].ExpiresAt) })
+	return expiring, nil
+}
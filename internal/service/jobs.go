@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/storage"
+)
+
+// MaxJobRuns — ограничение на число запусков в одном ответе истории.
+const MaxJobRuns = 100
+
+// JobService — просмотр истории запусков фоновых задач.
+type JobService interface {
+	// Runs возвращает последние limit запусков задачи job (всех задач при пустом job).
+	Runs(ctx context.Context, job string, limit int) ([]models.JobRun, error)
+}
+
+type jobService struct {
+	log     *slog.Logger
+	runRepo storage.JobRunStorage
+}
+
+func NewJobService(log *slog.Logger, runRepo storage.JobRunStorage) JobService {
+	return &jobService{log: log, runRepo: runRepo}
+}
+
+func (s *jobService) Runs(ctx context.Context, job string, limit int) ([]models.JobRun, error) {
+	const op = "service.JobService.Runs"
+
+	if limit <= 0 || limit > MaxJobRuns {
+		limit = MaxJobRuns
+	}
+	runs, err := s.runRepo.ListJobRuns(ctx, job, limit)
+	if err != nil {
+		s.log.Error("failed to list job runs", slog.String("op", op), slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if runs == nil {
+		runs = []models.JobRun{}
+	}
+	return runs, nil
+}
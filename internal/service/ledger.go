@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/storage"
+)
+
+// ErrInsufficientFunds — на балансе недостаточно монет для списания.
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// CoinExpiration — срок жизни начисленных монет по типу операции начисления
+// (welcome_bonus, grant, transfer_received, allowance, adjustment). Монеты типа
+// без срока не сгорают; пустые правила — сгорание выключено.
+type CoinExpiration struct {
+	TTL map[string]time.Duration
+}
+
+// Enabled сообщает, сгорает ли хоть какой-то тип начислений.
+func (e CoinExpiration) Enabled() bool {
+	return len(e.TTL) > 0
+}
+
+// expiresAt возвращает срок партии, начисленной операцией txType в момент now, или nil.
+func (e CoinExpiration) expiresAt(txType string, now time.Time) *time.Time {
+	ttl, ok := e.TTL[txType]
+	if !ok || ttl <= 0 {
+		return nil
+	}
+	at := now.Add(ttl)
+	return &at
+}
+
+// Ledger меняет балансы пользователей и ведёт партии монет: каждое начисление создаёт
+// партию (со сроком по CoinExpiration), списание расходует партии от старых к новым.
+// Баланс, не покрытый партиями (начисленный до их появления или сидом), считается
+// самыми старыми монетами и не сгорает. После каждого начисления wishlist уведомляет
+// о товарах, на которые стало хватать монет. Методы вызываются внутри WithinTx.
+type Ledger struct {
+	userRepo   storage.UserStorage
+	coinTxRepo storage.CoinTransactionStorage
+	lotRepo    storage.CoinLotStorage
+	expiration CoinExpiration
+	wishlist   *WishlistNotifier
+}
+
+// NewLedger создаёт Ledger; один экземпляр разделяют все сервисы, которые меняют балансы.
+func NewLedger(userRepo storage.UserStorage, coinTxRepo storage.CoinTransactionStorage, lotRepo storage.CoinLotStorage, expiration CoinExpiration, wishlist *WishlistNotifier) *Ledger {
+	return &Ledger{
+		userRepo:   userRepo,
+		coinTxRepo: coinTxRepo,
+		lotRepo:    lotRepo,
+		expiration: expiration,
+		wishlist:   wishlist,
+	}
+}
+
+// apply прибавляет к балансу entry.Amount (отрицательная сумма — списание) и записывает entry.
+// Если у entry есть ключ идемпотентности и операция с ним уже записана, баланс не меняется
+// и возвращается storage.ErrDuplicateTransaction.
+func (l *Ledger) apply(ctx context.Context, entry *models.CoinTransaction) (*models.User, error) {
+	if entry.IdempotencyKey != nil {
+		// Проверяем заранее: в PostgreSQL нарушение уникальности оборвало бы всю транзакцию
+		exists, err := l.coinTxRepo.TransactionKeyExists(ctx, *entry.IdempotencyKey)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, storage.ErrDuplicateTransaction
+		}
+	}
+
+	user, err := l.userRepo.LockUserByID(ctx, entry.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock user: %w", err)
+	}
+	if err := l.changeBalance(ctx, user, entry.Amount, entry.Type); err != nil {
+		return nil, err
+	}
+	if err := l.coinTxRepo.InsertTransaction(ctx, entry); err != nil {
+		return nil, fmt.Errorf("failed to record transaction: %w", err)
+	}
+	return user, nil
+}
+
+// changeBalance меняет баланс пользователя, заблокированного LockUserByID, на amount
+// и обновляет user.CoinBalance. Начисление создаёт партию с источником source,
+// списание расходует партии.
+func (l *Ledger) changeBalance(ctx context.Context, user *models.User, amount int, source string) error {
+	newBalance := user.CoinBalance + amount
+	if newBalance < 0 {
+		return ErrInsufficientFunds
+	}
+
+	switch {
+	case amount > 0:
+		err := l.lotRepo.CreateLot(ctx, &models.CoinLot{
+			UserID:    user.ID,
+			Amount:    amount,
+			Remaining: amount,
+			Source:    source,
+			ExpiresAt: l.expiration.expiresAt(source, time.Now()),
+		})
+		if err != nil {
+			return err
+		}
+	case amount < 0:
+		if err := l.consume(ctx, user, -amount); err != nil {
+			return err
+		}
+	}
+
+	if err := l.userRepo.UpdateUserBalance(ctx, user.ID, newBalance); err != nil {
+		return fmt.Errorf("failed to update balance: %w", err)
+	}
+	oldBalance := user.CoinBalance
+	user.CoinBalance = newBalance
+	if amount > 0 {
+		if err := l.wishlist.BalanceIncreased(ctx, user, oldBalance); err != nil {
+			return fmt.Errorf("failed to check wishlist: %w", err)
+		}
+	}
+	return nil
+}
+
+// consume расходует amount монет: сначала баланс, не покрытый партиями, затем партии
+// от старых к новым (в том числе просроченные, которые ещё не сжёг ExpirationService).
+func (l *Ledger) consume(ctx context.Context, user *models.User, amount int) error {
+	lots, err := l.lotRepo.GetActiveLots(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	tracked := 0
+	for _, lot := range lots {
+		tracked += lot.Remaining
+	}
+	amount -= min(amount, max(user.CoinBalance-tracked, 0))
+
+	for _, lot := range lots {
+		if amount == 0 {
+			break
+		}
+		take := min(lot.Remaining, amount)
+		if err := l.lotRepo.UpdateLotRemaining(ctx, lot.ID, lot.Remaining-take); err != nil {
+			return err
+		}
+		amount -= take
+	}
+	if amount > 0 {
+		return fmt.Errorf("coin lots of user %d do not cover balance", user.ID)
+	}
+	return nil
+}
+
+// expireLot сжигает непотраченный остаток партии lotID, если её срок не позже now,
+// и записывает операцию expired. Возвращает сожжённую сумму.
+func (l *Ledger) expireLot(ctx context.Context, lotID int64, now time.Time) (int, error) {
+	lot, err := l.lotRepo.GetLotByID(ctx, lotID)
+	if err != nil {
+		return 0, err
+	}
+	user, err := l.userRepo.LockUserByID(ctx, lot.UserID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to lock user: %w", err)
+	}
+	// Перечитываем под блокировкой пользователя: партию могли потратить
+	lot, err = l.lotRepo.GetLotByID(ctx, lotID)
+	if err != nil {
+		return 0, err
+	}
+	if lot.Remaining == 0 || lot.ExpiresAt == nil || lot.ExpiresAt.After(now) {
+		return 0, nil
+	}
+
+	amount := min(lot.Remaining, user.CoinBalance)
+	if err := l.lotRepo.UpdateLotRemaining(ctx, lot.ID, 0); err != nil {
+		return 0, err
+	}
+	if err := l.userRepo.UpdateUserBalance(ctx, user.ID, user.CoinBalance-amount); err != nil {
+		return 0, fmt.Errorf("failed to update balance: %w", err)
+	}
+
+	key := "expire:" + strconv.FormatInt(lot.ID, 10)
+	err = l.coinTxRepo.InsertTransaction(ctx, &models.CoinTransaction{
+		UserID:         user.ID,
+		Amount:         -amount,
+		Type:           models.CoinTxExpired,
+		Comment:        "coins expired (" + lot.Source + ")",
+		IdempotencyKey: &key,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to record transaction: %w", err)
+	}
+	return amount, nil
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/storage"
+)
+
+// ErrTransferLimit — перевод нарушает правило лимитов (см. TransferLimits).
+var ErrTransferLimit = errors.New("transfer limit exceeded")
+
+// limitWindow — окно суточных лимитов (скользящее: последние 24 часа).
+const limitWindow = 24 * time.Hour
+
+// TransferLimits — правила против вывода монет и накрутки через новые аккаунты.
+// Нулевое значение поля выключает соответствующее правило.
+type TransferLimits struct {
+	// MaxAmount — максимальная сумма одного перевода
+	MaxAmount int
+	// DailyOutgoing — сколько монет пользователь может отправить за сутки
+	DailyOutgoing int
+	// DailyIncoming — сколько монет пользователь может получить переводами за сутки
+	DailyIncoming int
+	// MinAccountAge — сколько должно пройти с регистрации отправителя до первого перевода
+	MinAccountAge time.Duration
+	// VelocityCount переводов за VelocityWindow — максимум для одного отправителя
+	VelocityCount  int
+	VelocityWindow time.Duration
+}
+
+// transfer — перевод, который проверяют правила; отправитель и получатель заблокированы.
+type transfer struct {
+	sender, receiver *models.User
+	amount           int
+	now              time.Time
+}
+
+// transferRule возвращает ошибку с ErrTransferLimit, если перевод нарушает правило.
+type transferRule func(ctx context.Context, t *transfer) error
+
+// transferRules собирает включённые правила в порядке проверки: сначала те,
+// что не обращаются к хранилищу.
+func (l TransferLimits) transferRules(coinTxRepo storage.CoinTransactionStorage) []transferRule {
+	var rules []transferRule
+
+	if l.MaxAmount > 0 {
+		rules = append(rules, func(ctx context.Context, t *transfer) error {
+			if t.amount > l.MaxAmount {
+				return fmt.Errorf("%w: amount exceeds %d per transfer", ErrTransferLimit, l.MaxAmount)
+			}
+			return nil
+		})
+	}
+	if l.MinAccountAge > 0 {
+		rules = append(rules, func(ctx context.Context, t *transfer) error {
+			if t.sender.CreatedAt.Add(l.MinAccountAge).After(t.now) {
+				return fmt.Errorf("%w: account is younger than %s", ErrTransferLimit, l.MinAccountAge)
+			}
+			return nil
+		})
+	}
+	if l.VelocityCount > 0 && l.VelocityWindow > 0 {
+		rules = append(rules, func(ctx context.Context, t *transfer) error {
+			count, _, err := coinTxRepo.TransactionStats(ctx, t.sender.ID, models.CoinTxTransferSent, t.now.Add(-l.VelocityWindow))
+			if err != nil {
+				return err
+			}
+			if count >= l.VelocityCount {
+				return fmt.Errorf("%w: more than %d transfers in %s", ErrTransferLimit, l.VelocityCount, l.VelocityWindow)
+			}
+			return nil
+		})
+	}
+	if l.DailyOutgoing > 0 {
+		rules = append(rules, func(ctx context.Context, t *transfer) error {
+			_, sent, err := coinTxRepo.TransactionStats(ctx, t.sender.ID, models.CoinTxTransferSent, t.now.Add(-limitWindow))
+			if err != nil {
+				return err
+			}
+			if sent+t.amount > l.DailyOutgoing {
+				return fmt.Errorf("%w: daily outgoing limit %d, already sent %d", ErrTransferLimit, l.DailyOutgoing, sent)
+			}
+			return nil
+		})
+	}
+	if l.DailyIncoming > 0 {
+		rules = append(rules, func(ctx context.Context, t *transfer) error {
+			_, received, err := coinTxRepo.TransactionStats(ctx, t.receiver.ID, models.CoinTxTransferReceived, t.now.Add(-limitWindow))
+			if err != nil {
+				return err
+			}
+			if received+t.amount > l.DailyIncoming {
+				return fmt.Errorf("%w: receiver daily incoming limit %d reached", ErrTransferLimit, l.DailyIncoming)
+			}
+			return nil
+		})
+	}
+	return rules
+}
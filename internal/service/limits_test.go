@@ -0,0 +1,68 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendCoinService_Limits(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits service.TransferLimits
+		// суммы переводов alice -> bob; последний должен нарушить лимит
+		amounts []int
+	}{
+		{name: "max amount", limits: service.TransferLimits{MaxAmount: 100}, amounts: []int{100, 101}},
+		{name: "daily outgoing", limits: service.TransferLimits{DailyOutgoing: 150}, amounts: []int{100, 50, 1}},
+		{name: "daily incoming", limits: service.TransferLimits{DailyIncoming: 120}, amounts: []int{100, 30}},
+		{name: "velocity", limits: service.TransferLimits{VelocityCount: 2, VelocityWindow: time.Minute}, amounts: []int{1, 1, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := newTestStorage()
+			alice := st.createUser(t, "alice@example.com", 1000)
+			bob := st.createUser(t, "bob@example.com", 0)
+			svc := service.NewSendCoinService(newTestLogger(), st.txManager, st.userRepo, st.coinTxRepo, st.ledger, st.notifier, tt.limits)
+			ctx := context.Background()
+
+			sent := 0
+			last := len(tt.amounts) - 1
+			for _, amount := range tt.amounts[:last] {
+				require.NoError(t, svc.SendCoin(ctx, alice.ID, bob.Email, amount, ""))
+				sent += amount
+			}
+			err := svc.SendCoin(ctx, alice.ID, bob.Email, tt.amounts[last], "")
+			assert.ErrorIs(t, err, service.ErrTransferLimit)
+			assert.Equal(t, 1000-sent, st.balance(t, alice.ID))
+			assert.Equal(t, sent, st.balance(t, bob.ID))
+		})
+	}
+}
+
+func TestSendCoinService_MinAccountAge(t *testing.T) {
+	st := newTestStorage()
+	ctx := context.Background()
+	fresh := st.createUser(t, "fresh@example.com", 1000)
+	veteran, err := st.userRepo.CreateUser(ctx, &models.User{
+		Email:       "veteran@example.com",
+		PassHash:    []byte("hashed"),
+		CoinBalance: 1000,
+		CreatedAt:   time.Now().Add(-48 * time.Hour),
+	})
+	require.NoError(t, err)
+
+	svc := service.NewSendCoinService(newTestLogger(), st.txManager, st.userRepo, st.coinTxRepo, st.ledger, st.notifier,
+		service.TransferLimits{MinAccountAge: 24 * time.Hour})
+
+	// Новый аккаунт не может отправлять, но может получать
+	err = svc.SendCoin(ctx, fresh.ID, veteran.Email, 10, "")
+	assert.ErrorIs(t, err, service.ErrTransferLimit)
+	require.NoError(t, svc.SendCoin(ctx, veteran.ID, fresh.Email, 10, ""))
+	assert.Equal(t, 1010, st.balance(t, fresh.ID))
+}
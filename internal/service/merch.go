@@ -0,0 +1,427 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/storage"
+	"github.com/linemk/avito-shop/internal/storage/blob"
+)
+
+var (
+	// ErrInvalidStock — некорректное количество товара для пополнения или установки остатка.
+	ErrInvalidStock = errors.New("invalid stock")
+	// ErrInvalidPurchaseLimit — некорректный лимит покупок или неизвестный период.
+	ErrInvalidPurchaseLimit = errors.New("invalid purchase limit")
+	// ErrInvalidPrice — цена товара должна быть положительной.
+	ErrInvalidPrice = errors.New("invalid price")
+	// ErrPriceChangeApplied — изменение цены уже вступило в силу, отменить можно только запланированное.
+	ErrPriceChangeApplied = errors.New("price change already applied")
+)
+
+// CatalogItem — товар каталога с остатком на складе.
+type CatalogItem struct {
+	Name  string `json:"name"`
+	Price int    `json:"price"`
+	// Stock — остаток; отсутствует, если количество не ограничено
+	Stock   *int `json:"stock,omitempty"`
+	SoldOut bool `json:"soldOut"`
+	// PurchaseLimit — сколько штук один сотрудник может получить; отсутствует, если без ограничения
+	PurchaseLimit *PurchaseLimit `json:"purchaseLimit,omitempty"`
+	// Variants — размеры и цвета товара; товар с вариантами покупается с указанием артикула
+	Variants    []CatalogVariant `json:"variants,omitempty"`
+	Category    string           `json:"category,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Tags        []string         `json:"tags,omitempty"`
+	// Image — адрес изображения товара; отсутствует, если изображения нет
+	Image string `json:"image,omitempty"`
+}
+
+// PurchaseLimit — не больше Limit штук товара на сотрудника за календарный Period
+// (month, quarter, year; пусто — за всё время).
+type PurchaseLimit struct {
+	Limit  int    `json:"limit"`
+	Period string `json:"period,omitempty"`
+}
+
+// PriceEntry — запись истории цен товара.
+type PriceEntry struct {
+	ID            int64     `json:"id"`
+	Price         int       `json:"price"`
+	EffectiveFrom time.Time `json:"effectiveFrom"`
+	ChangedBy     string    `json:"changedBy,omitempty"`
+	Comment       string    `json:"comment,omitempty"`
+}
+
+// PriceHistory — действующая цена товара, история её изменений (от старых к новым)
+// и запланированные изменения (от ближайших к дальним).
+type PriceHistory struct {
+	Item      string       `json:"item"`
+	Price     int          `json:"price"`
+	History   []PriceEntry `json:"history"`
+	Scheduled []PriceEntry `json:"scheduled"`
+}
+
+// MerchService — каталог мерча и управление остатками.
+type MerchService interface {
+	// Catalog возвращает активные товары с ценой и остатком, подходящие под filter.
+	Catalog(ctx context.Context, filter CatalogFilter) ([]CatalogItem, error)
+	// Categories возвращает категории каталога с числом товаров по названию.
+	Categories(ctx context.Context) ([]CatalogCategory, error)
+	// SetDetails задаёт категорию, описание и теги товара.
+	SetDetails(ctx context.Context, item string, details MerchDetails) (*CatalogItem, error)
+	// SetImage загружает изображение товара (PNG, JPEG, GIF или WebP), заменяя прежнее.
+	SetImage(ctx context.Context, item string, r io.Reader) (*CatalogItem, error)
+	// DeleteImage удаляет изображение товара.
+	DeleteImage(ctx context.Context, item string) (*CatalogItem, error)
+	// Image открывает изображение товара и возвращает его MIME-тип; вызывающий закрывает изображение.
+	Image(ctx context.Context, item string) (io.ReadCloser, string, error)
+	// Restock добавляет quantity единиц товара на склад и возвращает новый остаток.
+	// Товар без ограничения количества после пополнения становится ограниченным.
+	Restock(ctx context.Context, item string, quantity int) (*CatalogItem, error)
+	// SetStock задаёт остаток товара; nil снимает ограничение количества.
+	SetStock(ctx context.Context, item string, stock *int) (*CatalogItem, error)
+	// SetPurchaseLimit задаёт лимит покупок товара одним сотрудником; nil снимает ограничение.
+	SetPurchaseLimit(ctx context.Context, item string, limit *PurchaseLimit) (*CatalogItem, error)
+	// SetPrice меняет цену товара от имени администратора adminID: сразу, если effectiveFrom
+	// нулевое или уже наступило, иначе планирует изменение на effectiveFrom.
+	SetPrice(ctx context.Context, adminID int64, item string, price int, effectiveFrom time.Time, comment string) (*PriceEntry, error)
+	// PriceHistory возвращает действующую цену товара, историю цен и запланированные изменения.
+	PriceHistory(ctx context.Context, item string) (*PriceHistory, error)
+	// CancelPriceChange отменяет запланированное изменение цены id.
+	CancelPriceChange(ctx context.Context, item string, id int64) error
+	// CreateVariant добавляет товару вариант (размер, цвет) со своим артикулом.
+	CreateVariant(ctx context.Context, item string, input VariantInput) (*CatalogVariant, error)
+	// UpdateVariant задаёт собственные цену и остаток варианта; nil — действуют цена и остаток товара.
+	UpdateVariant(ctx context.Context, item, sku string, price, stock *int) (*CatalogVariant, error)
+	// DeleteVariant снимает вариант с продажи.
+	DeleteVariant(ctx context.Context, item, sku string) error
+}
+
+type merchService struct {
+	log          *slog.Logger
+	txManager    storage.TxManager
+	userRepo     storage.UserStorage
+	merchRepo    storage.MerchStorage
+	images       blob.Store
+	maxImageSize int64
+	wishlist     *WishlistNotifier
+}
+
+// NewMerchService создаёт сервис каталога; изображения товаров хранятся в images
+// и не могут быть больше maxImageSize байт. О поступлении распроданных товаров
+// уведомляет wishlist.
+func NewMerchService(log *slog.Logger, txManager storage.TxManager, userRepo storage.UserStorage, merchRepo storage.MerchStorage, images blob.Store, maxImageSize int64, wishlist *WishlistNotifier) MerchService {
+	return &merchService{
+		log:          log,
+		txManager:    txManager,
+		userRepo:     userRepo,
+		merchRepo:    merchRepo,
+		images:       images,
+		maxImageSize: maxImageSize,
+		wishlist:     wishlist,
+	}
+}
+
+func (s *merchService) Catalog(ctx context.Context, filter CatalogFilter) ([]CatalogItem, error) {
+	const op = "service.MerchService.Catalog"
+
+	filter, err := normalizeCatalogFilter(filter)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	merch, err := s.merchRepo.ListMerch(ctx)
+	if err != nil {
+		s.log.Error("failed to list merch", slog.String("op", op), slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	items := make([]CatalogItem, 0, len(merch))
+	for _, m := range merch {
+		if !filter.matches(&m) {
+			continue
+		}
+		item, err := catalogItemWithVariants(ctx, s.merchRepo, &m)
+		if err != nil {
+			s.log.Error("failed to list merch variants", slog.String("op", op), slog.Any("error", err))
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if filter.matchesPrice(item) {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+func (s *merchService) Restock(ctx context.Context, item string, quantity int) (*CatalogItem, error) {
+	const op = "service.MerchService.Restock"
+	if quantity <= 0 {
+		return nil, fmt.Errorf("%s: %w: quantity must be positive", op, ErrInvalidStock)
+	}
+	return s.updateStock(ctx, op, item, func(current *int) *int {
+		stock := quantity
+		if current != nil {
+			stock += *current
+		}
+		return &stock
+	})
+}
+
+func (s *merchService) SetPurchaseLimit(ctx context.Context, item string, limit *PurchaseLimit) (*CatalogItem, error) {
+	const op = "service.MerchService.SetPurchaseLimit"
+	logger := s.log.With(slog.String("op", op), slog.String("item", item))
+
+	var value PurchaseLimit
+	if limit != nil {
+		value = *limit
+		if value.Limit <= 0 {
+			return nil, fmt.Errorf("%s: %w: limit must be positive", op, ErrInvalidPurchaseLimit)
+		}
+		if _, err := purchaseLimitSince(value.Period, time.Now()); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	var res CatalogItem
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		merch, err := s.merchRepo.LockMerchByName(ctx, item)
+		if err != nil {
+			return err
+		}
+		if err := s.merchRepo.UpdatePurchaseLimit(ctx, merch.ID, value.Limit, value.Period); err != nil {
+			return err
+		}
+		merch.PurchaseLimit, merch.PurchaseLimitPeriod = value.Limit, value.Period
+		res = newCatalogItem(merch)
+		return nil
+	})
+	if err != nil {
+		logger.Error("failed to update purchase limit", slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	logger.Info("purchase limit updated", slog.Int("limit", value.Limit), slog.String("period", value.Period))
+	return &res, nil
+}
+
+func (s *merchService) SetStock(ctx context.Context, item string, stock *int) (*CatalogItem, error) {
+	const op = "service.MerchService.SetStock"
+	if stock != nil && *stock < 0 {
+		return nil, fmt.Errorf("%s: %w: stock must not be negative", op, ErrInvalidStock)
+	}
+	return s.updateStock(ctx, op, item, func(*int) *int { return stock })
+}
+
+func (s *merchService) SetPrice(ctx context.Context, adminID int64, item string, price int, effectiveFrom time.Time, comment string) (*PriceEntry, error) {
+	const op = "service.MerchService.SetPrice"
+	logger := s.log.With(slog.String("op", op), slog.Int64("adminID", adminID), slog.String("item", item))
+
+	if price <= 0 {
+		return nil, fmt.Errorf("%s: %w: price must be positive", op, ErrInvalidPrice)
+	}
+	comment, err := sanitizeComment(comment)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	// Немедленное изменение получает время из часов базы: нулевой EffectiveFrom заполняет хранилище
+	immediate := !effectiveFrom.After(time.Now())
+	if immediate {
+		effectiveFrom = time.Time{}
+	}
+
+	change := &models.MerchPriceChange{Price: price, EffectiveFrom: effectiveFrom, ChangedBy: &adminID, Comment: comment}
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		merch, err := s.merchRepo.LockMerchByName(ctx, item)
+		if err != nil {
+			return err
+		}
+		change.MerchID = merch.ID
+		if immediate {
+			if err := s.merchRepo.UpdateMerchPrice(ctx, merch.ID, price); err != nil {
+				return err
+			}
+		}
+		return s.merchRepo.AddPriceChange(ctx, change)
+	})
+	if err != nil {
+		logger.Error("failed to change merch price", slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	logger.Info("merch price changed", slog.Int("price", price), slog.Time("effectiveFrom", change.EffectiveFrom))
+	entry := s.newPriceEntry(ctx, *change)
+	return &entry, nil
+}
+
+func (s *merchService) PriceHistory(ctx context.Context, item string) (*PriceHistory, error) {
+	const op = "service.MerchService.PriceHistory"
+
+	merch, err := s.merchRepo.GetMerchByName(ctx, item)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	changes, err := s.merchRepo.GetPriceHistory(ctx, merch.ID)
+	if err != nil {
+		s.log.Error("failed to get price history", slog.String("op", op), slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res := &PriceHistory{Item: merch.Name, Price: merch.Price, History: []PriceEntry{}, Scheduled: []PriceEntry{}}
+	now := time.Now()
+	for _, c := range changes {
+		if c.EffectiveFrom.After(now) {
+			res.Scheduled = append(res.Scheduled, s.newPriceEntry(ctx, c))
+		} else {
+			res.History = append(res.History, s.newPriceEntry(ctx, c))
+		}
+	}
+	return res, nil
+}
+
+func (s *merchService) CancelPriceChange(ctx context.Context, item string, id int64) error {
+	const op = "service.MerchService.CancelPriceChange"
+	logger := s.log.With(slog.String("op", op), slog.String("item", item), slog.Int64("priceChangeID", id))
+
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		merch, err := s.merchRepo.LockMerchByName(ctx, item)
+		if err != nil {
+			return err
+		}
+		changes, err := s.merchRepo.GetPriceHistory(ctx, merch.ID)
+		if err != nil {
+			return err
+		}
+		for _, c := range changes {
+			if c.ID != id {
+				continue
+			}
+			if !c.EffectiveFrom.After(time.Now()) {
+				return ErrPriceChangeApplied
+			}
+			return s.merchRepo.DeletePriceChange(ctx, id)
+		}
+		return storage.ErrPriceChangeNotFound
+	})
+	if err != nil {
+		logger.Error("failed to cancel price change", slog.Any("error", err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	logger.Info("scheduled price change cancelled")
+	return nil
+}
+
+func (s *merchService) newPriceEntry(ctx context.Context, c models.MerchPriceChange) PriceEntry {
+	entry := PriceEntry{ID: c.ID, Price: c.Price, EffectiveFrom: c.EffectiveFrom, Comment: c.Comment}
+	if c.ChangedBy != nil {
+		if u, err := s.userRepo.GetUserByID(ctx, *c.ChangedBy); err == nil {
+			entry.ChangedBy = u.Email
+		}
+	}
+	return entry
+}
+
+// updateStock меняет остаток товара под блокировкой его строки, чтобы не потерять
+// параллельные покупки. Если распроданный товар снова в продаже, уведомляет тех,
+// у кого он в списке желаний.
+func (s *merchService) updateStock(ctx context.Context, op, item string, next func(current *int) *int) (*CatalogItem, error) {
+	logger := s.log.With(slog.String("op", op), slog.String("item", item))
+
+	var res CatalogItem
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		merch, err := s.merchRepo.LockMerchByName(ctx, item)
+		if err != nil {
+			return err
+		}
+		wasSoldOut, err := s.soldOut(ctx, merch)
+		if err != nil {
+			return err
+		}
+		merch.Stock = next(merch.Stock)
+		if err := s.merchRepo.UpdateMerchStock(ctx, merch.ID, merch.Stock); err != nil {
+			return err
+		}
+		res = newCatalogItem(merch)
+		return s.notifyBackInStock(ctx, merch, wasSoldOut)
+	})
+	if err != nil {
+		logger.Error("failed to update merch stock", slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	logger.Info("merch stock updated", slog.Any("stock", res.Stock))
+	return &res, nil
+}
+
+// soldOut сообщает, распродан ли товар с учётом его вариантов.
+func (s *merchService) soldOut(ctx context.Context, merch *models.Merch) (bool, error) {
+	item, err := catalogItemWithVariants(ctx, s.merchRepo, merch)
+	if err != nil {
+		return false, err
+	}
+	return item.SoldOut, nil
+}
+
+// notifyBackInStock уведомляет тех, у кого товар в списке желаний, если он был распродан
+// (wasSoldOut) и после изменения остатков снова в продаже.
+func (s *merchService) notifyBackInStock(ctx context.Context, merch *models.Merch, wasSoldOut bool) error {
+	if !wasSoldOut {
+		return nil
+	}
+	soldOut, err := s.soldOut(ctx, merch)
+	if err != nil || soldOut {
+		return err
+	}
+	return s.wishlist.BackInStock(ctx, merch)
+}
+
+func newCatalogItem(m *models.Merch) CatalogItem {
+	item := CatalogItem{
+		Name:        m.Name,
+		Price:       m.Price,
+		Stock:       m.Stock,
+		SoldOut:     m.Stock != nil && *m.Stock <= 0,
+		Category:    m.Category,
+		Description: m.Description,
+		Tags:        m.Tags,
+	}
+	if m.ImageKey != "" {
+		item.Image = imageURL(m.Name)
+	}
+	if m.PurchaseLimit > 0 {
+		item.PurchaseLimit = &PurchaseLimit{Limit: m.PurchaseLimit, Period: m.PurchaseLimitPeriod}
+	}
+	return item
+}
+
+// purchaseLimitSince возвращает начало текущего календарного периода лимита (в UTC);
+// для лимита за всё время — нулевое время.
+func purchaseLimitSince(period string, now time.Time) (time.Time, error) {
+	now = now.UTC()
+	switch period {
+	case models.PurchaseLimitForever:
+		return time.Time{}, nil
+	case models.PurchaseLimitMonth:
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC), nil
+	case models.PurchaseLimitQuarter:
+		month := (now.Month()-1)/3*3 + 1
+		return time.Date(now.Year(), month, 1, 0, 0, 0, 0, time.UTC), nil
+	case models.PurchaseLimitYear:
+		return time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, time.UTC), nil
+	default:
+		return time.Time{}, fmt.Errorf("%w: unknown period %q", ErrInvalidPurchaseLimit, period)
+	}
+}
+
+// describePurchaseLimit формирует текст лимита для ошибки, например "1 per quarter".
+func describePurchaseLimit(limit int, period string) string {
+	if period == models.PurchaseLimitForever {
+		return fmt.Sprintf("%d per person", limit)
+	}
+	return fmt.Sprintf("%d per %s", limit, period)
+}
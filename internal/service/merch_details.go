@@ -0,0 +1,351 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/storage/blob"
+)
+
+// Ограничения карточки товара
+const (
+	maxCategoryLength    = 64
+	maxDescriptionLength = 2000
+	maxTags              = 20
+	maxTagLength         = 32
+)
+
+var (
+	// ErrInvalidCatalogFilter — некорректный диапазон цен в отборе каталога.
+	ErrInvalidCatalogFilter = errors.New("invalid catalog filter")
+	// ErrInvalidMerchDetails — слишком длинные категория, описание или теги товара.
+	ErrInvalidMerchDetails = errors.New("invalid merch details")
+	// ErrInvalidImage — файл не является изображением PNG, JPEG, GIF или WebP.
+	ErrInvalidImage = errors.New("unsupported image format")
+	// ErrImageTooLarge — изображение больше допустимого размера.
+	ErrImageTooLarge = errors.New("image too large")
+	// ErrImageNotFound — у товара нет изображения.
+	ErrImageNotFound = errors.New("merch image not found")
+)
+
+// imageExtensions — допустимые типы изображений (по содержимому файла) и расширения их ключей.
+var imageExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// CatalogFilter — отбор товаров каталога; пустые поля не ограничивают. Query ищет подстроку
+// в названии, категории, описании и тегах без учёта регистра. Товар с вариантами подходит
+// под диапазон цен, если подходит хотя бы один вариант.
+type CatalogFilter struct {
+	Category string
+	Tag      string
+	Query    string
+	MinPrice *int
+	MaxPrice *int
+}
+
+// MerchDetails — карточка товара: категория, описание и теги.
+type MerchDetails struct {
+	Category    string
+	Description string
+	Tags        []string
+}
+
+// CatalogCategory — категория каталога и число активных товаров в ней.
+type CatalogCategory struct {
+	Name  string `json:"name"`
+	Items int    `json:"items"`
+}
+
+func (s *merchService) Categories(ctx context.Context) ([]CatalogCategory, error) {
+	const op = "service.MerchService.Categories"
+
+	merch, err := s.merchRepo.ListMerch(ctx)
+	if err != nil {
+		s.log.Error("failed to list merch", slog.String("op", op), slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	counts := make(map[string]int)
+	for _, m := range merch {
+		if m.Category != "" {
+			counts[m.Category]++
+		}
+	}
+	categories := make([]CatalogCategory, 0, len(counts))
+	for name, n := range counts {
+		categories = append(categories, CatalogCategory{Name: name, Items: n})
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i].Name < categories[j].Name })
+	return categories, nil
+}
+
+func (s *merchService) SetDetails(ctx context.Context, item string, details MerchDetails) (*CatalogItem, error) {
+	const op = "service.MerchService.SetDetails"
+	logger := s.log.With(slog.String("op", op), slog.String("item", item))
+
+	details, err := normalizeMerchDetails(details)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var res CatalogItem
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		merch, err := s.merchRepo.LockMerchByName(ctx, item)
+		if err != nil {
+			return err
+		}
+		if err := s.merchRepo.UpdateMerchDetails(ctx, merch.ID, details.Category, details.Description, details.Tags); err != nil {
+			return err
+		}
+		merch.Category, merch.Description, merch.Tags = details.Category, details.Description, details.Tags
+		res = newCatalogItem(merch)
+		return nil
+	})
+	if err != nil {
+		logger.Error("failed to update merch details", slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	logger.Info("merch details updated", slog.String("category", details.Category))
+	return &res, nil
+}
+
+// SetImage сохраняет изображение под новым ключом и переключает на него товар; прежнее
+// изображение удаляется после фиксации транзакции, чтобы при откате товар не остался без файла.
+func (s *merchService) SetImage(ctx context.Context, item string, r io.Reader) (*CatalogItem, error) {
+	const op = "service.MerchService.SetImage"
+	logger := s.log.With(slog.String("op", op), slog.String("item", item))
+
+	data, err := io.ReadAll(io.LimitReader(r, s.maxImageSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to read image: %w", op, err)
+	}
+	if int64(len(data)) > s.maxImageSize {
+		return nil, fmt.Errorf("%s: %w: limit is %d bytes", op, ErrImageTooLarge, s.maxImageSize)
+	}
+	ext, ok := imageExtensions[http.DetectContentType(data)]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidImage)
+	}
+
+	merch, err := s.merchRepo.GetMerchByName(ctx, item)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	key, err := newImageKey(merch.ID, ext)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := s.images.Put(ctx, key, bytes.NewReader(data)); err != nil {
+		logger.Error("failed to store merch image", slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var res CatalogItem
+	var oldKey string
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		merch, err := s.merchRepo.LockMerchByName(ctx, item)
+		if err != nil {
+			return err
+		}
+		oldKey = merch.ImageKey
+		if err := s.merchRepo.UpdateMerchImage(ctx, merch.ID, key); err != nil {
+			return err
+		}
+		merch.ImageKey = key
+		res = newCatalogItem(merch)
+		return nil
+	})
+	if err != nil {
+		logger.Error("failed to update merch image", slog.Any("error", err))
+		s.deleteImage(ctx, logger, key)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	s.deleteImage(ctx, logger, oldKey)
+
+	logger.Info("merch image updated", slog.String("key", key), slog.Int("size", len(data)))
+	return &res, nil
+}
+
+func (s *merchService) DeleteImage(ctx context.Context, item string) (*CatalogItem, error) {
+	const op = "service.MerchService.DeleteImage"
+	logger := s.log.With(slog.String("op", op), slog.String("item", item))
+
+	var res CatalogItem
+	var oldKey string
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		merch, err := s.merchRepo.LockMerchByName(ctx, item)
+		if err != nil {
+			return err
+		}
+		if merch.ImageKey == "" {
+			return ErrImageNotFound
+		}
+		oldKey = merch.ImageKey
+		if err := s.merchRepo.UpdateMerchImage(ctx, merch.ID, ""); err != nil {
+			return err
+		}
+		merch.ImageKey = ""
+		res = newCatalogItem(merch)
+		return nil
+	})
+	if err != nil {
+		logger.Error("failed to delete merch image", slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	s.deleteImage(ctx, logger, oldKey)
+
+	logger.Info("merch image deleted")
+	return &res, nil
+}
+
+func (s *merchService) Image(ctx context.Context, item string) (io.ReadCloser, string, error) {
+	const op = "service.MerchService.Image"
+
+	merch, err := s.merchRepo.GetMerchByName(ctx, item)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+	if merch.ImageKey == "" {
+		return nil, "", fmt.Errorf("%s: %w", op, ErrImageNotFound)
+	}
+	rc, err := s.images.Open(ctx, merch.ImageKey)
+	if err != nil {
+		if errors.Is(err, blob.ErrNotFound) {
+			s.log.Warn("merch image is missing in blob store", slog.String("op", op), slog.String("key", merch.ImageKey))
+			return nil, "", fmt.Errorf("%s: %w", op, ErrImageNotFound)
+		}
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+	return rc, imageContentType(merch.ImageKey), nil
+}
+
+// deleteImage удаляет изображение key, если оно задано. Ошибка только логируется:
+// осиротевший файл не мешает работе каталога.
+func (s *merchService) deleteImage(ctx context.Context, logger *slog.Logger, key string) {
+	if key == "" {
+		return
+	}
+	if err := s.images.Delete(ctx, key); err != nil {
+		logger.Warn("failed to delete merch image", slog.String("key", key), slog.Any("error", err))
+	}
+}
+
+// matches сообщает, подходит ли товар под отбор по категории, тегу и строке поиска.
+func (f CatalogFilter) matches(m *models.Merch) bool {
+	if f.Category != "" && m.Category != f.Category {
+		return false
+	}
+	if f.Tag != "" && !slices.Contains(m.Tags, f.Tag) {
+		return false
+	}
+	if f.Query == "" {
+		return true
+	}
+	text := strings.ToLower(strings.Join(append([]string{m.Name, m.Category, m.Description}, m.Tags...), "\n"))
+	return strings.Contains(text, f.Query)
+}
+
+// matchesPrice сообщает, попадает ли в диапазон цен товар или, если у него есть варианты, хотя бы один вариант.
+func (f CatalogFilter) matchesPrice(item CatalogItem) bool {
+	inRange := func(price int) bool {
+		return (f.MinPrice == nil || price >= *f.MinPrice) && (f.MaxPrice == nil || price <= *f.MaxPrice)
+	}
+	if len(item.Variants) == 0 {
+		return inRange(item.Price)
+	}
+	for _, v := range item.Variants {
+		if inRange(v.Price) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeCatalogFilter приводит категорию, тег и строку поиска к нижнему регистру и проверяет диапазон цен.
+func normalizeCatalogFilter(f CatalogFilter) (CatalogFilter, error) {
+	f.Category = strings.ToLower(strings.TrimSpace(f.Category))
+	f.Tag = strings.ToLower(strings.TrimSpace(f.Tag))
+	f.Query = strings.ToLower(strings.TrimSpace(f.Query))
+	if (f.MinPrice != nil && *f.MinPrice < 0) || (f.MaxPrice != nil && *f.MaxPrice < 0) {
+		return f, fmt.Errorf("%w: price must not be negative", ErrInvalidCatalogFilter)
+	}
+	if f.MinPrice != nil && f.MaxPrice != nil && *f.MinPrice > *f.MaxPrice {
+		return f, fmt.Errorf("%w: minPrice is greater than maxPrice", ErrInvalidCatalogFilter)
+	}
+	return f, nil
+}
+
+// normalizeMerchDetails приводит категорию и теги к нижнему регистру, убирает пустые
+// и повторяющиеся теги и проверяет длины.
+func normalizeMerchDetails(d MerchDetails) (MerchDetails, error) {
+	d.Category = strings.ToLower(strings.TrimSpace(d.Category))
+	d.Description = strings.TrimSpace(d.Description)
+	if utf8.RuneCountInString(d.Category) > maxCategoryLength {
+		return d, fmt.Errorf("%w: category is longer than %d characters", ErrInvalidMerchDetails, maxCategoryLength)
+	}
+	if utf8.RuneCountInString(d.Description) > maxDescriptionLength {
+		return d, fmt.Errorf("%w: description is longer than %d characters", ErrInvalidMerchDetails, maxDescriptionLength)
+	}
+
+	tags := make([]string, 0, len(d.Tags))
+	for _, tag := range d.Tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || slices.Contains(tags, tag) {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return d, fmt.Errorf("%w: tag %q is longer than %d characters", ErrInvalidMerchDetails, tag, maxTagLength)
+		}
+		tags = append(tags, tag)
+	}
+	if len(tags) > maxTags {
+		return d, fmt.Errorf("%w: more than %d tags", ErrInvalidMerchDetails, maxTags)
+	}
+	d.Tags = tags
+	return d, nil
+}
+
+// newImageKey возвращает новый ключ изображения товара, например "merch/12/3f9a0c1d2e4b5a69.png".
+// Ключ меняется при каждой загрузке, поэтому закэшированное клиентом старое изображение не подменяется.
+func newImageKey(merchID int64, ext string) (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate image key: %w", err)
+	}
+	return fmt.Sprintf("merch/%d/%s%s", merchID, hex.EncodeToString(b), ext), nil
+}
+
+// imageContentType определяет тип изображения по расширению ключа.
+func imageContentType(key string) string {
+	ext := path.Ext(key)
+	for contentType, e := range imageExtensions {
+		if e == ext {
+			return contentType
+		}
+	}
+	return "application/octet-stream"
+}
+
+// imageURL — адрес, по которому клиент загружает изображение товара.
+func imageURL(name string) string {
+	return "/api/merch/" + url.PathEscape(name) + "/image"
+}
@@ -0,0 +1,135 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/service"
+	"github.com/linemk/avito-shop/internal/storage/blob"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pngHeader — начало PNG-файла, по которому определяется тип изображения.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func catalogNames(items []service.CatalogItem) []string {
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		names = append(names, item.Name)
+	}
+	return names
+}
+
+func TestMerchService_CatalogFilter(t *testing.T) {
+	st := newTestStorage()
+	ctx := context.Background()
+	st.db.AddCatalogItem(models.Merch{Name: "hoody", Price: 300, Category: "clothing", Tags: []string{"warm"}})
+	st.db.AddCatalogItem(models.Merch{Name: "socks", Price: 10, Category: "clothing"})
+	st.db.AddCatalogItem(models.Merch{Name: "cup", Price: 20, Category: "accessories", Description: "Ceramic mug"})
+	st.db.AddMerch("pen", 10)
+
+	merchSvc := service.NewMerchService(newTestLogger(), st.txManager, st.userRepo, st.merchRepo, blob.NewMemoryStore(), 1024, st.wishlist)
+	_, err := merchSvc.CreateVariant(ctx, "hoody", service.VariantInput{SKU: "hoody-xl", Size: "XL", Price: intPtr(350)})
+	require.NoError(t, err)
+
+	cases := map[string]struct {
+		filter service.CatalogFilter
+		want   []string
+	}{
+		"all":           {service.CatalogFilter{}, []string{"cup", "hoody", "pen", "socks"}},
+		"category":      {service.CatalogFilter{Category: " Clothing "}, []string{"hoody", "socks"}},
+		"tag":           {service.CatalogFilter{Tag: "WARM"}, []string{"hoody"}},
+		"description":   {service.CatalogFilter{Query: "mug"}, []string{"cup"}},
+		"price range":   {service.CatalogFilter{MinPrice: intPtr(15), MaxPrice: intPtr(100)}, []string{"cup"}},
+		"variant price": {service.CatalogFilter{MinPrice: intPtr(320)}, []string{"hoody"}},
+		"combined":      {service.CatalogFilter{Category: "clothing", MaxPrice: intPtr(10)}, []string{"socks"}},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			items, err := merchSvc.Catalog(ctx, tc.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, catalogNames(items))
+		})
+	}
+
+	_, err = merchSvc.Catalog(ctx, service.CatalogFilter{MinPrice: intPtr(100), MaxPrice: intPtr(10)})
+	assert.ErrorIs(t, err, service.ErrInvalidCatalogFilter)
+
+	categories, err := merchSvc.Categories(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []service.CatalogCategory{{Name: "accessories", Items: 1}, {Name: "clothing", Items: 2}}, categories)
+}
+
+func TestMerchService_SetDetails(t *testing.T) {
+	st := newTestStorage()
+	ctx := context.Background()
+	st.db.AddMerch("cup", 20)
+	merchSvc := service.NewMerchService(newTestLogger(), st.txManager, st.userRepo, st.merchRepo, blob.NewMemoryStore(), 1024, st.wishlist)
+
+	// Категория и теги хранятся в нижнем регистре, пустые и повторяющиеся теги отбрасываются
+	item, err := merchSvc.SetDetails(ctx, "cup", service.MerchDetails{
+		Category:    " Accessories",
+		Description: "Ceramic mug ",
+		Tags:        []string{"Kitchen", "", "kitchen", "gift"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "accessories", item.Category)
+	assert.Equal(t, "Ceramic mug", item.Description)
+	assert.Equal(t, []string{"kitchen", "gift"}, item.Tags)
+
+	items, err := merchSvc.Catalog(ctx, service.CatalogFilter{Tag: "gift"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cup"}, catalogNames(items))
+
+	_, err = merchSvc.SetDetails(ctx, "cup", service.MerchDetails{Tags: []string{string(bytes.Repeat([]byte("x"), 33))}})
+	assert.ErrorIs(t, err, service.ErrInvalidMerchDetails)
+}
+
+func TestMerchService_Image(t *testing.T) {
+	st := newTestStorage()
+	ctx := context.Background()
+	st.db.AddMerch("cup", 20)
+	images := blob.NewMemoryStore()
+	merchSvc := service.NewMerchService(newTestLogger(), st.txManager, st.userRepo, st.merchRepo, images, 1024, st.wishlist)
+
+	_, _, err := merchSvc.Image(ctx, "cup")
+	assert.ErrorIs(t, err, service.ErrImageNotFound)
+
+	// Тип определяется по содержимому, а не по заявленному клиентом
+	_, err = merchSvc.SetImage(ctx, "cup", bytes.NewReader([]byte("<svg></svg>")))
+	assert.ErrorIs(t, err, service.ErrInvalidImage)
+	_, err = merchSvc.SetImage(ctx, "cup", bytes.NewReader(append(pngHeader, make([]byte, 1024)...)))
+	assert.ErrorIs(t, err, service.ErrImageTooLarge)
+
+	item, err := merchSvc.SetImage(ctx, "cup", bytes.NewReader(pngHeader))
+	require.NoError(t, err)
+	assert.Equal(t, "/api/merch/cup/image", item.Image)
+	first, err := st.merchRepo.GetMerchByName(ctx, "cup")
+	require.NoError(t, err)
+
+	rc, contentType, err := merchSvc.Image(ctx, "cup")
+	require.NoError(t, err)
+	data, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	assert.Equal(t, "image/png", contentType)
+	assert.Equal(t, pngHeader, data)
+
+	// Замена изображения удаляет прежний файл
+	_, err = merchSvc.SetImage(ctx, "cup", bytes.NewReader(pngHeader))
+	require.NoError(t, err)
+	_, err = images.Open(ctx, first.ImageKey)
+	assert.ErrorIs(t, err, blob.ErrNotFound)
+
+	item, err = merchSvc.DeleteImage(ctx, "cup")
+	require.NoError(t, err)
+	assert.Empty(t, item.Image)
+	_, _, err = merchSvc.Image(ctx, "cup")
+	assert.ErrorIs(t, err, service.ErrImageNotFound)
+	_, err = merchSvc.DeleteImage(ctx, "cup")
+	assert.ErrorIs(t, err, service.ErrImageNotFound)
+}
@@ -0,0 +1,290 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/service"
+	"github.com/linemk/avito-shop/internal/storage"
+	"github.com/linemk/avito-shop/internal/storage/blob"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestBuyService_SoldOut(t *testing.T) {
+	st := newTestStorage()
+	ctx := context.Background()
+	rich := st.createUser(t, "rich@example.com", 1000)
+	poor := st.createUser(t, "poor@example.com", 10)
+	st.db.AddMerch("pink-hoody", 500)
+
+	merchSvc := service.NewMerchService(newTestLogger(), st.txManager, st.userRepo, st.merchRepo, blob.NewMemoryStore(), 1024, st.wishlist)
+	_, err := merchSvc.SetStock(ctx, "pink-hoody", intPtr(1))
+	require.NoError(t, err)
+
+	buySvc := service.NewBuyService(newTestLogger(), st.txManager, st.userRepo, st.merchRepo, st.orderRepo, st.coinTxRepo, st.promoRepo, st.ledger)
+
+	// Нехватка монет и закончившийся товар — разные ошибки
+	assert.ErrorIs(t, buySvc.Buy(ctx, poor.ID, "pink-hoody", "", ""), service.ErrInsufficientFunds)
+	require.NoError(t, buySvc.Buy(ctx, rich.ID, "pink-hoody", "", ""))
+	assert.ErrorIs(t, buySvc.Buy(ctx, rich.ID, "pink-hoody", "", ""), service.ErrSoldOut)
+	assert.Equal(t, 500, st.balance(t, rich.ID))
+
+	catalog, err := merchSvc.Catalog(ctx, service.CatalogFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []service.CatalogItem{{Name: "pink-hoody", Price: 500, Stock: intPtr(0), SoldOut: true}}, catalog)
+
+	// Пополнение склада снова открывает продажи
+	item, err := merchSvc.Restock(ctx, "pink-hoody", 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, *item.Stock)
+	require.NoError(t, buySvc.Buy(ctx, rich.ID, "pink-hoody", "", ""))
+}
+
+func TestBuyService_ConcurrentStock(t *testing.T) {
+	st := newTestStorage()
+	ctx := context.Background()
+	st.db.AddMerch("pink-hoody", 500)
+
+	const stock, buyers = 5, 30
+	merchSvc := service.NewMerchService(newTestLogger(), st.txManager, st.userRepo, st.merchRepo, blob.NewMemoryStore(), 1024, st.wishlist)
+	_, err := merchSvc.SetStock(ctx, "pink-hoody", intPtr(stock))
+	require.NoError(t, err)
+
+	buySvc := service.NewBuyService(newTestLogger(), st.txManager, st.userRepo, st.merchRepo, st.orderRepo, st.coinTxRepo, st.promoRepo, st.ledger)
+
+	ids := make([]int64, buyers)
+	for i := range ids {
+		ids[i] = st.createUser(t, fmt.Sprintf("user%d@example.com", i), 1000).ID
+	}
+
+	// Покупателей больше, чем товара: продаётся ровно stock штук, остальные получают ErrSoldOut
+	var (
+		wg             sync.WaitGroup
+		mu             sync.Mutex
+		sold, soldOut  int
+		unexpectedErrs []error
+	)
+	for _, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := buySvc.Buy(ctx, id, "pink-hoody", "", "")
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				sold++
+			case errors.Is(err, service.ErrSoldOut):
+				soldOut++
+			default:
+				unexpectedErrs = append(unexpectedErrs, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Empty(t, unexpectedErrs)
+	assert.Equal(t, stock, sold)
+	assert.Equal(t, buyers-stock, soldOut)
+
+	merch, err := st.merchRepo.GetMerchByName(ctx, "pink-hoody")
+	require.NoError(t, err)
+	assert.Equal(t, 0, *merch.Stock)
+}
+
+func TestMerchService_InvalidStock(t *testing.T) {
+	st := newTestStorage()
+	ctx := context.Background()
+	st.db.AddMerch("cup", 20)
+	merchSvc := service.NewMerchService(newTestLogger(), st.txManager, st.userRepo, st.merchRepo, blob.NewMemoryStore(), 1024, st.wishlist)
+
+	_, err := merchSvc.Restock(ctx, "cup", 0)
+	assert.ErrorIs(t, err, service.ErrInvalidStock)
+	_, err = merchSvc.SetStock(ctx, "cup", intPtr(-1))
+	assert.ErrorIs(t, err, service.ErrInvalidStock)
+	_, err = merchSvc.Restock(ctx, "unknown", 1)
+	assert.ErrorIs(t, err, storage.ErrMerchNotFound)
+
+	// Снятие ограничения: товар снова продаётся без остатка
+	item, err := merchSvc.SetStock(ctx, "cup", nil)
+	require.NoError(t, err)
+	assert.Nil(t, item.Stock)
+	assert.False(t, item.SoldOut)
+}
+
+func TestBuyService_PurchaseLimit(t *testing.T) {
+	st := newTestStorage()
+	ctx := context.Background()
+	alice := st.createUser(t, "alice@example.com", 2000)
+	bob := st.createUser(t, "bob@example.com", 2000)
+	st.db.AddMerch("pink-hoody", 500)
+
+	merchSvc := service.NewMerchService(newTestLogger(), st.txManager, st.userRepo, st.merchRepo, blob.NewMemoryStore(), 1024, st.wishlist)
+	_, err := merchSvc.SetPurchaseLimit(ctx, "pink-hoody", &service.PurchaseLimit{Limit: 1})
+	require.NoError(t, err)
+
+	buySvc := service.NewBuyService(newTestLogger(), st.txManager, st.userRepo, st.merchRepo, st.orderRepo, st.coinTxRepo, st.promoRepo, st.ledger)
+	require.NoError(t, buySvc.Buy(ctx, alice.ID, "pink-hoody", "", ""))
+
+	// Второй экземпляр не купить ни себе, ни получить в подарок
+	assert.ErrorIs(t, buySvc.Buy(ctx, alice.ID, "pink-hoody", "", ""), service.ErrPurchaseLimit)
+	assert.ErrorIs(t, buySvc.Gift(ctx, bob.ID, "pink-hoody", "", alice.Email), service.ErrPurchaseLimit)
+	assert.Equal(t, 1500, st.balance(t, alice.ID))
+	assert.Equal(t, 2000, st.balance(t, bob.ID))
+
+	// Лимит считается для каждого сотрудника отдельно
+	require.NoError(t, buySvc.Gift(ctx, alice.ID, "pink-hoody", "", bob.Email))
+	assert.ErrorIs(t, buySvc.Buy(ctx, bob.ID, "pink-hoody", "", ""), service.ErrPurchaseLimit)
+
+	catalog, err := merchSvc.Catalog(ctx, service.CatalogFilter{})
+	require.NoError(t, err)
+	require.Len(t, catalog, 1)
+	assert.Equal(t, &service.PurchaseLimit{Limit: 1}, catalog[0].PurchaseLimit)
+
+	// Квартальный лимит на 2 штуки: текущий заказ alice уже учтён
+	_, err = merchSvc.SetPurchaseLimit(ctx, "pink-hoody", &service.PurchaseLimit{Limit: 2, Period: "quarter"})
+	require.NoError(t, err)
+	require.NoError(t, buySvc.Buy(ctx, alice.ID, "pink-hoody", "", ""))
+	assert.ErrorIs(t, buySvc.Buy(ctx, alice.ID, "pink-hoody", "", ""), service.ErrPurchaseLimit)
+
+	// Снятие лимита
+	item, err := merchSvc.SetPurchaseLimit(ctx, "pink-hoody", nil)
+	require.NoError(t, err)
+	assert.Nil(t, item.PurchaseLimit)
+	require.NoError(t, buySvc.Buy(ctx, alice.ID, "pink-hoody", "", ""))
+
+	_, err = merchSvc.SetPurchaseLimit(ctx, "pink-hoody", &service.PurchaseLimit{Limit: 1, Period: "week"})
+	assert.ErrorIs(t, err, service.ErrInvalidPurchaseLimit)
+}
+
+func TestMerchService_PriceChanges(t *testing.T) {
+	st := newTestStorage()
+	ctx := context.Background()
+	admin := st.createUser(t, "admin@example.com", 0)
+	buyer := st.createUser(t, "buyer@example.com", 1000)
+	st.db.AddMerch("pink-hoody", 500)
+
+	merchSvc := service.NewMerchService(newTestLogger(), st.txManager, st.userRepo, st.merchRepo, blob.NewMemoryStore(), 1024, st.wishlist)
+	buySvc := service.NewBuyService(newTestLogger(), st.txManager, st.userRepo, st.merchRepo, st.orderRepo, st.coinTxRepo, st.promoRepo, st.ledger)
+
+	// Изменение без даты вступает в силу сразу
+	entry, err := merchSvc.SetPrice(ctx, admin.ID, "pink-hoody", 450, time.Time{}, "новая партия")
+	require.NoError(t, err)
+	assert.Equal(t, admin.Email, entry.ChangedBy)
+	require.NoError(t, buySvc.Buy(ctx, buyer.ID, "pink-hoody", "", ""))
+	assert.Equal(t, 550, st.balance(t, buyer.ID))
+
+	// Распродажа начнётся чуть позже: до этого действует прежняя цена
+	saleAt := time.Now().Add(50 * time.Millisecond)
+	_, err = merchSvc.SetPrice(ctx, admin.ID, "pink-hoody", 300, saleAt, "распродажа")
+	require.NoError(t, err)
+	farFuture, err := merchSvc.SetPrice(ctx, admin.ID, "pink-hoody", 600, time.Now().Add(24*time.Hour), "")
+	require.NoError(t, err)
+
+	history, err := merchSvc.PriceHistory(ctx, "pink-hoody")
+	require.NoError(t, err)
+	assert.Equal(t, 450, history.Price)
+	require.Len(t, history.History, 1)
+	require.Len(t, history.Scheduled, 2)
+	assert.Equal(t, 300, history.Scheduled[0].Price)
+
+	time.Sleep(time.Until(saleAt) + 10*time.Millisecond)
+	require.NoError(t, buySvc.Buy(ctx, buyer.ID, "pink-hoody", "", ""))
+	assert.Equal(t, 250, st.balance(t, buyer.ID))
+
+	// Заказы хранят цену на момент покупки
+	orders, err := st.orderRepo.GetOrdersByUserID(ctx, buyer.ID)
+	require.NoError(t, err)
+	require.Len(t, orders, 2)
+	assert.ElementsMatch(t, []int{450, 300}, []int{orders[0].TotalPrice, orders[1].TotalPrice})
+
+	// Отменить можно только запланированное изменение
+	history, err = merchSvc.PriceHistory(ctx, "pink-hoody")
+	require.NoError(t, err)
+	require.Len(t, history.History, 2)
+	assert.ErrorIs(t, merchSvc.CancelPriceChange(ctx, "pink-hoody", history.History[1].ID), service.ErrPriceChangeApplied)
+	require.NoError(t, merchSvc.CancelPriceChange(ctx, "pink-hoody", farFuture.ID))
+	assert.ErrorIs(t, merchSvc.CancelPriceChange(ctx, "pink-hoody", farFuture.ID), storage.ErrPriceChangeNotFound)
+
+	history, err = merchSvc.PriceHistory(ctx, "pink-hoody")
+	require.NoError(t, err)
+	assert.Equal(t, 300, history.Price)
+	assert.Empty(t, history.Scheduled)
+
+	_, err = merchSvc.SetPrice(ctx, admin.ID, "pink-hoody", 0, time.Time{}, "")
+	assert.ErrorIs(t, err, service.ErrInvalidPrice)
+}
+
+func TestBuyService_Variants(t *testing.T) {
+	st := newTestStorage()
+	ctx := context.Background()
+	alice := st.createUser(t, "alice@example.com", 1000)
+	bob := st.createUser(t, "bob@example.com", 1000)
+	st.db.AddMerch("t-shirt", 80)
+
+	merchSvc := service.NewMerchService(newTestLogger(), st.txManager, st.userRepo, st.merchRepo, blob.NewMemoryStore(), 1024, st.wishlist)
+	buySvc := service.NewBuyService(newTestLogger(), st.txManager, st.userRepo, st.merchRepo, st.orderRepo, st.coinTxRepo, st.promoRepo, st.ledger)
+
+	// Без вариантов товар покупается как раньше
+	require.NoError(t, buySvc.Buy(ctx, alice.ID, "t-shirt", "", ""))
+
+	_, err := merchSvc.CreateVariant(ctx, "t-shirt", service.VariantInput{SKU: "TS-M", Size: "M", Stock: intPtr(1)})
+	require.NoError(t, err)
+	xxl, err := merchSvc.CreateVariant(ctx, "t-shirt", service.VariantInput{SKU: "TS-XXL", Size: "XXL", Price: intPtr(100)})
+	require.NoError(t, err)
+	assert.Equal(t, 100, xxl.Price)
+	_, err = merchSvc.CreateVariant(ctx, "t-shirt", service.VariantInput{SKU: "TS-M"})
+	assert.ErrorIs(t, err, storage.ErrVariantExists)
+	_, err = merchSvc.CreateVariant(ctx, "t-shirt", service.VariantInput{SKU: "TS-L", Price: intPtr(0)})
+	assert.ErrorIs(t, err, service.ErrInvalidVariant)
+
+	assert.ErrorIs(t, buySvc.Buy(ctx, alice.ID, "t-shirt", "", ""), service.ErrVariantRequired)
+	assert.ErrorIs(t, buySvc.Buy(ctx, alice.ID, "t-shirt", "TS-S", ""), storage.ErrVariantNotFound)
+	require.NoError(t, buySvc.Buy(ctx, alice.ID, "t-shirt", "TS-M", ""))
+	assert.ErrorIs(t, buySvc.Gift(ctx, bob.ID, "t-shirt", "TS-M", alice.Email), service.ErrSoldOut)
+	require.NoError(t, buySvc.Buy(ctx, alice.ID, "t-shirt", "TS-XXL", ""))
+	require.NoError(t, buySvc.Gift(ctx, bob.ID, "t-shirt", "TS-XXL", alice.Email))
+	assert.Equal(t, 1000-80-80-100, st.balance(t, alice.ID))
+	assert.Equal(t, 900, st.balance(t, bob.ID))
+
+	catalog, err := merchSvc.Catalog(ctx, service.CatalogFilter{})
+	require.NoError(t, err)
+	require.Len(t, catalog, 1)
+	require.Len(t, catalog[0].Variants, 2)
+	assert.True(t, catalog[0].Variants[0].SoldOut)
+	assert.False(t, catalog[0].SoldOut)
+
+	// Инвентарь считается по вариантам
+	infoSvc := service.NewInfoService(newTestLogger(), st.userRepo, st.orderRepo, st.coinTxRepo, st.lotRepo, 0)
+	info, err := infoSvc.GetInfo(ctx, alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []service.InventoryItem{
+		{Type: "t-shirt", Quantity: 1},
+		{Type: "t-shirt", Variant: "TS-M", Size: "M", Quantity: 1},
+		{Type: "t-shirt", Variant: "TS-XXL", Size: "XXL", Quantity: 2},
+	}, info.Inventory)
+
+	// Отмена возвращает товар на остаток варианта
+	orderSvc := st.newOrderService(&eventRecorder{}, time.Hour)
+	orders, err := orderSvc.List(ctx, alice.ID, "")
+	require.NoError(t, err)
+	require.Len(t, orders, 4)
+	assert.Equal(t, "TS-M", orders[2].Variant)
+	_, err = orderSvc.Cancel(ctx, alice.ID, orders[2].ID)
+	require.NoError(t, err)
+	catalog, err = merchSvc.Catalog(ctx, service.CatalogFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, intPtr(1), catalog[0].Variants[0].Stock)
+
+	require.NoError(t, merchSvc.DeleteVariant(ctx, "t-shirt", "TS-M"))
+	assert.ErrorIs(t, buySvc.Buy(ctx, alice.ID, "t-shirt", "TS-M", ""), storage.ErrVariantNotFound)
+}
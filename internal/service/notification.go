@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/storage"
+)
+
+// Notifier записывает уведомления пользователям. Методы вызываются внутри WithinTx:
+// уведомление фиксируется или откатывается вместе с изменением, которое его вызвало.
+type Notifier struct {
+	notificationRepo storage.NotificationStorage
+}
+
+// NewNotifier создаёт Notifier; один экземпляр разделяют все сервисы, которые уведомляют пользователей.
+func NewNotifier(notificationRepo storage.NotificationStorage) *Notifier {
+	return &Notifier{notificationRepo: notificationRepo}
+}
+
+// Notify записывает пользователю userID уведомление типа notificationType.
+func (n *Notifier) Notify(ctx context.Context, userID int64, notificationType, message string, data map[string]any) error {
+	err := n.notificationRepo.CreateNotification(ctx, &models.Notification{
+		UserID:  userID,
+		Type:    notificationType,
+		Message: message,
+		Data:    data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to notify user %d: %w", userID, err)
+	}
+	return nil
+}
+
+// MaxNotifications — сколько уведомлений возвращает Inbox за один запрос.
+const MaxNotifications = 100
+
+// NotificationView — уведомление во входящих пользователя.
+type NotificationView struct {
+	ID        int64          `json:"id"`
+	Type      string         `json:"type"`
+	Message   string         `json:"message"`
+	Data      map[string]any `json:"data"`
+	Read      bool           `json:"read"`
+	ReadAt    *time.Time     `json:"readAt,omitempty"`
+	CreatedAt time.Time      `json:"createdAt"`
+}
+
+// Inbox — входящие уведомления пользователя.
+type Inbox struct {
+	Unread        int                `json:"unread"`
+	Notifications []NotificationView `json:"notifications"`
+}
+
+// NotificationService — входящие уведомления пользователя.
+type NotificationService interface {
+	// Inbox возвращает последние limit уведомлений (не больше MaxNotifications) и число
+	// непрочитанных. unreadOnly оставляет только непрочитанные.
+	Inbox(ctx context.Context, userID int64, unreadOnly bool, limit int) (*Inbox, error)
+	// MarkRead отмечает уведомление id прочитанным и возвращает оставшееся число непрочитанных.
+	MarkRead(ctx context.Context, userID, id int64) (int, error)
+	// MarkAllRead отмечает прочитанными все уведомления и возвращает, сколько их было отмечено.
+	MarkAllRead(ctx context.Context, userID int64) (int, error)
+}
+
+type notificationService struct {
+	log              *slog.Logger
+	txManager        storage.TxManager
+	notificationRepo storage.NotificationStorage
+}
+
+// NewNotificationService создаёт сервис входящих уведомлений.
+func NewNotificationService(log *slog.Logger, txManager storage.TxManager, notificationRepo storage.NotificationStorage) NotificationService {
+	return &notificationService{
+		log:              log,
+		txManager:        txManager,
+		notificationRepo: notificationRepo,
+	}
+}
+
+func (s *notificationService) Inbox(ctx context.Context, userID int64, unreadOnly bool, limit int) (*Inbox, error) {
+	const op = "service.NotificationService.Inbox"
+
+	if limit <= 0 || limit > MaxNotifications {
+		limit = MaxNotifications
+	}
+	var (
+		notifications []models.Notification
+		unread        int
+	)
+	// Список и счётчик читаются в одной транзакции, чтобы не расходиться между собой
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		notifications, err = s.notificationRepo.ListNotifications(ctx, userID, unreadOnly, limit)
+		if err != nil {
+			return err
+		}
+		unread, err = s.notificationRepo.CountUnreadNotifications(ctx, userID)
+		return err
+	})
+	if err != nil {
+		s.log.Error("failed to get notifications", slog.String("op", op), slog.Int64("userID", userID), slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	inbox := &Inbox{Unread: unread, Notifications: make([]NotificationView, 0, len(notifications))}
+	for _, n := range notifications {
+		inbox.Notifications = append(inbox.Notifications, newNotificationView(n))
+	}
+	return inbox, nil
+}
+
+func (s *notificationService) MarkRead(ctx context.Context, userID, id int64) (int, error) {
+	const op = "service.NotificationService.MarkRead"
+	logger := s.log.With(slog.String("op", op), slog.Int64("userID", userID), slog.Int64("notificationID", id))
+
+	var unread int
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.notificationRepo.MarkNotificationRead(ctx, userID, id, time.Now()); err != nil {
+			return err
+		}
+		var err error
+		unread, err = s.notificationRepo.CountUnreadNotifications(ctx, userID)
+		return err
+	})
+	if err != nil {
+		logger.Warn("failed to mark notification read", slog.Any("error", err))
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return unread, nil
+}
+
+func (s *notificationService) MarkAllRead(ctx context.Context, userID int64) (int, error) {
+	const op = "service.NotificationService.MarkAllRead"
+
+	marked, err := s.notificationRepo.MarkAllNotificationsRead(ctx, userID, time.Now())
+	if err != nil {
+		s.log.Error("failed to mark notifications read", slog.String("op", op), slog.Int64("userID", userID), slog.Any("error", err))
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return marked, nil
+}
+
+func newNotificationView(n models.Notification) NotificationView {
+	data := n.Data
+	if data == nil {
+		data = map[string]any{}
+	}
+	return NotificationView{
+		ID:        n.ID,
+		Type:      n.Type,
+		Message:   n.Message,
+		Data:      data,
+		Read:      n.ReadAt != nil,
+		ReadAt:    n.ReadAt,
+		CreatedAt: n.CreatedAt,
+	}
+}
@@ -0,0 +1,148 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/service"
+	"github.com/linemk/avito-shop/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestNotificationService(st *testStorage) service.NotificationService {
+	return service.NewNotificationService(newTestLogger(), st.txManager, st.notes)
+}
+
+func TestNotifications_SendCoin(t *testing.T) {
+	st := newTestStorage()
+	ctx := context.Background()
+	alice := st.createUser(t, "alice@example.com", 100)
+	bob := st.createUser(t, "bob@example.com", 0)
+	sendCoinSvc := service.NewSendCoinService(newTestLogger(), st.txManager, st.userRepo, st.coinTxRepo, st.ledger, st.notifier, service.TransferLimits{})
+
+	require.NoError(t, sendCoinSvc.SendCoin(ctx, alice.ID, bob.Email, 30, "спасибо за ревью"))
+
+	inbox, err := newTestNotificationService(st).Inbox(ctx, bob.ID, false, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, inbox.Unread)
+	require.Len(t, inbox.Notifications, 1)
+	n := inbox.Notifications[0]
+	assert.Equal(t, models.NotificationCoinsReceived, n.Type)
+	assert.Equal(t, map[string]any{"from": alice.Email, "amount": 30, "comment": "спасибо за ревью"}, n.Data)
+	assert.False(t, n.Read)
+	assert.Empty(t, st.notificationTypes(t, alice.ID))
+
+	// Отклонённый перевод откатывается вместе с уведомлением
+	require.Error(t, sendCoinSvc.SendCoin(ctx, alice.ID, bob.Email, 500, ""))
+	assert.Len(t, st.notificationTypes(t, bob.ID), 1)
+}
+
+func TestNotifications_OrderStatusChanged(t *testing.T) {
+	st := newTestStorage()
+	ctx := context.Background()
+	buyer := st.createUser(t, "buyer@example.com", 100)
+	admin := st.createUser(t, "admin@example.com", 0)
+	st.db.AddMerch("cup", 20)
+	buySvc := service.NewBuyService(newTestLogger(), st.txManager, st.userRepo, st.merchRepo, st.orderRepo, st.coinTxRepo, st.promoRepo, st.ledger)
+	require.NoError(t, buySvc.Buy(ctx, buyer.ID, "cup", "", ""))
+	require.NoError(t, buySvc.Buy(ctx, buyer.ID, "cup", "", ""))
+	orderSvc := st.newOrderService(&eventRecorder{}, time.Hour)
+	orders, err := orderSvc.List(ctx, buyer.ID, "")
+	require.NoError(t, err)
+	require.Len(t, orders, 2)
+
+	_, err = orderSvc.Transition(ctx, admin.ID, orders[0].ID, models.OrderReadyForPickup, "стойка на 3 этаже")
+	require.NoError(t, err)
+	notifications, err := st.notes.ListNotifications(ctx, buyer.ID, false, 0)
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, models.NotificationOrderStatusChanged, notifications[0].Type)
+	assert.Equal(t, models.OrderPlaced, notifications[0].Data["from"])
+	assert.Equal(t, models.OrderReadyForPickup, notifications[0].Data["to"])
+	assert.Equal(t, "стойка на 3 этаже", notifications[0].Data["comment"])
+
+	// Собственная отмена пользователя уведомления не создаёт
+	_, err = orderSvc.Cancel(ctx, buyer.ID, orders[1].ID)
+	require.NoError(t, err)
+	assert.Len(t, st.notificationTypes(t, buyer.ID), 1)
+}
+
+func TestNotifications_BulkGrant(t *testing.T) {
+	st := newTestStorage()
+	ctx := context.Background()
+	admin := st.createUser(t, "hr@example.com", 0)
+	alice := st.createUser(t, "alice@example.com", 0)
+	bob := st.createUser(t, "bob@example.com", 0)
+	adminSvc := newTestAdminService(st, 10)
+
+	grant := service.BulkGrant{
+		ID:     "q3-bonus",
+		Reason: "квартальная премия",
+		Items:  []service.GrantItem{{Username: alice.Email, Amount: 50}, {Username: bob.Email, Amount: 70}},
+	}
+	_, err := adminSvc.BulkGrant(ctx, admin.ID, grant)
+	require.NoError(t, err)
+	// Повторный запуск пропускает начисленное и не уведомляет второй раз
+	_, err = adminSvc.BulkGrant(ctx, admin.ID, grant)
+	require.NoError(t, err)
+
+	notifications, err := st.notes.ListNotifications(ctx, bob.ID, false, 0)
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, models.NotificationCoinsGranted, notifications[0].Type)
+	assert.Equal(t, map[string]any{"amount": 70, "reason": "квартальная премия"}, notifications[0].Data)
+	assert.Len(t, st.notificationTypes(t, alice.ID), 1)
+
+	// Списание администратором не уведомляет
+	_, err = adminSvc.AdjustBalance(ctx, admin.ID, alice.Email, -10, "ошибка начисления")
+	require.NoError(t, err)
+	assert.Len(t, st.notificationTypes(t, alice.ID), 1)
+}
+
+func TestNotificationService_MarkRead(t *testing.T) {
+	st := newTestStorage()
+	ctx := context.Background()
+	user := st.createUser(t, "user@example.com", 0)
+	other := st.createUser(t, "other@example.com", 0)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, st.notifier.Notify(ctx, user.ID, models.NotificationCoinsGranted, "granted", nil))
+	}
+	notificationSvc := newTestNotificationService(st)
+
+	inbox, err := notificationSvc.Inbox(ctx, user.ID, false, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 3, inbox.Unread)
+	require.Len(t, inbox.Notifications, 2)
+	assert.Equal(t, map[string]any{}, inbox.Notifications[0].Data)
+
+	unread, err := notificationSvc.MarkRead(ctx, user.ID, inbox.Notifications[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, unread)
+	// Повторная отметка не ошибка
+	unread, err = notificationSvc.MarkRead(ctx, user.ID, inbox.Notifications[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, unread)
+	// Чужое уведомление не найдётся
+	_, err = notificationSvc.MarkRead(ctx, other.ID, inbox.Notifications[1].ID)
+	assert.ErrorIs(t, err, storage.ErrNotificationNotFound)
+
+	inbox, err = notificationSvc.Inbox(ctx, user.ID, true, 0)
+	require.NoError(t, err)
+	require.Len(t, inbox.Notifications, 2)
+	for _, n := range inbox.Notifications {
+		assert.False(t, n.Read)
+	}
+
+	marked, err := notificationSvc.MarkAllRead(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, marked)
+	inbox, err = notificationSvc.Inbox(ctx, user.ID, false, 0)
+	require.NoError(t, err)
+	assert.Zero(t, inbox.Unread)
+	require.Len(t, inbox.Notifications, 3)
+	assert.True(t, inbox.Notifications[0].Read)
+	assert.NotNil(t, inbox.Notifications[0].ReadAt)
+}
@@ -0,0 +1,410 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/storage"
+)
+
+// MaxOrders — ограничение на число заказов в одном ответе.
+const MaxOrders = 100
+
+var (
+	// ErrInvalidOrderStatus — неизвестный статус заказа.
+	ErrInvalidOrderStatus = errors.New("invalid order status")
+	// ErrOrderTransition — заказ нельзя перевести из текущего статуса в запрошенный.
+	ErrOrderTransition = errors.New("order status transition not allowed")
+	// ErrCancelNotAllowed — сотрудник не может сам отменить заказ (истекло окно отмены или это подарок).
+	ErrCancelNotAllowed = errors.New("order cancellation not allowed")
+)
+
+// orderTransitions — допустимые переходы статусов заказа. Выданный и отменённый заказы не меняются.
+var orderTransitions = map[string][]string{
+	models.OrderPlaced:         {models.OrderReadyForPickup, models.OrderDelivered, models.OrderCancelled},
+	models.OrderReadyForPickup: {models.OrderDelivered, models.OrderCancelled},
+}
+
+// OrderStatusEntry — запись истории статусов заказа.
+type OrderStatusEntry struct {
+	From      string    `json:"from,omitempty"`
+	To        string    `json:"to"`
+	ChangedBy string    `json:"changedBy,omitempty"`
+	Comment   string    `json:"comment,omitempty"`
+	At        time.Time `json:"at"`
+}
+
+// OrderView — заказ с названием товара и email участников.
+type OrderView struct {
+	ID         int64     `json:"id"`
+	Item       string    `json:"item"`
+	Quantity   int       `json:"quantity"`
+	TotalPrice int       `json:"totalPrice"`
+	Discount   int       `json:"discount,omitempty"`  // скидка по акции, уже вычтенная из TotalPrice
+	Promotion  string    `json:"promotion,omitempty"` // название применённой акции
+	Variant    string    `json:"variant,omitempty"`   // артикул купленного варианта
+	Size       string    `json:"size,omitempty"`
+	Color      string    `json:"color,omitempty"`
+	Status     string    `json:"status"`
+	User       string    `json:"user,omitempty"`     // владелец заказа (в ответах администратору)
+	GiftedBy   string    `json:"giftedBy,omitempty"` // кто оплатил подарок
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+	// History — история статусов; заполняется только для одного заказа
+	History []OrderStatusEntry `json:"history,omitempty"`
+}
+
+// OrderService — просмотр заказов и их выдача.
+type OrderService interface {
+	// List возвращает последние заказы пользователя userID в статусе status (пусто — в любом).
+	// userID = 0 — заказы всех пользователей (для администратора).
+	List(ctx context.Context, userID int64, status string) ([]OrderView, error)
+	// Get возвращает заказ с историей статусов. Если userID != 0, чужой заказ не найдётся.
+	Get(ctx context.Context, userID, orderID int64) (*OrderView, error)
+	// Transition переводит заказ в статус status от имени администратора adminID
+	// и публикует EventOrderStatusChanged. Отмена возвращает монеты, как и Cancel, но без окна отмены.
+	Transition(ctx context.Context, adminID, orderID int64, status, comment string) (*OrderView, error)
+	// Cancel отменяет невыданный заказ пользователя userID в течение окна отмены: цена
+	// возвращается на баланс, товар — на склад.
+	Cancel(ctx context.Context, userID, orderID int64) (*OrderView, error)
+}
+
+type orderService struct {
+	log          *slog.Logger
+	txManager    storage.TxManager
+	userRepo     storage.UserStorage
+	merchRepo    storage.MerchStorage
+	orderRepo    storage.OrderStorage
+	ledger       *Ledger
+	notifier     *Notifier
+	events       EventPublisher
+	cancelWindow time.Duration
+}
+
+func NewOrderService(log *slog.Logger, txManager storage.TxManager, userRepo storage.UserStorage, merchRepo storage.MerchStorage, orderRepo storage.OrderStorage, ledger *Ledger, notifier *Notifier, events EventPublisher, cancelWindow time.Duration) OrderService {
+	if cancelWindow <= 0 {
+		cancelWindow = 24 * time.Hour
+	}
+	return &orderService{
+		log:          log,
+		txManager:    txManager,
+		userRepo:     userRepo,
+		merchRepo:    merchRepo,
+		orderRepo:    orderRepo,
+		ledger:       ledger,
+		notifier:     notifier,
+		events:       events,
+		cancelWindow: cancelWindow,
+	}
+}
+
+func (s *orderService) List(ctx context.Context, userID int64, status string) ([]OrderView, error) {
+	const op = "service.OrderService.List"
+
+	if status != "" && !validOrderStatus(status) {
+		return nil, fmt.Errorf("%s: %w: %q", op, ErrInvalidOrderStatus, status)
+	}
+	orders, err := s.orderRepo.ListOrders(ctx, userID, status, MaxOrders)
+	if err != nil {
+		s.log.Error("failed to list orders", slog.String("op", op), slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	emails := s.emailResolver(ctx)
+	views := make([]OrderView, 0, len(orders))
+	for _, order := range orders {
+		view := newOrderView(order, emails)
+		if userID != 0 {
+			view.User = ""
+		}
+		views = append(views, view)
+	}
+	return views, nil
+}
+
+func (s *orderService) Get(ctx context.Context, userID, orderID int64) (*OrderView, error) {
+	const op = "service.OrderService.Get"
+
+	order, err := s.orderRepo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if userID != 0 && order.UserID != userID {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrOrderNotFound)
+	}
+
+	view, err := s.viewWithHistory(ctx, order)
+	if err != nil {
+		s.log.Error("failed to get order history", slog.String("op", op), slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if userID != 0 {
+		view.User = ""
+	}
+	return view, nil
+}
+
+func (s *orderService) Transition(ctx context.Context, adminID, orderID int64, status, comment string) (*OrderView, error) {
+	const op = "service.OrderService.Transition"
+	logger := s.log.With(slog.String("op", op), slog.Int64("adminID", adminID), slog.Int64("orderID", orderID), slog.String("status", status))
+
+	if !validOrderStatus(status) {
+		return nil, fmt.Errorf("%s: %w: %q", op, ErrInvalidOrderStatus, status)
+	}
+	comment, err := sanitizeComment(comment)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var (
+		order *models.Order
+		from  string
+	)
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		order, err = s.orderRepo.LockOrder(ctx, orderID)
+		if err != nil {
+			return err
+		}
+		from = order.Status
+		return s.changeStatus(ctx, order, status, &adminID, comment)
+	})
+	if err != nil {
+		logger.Error("failed to change order status", slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	logger.Info("order status changed", slog.String("from", from))
+	s.publishStatusChanged(ctx, order, from, comment)
+	return s.resultView(ctx, logger, order), nil
+}
+
+func (s *orderService) Cancel(ctx context.Context, userID, orderID int64) (*OrderView, error) {
+	const op = "service.OrderService.Cancel"
+	logger := s.log.With(slog.String("op", op), slog.Int64("userID", userID), slog.Int64("orderID", orderID))
+
+	var (
+		order *models.Order
+		from  string
+	)
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		order, err = s.orderRepo.LockOrder(ctx, orderID)
+		if err != nil {
+			return err
+		}
+		if order.UserID != userID {
+			return storage.ErrOrderNotFound
+		}
+		if order.GiftedBy != nil {
+			return fmt.Errorf("%w: gifts are cancelled by an administrator", ErrCancelNotAllowed)
+		}
+		if time.Since(order.CreatedAt) > s.cancelWindow {
+			return fmt.Errorf("%w: cancellation window of %s expired", ErrCancelNotAllowed, s.cancelWindow)
+		}
+		from = order.Status
+		return s.changeStatus(ctx, order, models.OrderCancelled, &userID, "")
+	})
+	if err != nil {
+		logger.Warn("failed to cancel order", slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	logger.Info("order cancelled", slog.String("from", from), slog.Int("refund", order.TotalPrice))
+	s.publishStatusChanged(ctx, order, from, "")
+	return s.resultView(ctx, logger, order), nil
+}
+
+// resultView возвращает заказ после смены статуса. Статус уже изменён, поэтому
+// при ошибке чтения истории заказ возвращается без неё.
+func (s *orderService) resultView(ctx context.Context, logger *slog.Logger, order *models.Order) *OrderView {
+	view, err := s.viewWithHistory(ctx, order)
+	if err != nil {
+		logger.Error("failed to get order history", slog.Any("error", err))
+		v := newOrderView(order, s.emailResolver(ctx))
+		return &v
+	}
+	return view
+}
+
+// changeStatus проверяет переход заказа, заблокированного LockOrder, в статус to,
+// меняет его и пишет историю; при отмене возвращает монеты и товар. Владелец заказа получает
+// уведомление, если статус сменил не он сам. Вызывается внутри WithinTx; order обновляется.
+func (s *orderService) changeStatus(ctx context.Context, order *models.Order, to string, changedBy *int64, comment string) error {
+	if !orderTransitionAllowed(order.Status, to) {
+		return fmt.Errorf("%w: %s -> %s", ErrOrderTransition, order.Status, to)
+	}
+	if to == models.OrderCancelled {
+		if err := s.refund(ctx, order); err != nil {
+			return err
+		}
+	}
+	now := time.Now()
+	if err := s.orderRepo.UpdateOrderStatus(ctx, order.ID, to, now); err != nil {
+		return err
+	}
+	err := s.orderRepo.AddOrderStatusChange(ctx, &models.OrderStatusChange{
+		OrderID:    order.ID,
+		FromStatus: order.Status,
+		ToStatus:   to,
+		ChangedBy:  changedBy,
+		Comment:    comment,
+	})
+	if err != nil {
+		return err
+	}
+	if changedBy == nil || *changedBy != order.UserID {
+		err = s.notifier.Notify(ctx, order.UserID, models.NotificationOrderStatusChanged,
+			fmt.Sprintf("Order #%d (%s) is now %s", order.ID, order.MerchName, to),
+			map[string]any{"orderId": order.ID, "item": order.MerchName, "from": order.Status, "to": to, "comment": comment})
+		if err != nil {
+			return err
+		}
+	}
+	order.Status, order.UpdatedAt = to, now
+	return nil
+}
+
+// refund возвращает товар отменённого заказа на склад (если остаток учитывается) и цену — тому,
+// кто заплатил: покупателю или дарителю. Товар блокируется раньше пользователя, как при покупке.
+func (s *orderService) refund(ctx context.Context, order *models.Order) error {
+	merch, err := s.merchRepo.LockMerchByID(ctx, order.MerchID)
+	if err != nil {
+		return fmt.Errorf("failed to lock merch: %w", err)
+	}
+	// Товар возвращается туда, откуда был списан: на остаток варианта, если он учитывается отдельно
+	var variant *models.MerchVariant
+	if order.VariantID != nil {
+		variant, err = s.merchRepo.LockVariant(ctx, *order.VariantID)
+		if err != nil {
+			return fmt.Errorf("failed to lock merch variant: %w", err)
+		}
+	}
+	switch {
+	case order.VariantID == nil && order.VariantSKU != "":
+		// Вариант заказа удалён: неизвестно, вёл ли он свой остаток, поэтому на склад ничего не возвращаем
+		s.log.Warn("order variant was deleted, stock is not restored",
+			slog.Int64("orderID", order.ID), slog.String("sku", order.VariantSKU))
+	case variant != nil && variant.Stock != nil:
+		stock := *variant.Stock + order.Quantity
+		variant.Stock = &stock
+		if err := s.merchRepo.UpdateVariant(ctx, variant); err != nil {
+			return fmt.Errorf("failed to restore variant stock: %w", err)
+		}
+	case merch.Stock != nil:
+		stock := *merch.Stock + order.Quantity
+		if err := s.merchRepo.UpdateMerchStock(ctx, merch.ID, &stock); err != nil {
+			return fmt.Errorf("failed to restore merch stock: %w", err)
+		}
+	}
+
+	payerID := order.UserID
+	var related *int64
+	if order.GiftedBy != nil {
+		payerID, related = *order.GiftedBy, &order.UserID
+	}
+	key := "refund:" + strconv.FormatInt(order.ID, 10)
+	_, err = s.ledger.apply(ctx, &models.CoinTransaction{
+		UserID:         payerID,
+		Amount:         order.TotalPrice,
+		Type:           models.CoinTxRefund,
+		RelatedUserID:  related,
+		Comment:        order.MerchName,
+		IdempotencyKey: &key,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to refund order: %w", err)
+	}
+	return nil
+}
+
+func (s *orderService) publishStatusChanged(ctx context.Context, order *models.Order, from, comment string) {
+	s.events.Publish(ctx, Event{
+		Type:   EventOrderStatusChanged,
+		UserID: order.UserID,
+		Data: map[string]any{
+			"orderId": order.ID,
+			"item":    order.MerchName,
+			"from":    from,
+			"to":      order.Status,
+			"comment": comment,
+		},
+		At: order.UpdatedAt,
+	})
+}
+
+func (s *orderService) viewWithHistory(ctx context.Context, order *models.Order) (*OrderView, error) {
+	history, err := s.orderRepo.GetOrderStatusHistory(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+	emails := s.emailResolver(ctx)
+	view := newOrderView(order, emails)
+	view.History = make([]OrderStatusEntry, 0, len(history))
+	for _, c := range history {
+		entry := OrderStatusEntry{From: c.FromStatus, To: c.ToStatus, Comment: c.Comment, At: c.CreatedAt}
+		if c.ChangedBy != nil {
+			entry.ChangedBy = emails(*c.ChangedBy)
+		}
+		view.History = append(view.History, entry)
+	}
+	return &view, nil
+}
+
+// emailResolver возвращает функцию, которая находит email пользователя по id с кэшем
+// на время одного запроса.
+func (s *orderService) emailResolver(ctx context.Context) func(id int64) string {
+	emails := make(map[int64]string)
+	return func(id int64) string {
+		if e, ok := emails[id]; ok {
+			return e
+		}
+		if u, err := s.userRepo.GetUserByID(ctx, id); err == nil {
+			emails[id] = u.Email
+		}
+		return emails[id]
+	}
+}
+
+func newOrderView(order *models.Order, emails func(id int64) string) OrderView {
+	view := OrderView{
+		ID:         order.ID,
+		Item:       order.MerchName,
+		Quantity:   order.Quantity,
+		TotalPrice: order.TotalPrice,
+		Discount:   order.Discount,
+		Promotion:  order.PromotionName,
+		Variant:    order.VariantSKU,
+		Size:       order.VariantSize,
+		Color:      order.VariantColor,
+		Status:     order.Status,
+		User:       emails(order.UserID),
+		CreatedAt:  order.CreatedAt,
+		UpdatedAt:  order.UpdatedAt,
+	}
+	if order.GiftedBy != nil {
+		view.GiftedBy = emails(*order.GiftedBy)
+	}
+	return view
+}
+
+func validOrderStatus(status string) bool {
+	switch status {
+	case models.OrderPlaced, models.OrderReadyForPickup, models.OrderDelivered, models.OrderCancelled:
+		return true
+	}
+	return false
+}
+
+func orderTransitionAllowed(from, to string) bool {
+	for _, s := range orderTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
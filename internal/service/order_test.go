@@ -0,0 +1,205 @@
+package service_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/service"
+	"github.com/linemk/avito-shop/internal/storage"
+	"github.com/linemk/avito-shop/internal/storage/blob"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eventRecorder запоминает опубликованные события.
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []service.Event
+}
+
+func (r *eventRecorder) Publish(_ context.Context, event service.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// newOrderService создаёт OrderService поверх тестового хранилища с окном отмены cancelWindow.
+func (s *testStorage) newOrderService(events service.EventPublisher, cancelWindow time.Duration) service.OrderService {
+	return service.NewOrderService(newTestLogger(), s.txManager, s.userRepo, s.merchRepo, s.orderRepo, s.ledger, s.notifier, events, cancelWindow)
+}
+
+func TestOrderService_Transition(t *testing.T) {
+	st := newTestStorage()
+	ctx := context.Background()
+	buyer := st.createUser(t, "buyer@example.com", 1000)
+	friend := st.createUser(t, "friend@example.com", 0)
+	admin := st.createUser(t, "admin@example.com", 0)
+	st.db.AddMerch("cup", 20)
+
+	buySvc := service.NewBuyService(newTestLogger(), st.txManager, st.userRepo, st.merchRepo, st.orderRepo, st.coinTxRepo, st.promoRepo, st.ledger)
+	require.NoError(t, buySvc.Buy(ctx, buyer.ID, "cup", "", ""))
+	require.NoError(t, buySvc.Gift(ctx, buyer.ID, "cup", "", friend.Email))
+
+	events := &eventRecorder{}
+	orderSvc := st.newOrderService(events, time.Hour)
+
+	orders, err := orderSvc.List(ctx, buyer.ID, "")
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, models.OrderPlaced, orders[0].Status)
+	orderID := orders[0].ID
+
+	view, err := orderSvc.Transition(ctx, admin.ID, orderID, models.OrderReadyForPickup, "стойка на 3 этаже")
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderReadyForPickup, view.Status)
+	_, err = orderSvc.Transition(ctx, admin.ID, orderID, models.OrderDelivered, "")
+	require.NoError(t, err)
+
+	// Из конечного статуса заказ не выходит
+	_, err = orderSvc.Transition(ctx, admin.ID, orderID, models.OrderCancelled, "")
+	assert.ErrorIs(t, err, service.ErrOrderTransition)
+	_, err = orderSvc.Transition(ctx, admin.ID, orderID, "lost", "")
+	assert.ErrorIs(t, err, service.ErrInvalidOrderStatus)
+
+	view, err = orderSvc.Get(ctx, buyer.ID, orderID)
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderDelivered, view.Status)
+	require.Len(t, view.History, 3)
+	assert.Equal(t, service.OrderStatusEntry{To: models.OrderPlaced, ChangedBy: buyer.Email, At: view.History[0].At}, view.History[0])
+	assert.Equal(t, models.OrderPlaced, view.History[1].From)
+	assert.Equal(t, "стойка на 3 этаже", view.History[1].Comment)
+	assert.Equal(t, admin.Email, view.History[2].ChangedBy)
+
+	// Чужой заказ пользователю не виден, подарок виден получателю
+	_, err = orderSvc.Get(ctx, friend.ID, orderID)
+	assert.ErrorIs(t, err, storage.ErrOrderNotFound)
+	gifts, err := orderSvc.List(ctx, friend.ID, models.OrderPlaced)
+	require.NoError(t, err)
+	require.Len(t, gifts, 1)
+	assert.Equal(t, buyer.Email, gifts[0].GiftedBy)
+
+	all, err := orderSvc.List(ctx, 0, models.OrderDelivered)
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	assert.Equal(t, buyer.Email, all[0].User)
+
+	// Событие публикуется на каждый успешный переход
+	require.Len(t, events.events, 2)
+	assert.Equal(t, service.EventOrderStatusChanged, events.events[1].Type)
+	assert.Equal(t, buyer.ID, events.events[1].UserID)
+	assert.Equal(t, models.OrderReadyForPickup, events.events[1].Data["from"])
+	assert.Equal(t, models.OrderDelivered, events.events[1].Data["to"])
+}
+
+func TestOrderService_Cancel(t *testing.T) {
+	st := newTestStorage()
+	ctx := context.Background()
+	buyer := st.createUser(t, "buyer@example.com", 1000)
+	friend := st.createUser(t, "friend@example.com", 0)
+	admin := st.createUser(t, "admin@example.com", 0)
+	st.db.AddMerch("pink-hoody", 500)
+
+	merchSvc := service.NewMerchService(newTestLogger(), st.txManager, st.userRepo, st.merchRepo, blob.NewMemoryStore(), 1024, st.wishlist)
+	_, err := merchSvc.SetStock(ctx, "pink-hoody", intPtr(1))
+	require.NoError(t, err)
+	buySvc := service.NewBuyService(newTestLogger(), st.txManager, st.userRepo, st.merchRepo, st.orderRepo, st.coinTxRepo, st.promoRepo, st.ledger)
+	require.NoError(t, buySvc.Buy(ctx, buyer.ID, "pink-hoody", "", ""))
+
+	events := &eventRecorder{}
+	orderSvc := st.newOrderService(events, time.Hour)
+	orders, err := orderSvc.List(ctx, buyer.ID, "")
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	orderID := orders[0].ID
+
+	// Чужой заказ отменить нельзя
+	_, err = orderSvc.Cancel(ctx, friend.ID, orderID)
+	assert.ErrorIs(t, err, storage.ErrOrderNotFound)
+
+	view, err := orderSvc.Cancel(ctx, buyer.ID, orderID)
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderCancelled, view.Status)
+	assert.Equal(t, 1000, st.balance(t, buyer.ID))
+	_, err = orderSvc.Cancel(ctx, buyer.ID, orderID)
+	assert.ErrorIs(t, err, service.ErrOrderTransition)
+	assert.Equal(t, 1000, st.balance(t, buyer.ID))
+
+	// Товар вернулся на склад и пропал из инвентаря
+	catalog, err := merchSvc.Catalog(ctx, service.CatalogFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, intPtr(1), catalog[0].Stock)
+	infoSvc := service.NewInfoService(newTestLogger(), st.userRepo, st.orderRepo, st.coinTxRepo, st.lotRepo, 0)
+	info, err := infoSvc.GetInfo(ctx, buyer.ID)
+	require.NoError(t, err)
+	assert.Empty(t, info.Inventory)
+
+	txs, err := st.coinTxRepo.GetTransactionsByUserID(ctx, buyer.ID)
+	require.NoError(t, err)
+	require.Len(t, txs, 1)
+	assert.Equal(t, models.CoinTxRefund, txs[0].Type)
+	assert.Equal(t, 500, txs[0].Amount)
+	assert.Equal(t, "pink-hoody", txs[0].Comment)
+	require.Len(t, events.events, 1)
+	assert.Equal(t, models.OrderCancelled, events.events[0].Data["to"])
+
+	// Подарок отменяет администратор, монеты возвращаются дарителю
+	require.NoError(t, buySvc.Gift(ctx, buyer.ID, "pink-hoody", "", friend.Email))
+	gifts, err := orderSvc.List(ctx, friend.ID, "")
+	require.NoError(t, err)
+	require.Len(t, gifts, 1)
+	_, err = orderSvc.Cancel(ctx, friend.ID, gifts[0].ID)
+	assert.ErrorIs(t, err, service.ErrCancelNotAllowed)
+	_, err = orderSvc.Transition(ctx, admin.ID, gifts[0].ID, models.OrderCancelled, "")
+	require.NoError(t, err)
+	assert.Equal(t, 1000, st.balance(t, buyer.ID))
+	assert.Equal(t, 0, st.balance(t, friend.ID))
+}
+
+func TestOrderService_CancelDeletedVariant(t *testing.T) {
+	st := newTestStorage()
+	ctx := context.Background()
+	buyer := st.createUser(t, "buyer@example.com", 1000)
+	st.db.AddMerch("pink-hoody", 500)
+
+	merchSvc := service.NewMerchService(newTestLogger(), st.txManager, st.userRepo, st.merchRepo, blob.NewMemoryStore(), 1024, st.wishlist)
+	_, err := merchSvc.SetStock(ctx, "pink-hoody", intPtr(1))
+	require.NoError(t, err)
+	merch, err := st.merchRepo.GetMerchByName(ctx, "pink-hoody")
+	require.NoError(t, err)
+	// Заказ варианта, строку которого потом удалили: артикул остался, VariantID — nil
+	order := &models.Order{UserID: buyer.ID, MerchID: merch.ID, Quantity: 1, TotalPrice: 500, VariantSKU: "PH-M"}
+	require.NoError(t, st.orderRepo.CreateOrder(ctx, order))
+
+	orderSvc := st.newOrderService(&eventRecorder{}, time.Hour)
+	_, err = orderSvc.Cancel(ctx, buyer.ID, order.ID)
+	require.NoError(t, err)
+
+	// Монеты возвращаются, а остаток самого товара не меняется
+	assert.Equal(t, 1500, st.balance(t, buyer.ID))
+	catalog, err := merchSvc.Catalog(ctx, service.CatalogFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, intPtr(1), catalog[0].Stock)
+}
+
+func TestOrderService_CancelWindowExpired(t *testing.T) {
+	st := newTestStorage()
+	ctx := context.Background()
+	buyer := st.createUser(t, "buyer@example.com", 1000)
+	st.db.AddMerch("cup", 20)
+
+	buySvc := service.NewBuyService(newTestLogger(), st.txManager, st.userRepo, st.merchRepo, st.orderRepo, st.coinTxRepo, st.promoRepo, st.ledger)
+	require.NoError(t, buySvc.Buy(ctx, buyer.ID, "cup", "", ""))
+
+	orderSvc := st.newOrderService(&eventRecorder{}, time.Millisecond)
+	orders, err := orderSvc.List(ctx, buyer.ID, "")
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+
+	time.Sleep(5 * time.Millisecond)
+	_, err = orderSvc.Cancel(ctx, buyer.ID, orders[0].ID)
+	assert.ErrorIs(t, err, service.ErrCancelNotAllowed)
+	assert.Equal(t, 980, st.balance(t, buyer.ID))
+}
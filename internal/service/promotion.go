@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/storage"
+)
+
+// ErrInvalidPromotion — некорректные параметры акции.
+var ErrInvalidPromotion = errors.New("invalid promotion")
+
+// PromotionInput — параметры новой акции. Item и User ограничивают акцию товаром и покупателем
+// (пусто — без ограничения), Code — промокод (пусто — акция применяется автоматически).
+type PromotionInput struct {
+	Name           string
+	Code           string
+	DiscountType   string
+	DiscountValue  int
+	Item           string
+	User           string
+	StartsAt       *time.Time
+	EndsAt         *time.Time
+	MaxUses        int
+	MaxUsesPerUser int
+}
+
+// PromotionView — акция для администратора.
+type PromotionView struct {
+	ID            int64      `json:"id"`
+	Name          string     `json:"name"`
+	Code          string     `json:"code,omitempty"`
+	DiscountType  string     `json:"discountType"`
+	DiscountValue int        `json:"discountValue"`
+	Item          string     `json:"item,omitempty"`
+	User          string     `json:"user,omitempty"`
+	StartsAt      *time.Time `json:"startsAt,omitempty"`
+	EndsAt        *time.Time `json:"endsAt,omitempty"`
+	// MaxUses и MaxUsesPerUser — лимиты использований; 0 — без ограничения
+	MaxUses        int `json:"maxUses"`
+	MaxUsesPerUser int `json:"maxUsesPerUser"`
+	// Uses — сколько неотменённых заказов оформлено по акции
+	Uses      int       `json:"uses"`
+	Active    bool      `json:"active"`
+	CreatedBy string    `json:"createdBy,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// PromotionService — управление акциями и промокодами.
+type PromotionService interface {
+	// Create создаёт акцию от имени администратора adminID.
+	Create(ctx context.Context, adminID int64, input PromotionInput) (*PromotionView, error)
+	// List возвращает все акции от новых к старым.
+	List(ctx context.Context) ([]PromotionView, error)
+	// Deactivate выключает акцию: новые покупки по ней невозможны, оформленные заказы не меняются.
+	Deactivate(ctx context.Context, id int64) (*PromotionView, error)
+}
+
+type promotionService struct {
+	log       *slog.Logger
+	txManager storage.TxManager
+	userRepo  storage.UserStorage
+	merchRepo storage.MerchStorage
+	orderRepo storage.OrderStorage
+	promoRepo storage.PromotionStorage
+}
+
+// NewPromotionService создаёт сервис акций.
+func NewPromotionService(log *slog.Logger, txManager storage.TxManager, userRepo storage.UserStorage, merchRepo storage.MerchStorage, orderRepo storage.OrderStorage, promoRepo storage.PromotionStorage) PromotionService {
+	return &promotionService{
+		log:       log,
+		txManager: txManager,
+		userRepo:  userRepo,
+		merchRepo: merchRepo,
+		orderRepo: orderRepo,
+		promoRepo: promoRepo,
+	}
+}
+
+func (s *promotionService) Create(ctx context.Context, adminID int64, input PromotionInput) (*PromotionView, error) {
+	const op = "service.PromotionService.Create"
+	logger := s.log.With(slog.String("op", op), slog.String("name", input.Name))
+
+	promo := &models.Promotion{
+		Name:           strings.TrimSpace(input.Name),
+		Code:           normalizePromoCode(input.Code),
+		DiscountType:   input.DiscountType,
+		DiscountValue:  input.DiscountValue,
+		StartsAt:       input.StartsAt,
+		EndsAt:         input.EndsAt,
+		MaxUses:        input.MaxUses,
+		MaxUsesPerUser: input.MaxUsesPerUser,
+		Active:         true,
+		CreatedBy:      &adminID,
+	}
+	if err := validatePromotion(promo); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if input.Item != "" {
+		merch, err := s.merchRepo.GetMerchByName(ctx, input.Item)
+		if err != nil {
+			if errors.Is(err, storage.ErrMerchNotFound) {
+				return nil, fmt.Errorf("%s: %w: unknown item", op, ErrInvalidPromotion)
+			}
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		promo.MerchID = &merch.ID
+	}
+	if input.User != "" {
+		user, err := s.userRepo.GetUserByEmail(ctx, input.User)
+		if err != nil {
+			if errors.Is(err, storage.ErrUserNotFound) {
+				return nil, fmt.Errorf("%s: %w: unknown user", op, ErrInvalidPromotion)
+			}
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		promo.UserID = &user.ID
+	}
+
+	if err := s.promoRepo.CreatePromotion(ctx, promo); err != nil {
+		logger.Error("failed to create promotion", slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	logger.Info("promotion created", slog.Int64("id", promo.ID), slog.Int64("adminID", adminID))
+	return s.view(ctx, promo)
+}
+
+func (s *promotionService) List(ctx context.Context) ([]PromotionView, error) {
+	const op = "service.PromotionService.List"
+
+	promos, err := s.promoRepo.ListPromotions(ctx)
+	if err != nil {
+		s.log.Error("failed to list promotions", slog.String("op", op), slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	views := make([]PromotionView, 0, len(promos))
+	for i := range promos {
+		view, err := s.view(ctx, &promos[i])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		views = append(views, *view)
+	}
+	return views, nil
+}
+
+func (s *promotionService) Deactivate(ctx context.Context, id int64) (*PromotionView, error) {
+	const op = "service.PromotionService.Deactivate"
+
+	var promo *models.Promotion
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.promoRepo.SetPromotionActive(ctx, id, false); err != nil {
+			return err
+		}
+		var err error
+		promo, err = s.promoRepo.GetPromotionByID(ctx, id)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Info("promotion deactivated", slog.String("op", op), slog.Int64("id", id))
+	return s.view(ctx, promo)
+}
+
+// view дополняет акцию названием товара, email покупателя и автора и числом использований.
+func (s *promotionService) view(ctx context.Context, promo *models.Promotion) (*PromotionView, error) {
+	uses, err := s.orderRepo.CountPromotionOrders(ctx, promo.ID, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count promotion uses: %w", err)
+	}
+
+	view := &PromotionView{
+		ID:             promo.ID,
+		Name:           promo.Name,
+		Code:           promo.Code,
+		DiscountType:   promo.DiscountType,
+		DiscountValue:  promo.DiscountValue,
+		StartsAt:       promo.StartsAt,
+		EndsAt:         promo.EndsAt,
+		MaxUses:        promo.MaxUses,
+		MaxUsesPerUser: promo.MaxUsesPerUser,
+		Uses:           uses,
+		Active:         promo.Active,
+		CreatedAt:      promo.CreatedAt,
+	}
+	if promo.MerchID != nil {
+		merch, err := s.merchRepo.ListMerch(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list merch: %w", err)
+		}
+		for _, m := range merch {
+			if m.ID == *promo.MerchID {
+				view.Item = m.Name
+			}
+		}
+	}
+	if promo.UserID != nil {
+		if u, err := s.userRepo.GetUserByID(ctx, *promo.UserID); err == nil {
+			view.User = u.Email
+		}
+	}
+	if promo.CreatedBy != nil {
+		if u, err := s.userRepo.GetUserByID(ctx, *promo.CreatedBy); err == nil {
+			view.CreatedBy = u.Email
+		}
+	}
+	return view, nil
+}
+
+// validatePromotion проверяет тип и размер скидки, лимиты и период действия акции.
+func validatePromotion(promo *models.Promotion) error {
+	if promo.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidPromotion)
+	}
+	if strings.ContainsAny(promo.Code, " \t\n") {
+		return fmt.Errorf("%w: code must not contain spaces", ErrInvalidPromotion)
+	}
+	switch promo.DiscountType {
+	case models.DiscountPercent:
+		if promo.DiscountValue < 1 || promo.DiscountValue > 100 {
+			return fmt.Errorf("%w: percent discount must be between 1 and 100", ErrInvalidPromotion)
+		}
+	case models.DiscountFixed:
+		if promo.DiscountValue <= 0 {
+			return fmt.Errorf("%w: fixed discount must be positive", ErrInvalidPromotion)
+		}
+	default:
+		return fmt.Errorf("%w: unknown discount type %q", ErrInvalidPromotion, promo.DiscountType)
+	}
+	if promo.MaxUses < 0 || promo.MaxUsesPerUser < 0 {
+		return fmt.Errorf("%w: usage limits must not be negative", ErrInvalidPromotion)
+	}
+	if promo.StartsAt != nil && promo.EndsAt != nil && !promo.EndsAt.After(*promo.StartsAt) {
+		return fmt.Errorf("%w: endsAt must be after startsAt", ErrInvalidPromotion)
+	}
+	return nil
+}
+
+// normalizePromoCode приводит промокод к виду, в котором он хранится: без пробелов по краям, в верхнем регистре.
+func normalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// promotionApplies сообщает, действует ли акция в момент at для товара merchID и покупателя userID.
+func promotionApplies(promo *models.Promotion, merchID, userID int64, at time.Time) bool {
+	return promo.Active &&
+		(promo.MerchID == nil || *promo.MerchID == merchID) &&
+		(promo.UserID == nil || *promo.UserID == userID) &&
+		(promo.StartsAt == nil || !promo.StartsAt.After(at)) &&
+		(promo.EndsAt == nil || promo.EndsAt.After(at))
+}
+
+// promotionDiscount возвращает скидку акции на товар с ценой price; скидка не больше цены.
+func promotionDiscount(promo *models.Promotion, price int) int {
+	discount := promo.DiscountValue
+	if promo.DiscountType == models.DiscountPercent {
+		discount = price * promo.DiscountValue / 100
+	}
+	return min(discount, price)
+}
@@ -0,0 +1,75 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuyService_Promotions(t *testing.T) {
+	st := newTestStorage()
+	ctx := context.Background()
+	alice := st.createUser(t, "alice@example.com", 1000)
+	bob := st.createUser(t, "bob@example.com", 1000)
+	admin := st.createUser(t, "admin@example.com", 0)
+	st.db.AddMerch("hoody", 300)
+	st.db.AddMerch("cup", 20)
+
+	promoSvc := service.NewPromotionService(newTestLogger(), st.txManager, st.userRepo, st.merchRepo, st.orderRepo, st.promoRepo)
+	buySvc := service.NewBuyService(newTestLogger(), st.txManager, st.userRepo, st.merchRepo, st.orderRepo, st.coinTxRepo, st.promoRepo, st.ledger)
+	orderSvc := st.newOrderService(&eventRecorder{}, time.Hour)
+
+	// Автоматическая скидка 10% на худи, код на 100 монет — один раз на всех
+	_, err := promoSvc.Create(ctx, admin.ID, service.PromotionInput{Name: "autumn", DiscountType: models.DiscountPercent, DiscountValue: 10, Item: "hoody"})
+	require.NoError(t, err)
+	code, err := promoSvc.Create(ctx, admin.ID, service.PromotionInput{Name: "vip", Code: " vip100 ", DiscountType: models.DiscountFixed, DiscountValue: 100, MaxUses: 1})
+	require.NoError(t, err)
+	assert.Equal(t, "VIP100", code.Code)
+	_, err = promoSvc.Create(ctx, admin.ID, service.PromotionInput{Name: "bad", DiscountType: models.DiscountPercent, DiscountValue: 0})
+	assert.ErrorIs(t, err, service.ErrInvalidPromotion)
+	_, err = promoSvc.Create(ctx, admin.ID, service.PromotionInput{Name: "bad", DiscountType: models.DiscountFixed, DiscountValue: 5, Item: "unknown"})
+	assert.ErrorIs(t, err, service.ErrInvalidPromotion)
+
+	require.NoError(t, buySvc.Buy(ctx, alice.ID, "hoody", "", ""))
+	assert.Equal(t, 730, st.balance(t, alice.ID))
+	// Из двух акций применяется та, что выгоднее
+	require.NoError(t, buySvc.Buy(ctx, alice.ID, "hoody", "", "vip100"))
+	assert.Equal(t, 530, st.balance(t, alice.ID))
+	assert.ErrorIs(t, buySvc.Buy(ctx, bob.ID, "hoody", "", "VIP100"), service.ErrPromoCodeExhausted)
+	assert.ErrorIs(t, buySvc.Buy(ctx, bob.ID, "cup", "", "nope"), service.ErrInvalidPromoCode)
+	assert.Equal(t, 1000, st.balance(t, bob.ID))
+
+	orders, err := orderSvc.List(ctx, alice.ID, "")
+	require.NoError(t, err)
+	require.Len(t, orders, 2)
+	assert.Equal(t, 200, orders[0].TotalPrice)
+	assert.Equal(t, 100, orders[0].Discount)
+	assert.Equal(t, "vip", orders[0].Promotion)
+	assert.Equal(t, 30, orders[1].Discount)
+	assert.Equal(t, "autumn", orders[1].Promotion)
+
+	// Отмена заказа возвращает оплаченную сумму и освобождает использование кода
+	_, err = orderSvc.Cancel(ctx, alice.ID, orders[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, 730, st.balance(t, alice.ID))
+	require.NoError(t, buySvc.Buy(ctx, bob.ID, "cup", "", "vip100"))
+	assert.Equal(t, 1000, st.balance(t, bob.ID))
+
+	// Выключенная акция больше не применяется
+	_, err = promoSvc.Deactivate(ctx, code.ID)
+	require.NoError(t, err)
+	assert.ErrorIs(t, buySvc.Buy(ctx, alice.ID, "cup", "", "vip100"), service.ErrInvalidPromoCode)
+
+	promos, err := promoSvc.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, promos, 2)
+	assert.Equal(t, 1, promos[0].Uses)
+	assert.False(t, promos[0].Active)
+	assert.Equal(t, "hoody", promos[1].Item)
+	assert.Equal(t, admin.Email, promos[1].CreatedBy)
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/storage"
+)
+
+// MaxCoinRequests — ограничение на число входящих и исходящих запросов в одном ответе.
+const MaxCoinRequests = 100
+
+var (
+	// ErrInvalidCoinRequest — некорректный запрос монет (сумма, получатель).
+	ErrInvalidCoinRequest = errors.New("invalid coin request")
+	// ErrCoinRequestResolved — запрос уже принят, отклонён или истёк.
+	ErrCoinRequestResolved = errors.New("coin request is no longer pending")
+)
+
+// CoinRequestView — запрос монет с email участников.
+type CoinRequestView struct {
+	ID         int64      `json:"id"`
+	FromUser   string     `json:"fromUser"` // кто просит монеты
+	ToUser     string     `json:"toUser"`   // у кого просят (плательщик)
+	Amount     int        `json:"amount"`
+	Comment    string     `json:"comment,omitempty"`
+	Status     string     `json:"status"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+}
+
+// CoinRequests — запросы монет пользователя.
+type CoinRequests struct {
+	Incoming []CoinRequestView `json:"incoming"` // запросы к пользователю
+	Outgoing []CoinRequestView `json:"outgoing"` // запросы пользователя к коллегам
+}
+
+// CoinRequestService — запросы монет у коллег: пользователь просит перевод,
+// плательщик принимает (выполняется обычный перевод через SendCoinService) или отклоняет.
+type CoinRequestService interface {
+	// Create создаёт запрос requesterID к пользователю payer на amount монет.
+	Create(ctx context.Context, requesterID int64, payer string, amount int, comment string) (*CoinRequestView, error)
+	// List возвращает последние входящие и исходящие запросы пользователя.
+	List(ctx context.Context, userID int64) (*CoinRequests, error)
+	// Accept принимает запрос: плательщик payerID переводит монеты инициатору.
+	Accept(ctx context.Context, payerID, requestID int64) error
+	// Decline отклоняет запрос без перевода.
+	Decline(ctx context.Context, payerID, requestID int64) error
+	// ExpireStale переводит в expired запросы, не рассмотренные до now, и возвращает их число.
+	ExpireStale(ctx context.Context, now time.Time) (int, error)
+}
+
+type coinRequestService struct {
+	log         *slog.Logger
+	txManager   storage.TxManager
+	userRepo    storage.UserStorage
+	requestRepo storage.CoinRequestStorage
+	sendCoin    SendCoinService
+	ttl         time.Duration
+}
+
+func NewCoinRequestService(log *slog.Logger, txManager storage.TxManager, userRepo storage.UserStorage, requestRepo storage.CoinRequestStorage, sendCoin SendCoinService, ttl time.Duration) CoinRequestService {
+	if ttl <= 0 {
+		ttl = 7 * 24 * time.Hour
+	}
+	return &coinRequestService{
+		log:         log,
+		txManager:   txManager,
+		userRepo:    userRepo,
+		requestRepo: requestRepo,
+		sendCoin:    sendCoin,
+		ttl:         ttl,
+	}
+}
+
+func (s *coinRequestService) Create(ctx context.Context, requesterID int64, payer string, amount int, comment string) (*CoinRequestView, error) {
+	const op = "service.CoinRequestService.Create"
+	logger := s.log.With(
+		slog.String("op", op),
+		slog.Int64("requesterID", requesterID),
+		slog.String("payer", payer),
+		slog.Int("amount", amount),
+	)
+
+	if amount <= 0 {
+		return nil, fmt.Errorf("%s: %w: amount must be positive", op, ErrInvalidCoinRequest)
+	}
+	comment, err := sanitizeComment(comment)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	requester, err := s.userRepo.GetUserByID(ctx, requesterID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get requester: %w", op, err)
+	}
+	payerUser, err := s.userRepo.GetUserByEmail(ctx, payer)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return nil, fmt.Errorf("%s: %w: payer not found", op, ErrInvalidCoinRequest)
+		}
+		return nil, fmt.Errorf("%s: failed to get payer: %w", op, err)
+	}
+	if payerUser.ID == requesterID {
+		return nil, fmt.Errorf("%s: %w: cannot request coins from yourself", op, ErrInvalidCoinRequest)
+	}
+
+	req := &models.CoinRequest{
+		RequesterID: requesterID,
+		PayerID:     payerUser.ID,
+		Amount:      amount,
+		Comment:     comment,
+		Status:      models.CoinRequestPending,
+		ExpiresAt:   time.Now().Add(s.ttl),
+	}
+	if err := s.requestRepo.CreateCoinRequest(ctx, req); err != nil {
+		logger.Error("failed to create coin request", slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	logger.Info("coin request created", slog.Int64("requestID", req.ID))
+	view := newCoinRequestView(req, requester.Email, payerUser.Email, time.Now())
+	return &view, nil
+}
+
+func (s *coinRequestService) List(ctx context.Context, userID int64) (*CoinRequests, error) {
+	const op = "service.CoinRequestService.List"
+
+	incoming, err := s.requestRepo.ListCoinRequests(ctx, userID, true, MaxCoinRequests)
+	if err != nil {
+		s.log.Error("failed to list coin requests", slog.String("op", op), slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	outgoing, err := s.requestRepo.ListCoinRequests(ctx, userID, false, MaxCoinRequests)
+	if err != nil {
+		s.log.Error("failed to list coin requests", slog.String("op", op), slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now()
+	// email участников: один коллега обычно встречается в списке много раз
+	emails := make(map[int64]string)
+	email := func(id int64) string {
+		if e, ok := emails[id]; ok {
+			return e
+		}
+		if u, err := s.userRepo.GetUserByID(ctx, id); err == nil {
+			emails[id] = u.Email
+		}
+		return emails[id]
+	}
+
+	res := &CoinRequests{
+		Incoming: make([]CoinRequestView, 0, len(incoming)),
+		Outgoing: make([]CoinRequestView, 0, len(outgoing)),
+	}
+	for i := range incoming {
+		res.Incoming = append(res.Incoming, newCoinRequestView(&incoming[i], email(incoming[i].RequesterID), email(incoming[i].PayerID), now))
+	}
+	for i := range outgoing {
+		res.Outgoing = append(res.Outgoing, newCoinRequestView(&outgoing[i], email(outgoing[i].RequesterID), email(outgoing[i].PayerID), now))
+	}
+	return res, nil
+}
+
+func (s *coinRequestService) Accept(ctx context.Context, payerID, requestID int64) error {
+	const op = "service.CoinRequestService.Accept"
+	logger := s.log.With(slog.String("op", op), slog.Int64("payerID", payerID), slog.Int64("requestID", requestID))
+
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		req, err := s.lockPending(ctx, payerID, requestID)
+		if err != nil {
+			return err
+		}
+		requester, err := s.userRepo.GetUserByID(ctx, req.RequesterID)
+		if err != nil {
+			return fmt.Errorf("failed to get requester: %w", err)
+		}
+		// Перевод выполняется в этой же транзакции со всеми проверками и лимитами SendCoin
+		if err := s.sendCoin.SendCoin(ctx, payerID, requester.Email, req.Amount, req.Comment); err != nil {
+			return err
+		}
+		return s.requestRepo.ResolveCoinRequest(ctx, req.ID, models.CoinRequestAccepted, time.Now())
+	})
+	if err != nil {
+		logger.Error("failed to accept coin request", slog.Any("error", err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	logger.Info("coin request accepted")
+	return nil
+}
+
+func (s *coinRequestService) Decline(ctx context.Context, payerID, requestID int64) error {
+	const op = "service.CoinRequestService.Decline"
+	logger := s.log.With(slog.String("op", op), slog.Int64("payerID", payerID), slog.Int64("requestID", requestID))
+
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		req, err := s.lockPending(ctx, payerID, requestID)
+		if err != nil {
+			return err
+		}
+		return s.requestRepo.ResolveCoinRequest(ctx, req.ID, models.CoinRequestDeclined, time.Now())
+	})
+	if err != nil {
+		logger.Error("failed to decline coin request", slog.Any("error", err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	logger.Info("coin request declined")
+	return nil
+}
+
+// lockPending блокирует запрос requestID, адресованный payerID, и проверяет, что он ещё ждёт ответа.
+// Чужой запрос для плательщика не существует.
+func (s *coinRequestService) lockPending(ctx context.Context, payerID, requestID int64) (*models.CoinRequest, error) {
+	req, err := s.requestRepo.LockCoinRequest(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if req.PayerID != payerID {
+		return nil, storage.ErrCoinRequestNotFound
+	}
+	if req.Status != models.CoinRequestPending {
+		return nil, fmt.Errorf("%w: %s", ErrCoinRequestResolved, req.Status)
+	}
+	// срок мог истечь до запуска задачи, которая переводит такие запросы в expired
+	if !req.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: %s", ErrCoinRequestResolved, models.CoinRequestExpired)
+	}
+	return req, nil
+}
+
+func (s *coinRequestService) ExpireStale(ctx context.Context, now time.Time) (int, error) {
+	const op = "service.CoinRequestService.ExpireStale"
+
+	expired, err := s.requestRepo.ExpireCoinRequests(ctx, now)
+	if err != nil {
+		s.log.Error("failed to expire coin requests", slog.String("op", op), slog.Any("error", err))
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	s.log.Info("coin requests expired", slog.String("op", op), slog.Int("count", expired))
+	return expired, nil
+}
+
+// newCoinRequestView показывает просроченный, но ещё не обработанный задачей запрос как expired.
+func newCoinRequestView(req *models.CoinRequest, from, to string, now time.Time) CoinRequestView {
+	status := req.Status
+	if status == models.CoinRequestPending && !req.ExpiresAt.After(now) {
+		status = models.CoinRequestExpired
+	}
+	return CoinRequestView{
+		ID:         req.ID,
+		FromUser:   from,
+		ToUser:     to,
+		Amount:     req.Amount,
+		Comment:    req.Comment,
+		Status:     status,
+		ExpiresAt:  req.ExpiresAt,
+		CreatedAt:  req.CreatedAt,
+		ResolvedAt: req.ResolvedAt,
+	}
+}
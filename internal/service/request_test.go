@@ -0,0 +1,133 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/service"
+	"github.com/linemk/avito-shop/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *testStorage) newCoinRequestService(limits service.TransferLimits) service.CoinRequestService {
+	sendCoin := service.NewSendCoinService(newTestLogger(), s.txManager, s.userRepo, s.coinTxRepo, s.ledger, s.notifier, limits)
+	return service.NewCoinRequestService(newTestLogger(), s.txManager, s.userRepo, s.requests, sendCoin, time.Hour)
+}
+
+func TestCoinRequestService_Accept(t *testing.T) {
+	st := newTestStorage()
+	alice := st.createUser(t, "alice@example.com", 0)
+	bob := st.createUser(t, "bob@example.com", 500)
+	carol := st.createUser(t, "carol@example.com", 500)
+	svc := st.newCoinRequestService(service.TransferLimits{})
+	ctx := context.Background()
+
+	req, err := svc.Create(ctx, alice.ID, bob.Email, 200, "за пиццу")
+	require.NoError(t, err)
+	assert.Equal(t, models.CoinRequestPending, req.Status)
+
+	list, err := svc.List(ctx, bob.ID)
+	require.NoError(t, err)
+	require.Len(t, list.Incoming, 1)
+	assert.Equal(t, alice.Email, list.Incoming[0].FromUser)
+	assert.Empty(t, list.Outgoing)
+
+	// Чужой запрос принять нельзя
+	err = svc.Accept(ctx, carol.ID, req.ID)
+	assert.ErrorIs(t, err, storage.ErrCoinRequestNotFound)
+
+	require.NoError(t, svc.Accept(ctx, bob.ID, req.ID))
+	assert.Equal(t, 200, st.balance(t, alice.ID))
+	assert.Equal(t, 300, st.balance(t, bob.ID))
+
+	// Перевод записан с комментарием запроса
+	txs, err := st.coinTxRepo.GetTransactionsByUserID(ctx, alice.ID)
+	require.NoError(t, err)
+	require.Len(t, txs, 1)
+	assert.Equal(t, models.CoinTxTransferReceived, txs[0].Type)
+	assert.Equal(t, "за пиццу", txs[0].Comment)
+
+	// Повторно принять или отклонить уже принятый запрос нельзя
+	assert.ErrorIs(t, svc.Accept(ctx, bob.ID, req.ID), service.ErrCoinRequestResolved)
+	assert.ErrorIs(t, svc.Decline(ctx, bob.ID, req.ID), service.ErrCoinRequestResolved)
+	assert.Equal(t, 300, st.balance(t, bob.ID))
+
+	list, err = svc.List(ctx, alice.ID)
+	require.NoError(t, err)
+	require.Len(t, list.Outgoing, 1)
+	assert.Equal(t, models.CoinRequestAccepted, list.Outgoing[0].Status)
+	assert.NotNil(t, list.Outgoing[0].ResolvedAt)
+}
+
+func TestCoinRequestService_AcceptFailsKeepsPending(t *testing.T) {
+	st := newTestStorage()
+	alice := st.createUser(t, "alice@example.com", 0)
+	bob := st.createUser(t, "bob@example.com", 500)
+	svc := st.newCoinRequestService(service.TransferLimits{MaxAmount: 100})
+	ctx := context.Background()
+
+	req, err := svc.Create(ctx, alice.ID, bob.Email, 200, "")
+	require.NoError(t, err)
+
+	// Перевод проверяется лимитами SendCoin; при ошибке запрос остаётся в ожидании
+	assert.ErrorIs(t, svc.Accept(ctx, bob.ID, req.ID), service.ErrTransferLimit)
+	assert.Equal(t, 500, st.balance(t, bob.ID))
+
+	require.NoError(t, svc.Decline(ctx, bob.ID, req.ID))
+	list, err := svc.List(ctx, bob.ID)
+	require.NoError(t, err)
+	require.Len(t, list.Incoming, 1)
+	assert.Equal(t, models.CoinRequestDeclined, list.Incoming[0].Status)
+}
+
+func TestCoinRequestService_Invalid(t *testing.T) {
+	st := newTestStorage()
+	alice := st.createUser(t, "alice@example.com", 0)
+	svc := st.newCoinRequestService(service.TransferLimits{})
+	ctx := context.Background()
+
+	_, err := svc.Create(ctx, alice.ID, alice.Email, 10, "")
+	assert.ErrorIs(t, err, service.ErrInvalidCoinRequest)
+	_, err = svc.Create(ctx, alice.ID, "nobody@example.com", 10, "")
+	assert.ErrorIs(t, err, service.ErrInvalidCoinRequest)
+	_, err = svc.Create(ctx, alice.ID, "bob@example.com", 0, "")
+	assert.ErrorIs(t, err, service.ErrInvalidCoinRequest)
+}
+
+func TestCoinRequestService_Expiration(t *testing.T) {
+	st := newTestStorage()
+	alice := st.createUser(t, "alice@example.com", 0)
+	bob := st.createUser(t, "bob@example.com", 500)
+	svc := st.newCoinRequestService(service.TransferLimits{})
+	ctx := context.Background()
+
+	stale := &models.CoinRequest{
+		RequesterID: alice.ID,
+		PayerID:     bob.ID,
+		Amount:      50,
+		Status:      models.CoinRequestPending,
+		ExpiresAt:   time.Now().Add(-time.Minute),
+	}
+	require.NoError(t, st.requests.CreateCoinRequest(ctx, stale))
+	fresh, err := svc.Create(ctx, alice.ID, bob.Email, 50, "")
+	require.NoError(t, err)
+
+	// Просроченный запрос нельзя принять, даже если задача его ещё не обработала
+	assert.ErrorIs(t, svc.Accept(ctx, bob.ID, stale.ID), service.ErrCoinRequestResolved)
+	list, err := svc.List(ctx, bob.ID)
+	require.NoError(t, err)
+	require.Len(t, list.Incoming, 2)
+	assert.Equal(t, models.CoinRequestExpired, list.Incoming[1].Status)
+
+	expired, err := svc.ExpireStale(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	expired, err = svc.ExpireStale(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, expired)
+
+	require.NoError(t, svc.Accept(ctx, bob.ID, fresh.ID))
+}
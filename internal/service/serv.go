@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/linemk/avito-shop/internal/domain/models"
@@ -13,17 +14,34 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// ErrRegistrationForbidden — email зарезервирован за администратором и не регистрируется при входе.
+var ErrRegistrationForbidden = errors.New("registration is not allowed for this email")
+
 type AuthService struct {
-	log      *slog.Logger
-	userRepo storage.UserStorage
-	tokenTTL time.Duration
+	log          *slog.Logger
+	txManager    storage.TxManager
+	userRepo     storage.UserStorage
+	ledger       *Ledger
+	tokenTTL     time.Duration
+	welcomeBonus WelcomeBonus
+	reserved     map[string]bool
 }
 
-func NewAuthService(log *slog.Logger, userRepo storage.UserStorage, tokenTTL time.Duration) *AuthService {
+// NewAuthService создаёт сервис входа. Email из reserved (администраторы из конфига) не регистрируются
+// автоматически: такие учётные записи заводятся только фикстурами.
+func NewAuthService(log *slog.Logger, txManager storage.TxManager, userRepo storage.UserStorage, ledger *Ledger, tokenTTL time.Duration, welcomeBonus WelcomeBonus, reserved []string) *AuthService {
+	reservedSet := make(map[string]bool, len(reserved))
+	for _, email := range reserved {
+		reservedSet[strings.ToLower(strings.TrimSpace(email))] = true
+	}
 	return &AuthService{
-		log:      log,
-		userRepo: userRepo,
-		tokenTTL: tokenTTL,
+		log:          log,
+		txManager:    txManager,
+		userRepo:     userRepo,
+		ledger:       ledger,
+		tokenTTL:     tokenTTL,
+		welcomeBonus: welcomeBonus,
+		reserved:     reservedSet,
 	}
 }
 
@@ -47,6 +65,10 @@ func (a *AuthService) Login(ctx context.Context, email, password string) (string
 	user, err := a.userRepo.GetUserByEmail(ctx, email)
 	if err != nil {
 		if errors.Is(err, storage.ErrUserNotFound) {
+			if a.reserved[strings.ToLower(email)] {
+				logger.Warn("refusing to register reserved email")
+				return "", fmt.Errorf("%s: %w", op, ErrRegistrationForbidden)
+			}
 			logger.Info("user not found, creating new user")
 			// Хеширование пароля с помощью bcrypt (автоматически добавляет соль)
 			passHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -54,12 +76,12 @@ func (a *AuthService) Login(ctx context.Context, email, password string) (string
 				logger.Error("failed to hash password", slog.Any("error", err))
 				return "", fmt.Errorf("%s: failed to hash password: %w", op, err)
 			}
-			newUser := &models.User{
-				Email:       email,
-				PassHash:    passHash,
-				CoinBalance: 1000, // начальный баланс
+			user, err = a.register(ctx, email, passHash)
+			if errors.Is(err, storage.ErrUserExists) {
+				// Пользователя успел создать параллельный запрос — проверяем пароль как для существующего
+				logger.Info("user was created concurrently")
+				return a.loginExisting(ctx, logger, email, password)
 			}
-			user, err = a.userRepo.CreateUser(ctx, newUser)
 			if err != nil {
 				logger.Error("failed to create user", slog.Any("error", err))
 				return "", fmt.Errorf("%s: failed to create user: %w", op, err)
@@ -76,6 +98,56 @@ func (a *AuthService) Login(ctx context.Context, email, password string) (string
 		}
 	}
 
+	return a.issueToken(ctx, logger, user)
+}
+
+// register создаёт пользователя и в той же транзакции начисляет стартовый бонус
+// операцией welcome_bonus, чтобы он был виден в истории.
+func (a *AuthService) register(ctx context.Context, email string, passHash []byte) (*models.User, error) {
+	bonus := a.welcomeBonus.AmountFor(email, time.Now())
+
+	var user *models.User
+	err := a.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		user, err = a.userRepo.CreateUser(ctx, &models.User{
+			Email:    email,
+			PassHash: passHash,
+		})
+		if err != nil || bonus <= 0 {
+			return err
+		}
+
+		user, err = a.ledger.apply(ctx, &models.CoinTransaction{
+			UserID:  user.ID,
+			Amount:  bonus,
+			Type:    models.CoinTxWelcomeBonus,
+			Comment: "welcome bonus",
+		})
+		return err
+	})
+	return user, err
+}
+
+// loginExisting проверяет пароль уже существующего пользователя и выдаёт токен.
+func (a *AuthService) loginExisting(ctx context.Context, logger *slog.Logger, email, password string) (string, error) {
+	const op = "auth.Login"
+
+	user, err := a.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		logger.Error("failed to get user", slog.Any("error", err))
+		return "", fmt.Errorf("%s: failed to get user: %w", op, err)
+	}
+	if err := bcrypt.CompareHashAndPassword(user.PassHash, []byte(password)); err != nil {
+		logger.Warn("invalid password")
+		return "", fmt.Errorf("%s: invalid credentials: %w", op, err)
+	}
+	return a.issueToken(ctx, logger, user)
+}
+
+// issueToken генерирует JWT-токен для пользователя.
+func (a *AuthService) issueToken(ctx context.Context, logger *slog.Logger, user *models.User) (string, error) {
+	const op = "auth.Login"
+
 	// Генерация JWT-токена. Функция auth.NewToken внутри сама загружает секрет из переменной окружения JWT_SECRET.
 	token, err := security.NewToken(ctx, user, a.tokenTTL)
 	if err != nil {
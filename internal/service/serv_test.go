@@ -2,137 +2,103 @@ package service_test
 
 import (
 	"context"
-	"database/sql"
-	"errors"
 	"log/slog"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
-	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/linemk/avito-shop/internal/domain/models"
 	"github.com/linemk/avito-shop/internal/service"
 	"github.com/linemk/avito-shop/internal/storage"
+	"github.com/linemk/avito-shop/internal/storage/memory"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
 )
 
-type fakeUserRepo struct {
-	users map[string]*models.User // ключ — email
+// testStorage — хранилище в памяти, общее для всех тестов сервисов.
+type testStorage struct {
+	db         *memory.DB
+	txManager  storage.TxManager
+	userRepo   storage.UserStorage
+	merchRepo  storage.MerchStorage
+	orderRepo  storage.OrderStorage
+	coinTxRepo storage.CoinTransactionStorage
+	lotRepo    storage.CoinLotStorage
+	requests   storage.CoinRequestStorage
+	promoRepo  storage.PromotionStorage
+	wishlists  storage.WishlistStorage
+	notes      storage.NotificationStorage
+	notifier   *service.Notifier
+	wishlist   *service.WishlistNotifier
+	ledger     *service.Ledger
 }
 
-var _ storage.UserStorage = (*fakeUserRepo)(nil)
-
-func newFakeUserRepo() *fakeUserRepo {
-	return &fakeUserRepo{users: make(map[string]*models.User)}
-}
-
-func (f *fakeUserRepo) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
-	user, ok := f.users[email]
-	if !ok {
-		return nil, storage.ErrUserNotFound
-	}
-	return user, nil
-}
-
-func (f *fakeUserRepo) CreateUser(ctx context.Context, user *models.User) (*models.User, error) {
-	user.ID = int64(len(f.users) + 1)
-	f.users[user.Email] = user
-	return user, nil
-}
-
-func (f *fakeUserRepo) GetUserByID(ctx context.Context, id int64) (*models.User, error) {
-	for _, u := range f.users {
-		if u.ID == id {
-			return u, nil
-		}
-	}
-	return nil, storage.ErrUserNotFound
-}
-
-func (f *fakeUserRepo) LockUserByIDTx(ctx context.Context, tx *sql.Tx, id int64) (*models.User, error) {
-	return f.GetUserByID(ctx, id)
-}
-
-func (f *fakeUserRepo) UpdateUserBalance(ctx context.Context, tx *sql.Tx, id int64, newBalance int) error {
-	for _, u := range f.users {
-		if u.ID == id {
-			u.CoinBalance = newBalance
-			return nil
-		}
-	}
-	return storage.ErrUserNotFound
-}
-
-type fakeOrderRepo struct {
-	orders map[int64][]*models.Order // ключ: userID
-}
-
-var _ storage.OrderStorage = (*fakeOrderRepo)(nil)
-
-func newFakeOrderRepo() *fakeOrderRepo {
-	return &fakeOrderRepo{orders: make(map[int64][]*models.Order)}
-}
-
-func (f *fakeOrderRepo) GetOrdersByUserID(ctx context.Context, userID int64) ([]*models.Order, error) {
-	if orders, ok := f.orders[userID]; ok {
-		return orders, nil
-	}
-	return []*models.Order{}, nil
-}
-
-func (f *fakeOrderRepo) CreateOrder(ctx context.Context, tx *sql.Tx, userID int64, merchID int64, quantity int, totalPrice int) error {
-	// Не требуется для теста InfoService
-	return nil
-}
-
-type fakeMerchRepo struct {
-	merchs map[string]*models.Merch // ключ — название мерча
-}
-
-var _ storage.MerchStorage = (*fakeMerchRepo)(nil)
-
-func newFakeMerchRepo() *fakeMerchRepo {
-	return &fakeMerchRepo{merchs: make(map[string]*models.Merch)}
+func newTestStorage() *testStorage {
+	return newTestStorageWithExpiration(service.CoinExpiration{})
 }
 
-func (f *fakeMerchRepo) GetMerchByName(ctx context.Context, tx *sql.Tx, name string) (*models.Merch, error) {
-	merch, ok := f.merchs[name]
-	if !ok {
-		return nil, errors.New("merch not found")
+// newTestStorageWithExpiration создаёт тестовое хранилище, в котором монеты сгорают по правилам expiration.
+func newTestStorageWithExpiration(expiration service.CoinExpiration) *testStorage {
+	db := memory.New()
+	st := &testStorage{
+		db:         db,
+		txManager:  memory.NewTxManager(db),
+		userRepo:   memory.NewUserRepository(db),
+		merchRepo:  memory.NewMerchRepository(db),
+		orderRepo:  memory.NewOrderRepository(db),
+		coinTxRepo: memory.NewCoinTransactionRepository(db),
+		lotRepo:    memory.NewCoinLotRepository(db),
+		requests:   memory.NewCoinRequestRepository(db),
+		promoRepo:  memory.NewPromotionRepository(db),
+		wishlists:  memory.NewWishlistRepository(db),
+		notes:      memory.NewNotificationRepository(db),
 	}
-	return merch, nil
+	st.notifier = service.NewNotifier(st.notes)
+	st.wishlist = service.NewWishlistNotifier(st.wishlists, st.notifier)
+	st.ledger = service.NewLedger(st.userRepo, st.coinTxRepo, st.lotRepo, expiration, st.wishlist)
+	return st
 }
 
-type fakeCoinTxRepo struct {
-	transactions map[int64][]*models.CoinTransaction // ключ: userID
+// createUser добавляет пользователя с заданным балансом.
+func (s *testStorage) createUser(t *testing.T, email string, balance int) *models.User {
+	t.Helper()
+	user, err := s.userRepo.CreateUser(context.Background(), &models.User{
+		Email:       email,
+		PassHash:    []byte("hashed"),
+		CoinBalance: balance,
+	})
+	require.NoError(t, err)
+	return user
 }
 
-var _ storage.CoinTransactionStorage = (*fakeCoinTxRepo)(nil)
-
-func newFakeCoinTxRepo() *fakeCoinTxRepo {
-	return &fakeCoinTxRepo{transactions: make(map[int64][]*models.CoinTransaction)}
+// balance возвращает текущий баланс пользователя.
+func (s *testStorage) balance(t *testing.T, userID int64) int {
+	t.Helper()
+	user, err := s.userRepo.GetUserByID(context.Background(), userID)
+	require.NoError(t, err)
+	return user.CoinBalance
 }
 
-func (f *fakeCoinTxRepo) GetTransactionsByUserID(ctx context.Context, userID int64) ([]*models.CoinTransaction, error) {
-	if txs, ok := f.transactions[userID]; ok {
-		return txs, nil
-	}
-	return []*models.CoinTransaction{}, nil
+// newAuthService создаёт AuthService поверх тестового хранилища с заданными правилами бонуса;
+// hr@example.com зарезервирован за администратором.
+func (s *testStorage) newAuthService(bonus service.WelcomeBonus) *service.AuthService {
+	return service.NewAuthService(newTestLogger(), s.txManager, s.userRepo, s.ledger, 60*time.Minute, bonus,
+		[]string{"HR@example.com"})
 }
 
-func (f *fakeCoinTxRepo) CreateTransaction(ctx context.Context, tx *sql.Tx, userID int64, amount int, txType string, relatedUserID *int64) error {
-	// Не требуется для теста InfoService
-	return nil
+func newTestLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stdout, nil))
 }
 
 func TestAuthService_Login_NewUser(t *testing.T) {
 	os.Setenv("JWT_SECRET", "testsecret")
 	defer os.Unsetenv("JWT_SECRET")
 
-	fakeRepo := newFakeUserRepo()
-	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
-	authSvc := service.NewAuthService(logger, fakeRepo, 60*time.Minute)
+	st := newTestStorage()
+	authSvc := st.newAuthService(service.WelcomeBonus{Amount: 1000})
 	ctx := context.Background()
 
 	email := "newuser@example.com"
@@ -142,20 +108,43 @@ func TestAuthService_Login_NewUser(t *testing.T) {
 	assert.NoError(t, err, "Login should succeed for a new user")
 	assert.NotEmpty(t, token, "Token should not be empty")
 
-	user, err := fakeRepo.GetUserByEmail(ctx, email)
+	user, err := st.userRepo.GetUserByEmail(ctx, email)
 	assert.NoError(t, err, "User should exist after creation")
 	assert.Equal(t, 1000, user.CoinBalance, "Initial coin balance should be 1000")
 	// Проверяем, что пароль хэширован (не равен исходному паролю)
 	assert.NotEqual(t, password, string(user.PassHash), "Password should be hashed")
 }
 
+func TestAuthService_Login_ReservedEmail(t *testing.T) {
+	os.Setenv("JWT_SECRET", "testsecret")
+	defer os.Unsetenv("JWT_SECRET")
+
+	st := newTestStorage()
+	authSvc := st.newAuthService(service.WelcomeBonus{Amount: 1000})
+	ctx := context.Background()
+
+	// Email администратора не регистрируется первым вошедшим
+	_, err := authSvc.Login(ctx, "hr@example.com", "password123")
+	assert.ErrorIs(t, err, service.ErrRegistrationForbidden)
+	_, err = st.userRepo.GetUserByEmail(ctx, "hr@example.com")
+	assert.ErrorIs(t, err, storage.ErrUserNotFound)
+
+	// Заведённая фикстурой учётная запись входит как обычно
+	hashed, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	require.NoError(t, err)
+	_, err = st.userRepo.CreateUser(ctx, &models.User{Email: "hr@example.com", PassHash: hashed, IsAdmin: true})
+	require.NoError(t, err)
+	token, err := authSvc.Login(ctx, "hr@example.com", "password123")
+	require.NoError(t, err)
+	assert.NotEmpty(t, token)
+}
+
 func TestAuthService_Login_ExistingUser_CorrectPassword(t *testing.T) {
 	os.Setenv("JWT_SECRET", "testsecret")
 	defer os.Unsetenv("JWT_SECRET")
 
-	fakeRepo := newFakeUserRepo()
-	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
-	authSvc := service.NewAuthService(logger, fakeRepo, 60*time.Minute)
+	st := newTestStorage()
+	authSvc := st.newAuthService(service.WelcomeBonus{Amount: 1000})
 	ctx := context.Background()
 
 	email := "existing@example.com"
@@ -167,7 +156,7 @@ func TestAuthService_Login_ExistingUser_CorrectPassword(t *testing.T) {
 		PassHash:    hashed,
 		CoinBalance: 1000,
 	}
-	_, err = fakeRepo.CreateUser(ctx, user)
+	_, err = st.userRepo.CreateUser(ctx, user)
 	assert.NoError(t, err)
 
 	token, err := authSvc.Login(ctx, email, password)
@@ -179,9 +168,8 @@ func TestAuthService_Login_ExistingUser_WrongPassword(t *testing.T) {
 	os.Setenv("JWT_SECRET", "testsecret")
 	defer os.Unsetenv("JWT_SECRET")
 
-	fakeRepo := newFakeUserRepo()
-	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
-	authSvc := service.NewAuthService(logger, fakeRepo, 60*time.Minute)
+	st := newTestStorage()
+	authSvc := st.newAuthService(service.WelcomeBonus{Amount: 1000})
 	ctx := context.Background()
 
 	email := "existing@example.com"
@@ -193,7 +181,7 @@ func TestAuthService_Login_ExistingUser_WrongPassword(t *testing.T) {
 		PassHash:    hashed,
 		CoinBalance: 1000,
 	}
-	_, err = fakeRepo.CreateUser(ctx, user)
+	_, err = st.userRepo.CreateUser(ctx, user)
 	assert.NoError(t, err)
 
 	token, err := authSvc.Login(ctx, email, "wrongpassword")
@@ -202,67 +190,24 @@ func TestAuthService_Login_ExistingUser_WrongPassword(t *testing.T) {
 }
 
 func TestInfoService_GetInfo_Success(t *testing.T) {
-	// Создаем фиктивные репозитории.
-	userRepo := newFakeUserRepo()
-	orderRepo := newFakeOrderRepo()
-	coinTxRepo := newFakeCoinTxRepo()
+	st := newTestStorage()
+	ctx := context.Background()
 
-	// Добавляем пользователя с балансом 920, ID=1.
-	user := &models.User{
-		ID:          1,
-		Email:       "test@example.com",
-		PassHash:    []byte("hashed-password"),
-		CoinBalance: 920,
-	}
+	// Добавляем пользователя с балансом 920 и отправителя монет.
+	user := st.createUser(t, "test@example.com", 920)
+	other := st.createUser(t, "other@example.com", 1000)
 
-	userRepo.users[user.Email] = user
-
-	// Добавляем пару заказов для пользователя (например, куплена футболка: 2 единицы)
-	orderRepo.orders[user.ID] = []*models.Order{
-		{
-			ID:         1,
-			UserID:     user.ID,
-			MerchID:    1,
-			MerchName:  "t-shirt",
-			Quantity:   1,
-			TotalPrice: 80,
-			CreatedAt:  time.Now().Add(-time.Hour),
-		},
-		{
-			ID:         2,
-			UserID:     user.ID,
-			MerchID:    1,
-			MerchName:  "t-shirt",
-			Quantity:   1,
-			TotalPrice: 80,
-			CreatedAt:  time.Now().Add(-30 * time.Minute),
-		},
-	}
+	// Добавляем пару заказов для пользователя (куплена футболка: 2 единицы)
+	tShirt := st.db.AddMerch("t-shirt", 80)
+	require.NoError(t, st.orderRepo.CreateOrder(ctx, &models.Order{UserID: user.ID, MerchID: tShirt.ID, Quantity: 1, TotalPrice: 80}))
+	require.NoError(t, st.orderRepo.CreateOrder(ctx, &models.Order{UserID: user.ID, MerchID: tShirt.ID, Quantity: 1, TotalPrice: 80}))
 
 	// Добавляем транзакции для пользователя
-	coinTxRepo.transactions[user.ID] = []*models.CoinTransaction{
-		{
-			ID:            1,
-			UserID:        user.ID,
-			Amount:        80,
-			Type:          "transfer_received",
-			RelatedUserID: nil,
-			CreatedAt:     time.Now().Add(-45 * time.Minute),
-		},
-		{
-			ID:            2,
-			UserID:        user.ID,
-			Amount:        80,
-			Type:          "transfer_sent",
-			RelatedUserID: nil,
-			CreatedAt:     time.Now().Add(-50 * time.Minute),
-		},
-	}
+	require.NoError(t, st.coinTxRepo.CreateTransaction(ctx, user.ID, 80, "transfer_received", &other.ID))
+	require.NoError(t, st.coinTxRepo.CreateTransaction(ctx, user.ID, 80, "transfer_sent", nil))
 
-	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
-	infoSvc := service.NewInfoService(logger, userRepo, orderRepo, coinTxRepo)
+	infoSvc := service.NewInfoService(newTestLogger(), st.userRepo, st.orderRepo, st.coinTxRepo, st.lotRepo, 0)
 
-	ctx := context.Background()
 	infoResp, err := infoSvc.GetInfo(ctx, user.ID)
 	assert.NoError(t, err, "GetInfo should succeed")
 	assert.Equal(t, 920, infoResp.Coins, "User coin balance should match")
@@ -277,15 +222,55 @@ func TestInfoService_GetInfo_Success(t *testing.T) {
 	// Проверяем транзакции: ожидаем одну запись для каждого типа
 	assert.Len(t, infoResp.CoinHistory.Received, 1, "There should be one received transaction")
 	assert.Len(t, infoResp.CoinHistory.Sent, 1, "There should be one sent transaction")
+	if len(infoResp.CoinHistory.Received) > 0 {
+		assert.Equal(t, other.Email, infoResp.CoinHistory.Received[0].FromUser)
+	}
 }
 
-func TestInfoService_GetInfo_UserNotFound(t *testing.T) {
-	userRepo := newFakeUserRepo()
-	orderRepo := newFakeOrderRepo()
-	coinTxRepo := newFakeCoinTxRepo()
+func TestInfoService_GetInfo_CreditsAndDebits(t *testing.T) {
+	st := newTestStorage()
+	ctx := context.Background()
+	admin := st.createUser(t, "hr@example.com", 0)
+	user := st.createUser(t, "user@example.com", 1050)
+
+	require.NoError(t, st.coinTxRepo.CreateTransaction(ctx, user.ID, 1000, models.CoinTxWelcomeBonus, nil))
+	require.NoError(t, st.coinTxRepo.CreateTransaction(ctx, user.ID, 200, models.CoinTxAllowance, nil))
+	require.NoError(t, st.coinTxRepo.CreateTransaction(ctx, user.ID, -150, models.CoinTxExpired, nil))
+	_, err := newTestAdminService(st, 10).AdjustBalance(ctx, admin.ID, user.Email, -30, "ошибка начисления")
+	require.NoError(t, err)
+	// переводы по-прежнему попадают только в received/sent
+	require.NoError(t, st.coinTxRepo.CreateTransaction(ctx, user.ID, 80, models.CoinTxTransferReceived, &admin.ID))
+
+	info, err := service.NewInfoService(newTestLogger(), st.userRepo, st.orderRepo, st.coinTxRepo, st.lotRepo, 0).GetInfo(ctx, user.ID)
+	require.NoError(t, err)
+
+	type entry struct {
+		Type    string
+		Amount  int
+		Comment string
+	}
+	strip := func(entries []service.BalanceEntry) []entry {
+		var res []entry
+		for _, e := range entries {
+			assert.False(t, e.CreatedAt.IsZero())
+			res = append(res, entry{Type: e.Type, Amount: e.Amount, Comment: e.Comment})
+		}
+		return res
+	}
+	assert.ElementsMatch(t, []entry{
+		{Type: models.CoinTxWelcomeBonus, Amount: 1000},
+		{Type: models.CoinTxAllowance, Amount: 200},
+	}, strip(info.CoinHistory.Credits))
+	assert.ElementsMatch(t, []entry{
+		{Type: models.CoinTxExpired, Amount: 150},
+		{Type: models.CoinTxAdjustment, Amount: 30, Comment: "ошибка начисления"},
+	}, strip(info.CoinHistory.Debits))
+	assert.Len(t, info.CoinHistory.Received, 1)
+}
 
-	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
-	infoSvc := service.NewInfoService(logger, userRepo, orderRepo, coinTxRepo)
+func TestInfoService_GetInfo_UserNotFound(t *testing.T) {
+	st := newTestStorage()
+	infoSvc := service.NewInfoService(newTestLogger(), st.userRepo, st.orderRepo, st.coinTxRepo, st.lotRepo, 0)
 
 	ctx := context.Background()
 	_, err := infoSvc.GetInfo(ctx, 999) // Пользователь с таким ID не существует
@@ -293,210 +278,228 @@ func TestInfoService_GetInfo_UserNotFound(t *testing.T) {
 }
 
 func TestBuyService_Buy_Success(t *testing.T) {
-	// Используем sqlmock для создания фиктивной БД.
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	// Ожидаем вызов BeginTx и Commit.
-	mock.ExpectBegin()
-	mock.ExpectCommit()
+	st := newTestStorage()
+	user := st.createUser(t, "test@example.com", 1000)
+	st.db.AddMerch("t-shirt", 80)
 
-	// Создаем fake репозитории.
-	fakeUserRepo := newFakeUserRepo()
-	fakeMerchRepo := newFakeMerchRepo()
-	fakeOrderRepo := newFakeOrderRepo()
-
-	// Добавляем пользователя с балансом 1000, ID=1.
-	user := &models.User{
-		ID:          1,
-		Email:       "test@example.com",
-		PassHash:    []byte("hashed"),
-		CoinBalance: 1000,
-	}
-	fakeUserRepo.users[user.Email] = user
-
-	// Добавляем мерч "t-shirt" с ценой 80.
-	fakeMerchRepo.merchs["t-shirt"] = &models.Merch{
-		ID:    1,
-		Name:  "t-shirt",
-		Price: 80,
-	}
-
-	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
-	buySvc := service.NewBuyService(logger, db, fakeUserRepo, fakeMerchRepo, fakeOrderRepo)
+	buySvc := service.NewBuyService(newTestLogger(), st.txManager, st.userRepo, st.merchRepo, st.orderRepo, st.coinTxRepo, st.promoRepo, st.ledger)
 
 	// Вызываем метод Buy.
-	err = buySvc.Buy(context.Background(), user.ID, "t-shirt")
+	err := buySvc.Buy(context.Background(), user.ID, "t-shirt", "", "")
 	assert.NoError(t, err, "Buy should succeed")
 
-	// Проверяем, что баланс пользователя обновился: 1000 - 80 = 920.
-	updatedUser, err := fakeUserRepo.GetUserByID(context.Background(), user.ID)
+	// Проверяем, что баланс пользователя обновился: 1000 - 80 = 920, а заказ создан.
+	assert.Equal(t, 920, st.balance(t, user.ID), "User balance should be updated to 920")
+	orders, err := st.orderRepo.GetOrdersByUserID(context.Background(), user.ID)
 	assert.NoError(t, err)
-	assert.Equal(t, 920, updatedUser.CoinBalance, "User balance should be updated to 920")
-
-	// Проверяем ожидания sqlmock.
-	err = mock.ExpectationsWereMet()
-	assert.NoError(t, err, "sqlmock expectations should be met")
+	assert.Len(t, orders, 1, "One order should be created")
 }
 
 func TestBuyService_Buy_InsufficientFunds(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
+	st := newTestStorage()
+	user := st.createUser(t, "test@example.com", 50)
+	st.db.AddMerch("t-shirt", 80)
 
-	// Ожидаем вызов BeginTx и Commit не произойдет, а вместо этого будет Rollback.
-	mock.ExpectBegin()
-	mock.ExpectRollback()
+	buySvc := service.NewBuyService(newTestLogger(), st.txManager, st.userRepo, st.merchRepo, st.orderRepo, st.coinTxRepo, st.promoRepo, st.ledger)
 
-	fakeUserRepo := newFakeUserRepo()
-	fakeMerchRepo := newFakeMerchRepo()
-	fakeOrderRepo := newFakeOrderRepo()
-
-	// Пользователь с балансом 50, ID=1.
-	user := &models.User{
-		ID:          1,
-		Email:       "test@example.com",
-		PassHash:    []byte("hashed"),
-		CoinBalance: 50,
-	}
-	fakeUserRepo.users[user.Email] = user
+	err := buySvc.Buy(context.Background(), user.ID, "t-shirt", "", "")
+	assert.Error(t, err, "Buy should fail due to insufficient funds")
 
-	// Мерч "t-shirt" с ценой 80.
-	fakeMerchRepo.merchs["t-shirt"] = &models.Merch{
-		ID:    1,
-		Name:  "t-shirt",
-		Price: 80,
-	}
+	// Транзакция откатана: баланс не изменился, заказов нет.
+	assert.Equal(t, 50, st.balance(t, user.ID))
+	orders, err := st.orderRepo.GetOrdersByUserID(context.Background(), user.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, orders)
+}
 
-	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
-	buySvc := service.NewBuyService(logger, db, fakeUserRepo, fakeMerchRepo, fakeOrderRepo)
+func TestBuyService_Buy_UnknownItem(t *testing.T) {
+	st := newTestStorage()
+	user := st.createUser(t, "test@example.com", 1000)
 
-	err = buySvc.Buy(context.Background(), user.ID, "t-shirt")
-	assert.Error(t, err, "Buy should fail due to insufficient funds")
+	buySvc := service.NewBuyService(newTestLogger(), st.txManager, st.userRepo, st.merchRepo, st.orderRepo, st.coinTxRepo, st.promoRepo, st.ledger)
 
-	err = mock.ExpectationsWereMet()
-	assert.NoError(t, err, "sqlmock expectations should be met")
+	err := buySvc.Buy(context.Background(), user.ID, "nonexistent", "", "")
+	assert.ErrorIs(t, err, storage.ErrMerchNotFound)
+	assert.Equal(t, 1000, st.balance(t, user.ID))
 }
 
-func TestSendCoinService_Success(t *testing.T) {
-	// Используем sqlmock для создания фиктивной БД.
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	// Ожидаем вызов BeginTx и Commit.
-	mock.ExpectBegin()
-	mock.ExpectCommit()
+func TestBuyService_Gift_Success(t *testing.T) {
+	st := newTestStorage()
+	ctx := context.Background()
+	buyer := st.createUser(t, "buyer@example.com", 1000)
+	recipient := st.createUser(t, "recipient@example.com", 100)
+	st.db.AddMerch("cup", 20)
+
+	buySvc := service.NewBuyService(newTestLogger(), st.txManager, st.userRepo, st.merchRepo, st.orderRepo, st.coinTxRepo, st.promoRepo, st.ledger)
+	require.NoError(t, buySvc.Gift(ctx, buyer.ID, "cup", "", recipient.Email))
+
+	// Платит покупатель, баланс получателя не меняется
+	assert.Equal(t, 980, st.balance(t, buyer.ID))
+	assert.Equal(t, 100, st.balance(t, recipient.ID))
+
+	infoSvc := service.NewInfoService(newTestLogger(), st.userRepo, st.orderRepo, st.coinTxRepo, st.lotRepo, 0)
+	recipientInfo, err := infoSvc.GetInfo(ctx, recipient.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []service.InventoryItem{{Type: "cup", Quantity: 1}}, recipientInfo.Inventory)
+	assert.Equal(t, []service.GiftEntry{{FromUser: buyer.Email, Item: "cup"}}, recipientInfo.CoinHistory.GiftsReceived)
+
+	buyerInfo, err := infoSvc.GetInfo(ctx, buyer.ID)
+	require.NoError(t, err)
+	assert.Empty(t, buyerInfo.Inventory)
+	assert.Equal(t, []service.GiftEntry{{ToUser: recipient.Email, Item: "cup", Price: 20}}, buyerInfo.CoinHistory.GiftsSent)
+
+	// В истории сумма — изменение баланса: списание у покупателя, ноль у получателя
+	buyerTxs, err := st.coinTxRepo.GetTransactionsByUserID(ctx, buyer.ID)
+	require.NoError(t, err)
+	require.Len(t, buyerTxs, 1)
+	assert.Equal(t, -20, buyerTxs[0].Amount)
+	recipientTxs, err := st.coinTxRepo.GetTransactionsByUserID(ctx, recipient.ID)
+	require.NoError(t, err)
+	require.Len(t, recipientTxs, 1)
+	assert.Equal(t, 0, recipientTxs[0].Amount)
+
+	orders, err := st.orderRepo.GetOrdersByUserID(ctx, recipient.ID)
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	require.NotNil(t, orders[0].GiftedBy)
+	assert.Equal(t, buyer.ID, *orders[0].GiftedBy)
+}
 
-	// Создаем фиктивный репозиторий пользователей.
-	fakeUserRepo := newFakeUserRepo()
-	fakeCoinTxRepo := newFakeCoinTxRepo()
+func TestBuyService_Gift_Invalid(t *testing.T) {
+	st := newTestStorage()
+	ctx := context.Background()
+	buyer := st.createUser(t, "buyer@example.com", 10)
+	recipient := st.createUser(t, "recipient@example.com", 0)
+	st.db.AddMerch("cup", 20)
+
+	buySvc := service.NewBuyService(newTestLogger(), st.txManager, st.userRepo, st.merchRepo, st.orderRepo, st.coinTxRepo, st.promoRepo, st.ledger)
+
+	assert.ErrorIs(t, buySvc.Gift(ctx, buyer.ID, "cup", "", buyer.Email), service.ErrInvalidGift)
+	assert.ErrorIs(t, buySvc.Gift(ctx, buyer.ID, "cup", "", "nobody@example.com"), service.ErrInvalidGift)
+	// Не хватает монет — ни заказа, ни записей в истории
+	assert.Error(t, buySvc.Gift(ctx, buyer.ID, "cup", "", recipient.Email))
+
+	assert.Equal(t, 10, st.balance(t, buyer.ID))
+	orders, err := st.orderRepo.GetOrdersByUserID(ctx, recipient.ID)
+	require.NoError(t, err)
+	assert.Empty(t, orders)
+	txs, err := st.coinTxRepo.GetTransactionsByUserID(ctx, recipient.ID)
+	require.NoError(t, err)
+	assert.Empty(t, txs)
+}
 
-	// Добавляем отправителя и получателя.
-	sender := &models.User{
-		ID:          1,
-		Email:       "sender@example.com",
-		PassHash:    []byte("hashed"),
-		CoinBalance: 1000,
-	}
-	receiver := &models.User{
-		ID:          2,
-		Email:       "receiver@example.com",
-		PassHash:    []byte("hashed"),
-		CoinBalance: 500,
-	}
-	fakeUserRepo.users[sender.Email] = sender
-	fakeUserRepo.users[receiver.Email] = receiver
+func TestSendCoinService_Success(t *testing.T) {
+	st := newTestStorage()
+	sender := st.createUser(t, "sender@example.com", 1000)
+	receiver := st.createUser(t, "receiver@example.com", 500)
 
-	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
-	sendCoinSvc := service.NewSendCoinService(logger, db, fakeUserRepo, fakeCoinTxRepo)
+	sendCoinSvc := service.NewSendCoinService(newTestLogger(), st.txManager, st.userRepo, st.coinTxRepo, st.ledger, st.notifier, service.TransferLimits{})
 
 	// Перевод 100 монет от отправителя к получателю.
-	err = sendCoinSvc.SendCoin(context.Background(), sender.ID, receiver.Email, 100)
+	err := sendCoinSvc.SendCoin(context.Background(), sender.ID, receiver.Email, 100, "")
 	assert.NoError(t, err, "SendCoin should succeed with valid data")
 
 	// Проверяем, что баланс отправителя уменьшился, а получателя увеличился.
-	updatedSender, err := fakeUserRepo.GetUserByID(context.Background(), sender.ID)
+	assert.Equal(t, 900, st.balance(t, sender.ID), "Sender balance should be updated to 900")
+	assert.Equal(t, 600, st.balance(t, receiver.ID), "Receiver balance should be updated to 600")
+
+	// Обе стороны перевода записаны в историю.
+	sent, err := st.coinTxRepo.GetTransactionsByUserID(context.Background(), sender.ID)
 	assert.NoError(t, err)
-	updatedReceiver, err := fakeUserRepo.GetUserByEmail(context.Background(), receiver.Email)
+	assert.Len(t, sent, 1)
+	received, err := st.coinTxRepo.GetTransactionsByUserID(context.Background(), receiver.ID)
 	assert.NoError(t, err)
-	assert.Equal(t, 900, updatedSender.CoinBalance, "Sender balance should be updated to 900")
-	assert.Equal(t, 600, updatedReceiver.CoinBalance, "Receiver balance should be updated to 600")
-
-	err = mock.ExpectationsWereMet()
-	assert.NoError(t, err, "sqlmock expectations should be met")
+	assert.Len(t, received, 1)
 }
 
-func TestSendCoinService_SelfTransfer(t *testing.T) {
-	// Создаем фиктивную БД, хотя в этом тесте транзакция не дойдёт до вызова BeginTx.
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	// Ожидаем вызов BeginTx и затем Rollback.
-	mock.ExpectBegin()
-	mock.ExpectRollback()
-
-	fakeUserRepo := newFakeUserRepo()
-	fakeCoinTxRepo := newFakeCoinTxRepo()
+func TestSendCoinService_Comment(t *testing.T) {
+	st := newTestStorage()
+	sender := st.createUser(t, "sender@example.com", 1000)
+	receiver := st.createUser(t, "receiver@example.com", 0)
+	ctx := context.Background()
 
-	// Добавляем пользователя с балансом 1000.
-	user := &models.User{
-		ID:          1,
-		Email:       "user@example.com",
-		PassHash:    []byte("hashed"),
-		CoinBalance: 1000,
+	sendCoinSvc := service.NewSendCoinService(newTestLogger(), st.txManager, st.userRepo, st.coinTxRepo, st.ledger, st.notifier, service.TransferLimits{})
+	infoSvc := service.NewInfoService(newTestLogger(), st.userRepo, st.orderRepo, st.coinTxRepo, st.lotRepo, 0)
+
+	// Управляющие символы удаляются, пробелы схлопываются
+	require.NoError(t, sendCoinSvc.SendCoin(ctx, sender.ID, receiver.Email, 50, "  Thanks for\nthe\u200b review\x07 "))
+	require.NoError(t, sendCoinSvc.SendCoin(ctx, sender.ID, receiver.Email, 10, ""))
+
+	info, err := infoSvc.GetInfo(ctx, receiver.ID)
+	require.NoError(t, err)
+	require.Len(t, info.CoinHistory.Received, 2)
+	assert.Equal(t, "", info.CoinHistory.Received[0].Comment)
+	assert.Equal(t, "Thanks for the review", info.CoinHistory.Received[1].Comment)
+
+	// Поиск по комментарию без учёта регистра — у обоих участников
+	for _, userID := range []int64{sender.ID, receiver.ID} {
+		found, err := infoSvc.SearchHistory(ctx, userID, "REVIEW", 0)
+		require.NoError(t, err)
+		require.Len(t, found, 1)
+		assert.Equal(t, 50, found[0].Amount)
 	}
-	fakeUserRepo.users[user.Email] = user
+	found, err := infoSvc.SearchHistory(ctx, sender.ID, "", 0)
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	assert.Equal(t, receiver.Email, found[0].ToUser)
+
+	// Слишком длинный комментарий отклоняется, перевод не выполняется
+	err = sendCoinSvc.SendCoin(ctx, sender.ID, receiver.Email, 10, strings.Repeat("я", service.MaxTransferCommentLength+1))
+	assert.ErrorIs(t, err, service.ErrInvalidComment)
+	assert.Equal(t, 60, st.balance(t, receiver.ID))
+}
 
-	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
-	sendCoinSvc := service.NewSendCoinService(logger, db, fakeUserRepo, fakeCoinTxRepo)
+func TestSendCoinService_SelfTransfer(t *testing.T) {
+	st := newTestStorage()
+	user := st.createUser(t, "user@example.com", 1000)
+
+	sendCoinSvc := service.NewSendCoinService(newTestLogger(), st.txManager, st.userRepo, st.coinTxRepo, st.ledger, st.notifier, service.TransferLimits{})
 
 	// Пытаемся перевести монеты самому себе.
-	err = sendCoinSvc.SendCoin(context.Background(), user.ID, user.Email, 100)
+	err := sendCoinSvc.SendCoin(context.Background(), user.ID, user.Email, 100, "")
 	assert.Error(t, err, "SendCoin should fail when transferring coins to self")
-
-	err = mock.ExpectationsWereMet()
-	assert.NoError(t, err, "sqlmock expectations should be met")
+	assert.Equal(t, 1000, st.balance(t, user.ID))
 }
 
 func TestSendCoinService_InsufficientFunds(t *testing.T) {
-	db, mock, err := sqlmock.New()
-	assert.NoError(t, err)
-	defer db.Close()
-
-	// Ожидаем вызов BeginTx и Rollback, поскольку средств недостаточно.
-	mock.ExpectBegin()
-	mock.ExpectRollback()
-
-	fakeUserRepo := newFakeUserRepo()
-	fakeCoinTxRepo := newFakeCoinTxRepo()
-
-	// Добавляем отправителя с балансом 50.
-	sender := &models.User{
-		ID:          1,
-		Email:       "sender@example.com",
-		PassHash:    []byte("hashed"),
-		CoinBalance: 50,
-	}
-	// Получатель с балансом 500.
-	receiver := &models.User{
-		ID:          2,
-		Email:       "receiver@example.com",
-		PassHash:    []byte("hashed"),
-		CoinBalance: 500,
-	}
-	fakeUserRepo.users[sender.Email] = sender
-	fakeUserRepo.users[receiver.Email] = receiver
+	st := newTestStorage()
+	sender := st.createUser(t, "sender@example.com", 50)
+	receiver := st.createUser(t, "receiver@example.com", 500)
 
-	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
-	sendCoinSvc := service.NewSendCoinService(logger, db, fakeUserRepo, fakeCoinTxRepo)
+	sendCoinSvc := service.NewSendCoinService(newTestLogger(), st.txManager, st.userRepo, st.coinTxRepo, st.ledger, st.notifier, service.TransferLimits{})
 
 	// Пытаемся перевести 100 монет, но у отправителя недостаточно средств.
-	err = sendCoinSvc.SendCoin(context.Background(), sender.ID, receiver.Email, 100)
+	err := sendCoinSvc.SendCoin(context.Background(), sender.ID, receiver.Email, 100, "")
 	assert.Error(t, err, "SendCoin should fail due to insufficient funds")
+	assert.Equal(t, 50, st.balance(t, sender.ID))
+	assert.Equal(t, 500, st.balance(t, receiver.ID))
+}
+
+func TestSendCoinService_ConcurrentTransfers(t *testing.T) {
+	st := newTestStorage()
+	alice := st.createUser(t, "alice@example.com", 1000)
+	bob := st.createUser(t, "bob@example.com", 1000)
+
+	sendCoinSvc := service.NewSendCoinService(newTestLogger(), st.txManager, st.userRepo, st.coinTxRepo, st.ledger, st.notifier, service.TransferLimits{})
+
+	// Встречные переводы в несколько горутин: сумма балансов сохраняется, балансы не уходят в минус.
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_ = sendCoinSvc.SendCoin(context.Background(), alice.ID, bob.Email, 30, "")
+		}()
+		go func() {
+			defer wg.Done()
+			_ = sendCoinSvc.SendCoin(context.Background(), bob.ID, alice.Email, 20, "")
+		}()
+	}
+	wg.Wait()
 
-	err = mock.ExpectationsWereMet()
-	assert.NoError(t, err, "sqlmock expectations should be met")
+	aliceBalance := st.balance(t, alice.ID)
+	bobBalance := st.balance(t, bob.ID)
+	assert.Equal(t, 2000, aliceBalance+bobBalance, "total amount of coins must be preserved")
+	assert.GreaterOrEqual(t, aliceBalance, 0)
+	assert.GreaterOrEqual(t, bobBalance, 0)
 }
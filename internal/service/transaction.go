@@ -2,36 +2,55 @@ package service
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/linemk/avito-shop/internal/domain/models"
 	"github.com/linemk/avito-shop/internal/storage"
 )
 
+// MaxTransferCommentLength — максимальная длина комментария к переводу в символах.
+const MaxTransferCommentLength = 200
+
+// ErrInvalidComment — комментарий к переводу слишком длинный.
+var ErrInvalidComment = errors.New("invalid comment")
+
 // SendCoinService определяет интерфейс для перевода монет.
 type SendCoinService interface {
-	SendCoin(ctx context.Context, fromUserID int64, toUser string, amount int) error
+	// SendCoin переводит amount монет пользователю toUser. Необязательный comment
+	// (например, "спасибо за ревью") сохраняется в истории обоих участников, получатель
+	// получает уведомление models.NotificationCoinsReceived.
+	SendCoin(ctx context.Context, fromUserID int64, toUser string, amount int, comment string) error
 }
 
 type sendCoinService struct {
 	log        *slog.Logger
-	db         *sql.DB
+	txManager  storage.TxManager
 	userRepo   storage.UserStorage
 	coinTxRepo storage.CoinTransactionStorage
+	ledger     *Ledger
+	notifier   *Notifier
+	rules      []transferRule
 }
 
-func NewSendCoinService(log *slog.Logger, db *sql.DB, userRepo storage.UserStorage, coinTxRepo storage.CoinTransactionStorage) SendCoinService {
+func NewSendCoinService(log *slog.Logger, txManager storage.TxManager, userRepo storage.UserStorage, coinTxRepo storage.CoinTransactionStorage, ledger *Ledger, notifier *Notifier, limits TransferLimits) SendCoinService {
 	return &sendCoinService{
 		log:        log,
-		db:         db,
+		txManager:  txManager,
 		userRepo:   userRepo,
 		coinTxRepo: coinTxRepo,
+		ledger:     ledger,
+		notifier:   notifier,
+		rules:      limits.transferRules(coinTxRepo),
 	}
 }
 
-func (s *sendCoinService) SendCoin(ctx context.Context, fromUserID int64, toUser string, amount int) error {
+func (s *sendCoinService) SendCoin(ctx context.Context, fromUserID int64, toUser string, amount int, comment string) error {
 	const op = "service.SendCoinService.SendCoin"
 	logger := s.log.With(
 		slog.String("op", op),
@@ -44,98 +63,130 @@ func (s *sendCoinService) SendCoin(ctx context.Context, fromUserID int64, toUser
 	if amount <= 0 {
 		return fmt.Errorf("%s: amount must be positive", op)
 	}
-
-	tx, err := s.db.BeginTx(ctx, nil)
+	comment, err := sanitizeComment(comment)
 	if err != nil {
-		logger.Error("failed to begin transaction", slog.Any("error", err))
-		return fmt.Errorf("%s: failed to begin transaction: %w", op, err)
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	// Получаем отправителя через метод LockUserByIDTx (используем транзакцию)
-	sender, err := s.userRepo.LockUserByIDTx(ctx, tx, fromUserID)
-	if err != nil {
-		if rbErr := tx.Rollback(); rbErr != nil {
-			logger.Error("transaction rollback failed", slog.Any("error", rbErr))
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		// Получаем отправителя через метод LockUserByID (блокировка до конца транзакции)
+		sender, err := s.userRepo.LockUserByID(ctx, fromUserID)
+		if err != nil {
+			logger.Error("failed to get sender", slog.Any("error", err))
+			return fmt.Errorf("%s: failed to get sender: %w", op, err)
 		}
-		logger.Error("failed to get sender", slog.Any("error", err))
-		return fmt.Errorf("%s: failed to get sender: %w", op, err)
-	}
 
-	// Получаем получателя по email (username)
-	receiver, err := s.userRepo.GetUserByEmail(ctx, toUser)
-	if err != nil {
-		if rbErr := tx.Rollback(); rbErr != nil {
-			logger.Error("transaction rollback failed", slog.Any("error", rbErr))
+		// Получаем получателя по email (username)
+		receiver, err := s.userRepo.GetUserByEmail(ctx, toUser)
+		if err != nil {
+			if errors.Is(err, storage.ErrUserNotFound) {
+				logger.Error("receiver not found", slog.String("toUser", toUser))
+				return fmt.Errorf("%s: receiver not found", op)
+			}
+			logger.Error("failed to get receiver", slog.Any("error", err))
+			return fmt.Errorf("%s: failed to get receiver: %w", op, err)
 		}
-		if errors.Is(err, storage.ErrUserNotFound) {
-			logger.Error("receiver not found", slog.String("toUser", toUser))
-			return fmt.Errorf("%s: receiver not found", op)
+
+		// проверяем, не отправитель ли пытается сам себе перевести деньги
+		if fromUserID == receiver.ID {
+			logger.Error("cannot transfer coins to yourself")
+			return fmt.Errorf("%s: cannot transfer coins to yourself", op)
 		}
-		logger.Error("failed to get receiver", slog.Any("error", err))
-		return fmt.Errorf("%s: failed to get receiver: %w", op, err)
-	}
 
-	// проверяем, не отправитель ли пытается сам себе перевести деньги
-	if fromUserID == receiver.ID {
-		if rbErr := tx.Rollback(); rbErr != nil {
-			logger.Error("transaction rollback failed", slog.Any("error", rbErr))
+		// Блокируем строку получателя, иначе параллельное изменение его баланса потеряется
+		receiver, err = s.userRepo.LockUserByID(ctx, receiver.ID)
+		if err != nil {
+			logger.Error("failed to lock receiver", slog.Any("error", err))
+			return fmt.Errorf("%s: failed to lock receiver: %w", op, err)
 		}
-		logger.Error("cannot transfer coins to yourself")
-		return fmt.Errorf("%s: cannot transfer coins to yourself", op)
-	}
 
-	// Проверяем, достаточно ли средств у отправителя
-	if sender.CoinBalance < amount {
-		if rbErr := tx.Rollback(); rbErr != nil {
-			logger.Error("transaction rollback failed", slog.Any("error", rbErr))
+		// Проверяем лимиты: оба участника заблокированы, поэтому параллельные переводы
+		// не обойдут суточные ограничения
+		t := &transfer{sender: sender, receiver: receiver, amount: amount, now: time.Now()}
+		for _, rule := range s.rules {
+			if err := rule(ctx, t); err != nil {
+				logger.Warn("transfer rejected by limits", slog.Any("error", err))
+				return fmt.Errorf("%s: %w", op, err)
+			}
 		}
-		logger.Warn("insufficient funds", slog.Int("senderBalance", sender.CoinBalance))
-		return fmt.Errorf("%s: insufficient funds", op)
-	}
 
-	// Обновляем баланс отправителя: списываем монеты
-	newSenderBalance := sender.CoinBalance - amount
-	if err := s.userRepo.UpdateUserBalance(ctx, tx, fromUserID, newSenderBalance); err != nil {
-		if rbErr := tx.Rollback(); rbErr != nil {
-			logger.Error("transaction rollback failed", slog.Any("error", rbErr))
+		// Проверяем, достаточно ли средств у отправителя
+		if sender.CoinBalance < amount {
+			logger.Warn("insufficient funds", slog.Int("senderBalance", sender.CoinBalance))
+			return fmt.Errorf("%s: insufficient funds", op)
 		}
-		logger.Error("failed to update sender balance", slog.Any("error", err))
-		return fmt.Errorf("%s: failed to update sender balance: %w", op, err)
-	}
 
-	// Обновляем баланс получателя: прибавляем монеты
-	newReceiverBalance := receiver.CoinBalance + amount
-	if err := s.userRepo.UpdateUserBalance(ctx, tx, receiver.ID, newReceiverBalance); err != nil {
-		if rbErr := tx.Rollback(); rbErr != nil {
-			logger.Error("transaction rollback failed", slog.Any("error", rbErr))
+		// Обновляем баланс отправителя: списываем монеты
+		if err := s.ledger.changeBalance(ctx, sender, -amount, models.CoinTxTransferSent); err != nil {
+			logger.Error("failed to update sender balance", slog.Any("error", err))
+			return fmt.Errorf("%s: failed to update sender balance: %w", op, err)
 		}
-		logger.Error("failed to update receiver balance", slog.Any("error", err))
-		return fmt.Errorf("%s: failed to update receiver balance: %w", op, err)
-	}
 
-	// Регистрируем транзакцию для отправителя (положительная сумма, тип "transfer_sent")
-	if err := s.coinTxRepo.CreateTransaction(ctx, tx, fromUserID, amount, "transfer_sent", &receiver.ID); err != nil {
-		if rbErr := tx.Rollback(); rbErr != nil {
-			logger.Error("transaction rollback failed", slog.Any("error", rbErr))
+		// Обновляем баланс получателя: прибавляем монеты (новая партия со сроком для transfer_received)
+		if err := s.ledger.changeBalance(ctx, receiver, amount, models.CoinTxTransferReceived); err != nil {
+			logger.Error("failed to update receiver balance", slog.Any("error", err))
+			return fmt.Errorf("%s: failed to update receiver balance: %w", op, err)
 		}
-		logger.Error("failed to record sender transaction", slog.Any("error", err))
-		return fmt.Errorf("%s: failed to record sender transaction: %w", op, err)
-	}
 
-	// Регистрируем транзакцию для получателя (положительная сумма, тип "transfer_received")
-	if err := s.coinTxRepo.CreateTransaction(ctx, tx, receiver.ID, amount, "transfer_received", &fromUserID); err != nil {
-		if rbErr := tx.Rollback(); rbErr != nil {
-			logger.Error("transaction rollback failed", slog.Any("error", rbErr))
+		// Регистрируем транзакцию для отправителя (положительная сумма, тип "transfer_sent")
+		err = s.coinTxRepo.InsertTransaction(ctx, &models.CoinTransaction{
+			UserID:        fromUserID,
+			Amount:        amount,
+			Type:          models.CoinTxTransferSent,
+			RelatedUserID: &receiver.ID,
+			Comment:       comment,
+		})
+		if err != nil {
+			logger.Error("failed to record sender transaction", slog.Any("error", err))
+			return fmt.Errorf("%s: failed to record sender transaction: %w", op, err)
 		}
-		logger.Error("failed to record receiver transaction", slog.Any("error", err))
-		return fmt.Errorf("%s: failed to record receiver transaction: %w", op, err)
-	}
 
-	if err := tx.Commit(); err != nil {
-		logger.Error("failed to commit transaction", slog.Any("error", err))
-		return fmt.Errorf("%s: failed to commit transaction: %w", op, err)
+		// Регистрируем транзакцию для получателя (положительная сумма, тип "transfer_received")
+		err = s.coinTxRepo.InsertTransaction(ctx, &models.CoinTransaction{
+			UserID:        receiver.ID,
+			Amount:        amount,
+			Type:          models.CoinTxTransferReceived,
+			RelatedUserID: &fromUserID,
+			Comment:       comment,
+		})
+		if err != nil {
+			logger.Error("failed to record receiver transaction", slog.Any("error", err))
+			return fmt.Errorf("%s: failed to record receiver transaction: %w", op, err)
+		}
+
+		// Уведомляем получателя в этой же транзакции: перевод без уведомления не зафиксируется
+		err = s.notifier.Notify(ctx, receiver.ID, models.NotificationCoinsReceived,
+			fmt.Sprintf("%s sent you %d coins", sender.Email, amount),
+			map[string]any{"from": sender.Email, "amount": amount, "comment": comment})
+		if err != nil {
+			logger.Error("failed to notify receiver", slog.Any("error", err))
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	logger.Info("coin transfer completed successfully")
 	return nil
 }
+
+// sanitizeComment убирает управляющие и невидимые символы, схлопывает пробелы
+// и проверяет длину комментария.
+func sanitizeComment(comment string) (string, error) {
+	comment = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsSpace(r):
+			return ' '
+		case unicode.IsControl(r), unicode.Is(unicode.Cf, r), r == utf8.RuneError:
+			return -1
+		}
+		return r
+	}, comment)
+	comment = strings.Join(strings.Fields(comment), " ")
+	if utf8.RuneCountInString(comment) > MaxTransferCommentLength {
+		return "", fmt.Errorf("%w: longer than %d characters", ErrInvalidComment, MaxTransferCommentLength)
+	}
+	return comment, nil
+}
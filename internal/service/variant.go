@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+)
+
+var (
+	// ErrInvalidVariant — некорректные артикул, цена или остаток варианта.
+	ErrInvalidVariant = errors.New("invalid merch variant")
+	// ErrVariantRequired — у товара есть варианты, а покупка их не указала.
+	ErrVariantRequired = errors.New("merch variant required")
+)
+
+// VariantInput — новый вариант товара. Price и Stock необязательны: nil — действуют цена
+// и остаток самого товара.
+type VariantInput struct {
+	SKU   string
+	Size  string
+	Color string
+	Price *int
+	Stock *int
+}
+
+// CatalogVariant — вариант товара в каталоге с действующими ценой и остатком.
+type CatalogVariant struct {
+	SKU   string `json:"sku"`
+	Size  string `json:"size,omitempty"`
+	Color string `json:"color,omitempty"`
+	Price int    `json:"price"`
+	// Stock — остаток варианта или, если у варианта нет своего, товара; отсутствует, если не ограничен
+	Stock   *int `json:"stock,omitempty"`
+	SoldOut bool `json:"soldOut"`
+}
+
+func (s *merchService) CreateVariant(ctx context.Context, item string, input VariantInput) (*CatalogVariant, error) {
+	const op = "service.MerchService.CreateVariant"
+	logger := s.log.With(slog.String("op", op), slog.String("item", item), slog.String("sku", input.SKU))
+
+	variant := &models.MerchVariant{
+		SKU:    strings.TrimSpace(input.SKU),
+		Size:   strings.TrimSpace(input.Size),
+		Color:  strings.TrimSpace(input.Color),
+		Price:  input.Price,
+		Stock:  input.Stock,
+		Active: true,
+	}
+	if variant.SKU == "" || strings.ContainsAny(variant.SKU, " \t\n") {
+		return nil, fmt.Errorf("%s: %w: sku must be non-empty and contain no spaces", op, ErrInvalidVariant)
+	}
+	if err := validateVariant(variant); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var res CatalogVariant
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		merch, err := s.merchRepo.LockMerchByName(ctx, item)
+		if err != nil {
+			return err
+		}
+		variant.MerchID = merch.ID
+		if err := s.merchRepo.CreateVariant(ctx, variant); err != nil {
+			return err
+		}
+		res = newCatalogVariant(merch, variant)
+		return nil
+	})
+	if err != nil {
+		logger.Error("failed to create merch variant", slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	logger.Info("merch variant created")
+	return &res, nil
+}
+
+func (s *merchService) UpdateVariant(ctx context.Context, item, sku string, price, stock *int) (*CatalogVariant, error) {
+	const op = "service.MerchService.UpdateVariant"
+	logger := s.log.With(slog.String("op", op), slog.String("item", item), slog.String("sku", sku))
+
+	var res CatalogVariant
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		merch, variant, err := s.lockVariant(ctx, item, sku)
+		if err != nil {
+			return err
+		}
+		wasSoldOut, err := s.soldOut(ctx, merch)
+		if err != nil {
+			return err
+		}
+		variant.Price, variant.Stock = price, stock
+		if err := validateVariant(variant); err != nil {
+			return err
+		}
+		if err := s.merchRepo.UpdateVariant(ctx, variant); err != nil {
+			return err
+		}
+		res = newCatalogVariant(merch, variant)
+		return s.notifyBackInStock(ctx, merch, wasSoldOut)
+	})
+	if err != nil {
+		logger.Error("failed to update merch variant", slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	logger.Info("merch variant updated")
+	return &res, nil
+}
+
+func (s *merchService) DeleteVariant(ctx context.Context, item, sku string) error {
+	const op = "service.MerchService.DeleteVariant"
+	logger := s.log.With(slog.String("op", op), slog.String("item", item), slog.String("sku", sku))
+
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		_, variant, err := s.lockVariant(ctx, item, sku)
+		if err != nil {
+			return err
+		}
+		// Вариант выключается, а не удаляется: на него ссылаются оформленные заказы
+		variant.Active = false
+		return s.merchRepo.UpdateVariant(ctx, variant)
+	})
+	if err != nil {
+		logger.Error("failed to delete merch variant", slog.Any("error", err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	logger.Info("merch variant deleted")
+	return nil
+}
+
+// lockVariant блокирует товар item и его активный вариант sku.
+func (s *merchService) lockVariant(ctx context.Context, item, sku string) (*models.Merch, *models.MerchVariant, error) {
+	merch, err := s.merchRepo.LockMerchByName(ctx, item)
+	if err != nil {
+		return nil, nil, err
+	}
+	variant, err := s.merchRepo.LockVariantBySKU(ctx, merch.ID, sku)
+	if err != nil {
+		return nil, nil, err
+	}
+	return merch, variant, nil
+}
+
+// withVariants дополняет товар каталога его вариантами. Товар с вариантами распродан,
+// когда распроданы все варианты.
+func withVariants(item CatalogItem, merch *models.Merch, variants []models.MerchVariant) CatalogItem {
+	if len(variants) == 0 {
+		return item
+	}
+	item.Variants = make([]CatalogVariant, 0, len(variants))
+	item.SoldOut = true
+	for i := range variants {
+		v := newCatalogVariant(merch, &variants[i])
+		item.SoldOut = item.SoldOut && v.SoldOut
+		item.Variants = append(item.Variants, v)
+	}
+	return item
+}
+
+func newCatalogVariant(merch *models.Merch, variant *models.MerchVariant) CatalogVariant {
+	price, stock := variantPriceAndStock(merch, variant)
+	return CatalogVariant{
+		SKU:     variant.SKU,
+		Size:    variant.Size,
+		Color:   variant.Color,
+		Price:   price,
+		Stock:   stock,
+		SoldOut: stock != nil && *stock <= 0,
+	}
+}
+
+// variantPriceAndStock возвращает действующие цену и остаток варианта: собственные,
+// а если их нет — товара. variant = nil — товар без вариантов.
+func variantPriceAndStock(merch *models.Merch, variant *models.MerchVariant) (int, *int) {
+	price, stock := merch.Price, merch.Stock
+	if variant != nil && variant.Price != nil {
+		price = *variant.Price
+	}
+	if variant != nil && variant.Stock != nil {
+		stock = variant.Stock
+	}
+	return price, stock
+}
+
+// validateVariant проверяет собственные цену и остаток варианта.
+func validateVariant(variant *models.MerchVariant) error {
+	if variant.Price != nil && *variant.Price <= 0 {
+		return fmt.Errorf("%w: price must be positive", ErrInvalidVariant)
+	}
+	if variant.Stock != nil && *variant.Stock < 0 {
+		return fmt.Errorf("%w: stock must not be negative", ErrInvalidVariant)
+	}
+	return nil
+}
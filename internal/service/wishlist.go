@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/storage"
+)
+
+// maxWishlistItems — сколько товаров может быть в списке желаний одного пользователя.
+const maxWishlistItems = 50
+
+// ErrWishlistFull — в списке желаний уже maxWishlistItems товаров.
+var ErrWishlistFull = errors.New("wishlist is full")
+
+// WishlistItem — товар из списка желаний с текущей ценой. У товара с вариантами Price —
+// цена самого дешёвого варианта. Affordable — баланса пользователя хватает на покупку.
+type WishlistItem struct {
+	Item       string    `json:"item"`
+	Price      int       `json:"price"`
+	SoldOut    bool      `json:"soldOut"`
+	Affordable bool      `json:"affordable"`
+	Image      string    `json:"image,omitempty"`
+	AddedAt    time.Time `json:"addedAt"`
+}
+
+// WishlistService — список желаний: товары, на которые сотрудник копит монеты или ждёт поступления.
+type WishlistService interface {
+	// Add добавляет товар в список желаний пользователя userID.
+	Add(ctx context.Context, userID int64, item string) (*WishlistItem, error)
+	// Remove убирает товар из списка желаний.
+	Remove(ctx context.Context, userID int64, item string) error
+	// List возвращает список желаний от недавно добавленных к давним; снятые с продажи товары пропускаются.
+	List(ctx context.Context, userID int64) ([]WishlistItem, error)
+}
+
+type wishlistService struct {
+	log          *slog.Logger
+	txManager    storage.TxManager
+	userRepo     storage.UserStorage
+	merchRepo    storage.MerchStorage
+	wishlistRepo storage.WishlistStorage
+}
+
+// NewWishlistService создаёт сервис списков желаний.
+func NewWishlistService(log *slog.Logger, txManager storage.TxManager, userRepo storage.UserStorage, merchRepo storage.MerchStorage, wishlistRepo storage.WishlistStorage) WishlistService {
+	return &wishlistService{
+		log:          log,
+		txManager:    txManager,
+		userRepo:     userRepo,
+		merchRepo:    merchRepo,
+		wishlistRepo: wishlistRepo,
+	}
+}
+
+func (s *wishlistService) Add(ctx context.Context, userID int64, item string) (*WishlistItem, error) {
+	const op = "service.WishlistService.Add"
+	logger := s.log.With(slog.String("op", op), slog.Int64("userID", userID), slog.String("item", item))
+
+	var res *WishlistItem
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		// Блокировка пользователя сериализует параллельные добавления, чтобы не превысить лимит
+		user, err := s.userRepo.LockUserByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		merch, err := s.merchRepo.GetMerchByName(ctx, item)
+		if err != nil {
+			return err
+		}
+		items, err := s.wishlistRepo.ListWishlist(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if len(items) >= maxWishlistItems {
+			return fmt.Errorf("%w: at most %d items", ErrWishlistFull, maxWishlistItems)
+		}
+		added, err := s.wishlistRepo.AddToWishlist(ctx, userID, merch.ID)
+		if err != nil {
+			return err
+		}
+		catalogItem, err := catalogItemWithVariants(ctx, s.merchRepo, merch)
+		if err != nil {
+			return err
+		}
+		view := newWishlistItem(catalogItem, user.CoinBalance, added.CreatedAt)
+		res = &view
+		return nil
+	})
+	if err != nil {
+		logger.Error("failed to add wishlist item", slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	logger.Info("wishlist item added")
+	return res, nil
+}
+
+func (s *wishlistService) Remove(ctx context.Context, userID int64, item string) error {
+	const op = "service.WishlistService.Remove"
+	logger := s.log.With(slog.String("op", op), slog.Int64("userID", userID), slog.String("item", item))
+
+	merch, err := s.merchRepo.GetMerchByName(ctx, item)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := s.wishlistRepo.RemoveFromWishlist(ctx, userID, merch.ID); err != nil {
+		logger.Error("failed to remove wishlist item", slog.Any("error", err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	logger.Info("wishlist item removed")
+	return nil
+}
+
+func (s *wishlistService) List(ctx context.Context, userID int64) ([]WishlistItem, error) {
+	const op = "service.WishlistService.List"
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	items, err := s.wishlistRepo.ListWishlist(ctx, userID)
+	if err != nil {
+		s.log.Error("failed to list wishlist", slog.String("op", op), slog.Any("error", err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	catalog, err := wishlistCatalog(ctx, s.merchRepo, items)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res := make([]WishlistItem, 0, len(items))
+	for _, item := range items {
+		if catalogItem, ok := catalog[item.MerchID]; ok {
+			res = append(res, newWishlistItem(catalogItem, user.CoinBalance, item.CreatedAt))
+		}
+	}
+	return res, nil
+}
+
+// WishlistNotifier уведомляет о товарах из списков желаний: когда баланса стало хватать
+// на товар и когда распроданный товар снова поступил в продажу. Методы вызываются внутри WithinTx.
+type WishlistNotifier struct {
+	wishlistRepo storage.WishlistStorage
+	notifier     *Notifier
+}
+
+// NewWishlistNotifier создаёт WishlistNotifier.
+func NewWishlistNotifier(wishlistRepo storage.WishlistStorage, notifier *Notifier) *WishlistNotifier {
+	return &WishlistNotifier{
+		wishlistRepo: wishlistRepo,
+		notifier:     notifier,
+	}
+}
+
+// BalanceIncreased уведомляет пользователя о товарах из его списка желаний, цена которых
+// оказалась между прежним балансом oldBalance и новым user.CoinBalance. Распроданные товары
+// пропускаются: о них придёт уведомление о поступлении. Вызывается на каждое начисление,
+// поэтому цены считает один запрос только по списку желаний пользователя.
+func (w *WishlistNotifier) BalanceIncreased(ctx context.Context, user *models.User, oldBalance int) error {
+	items, err := w.wishlistRepo.ListWishlistPrices(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if item.SoldOut || oldBalance >= item.Price || user.CoinBalance < item.Price {
+			continue
+		}
+		err := w.notifier.Notify(ctx, user.ID, models.NotificationWishlistAffordable,
+			fmt.Sprintf("You now have enough coins for %s (%d coins)", item.Name, item.Price),
+			map[string]any{"item": item.Name, "price": item.Price, "balance": user.CoinBalance})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BackInStock уведомляет всех, у кого товар в списке желаний, что он снова в продаже.
+func (w *WishlistNotifier) BackInStock(ctx context.Context, merch *models.Merch) error {
+	userIDs, err := w.wishlistRepo.ListWishlistUsers(ctx, merch.ID)
+	if err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		err := w.notifier.Notify(ctx, userID, models.NotificationBackInStock,
+			fmt.Sprintf("%s is back in stock", merch.Name),
+			map[string]any{"item": merch.Name})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// wishlistCatalog возвращает активные товары из списка желаний с вариантами по идентификатору товара.
+func wishlistCatalog(ctx context.Context, merchRepo storage.MerchStorage, items []models.WishlistItem) (map[int64]CatalogItem, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+	merch, err := merchRepo.ListMerch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[int64]bool, len(items))
+	for _, item := range items {
+		wanted[item.MerchID] = true
+	}
+
+	catalog := make(map[int64]CatalogItem, len(items))
+	for i := range merch {
+		if !wanted[merch[i].ID] {
+			continue
+		}
+		item, err := catalogItemWithVariants(ctx, merchRepo, &merch[i])
+		if err != nil {
+			return nil, err
+		}
+		catalog[merch[i].ID] = item
+	}
+	return catalog, nil
+}
+
+// catalogItemWithVariants возвращает товар каталога вместе с активными вариантами.
+func catalogItemWithVariants(ctx context.Context, merchRepo storage.MerchStorage, merch *models.Merch) (CatalogItem, error) {
+	variants, err := merchRepo.ListVariants(ctx, merch.ID)
+	if err != nil {
+		return CatalogItem{}, err
+	}
+	return withVariants(newCatalogItem(merch), merch, variants), nil
+}
+
+// wishlistPrice — цена, с которой товар становится доступен: цена товара или самого дешёвого
+// варианта в продаже.
+func wishlistPrice(item CatalogItem) int {
+	price := item.Price
+	first := true
+	for _, v := range item.Variants {
+		if v.SoldOut {
+			continue
+		}
+		if first || v.Price < price {
+			price, first = v.Price, false
+		}
+	}
+	return price
+}
+
+func newWishlistItem(item CatalogItem, balance int, addedAt time.Time) WishlistItem {
+	price := wishlistPrice(item)
+	return WishlistItem{
+		Item:       item.Name,
+		Price:      price,
+		SoldOut:    item.SoldOut,
+		Affordable: balance >= price,
+		Image:      item.Image,
+		AddedAt:    addedAt,
+	}
+}
@@ -0,0 +1,128 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/service"
+	"github.com/linemk/avito-shop/internal/storage"
+	"github.com/linemk/avito-shop/internal/storage/blob"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestWishlistService(st *testStorage) service.WishlistService {
+	return service.NewWishlistService(newTestLogger(), st.txManager, st.userRepo, st.merchRepo, st.wishlists)
+}
+
+// notificationTypes возвращает типы уведомлений пользователя от новых к старым.
+func (s *testStorage) notificationTypes(t *testing.T, userID int64) []string {
+	t.Helper()
+	notifications, err := s.notes.ListNotifications(context.Background(), userID, false, 0)
+	require.NoError(t, err)
+	types := make([]string, 0, len(notifications))
+	for _, n := range notifications {
+		types = append(types, n.Type)
+	}
+	return types
+}
+
+func TestWishlistService_AddListRemove(t *testing.T) {
+	st := newTestStorage()
+	ctx := context.Background()
+	user := st.createUser(t, "user@example.com", 100)
+	st.db.AddMerch("cup", 20)
+	st.db.AddMerch("hoody", 300)
+	wishlistSvc := newTestWishlistService(st)
+
+	item, err := wishlistSvc.Add(ctx, user.ID, "hoody")
+	require.NoError(t, err)
+	assert.Equal(t, "hoody", item.Item)
+	assert.Equal(t, 300, item.Price)
+	assert.False(t, item.Affordable)
+
+	_, err = wishlistSvc.Add(ctx, user.ID, "hoody")
+	assert.ErrorIs(t, err, storage.ErrWishlistItemExists)
+	_, err = wishlistSvc.Add(ctx, user.ID, "unknown")
+	assert.ErrorIs(t, err, storage.ErrMerchNotFound)
+
+	_, err = wishlistSvc.Add(ctx, user.ID, "cup")
+	require.NoError(t, err)
+	items, err := wishlistSvc.List(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, "cup", items[0].Item)
+	assert.True(t, items[0].Affordable)
+
+	require.NoError(t, wishlistSvc.Remove(ctx, user.ID, "cup"))
+	assert.ErrorIs(t, wishlistSvc.Remove(ctx, user.ID, "cup"), storage.ErrWishlistItemNotFound)
+	items, err = wishlistSvc.List(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "hoody", items[0].Item)
+}
+
+func TestWishlist_AffordableNotification(t *testing.T) {
+	st := newTestStorage()
+	ctx := context.Background()
+	admin := st.createUser(t, "hr@example.com", 0)
+	user := st.createUser(t, "user@example.com", 250)
+	st.db.AddMerch("hoody", 300)
+	_, err := newTestWishlistService(st).Add(ctx, user.ID, "hoody")
+	require.NoError(t, err)
+	adminSvc := newTestAdminService(st, 10)
+
+	// Начисление, после которого монет всё ещё не хватает, уведомления о товаре не создаёт
+	_, err = adminSvc.AdjustBalance(ctx, admin.ID, user.Email, 20, "bonus")
+	require.NoError(t, err)
+	assert.Equal(t, []string{models.NotificationCoinsGranted}, st.notificationTypes(t, user.ID))
+
+	_, err = adminSvc.AdjustBalance(ctx, admin.ID, user.Email, 40, "bonus")
+	require.NoError(t, err)
+	notifications, err := st.notes.ListNotifications(ctx, user.ID, false, 0)
+	require.NoError(t, err)
+	require.Len(t, notifications, 3)
+	assert.Equal(t, models.NotificationCoinsGranted, notifications[0].Type)
+	assert.Equal(t, models.NotificationWishlistAffordable, notifications[1].Type)
+	assert.Equal(t, "hoody", notifications[1].Data["item"])
+	assert.Nil(t, notifications[1].ReadAt)
+
+	// Баланс уже выше цены — повторного уведомления о товаре нет
+	_, err = adminSvc.AdjustBalance(ctx, admin.ID, user.Email, 10, "bonus")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		models.NotificationCoinsGranted,
+		models.NotificationCoinsGranted,
+		models.NotificationWishlistAffordable,
+		models.NotificationCoinsGranted,
+	}, st.notificationTypes(t, user.ID))
+}
+
+func TestWishlist_BackInStockNotification(t *testing.T) {
+	st := newTestStorage()
+	ctx := context.Background()
+	alice := st.createUser(t, "alice@example.com", 0)
+	bob := st.createUser(t, "bob@example.com", 0)
+	st.db.AddMerch("pink-hoody", 500)
+	merchSvc := service.NewMerchService(newTestLogger(), st.txManager, st.userRepo, st.merchRepo, blob.NewMemoryStore(), 1024, st.wishlist)
+	_, err := merchSvc.SetStock(ctx, "pink-hoody", intPtr(0))
+	require.NoError(t, err)
+
+	wishlistSvc := newTestWishlistService(st)
+	_, err = wishlistSvc.Add(ctx, alice.ID, "pink-hoody")
+	require.NoError(t, err)
+	items, err := wishlistSvc.List(ctx, alice.ID)
+	require.NoError(t, err)
+	assert.True(t, items[0].SoldOut)
+
+	_, err = merchSvc.Restock(ctx, "pink-hoody", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{models.NotificationBackInStock}, st.notificationTypes(t, alice.ID))
+	assert.Empty(t, st.notificationTypes(t, bob.ID))
+
+	// Пополнение товара, который и так в продаже, не уведомляет
+	_, err = merchSvc.Restock(ctx, "pink-hoody", 1)
+	require.NoError(t, err)
+	assert.Len(t, st.notificationTypes(t, alice.ID), 1)
+}
@@ -0,0 +1,39 @@
+// Package blob хранит двоичные объекты (изображения товаров) по строковому ключу.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+var (
+	// ErrNotFound — объекта с таким ключом нет.
+	ErrNotFound = errors.New("blob not found")
+	// ErrInvalidKey — ключ пустой, абсолютный или выходит за пределы хранилища.
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// Store — хранилище двоичных объектов. Ключ — относительный путь через "/", например "merch/12/ab01.png".
+type Store interface {
+	// Put сохраняет объект key, заменяя существующий.
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Open открывает объект key на чтение; вызывающий закрывает его.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete удаляет объект key; отсутствие объекта ошибкой не считается.
+	Delete(ctx context.Context, key string) error
+}
+
+// validateKey проверяет, что ключ — относительный путь без "." и ".." в сегментах.
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
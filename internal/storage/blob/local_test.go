@@ -0,0 +1,59 @@
+package blob_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/linemk/avito-shop/internal/storage/blob"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readBlob(t *testing.T, store blob.Store, key string) string {
+	t.Helper()
+	rc, err := store.Open(context.Background(), key)
+	require.NoError(t, err)
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestLocalStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "images")
+	store, err := blob.NewLocalStore(dir)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, store.Put(ctx, "merch/1/a.png", strings.NewReader("first")))
+	require.NoError(t, store.Put(ctx, "merch/1/a.png", strings.NewReader("second")))
+	assert.Equal(t, "second", readBlob(t, store, "merch/1/a.png"))
+	assert.FileExists(t, filepath.Join(dir, "merch", "1", "a.png"))
+
+	// Во время записи рядом не остаётся временных файлов
+	entries, err := os.ReadDir(filepath.Join(dir, "merch", "1"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	require.NoError(t, store.Delete(ctx, "merch/1/a.png"))
+	_, err = store.Open(ctx, "merch/1/a.png")
+	assert.ErrorIs(t, err, blob.ErrNotFound)
+	// Повторное удаление — не ошибка
+	assert.NoError(t, store.Delete(ctx, "merch/1/a.png"))
+}
+
+func TestLocalStore_InvalidKey(t *testing.T) {
+	store, err := blob.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	for _, key := range []string{"", "/etc/passwd", "../secret", "merch/../../secret", "merch//a.png", `merch\a.png`} {
+		assert.ErrorIs(t, store.Put(ctx, key, strings.NewReader("x")), blob.ErrInvalidKey, key)
+		_, err := store.Open(ctx, key)
+		assert.ErrorIs(t, err, blob.ErrInvalidKey, key)
+	}
+}
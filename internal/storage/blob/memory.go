@@ -0,0 +1,60 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// memoryStore — реализация Store в памяти для демо-запуска без БД и тестов.
+type memoryStore struct {
+	mu      sync.RWMutex
+	objects map[string][]byte
+}
+
+// NewMemoryStore создаёт пустое хранилище в памяти.
+func NewMemoryStore() Store {
+	return &memoryStore{objects: make(map[string][]byte)}
+}
+
+func (s *memoryStore) Put(ctx context.Context, key string, r io.Reader) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read blob: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = data
+	return nil
+}
+
+func (s *memoryStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *memoryStore) Delete(ctx context.Context, key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, key)
+	return nil
+}
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/linemk/avito-shop/internal/domain/models"
+)
+
+var (
+	// ErrJobRunExists — запуск задачи за этот момент расписания уже записан.
+	ErrJobRunExists = errors.New("job run already exists")
+	// ErrJobRunNotFound — запуск задачи не найден.
+	ErrJobRunNotFound = errors.New("job run not found")
+)
+
+// JobRunStorage — история запусков фоновых задач планировщика.
+type JobRunStorage interface {
+	// CreateJobRun записывает начало запуска и заполняет ID. Если запуск задачи
+	// за run.ScheduledAt уже есть, возвращает ErrJobRunExists.
+	CreateJobRun(ctx context.Context, run *models.JobRun) error
+	// GetJobRun возвращает запуск задачи за момент расписания scheduledAt.
+	GetJobRun(ctx context.Context, job string, scheduledAt time.Time) (*models.JobRun, error)
+	// UpdateJobRun сохраняет время начала и окончания, статус и ошибку запуска.
+	UpdateJobRun(ctx context.Context, run *models.JobRun) error
+	// ListJobRuns возвращает последние limit запусков задачи (все задачи при пустом job),
+	// начиная с самого позднего по расписанию.
+	ListJobRuns(ctx context.Context, job string, limit int) ([]models.JobRun, error)
+}
+
+type jobRunRepository struct {
+	db *sql.DB
+}
+
+// NewJobRunRepository создаёт репозиторий истории запусков.
+func NewJobRunRepository(db *sql.DB) JobRunStorage {
+	return &jobRunRepository{db: db}
+}
+
+func (r *jobRunRepository) CreateJobRun(ctx context.Context, run *models.JobRun) error {
+	query := `INSERT INTO job_runs (job, scheduled_at, started_at, status, error)
+	          VALUES ($1, $2, $3, $4, $5)
+	          RETURNING id`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query,
+		run.Job, run.ScheduledAt, run.StartedAt, run.Status, run.Error,
+	).Scan(&run.ID)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" { // unique_violation
+			return ErrJobRunExists
+		}
+		return fmt.Errorf("failed to create job run: %w", err)
+	}
+	return nil
+}
+
+func (r *jobRunRepository) GetJobRun(ctx context.Context, job string, scheduledAt time.Time) (*models.JobRun, error) {
+	query := `SELECT id, job, scheduled_at, started_at, finished_at, status, error
+	          FROM job_runs WHERE job = $1 AND scheduled_at = $2`
+	run, err := scanJobRun(conn(ctx, r.db).QueryRowContext(ctx, query, job, scheduledAt))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrJobRunNotFound
+		}
+		return nil, fmt.Errorf("failed to get job run: %w", err)
+	}
+	return run, nil
+}
+
+func (r *jobRunRepository) UpdateJobRun(ctx context.Context, run *models.JobRun) error {
+	query := `UPDATE job_runs SET started_at = $1, finished_at = $2, status = $3, error = $4 WHERE id = $5`
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, run.StartedAt, run.FinishedAt, run.Status, run.Error, run.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update job run: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrJobRunNotFound
+	}
+	return nil
+}
+
+func (r *jobRunRepository) ListJobRuns(ctx context.Context, job string, limit int) ([]models.JobRun, error) {
+	query := `SELECT id, job, scheduled_at, started_at, finished_at, status, error
+	          FROM job_runs
+	          WHERE $1 = '' OR job = $1
+	          ORDER BY scheduled_at DESC, id DESC
+	          LIMIT $2`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, job, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query job runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []models.JobRun
+	for rows.Next() {
+		run, err := scanJobRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job run: %w", err)
+		}
+		runs = append(runs, *run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read job runs: %w", err)
+	}
+	return runs, nil
+}
+
+// scanJobRun читает строку job_runs из *sql.Row или *sql.Rows.
+func scanJobRun(row interface{ Scan(dest ...any) error }) (*models.JobRun, error) {
+	run := &models.JobRun{}
+	var finishedAt sql.NullTime
+	if err := row.Scan(&run.ID, &run.Job, &run.ScheduledAt, &run.StartedAt, &finishedAt, &run.Status, &run.Error); err != nil {
+		return nil, err
+	}
+	if finishedAt.Valid {
+		run.FinishedAt = &finishedAt.Time
+	}
+	return run, nil
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+)
+
+// Locker — распределённая блокировка по имени. Пока блокировка удерживается,
+// другие реплики сервиса не могут её получить.
+type Locker interface {
+	// TryLock пытается захватить блокировку key без ожидания. Если она занята,
+	// возвращает acquired = false. Захваченную блокировку освобождает release.
+	TryLock(ctx context.Context, key string) (release func(), acquired bool, err error)
+}
+
+// advisoryLocker — Locker на сессионных advisory lock PostgreSQL.
+type advisoryLocker struct {
+	db *sql.DB
+}
+
+// NewAdvisoryLocker создаёт Locker поверх pg_try_advisory_lock.
+func NewAdvisoryLocker(db *sql.DB) Locker {
+	return &advisoryLocker{db: db}
+}
+
+// TryLock держит блокировку на отдельном соединении из пула: сессионная блокировка
+// живёт, пока живо соединение, поэтому при падении реплики она снимается сама.
+func (l *advisoryLocker) TryLock(ctx context.Context, key string) (func(), bool, error) {
+	lockKey := advisoryKey(key)
+
+	c, err := l.db.Conn(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get connection for advisory lock: %w", err)
+	}
+
+	var acquired bool
+	if err := c.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", lockKey).Scan(&acquired); err != nil {
+		_ = c.Close()
+		return nil, false, fmt.Errorf("failed to acquire advisory lock: %w", err)
+	}
+	if !acquired {
+		_ = c.Close()
+		return nil, false, nil
+	}
+
+	release := func() {
+		_, _ = c.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey)
+		_ = c.Close()
+	}
+	return release, true, nil
+}
+
+// advisoryKey переводит имя блокировки в числовой ключ advisory lock.
+func advisoryKey(key string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return int64(h.Sum64())
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+)
+
+// ErrLotNotFound — партия монет не найдена.
+var ErrLotNotFound = errors.New("coin lot not found")
+
+// CoinLotStorage — партии начисленных монет (см. models.CoinLot). Партии пользователя меняются
+// только под блокировкой его строки (LockUserByID), как и баланс.
+type CoinLotStorage interface {
+	// CreateLot записывает партию и заполняет ID и CreatedAt.
+	CreateLot(ctx context.Context, lot *models.CoinLot) error
+	// GetLotByID возвращает партию по идентификатору.
+	GetLotByID(ctx context.Context, id int64) (*models.CoinLot, error)
+	// GetActiveLots возвращает партии пользователя с непотраченным остатком, от старых к новым.
+	GetActiveLots(ctx context.Context, userID int64) ([]models.CoinLot, error)
+	// UpdateLotRemaining сохраняет остаток партии.
+	UpdateLotRemaining(ctx context.Context, id int64, remaining int) error
+	// ListExpiredLots возвращает до limit партий с остатком и сроком не позже now,
+	// с идентификатором больше afterID, по возрастанию идентификатора.
+	ListExpiredLots(ctx context.Context, now time.Time, afterID int64, limit int) ([]models.CoinLot, error)
+}
+
+type coinLotRepository struct {
+	db *sql.DB
+}
+
+// NewCoinLotRepository создаёт репозиторий партий монет.
+func NewCoinLotRepository(db *sql.DB) CoinLotStorage {
+	return &coinLotRepository{db: db}
+}
+
+const lotColumns = "id, user_id, amount, remaining, source, expires_at, created_at"
+
+func (r *coinLotRepository) CreateLot(ctx context.Context, lot *models.CoinLot) error {
+	query := `INSERT INTO coin_lots (user_id, amount, remaining, source, expires_at, created_at)
+	          VALUES ($1, $2, $3, $4, $5, NOW())
+	          RETURNING id, created_at`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query,
+		lot.UserID, lot.Amount, lot.Remaining, lot.Source, lot.ExpiresAt,
+	).Scan(&lot.ID, &lot.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create coin lot: %w", err)
+	}
+	return nil
+}
+
+func (r *coinLotRepository) GetLotByID(ctx context.Context, id int64) (*models.CoinLot, error) {
+	query := "SELECT " + lotColumns + " FROM coin_lots WHERE id = $1"
+	lot, err := scanLot(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrLotNotFound
+		}
+		return nil, fmt.Errorf("failed to get coin lot: %w", err)
+	}
+	return lot, nil
+}
+
+func (r *coinLotRepository) GetActiveLots(ctx context.Context, userID int64) ([]models.CoinLot, error) {
+	query := "SELECT " + lotColumns + ` FROM coin_lots
+	          WHERE user_id = $1 AND remaining > 0
+	          ORDER BY created_at, id`
+	return r.queryLots(ctx, query, userID)
+}
+
+func (r *coinLotRepository) UpdateLotRemaining(ctx context.Context, id int64, remaining int) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, "UPDATE coin_lots SET remaining = $1 WHERE id = $2", remaining, id)
+	if err != nil {
+		return fmt.Errorf("failed to update coin lot: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrLotNotFound
+	}
+	return nil
+}
+
+func (r *coinLotRepository) ListExpiredLots(ctx context.Context, now time.Time, afterID int64, limit int) ([]models.CoinLot, error) {
+	query := "SELECT " + lotColumns + ` FROM coin_lots
+	          WHERE remaining > 0 AND expires_at <= $1 AND id > $2
+	          ORDER BY id
+	          LIMIT $3`
+	return r.queryLots(ctx, query, now, afterID, limit)
+}
+
+func (r *coinLotRepository) queryLots(ctx context.Context, query string, args ...any) ([]models.CoinLot, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query coin lots: %w", err)
+	}
+	defer rows.Close()
+
+	var lots []models.CoinLot
+	for rows.Next() {
+		lot, err := scanLot(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan coin lot: %w", err)
+		}
+		lots = append(lots, *lot)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read coin lots: %w", err)
+	}
+	return lots, nil
+}
+
+// scanLot читает строку coin_lots из *sql.Row или *sql.Rows.
+func scanLot(row interface{ Scan(dest ...any) error }) (*models.CoinLot, error) {
+	lot := &models.CoinLot{}
+	var expiresAt sql.NullTime
+	if err := row.Scan(&lot.ID, &lot.UserID, &lot.Amount, &lot.Remaining, &lot.Source, &expiresAt, &lot.CreatedAt); err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		lot.ExpiresAt = &expiresAt.Time
+	}
+	return lot, nil
+}
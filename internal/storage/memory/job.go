@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/storage"
+)
+
+// jobRunRepository — реализация storage.JobRunStorage в памяти.
+type jobRunRepository struct {
+	db *DB
+}
+
+// NewJobRunRepository создаёт репозиторий истории запусков в памяти.
+func NewJobRunRepository(db *DB) storage.JobRunStorage {
+	return &jobRunRepository{db: db}
+}
+
+func (r *jobRunRepository) CreateJobRun(ctx context.Context, run *models.JobRun) error {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	if r.db.tables.jobRunIndex(run.Job, run.ScheduledAt) >= 0 {
+		return storage.ErrJobRunExists
+	}
+	run.ID = r.db.tables.nextID()
+	r.db.tables.jobRuns = append(r.db.tables.jobRuns, *run)
+	return nil
+}
+
+func (r *jobRunRepository) GetJobRun(ctx context.Context, job string, scheduledAt time.Time) (*models.JobRun, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	i := r.db.tables.jobRunIndex(job, scheduledAt)
+	if i < 0 {
+		return nil, storage.ErrJobRunNotFound
+	}
+	run := r.db.tables.jobRuns[i]
+	return &run, nil
+}
+
+func (r *jobRunRepository) UpdateJobRun(ctx context.Context, run *models.JobRun) error {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	for i, stored := range r.db.tables.jobRuns {
+		if stored.ID == run.ID {
+			stored.StartedAt = run.StartedAt
+			stored.FinishedAt = run.FinishedAt
+			stored.Status = run.Status
+			stored.Error = run.Error
+			r.db.tables.jobRuns[i] = stored
+			return nil
+		}
+	}
+	return storage.ErrJobRunNotFound
+}
+
+func (r *jobRunRepository) ListJobRuns(ctx context.Context, job string, limit int) ([]models.JobRun, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	var runs []models.JobRun
+	for _, run := range r.db.tables.jobRuns {
+		if job == "" || run.Job == job {
+			runs = append(runs, run)
+		}
+	}
+	sort.SliceStable(runs, func(i, j int) bool {
+		if !runs[i].ScheduledAt.Equal(runs[j].ScheduledAt) {
+			return runs[i].ScheduledAt.After(runs[j].ScheduledAt)
+		}
+		return runs[i].ID > runs[j].ID
+	})
+	if len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs, nil
+}
+
+// jobRunIndex ищет запуск задачи за момент расписания, -1 — не найден.
+func (t *tables) jobRunIndex(job string, scheduledAt time.Time) int {
+	for i, run := range t.jobRuns {
+		if run.Job == job && run.ScheduledAt.Equal(scheduledAt) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,39 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/linemk/avito-shop/internal/storage"
+)
+
+// locker — storage.Locker в пределах одного процесса (реплика при хранилище в памяти одна).
+type locker struct {
+	mu   sync.Mutex
+	held map[string]bool
+}
+
+// NewLocker создаёт блокировки в памяти.
+func NewLocker() storage.Locker {
+	return &locker{held: make(map[string]bool)}
+}
+
+func (l *locker) TryLock(_ context.Context, key string) (func(), bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.held[key] {
+		return nil, false, nil
+	}
+	l.held[key] = true
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			l.mu.Lock()
+			delete(l.held, key)
+			l.mu.Unlock()
+		})
+	}
+	return release, true, nil
+}
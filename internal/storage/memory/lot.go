@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/storage"
+)
+
+// coinLotRepository — реализация storage.CoinLotStorage в памяти.
+type coinLotRepository struct {
+	db *DB
+}
+
+// NewCoinLotRepository создаёт репозиторий партий монет в памяти.
+func NewCoinLotRepository(db *DB) storage.CoinLotStorage {
+	return &coinLotRepository{db: db}
+}
+
+func (r *coinLotRepository) CreateLot(ctx context.Context, lot *models.CoinLot) error {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	lot.ID = r.db.tables.nextID()
+	lot.CreatedAt = time.Now()
+	r.db.tables.lots = append(r.db.tables.lots, *lot)
+	return nil
+}
+
+func (r *coinLotRepository) GetLotByID(ctx context.Context, id int64) (*models.CoinLot, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	for _, lot := range r.db.tables.lots {
+		if lot.ID == id {
+			return &lot, nil
+		}
+	}
+	return nil, storage.ErrLotNotFound
+}
+
+// GetActiveLots: партии хранятся в порядке создания, поэтому сортировка не нужна.
+func (r *coinLotRepository) GetActiveLots(ctx context.Context, userID int64) ([]models.CoinLot, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	var lots []models.CoinLot
+	for _, lot := range r.db.tables.lots {
+		if lot.UserID == userID && lot.Remaining > 0 {
+			lots = append(lots, lot)
+		}
+	}
+	return lots, nil
+}
+
+func (r *coinLotRepository) UpdateLotRemaining(ctx context.Context, id int64, remaining int) error {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	for i, lot := range r.db.tables.lots {
+		if lot.ID == id {
+			r.db.tables.lots[i].Remaining = remaining
+			return nil
+		}
+	}
+	return storage.ErrLotNotFound
+}
+
+func (r *coinLotRepository) ListExpiredLots(ctx context.Context, now time.Time, afterID int64, limit int) ([]models.CoinLot, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	var lots []models.CoinLot
+	for _, lot := range r.db.tables.lots {
+		if lot.Remaining > 0 && lot.ExpiresAt != nil && !lot.ExpiresAt.After(now) && lot.ID > afterID {
+			lots = append(lots, lot)
+		}
+	}
+	sort.Slice(lots, func(i, j int) bool { return lots[i].ID < lots[j].ID })
+	if len(lots) > limit {
+		lots = lots[:limit]
+	}
+	return lots, nil
+}
@@ -0,0 +1,151 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/storage"
+)
+
+// DB — хранилище в памяти, общее для всех репозиториев этого пакета.
+// Все операции сериализуются одним мьютексом: транзакция (см. TxManager) держит его
+// целиком, поэтому изменения внутри неё атомарны и изолированы от других горутин.
+type DB struct {
+	mu     sync.Mutex
+	tables tables
+}
+
+// tables — содержимое «таблиц». Хранятся значения, а не указатели,
+// чтобы снимок для отката транзакции можно было сделать простым копированием.
+type tables struct {
+	users         map[int64]models.User
+	merch         map[int64]models.Merch
+	prices        []models.MerchPriceChange
+	variants      []models.MerchVariant
+	promotions    []models.Promotion
+	orders        []models.Order
+	orderHistory  []models.OrderStatusChange
+	coinTxs       []models.CoinTransaction
+	jobRuns       []models.JobRun
+	lots          []models.CoinLot
+	coinRequests  []models.CoinRequest
+	wishlist      []models.WishlistItem
+	notifications []models.Notification
+	sequence      int64
+}
+
+// New создаёт пустое хранилище в памяти.
+func New() *DB {
+	return &DB{
+		tables: tables{
+			users: make(map[int64]models.User),
+			merch: make(map[int64]models.Merch),
+		},
+	}
+}
+
+// clone делает полную копию таблиц для отката транзакции.
+func (t tables) clone() tables {
+	c := tables{
+		users:         make(map[int64]models.User, len(t.users)),
+		merch:         make(map[int64]models.Merch, len(t.merch)),
+		prices:        append([]models.MerchPriceChange(nil), t.prices...),
+		variants:      append([]models.MerchVariant(nil), t.variants...),
+		promotions:    append([]models.Promotion(nil), t.promotions...),
+		orders:        append([]models.Order(nil), t.orders...),
+		orderHistory:  append([]models.OrderStatusChange(nil), t.orderHistory...),
+		coinTxs:       append([]models.CoinTransaction(nil), t.coinTxs...),
+		jobRuns:       append([]models.JobRun(nil), t.jobRuns...),
+		lots:          append([]models.CoinLot(nil), t.lots...),
+		coinRequests:  append([]models.CoinRequest(nil), t.coinRequests...),
+		wishlist:      append([]models.WishlistItem(nil), t.wishlist...),
+		notifications: append([]models.Notification(nil), t.notifications...),
+		sequence:      t.sequence,
+	}
+	for id, u := range t.users {
+		u.PassHash = append([]byte(nil), u.PassHash...)
+		c.users[id] = u
+	}
+	for id, m := range t.merch {
+		c.merch[id] = m
+	}
+	return c
+}
+
+// nextID выдаёт следующий идентификатор; одна последовательность на все таблицы.
+func (t *tables) nextID() int64 {
+	t.sequence++
+	return t.sequence
+}
+
+// txKey — ключ контекста, по которому репозитории понимают, что мьютекс уже захвачен транзакцией.
+type txKey struct{}
+
+// lock захватывает мьютекс, если вызов происходит вне транзакции этого же хранилища.
+func (db *DB) lock(ctx context.Context) func() {
+	if ctx.Value(txKey{}) == db {
+		return func() {}
+	}
+	db.mu.Lock()
+	return db.mu.Unlock
+}
+
+// AddMerch добавляет товар в каталог и возвращает его с присвоенным идентификатором.
+func (db *DB) AddMerch(name string, price int) *models.Merch {
+	return db.AddCatalogItem(models.Merch{Name: name, Price: price})
+}
+
+// AddCatalogItem добавляет товар с категорией, описанием и тегами и возвращает его
+// с присвоенным идентификатором.
+func (db *DB) AddCatalogItem(m models.Merch) *models.Merch {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	m.ID = db.tables.nextID()
+	m.Tags = append([]string(nil), m.Tags...)
+	db.tables.merch[m.ID] = m
+	return copyMerch(m)
+}
+
+// DefaultCatalog повторяет каталог из миграций 2_init_merch и 16_merch_details и используется
+// для демо-запуска без БД.
+var DefaultCatalog = []models.Merch{
+	{Name: "t-shirt", Price: 80, Category: "clothing"},
+	{Name: "cup", Price: 20, Category: "accessories"},
+	{Name: "book", Price: 50, Category: "stationery"},
+	{Name: "pen", Price: 10, Category: "stationery"},
+	{Name: "powerbank", Price: 200, Category: "accessories"},
+	{Name: "hoody", Price: 300, Category: "clothing"},
+	{Name: "umbrella", Price: 200, Category: "accessories"},
+	{Name: "socks", Price: 10, Category: "clothing"},
+	{Name: "wallet", Price: 50, Category: "accessories"},
+	{Name: "pink-hoody", Price: 500, Category: "clothing"},
+}
+
+// txManager — реализация storage.TxManager для хранилища в памяти.
+type txManager struct {
+	db *DB
+}
+
+// NewTxManager создаёт менеджер транзакций для хранилища в памяти.
+func NewTxManager(db *DB) storage.TxManager {
+	return &txManager{db: db}
+}
+
+// WithinTx выполняет fn под мьютексом хранилища; при ошибке состояние откатывается к снимку.
+func (m *txManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(txKey{}) == m.db {
+		return fn(ctx)
+	}
+
+	m.db.mu.Lock()
+	defer m.db.mu.Unlock()
+
+	snapshot := m.db.tables.clone()
+	if err := fn(context.WithValue(ctx, txKey{}, m.db)); err != nil {
+		m.db.tables = snapshot
+		return err
+	}
+	return nil
+}
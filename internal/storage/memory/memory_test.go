@@ -0,0 +1,247 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/storage"
+	"github.com/linemk/avito-shop/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserRepository_CreateAndGet(t *testing.T) {
+	db := memory.New()
+	repo := memory.NewUserRepository(db)
+	ctx := context.Background()
+
+	created, err := repo.CreateUser(ctx, &models.User{Email: "test@example.com", PassHash: []byte("hash"), CoinBalance: 1000})
+	require.NoError(t, err)
+	assert.NotZero(t, created.ID)
+
+	byEmail, err := repo.GetUserByEmail(ctx, "test@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, byEmail.ID)
+
+	byID, err := repo.GetUserByID(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1000, byID.CoinBalance)
+
+	// Повторное создание с тем же username запрещено, как и в PostgreSQL.
+	_, err = repo.CreateUser(ctx, &models.User{Email: "test@example.com"})
+	assert.ErrorIs(t, err, storage.ErrUserExists)
+
+	_, err = repo.GetUserByID(ctx, 999)
+	assert.ErrorIs(t, err, storage.ErrUserNotFound)
+}
+
+func TestUserRepository_ReturnsCopies(t *testing.T) {
+	db := memory.New()
+	repo := memory.NewUserRepository(db)
+	ctx := context.Background()
+
+	created, err := repo.CreateUser(ctx, &models.User{Email: "test@example.com", CoinBalance: 1000})
+	require.NoError(t, err)
+
+	// Изменение возвращённой структуры не должно менять данные в хранилище.
+	user, err := repo.GetUserByID(ctx, created.ID)
+	require.NoError(t, err)
+	user.CoinBalance = 0
+
+	user, err = repo.GetUserByID(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1000, user.CoinBalance)
+}
+
+func TestOrderRepository_JoinsMerchName(t *testing.T) {
+	db := memory.New()
+	repo := memory.NewOrderRepository(db)
+	ctx := context.Background()
+
+	cup := db.AddMerch("cup", 20)
+	pen := db.AddMerch("pen", 10)
+	require.NoError(t, repo.CreateOrder(ctx, &models.Order{UserID: 1, MerchID: cup.ID, Quantity: 1, TotalPrice: 20}))
+	require.NoError(t, repo.CreateOrder(ctx, &models.Order{UserID: 1, MerchID: pen.ID, Quantity: 1, TotalPrice: 10}))
+	require.NoError(t, repo.CreateOrder(ctx, &models.Order{UserID: 2, MerchID: pen.ID, Quantity: 1, TotalPrice: 10}))
+
+	orders, err := repo.GetOrdersByUserID(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, orders, 2)
+	// Как и в SQL-реализации, новые заказы идут первыми.
+	assert.Equal(t, "pen", orders[0].MerchName)
+	assert.Equal(t, "cup", orders[1].MerchName)
+}
+
+func TestTxManager_RollbackRestoresState(t *testing.T) {
+	db := memory.New()
+	txManager := memory.NewTxManager(db)
+	users := memory.NewUserRepository(db)
+	coinTxs := memory.NewCoinTransactionRepository(db)
+	ctx := context.Background()
+
+	user, err := users.CreateUser(ctx, &models.User{Email: "test@example.com", CoinBalance: 1000})
+	require.NoError(t, err)
+
+	errBoom := errors.New("boom")
+	err = txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := users.UpdateUserBalance(ctx, user.ID, 0); err != nil {
+			return err
+		}
+		if err := coinTxs.CreateTransaction(ctx, user.ID, 1000, "transfer_sent", nil); err != nil {
+			return err
+		}
+		return errBoom
+	})
+	assert.ErrorIs(t, err, errBoom)
+
+	reloaded, err := users.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1000, reloaded.CoinBalance, "balance change must be rolled back")
+	txs, err := coinTxs.GetTransactionsByUserID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, txs, "coin transaction must be rolled back")
+}
+
+func TestTxManager_SerializesConcurrentTransactions(t *testing.T) {
+	db := memory.New()
+	txManager := memory.NewTxManager(db)
+	users := memory.NewUserRepository(db)
+	ctx := context.Background()
+
+	user, err := users.CreateUser(ctx, &models.User{Email: "test@example.com"})
+	require.NoError(t, err)
+
+	// Read-modify-write в транзакциях из разных горутин не теряет обновлений.
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = txManager.WithinTx(ctx, func(ctx context.Context) error {
+				u, err := users.LockUserByID(ctx, user.ID)
+				if err != nil {
+					return err
+				}
+				return users.UpdateUserBalance(ctx, user.ID, u.CoinBalance+1)
+			})
+		}()
+	}
+	wg.Wait()
+
+	reloaded, err := users.GetUserByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 100, reloaded.CoinBalance)
+}
+
+func TestLocker_Exclusive(t *testing.T) {
+	locker := memory.NewLocker()
+	ctx := context.Background()
+
+	release, acquired, err := locker.TryLock(ctx, "job")
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	_, acquired, err = locker.TryLock(ctx, "job")
+	require.NoError(t, err)
+	assert.False(t, acquired, "lock is already held")
+
+	_, acquired, err = locker.TryLock(ctx, "other")
+	require.NoError(t, err)
+	assert.True(t, acquired, "locks with different keys are independent")
+
+	release()
+	release() // повторное освобождение безопасно
+	_, acquired, err = locker.TryLock(ctx, "job")
+	require.NoError(t, err)
+	assert.True(t, acquired)
+}
+
+func TestUserRepository_ListActiveUserIDs(t *testing.T) {
+	db := memory.New()
+	repo := memory.NewUserRepository(db)
+	ctx := context.Background()
+
+	var ids []int64
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		u, err := repo.CreateUser(ctx, &models.User{Email: email})
+		require.NoError(t, err)
+		ids = append(ids, u.ID)
+	}
+
+	page, err := repo.ListActiveUserIDs(ctx, 0, 2)
+	require.NoError(t, err)
+	assert.Equal(t, ids[:2], page)
+
+	page, err = repo.ListActiveUserIDs(ctx, page[len(page)-1], 2)
+	require.NoError(t, err)
+	assert.Equal(t, ids[2:], page)
+}
+
+func TestCoinLotRepository_ListExpiredLots(t *testing.T) {
+	db := memory.New()
+	repo := memory.NewCoinLotRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+
+	lots := []*models.CoinLot{
+		{UserID: 1, Amount: 10, Remaining: 10, ExpiresAt: &past},
+		{UserID: 1, Amount: 10, Remaining: 10, ExpiresAt: &future},
+		{UserID: 1, Amount: 10, Remaining: 10},
+		{UserID: 2, Amount: 10, Remaining: 0, ExpiresAt: &past},
+		{UserID: 2, Amount: 10, Remaining: 5, ExpiresAt: &past},
+	}
+	for _, lot := range lots {
+		require.NoError(t, repo.CreateLot(ctx, lot))
+	}
+
+	expired, err := repo.ListExpiredLots(ctx, now, 0, 1)
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+	assert.Equal(t, lots[0].ID, expired[0].ID)
+
+	expired, err = repo.ListExpiredLots(ctx, now, expired[0].ID, 10)
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+	assert.Equal(t, lots[4].ID, expired[0].ID)
+
+	active, err := repo.GetActiveLots(ctx, 1)
+	require.NoError(t, err)
+	assert.Len(t, active, 3)
+}
+
+func TestWishlistRepository_ListWishlistPrices(t *testing.T) {
+	db := memory.New()
+	merchRepo := memory.NewMerchRepository(db)
+	repo := memory.NewWishlistRepository(db)
+	ctx := context.Background()
+
+	zero, five := 0, 5
+	cup := db.AddMerch("cup", 20)
+	hoody := db.AddCatalogItem(models.Merch{Name: "hoody", Price: 300, Stock: &zero})
+	shirt := db.AddMerch("t-shirt", 80)
+	// У футболки самый дешёвый вариант распродан, остальные наследуют цену или имеют свою
+	cheap, own := 50, 70
+	require.NoError(t, merchRepo.CreateVariant(ctx, &models.MerchVariant{MerchID: shirt.ID, SKU: "TS-S", Price: &cheap, Stock: &zero, Active: true}))
+	require.NoError(t, merchRepo.CreateVariant(ctx, &models.MerchVariant{MerchID: shirt.ID, SKU: "TS-M", Price: &own, Stock: &five, Active: true}))
+	require.NoError(t, merchRepo.CreateVariant(ctx, &models.MerchVariant{MerchID: shirt.ID, SKU: "TS-L", Active: true}))
+
+	for _, m := range []*models.Merch{cup, hoody, shirt} {
+		_, err := repo.AddToWishlist(ctx, 1, m.ID)
+		require.NoError(t, err)
+	}
+	_, err := repo.AddToWishlist(ctx, 2, cup.ID)
+	require.NoError(t, err)
+
+	prices, err := repo.ListWishlistPrices(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []models.WishlistPrice{
+		{MerchID: shirt.ID, Name: "t-shirt", Price: 70},
+		{MerchID: hoody.ID, Name: "hoody", Price: 300, SoldOut: true},
+		{MerchID: cup.ID, Name: "cup", Price: 20},
+	}, prices)
+}
@@ -0,0 +1,303 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/storage"
+)
+
+// merchRepository — реализация storage.MerchStorage в памяти.
+type merchRepository struct {
+	db *DB
+}
+
+// NewMerchRepository создаёт репозиторий мерча в памяти.
+func NewMerchRepository(db *DB) storage.MerchStorage {
+	return &merchRepository{db: db}
+}
+
+func (r *merchRepository) GetMerchByName(ctx context.Context, name string) (*models.Merch, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	for _, m := range r.db.tables.merch {
+		if m.Name == name {
+			return r.db.tables.effectiveMerch(m, time.Now()), nil
+		}
+	}
+	return nil, storage.ErrMerchNotFound
+}
+
+// LockMerchByName совпадает с GetMerchByName: транзакция и так держит блокировку всей базы.
+func (r *merchRepository) LockMerchByName(ctx context.Context, name string) (*models.Merch, error) {
+	return r.GetMerchByName(ctx, name)
+}
+
+// LockMerchByID возвращает товар по идентификатору; блокировка, как и у LockMerchByName, не нужна.
+func (r *merchRepository) LockMerchByID(ctx context.Context, id int64) (*models.Merch, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	m, ok := r.db.tables.merch[id]
+	if !ok {
+		return nil, storage.ErrMerchNotFound
+	}
+	return r.db.tables.effectiveMerch(m, time.Now()), nil
+}
+
+func (r *merchRepository) UpsertMerch(ctx context.Context, name string, price int) (*models.Merch, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	for id, m := range r.db.tables.merch {
+		if m.Name == name {
+			m.Price = price
+			r.db.tables.merch[id] = m
+			return copyMerch(m), nil
+		}
+	}
+	m := models.Merch{ID: r.db.tables.nextID(), Name: name, Price: price}
+	r.db.tables.merch[m.ID] = m
+	return &m, nil
+}
+
+func (r *merchRepository) UpdateMerchStock(ctx context.Context, id int64, stock *int) error {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	m, ok := r.db.tables.merch[id]
+	if !ok {
+		return storage.ErrMerchNotFound
+	}
+	// Новый указатель: снимок таблиц для отката разделяет старый
+	m.Stock = nil
+	if stock != nil {
+		v := *stock
+		m.Stock = &v
+	}
+	r.db.tables.merch[id] = m
+	return nil
+}
+
+func (r *merchRepository) UpdateMerchDetails(ctx context.Context, id int64, category, description string, tags []string) error {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	m, ok := r.db.tables.merch[id]
+	if !ok {
+		return storage.ErrMerchNotFound
+	}
+	m.Category = category
+	m.Description = description
+	m.Tags = append([]string(nil), tags...)
+	r.db.tables.merch[id] = m
+	return nil
+}
+
+func (r *merchRepository) UpdateMerchImage(ctx context.Context, id int64, key string) error {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	m, ok := r.db.tables.merch[id]
+	if !ok {
+		return storage.ErrMerchNotFound
+	}
+	m.ImageKey = key
+	r.db.tables.merch[id] = m
+	return nil
+}
+
+func (r *merchRepository) UpdatePurchaseLimit(ctx context.Context, id int64, limit int, period string) error {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	m, ok := r.db.tables.merch[id]
+	if !ok {
+		return storage.ErrMerchNotFound
+	}
+	m.PurchaseLimit = limit
+	m.PurchaseLimitPeriod = period
+	r.db.tables.merch[id] = m
+	return nil
+}
+
+func (r *merchRepository) ListMerch(ctx context.Context) ([]models.Merch, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	now := time.Now()
+	items := make([]models.Merch, 0, len(r.db.tables.merch))
+	for _, m := range r.db.tables.merch {
+		items = append(items, *r.db.tables.effectiveMerch(m, now))
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+	return items, nil
+}
+
+func (r *merchRepository) UpdateMerchPrice(ctx context.Context, id int64, price int) error {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	m, ok := r.db.tables.merch[id]
+	if !ok {
+		return storage.ErrMerchNotFound
+	}
+	m.Price = price
+	r.db.tables.merch[id] = m
+	return nil
+}
+
+func (r *merchRepository) AddPriceChange(ctx context.Context, change *models.MerchPriceChange) error {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	change.ID = r.db.tables.nextID()
+	change.CreatedAt = time.Now()
+	if change.EffectiveFrom.IsZero() {
+		change.EffectiveFrom = change.CreatedAt
+	}
+	c := *change
+	if c.ChangedBy != nil {
+		by := *c.ChangedBy
+		c.ChangedBy = &by
+	}
+	r.db.tables.prices = append(r.db.tables.prices, c)
+	return nil
+}
+
+func (r *merchRepository) GetPriceHistory(ctx context.Context, merchID int64) ([]models.MerchPriceChange, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	var history []models.MerchPriceChange
+	for _, c := range r.db.tables.prices {
+		if c.MerchID == merchID {
+			history = append(history, c)
+		}
+	}
+	sort.SliceStable(history, func(i, j int) bool { return history[i].EffectiveFrom.Before(history[j].EffectiveFrom) })
+	return history, nil
+}
+
+func (r *merchRepository) DeletePriceChange(ctx context.Context, id int64) error {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	for i, c := range r.db.tables.prices {
+		if c.ID == id {
+			r.db.tables.prices = append(r.db.tables.prices[:i:i], r.db.tables.prices[i+1:]...)
+			return nil
+		}
+	}
+	return storage.ErrPriceChangeNotFound
+}
+
+func (r *merchRepository) CreateVariant(ctx context.Context, variant *models.MerchVariant) error {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	for _, v := range r.db.tables.variants {
+		if v.SKU == variant.SKU {
+			return storage.ErrVariantExists
+		}
+	}
+	variant.ID = r.db.tables.nextID()
+	r.db.tables.variants = append(r.db.tables.variants, *copyVariant(*variant))
+	return nil
+}
+
+func (r *merchRepository) ListVariants(ctx context.Context, merchID int64) ([]models.MerchVariant, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	var variants []models.MerchVariant
+	for _, v := range r.db.tables.variants {
+		if v.MerchID == merchID && v.Active {
+			variants = append(variants, *copyVariant(v))
+		}
+	}
+	sort.Slice(variants, func(i, j int) bool { return variants[i].SKU < variants[j].SKU })
+	return variants, nil
+}
+
+// LockVariantBySKU и LockVariant не блокируют отдельно: транзакция и так держит блокировку всей базы.
+func (r *merchRepository) LockVariantBySKU(ctx context.Context, merchID int64, sku string) (*models.MerchVariant, error) {
+	return r.findVariant(ctx, func(v models.MerchVariant) bool { return v.MerchID == merchID && v.SKU == sku && v.Active })
+}
+
+func (r *merchRepository) LockVariant(ctx context.Context, id int64) (*models.MerchVariant, error) {
+	return r.findVariant(ctx, func(v models.MerchVariant) bool { return v.ID == id })
+}
+
+func (r *merchRepository) findVariant(ctx context.Context, match func(v models.MerchVariant) bool) (*models.MerchVariant, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	for _, v := range r.db.tables.variants {
+		if match(v) {
+			return copyVariant(v), nil
+		}
+	}
+	return nil, storage.ErrVariantNotFound
+}
+
+func (r *merchRepository) UpdateVariant(ctx context.Context, variant *models.MerchVariant) error {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	for i, v := range r.db.tables.variants {
+		if v.ID == variant.ID {
+			// Новые указатели: снимок таблиц для отката разделяет старые
+			updated := copyVariant(*variant)
+			v.Price, v.Stock, v.Active = updated.Price, updated.Stock, updated.Active
+			r.db.tables.variants[i] = v
+			return nil
+		}
+	}
+	return storage.ErrVariantNotFound
+}
+
+// effectiveMerch возвращает копию товара с ценой, действующей в момент now:
+// последней вступившей в силу записью истории, а без истории — ценой из merch.
+func (t *tables) effectiveMerch(m models.Merch, now time.Time) *models.Merch {
+	var latest *models.MerchPriceChange
+	for i, c := range t.prices {
+		if c.MerchID != m.ID || c.EffectiveFrom.After(now) {
+			continue
+		}
+		// при равном времени действует более поздняя запись, как и в PostgreSQL
+		if latest == nil || !c.EffectiveFrom.Before(latest.EffectiveFrom) {
+			latest = &t.prices[i]
+		}
+	}
+	if latest != nil {
+		m.Price = latest.Price
+	}
+	return copyMerch(m)
+}
+
+// copyMerch возвращает копию товара, не разделяющую Stock и Tags с таблицей.
+func copyMerch(m models.Merch) *models.Merch {
+	if m.Stock != nil {
+		v := *m.Stock
+		m.Stock = &v
+	}
+	m.Tags = append([]string(nil), m.Tags...)
+	return &m
+}
+
+// copyVariant возвращает копию варианта, не разделяющую Price и Stock с таблицей.
+func copyVariant(v models.MerchVariant) *models.MerchVariant {
+	if v.Price != nil {
+		p := *v.Price
+		v.Price = &p
+	}
+	if v.Stock != nil {
+		s := *v.Stock
+		v.Stock = &s
+	}
+	return &v
+}
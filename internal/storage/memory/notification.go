@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/storage"
+)
+
+// notificationRepository — реализация storage.NotificationStorage в памяти.
+type notificationRepository struct {
+	db *DB
+}
+
+// NewNotificationRepository создаёт репозиторий уведомлений в памяти.
+func NewNotificationRepository(db *DB) storage.NotificationStorage {
+	return &notificationRepository{db: db}
+}
+
+func (r *notificationRepository) CreateNotification(ctx context.Context, n *models.Notification) error {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	n.ID = r.db.tables.nextID()
+	n.CreatedAt = time.Now()
+	r.db.tables.notifications = append(r.db.tables.notifications, *copyNotification(*n))
+	return nil
+}
+
+func (r *notificationRepository) ListNotifications(ctx context.Context, userID int64, unreadOnly bool, limit int) ([]models.Notification, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	var notifications []models.Notification
+	for i := len(r.db.tables.notifications) - 1; i >= 0; i-- {
+		if limit > 0 && len(notifications) == limit {
+			break
+		}
+		n := r.db.tables.notifications[i]
+		if n.UserID != userID || unreadOnly && n.ReadAt != nil {
+			continue
+		}
+		notifications = append(notifications, *copyNotification(n))
+	}
+	return notifications, nil
+}
+
+func (r *notificationRepository) CountUnreadNotifications(ctx context.Context, userID int64) (int, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	count := 0
+	for _, n := range r.db.tables.notifications {
+		if n.UserID == userID && n.ReadAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *notificationRepository) MarkNotificationRead(ctx context.Context, userID, id int64, at time.Time) error {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	for i, n := range r.db.tables.notifications {
+		if n.ID != id || n.UserID != userID {
+			continue
+		}
+		if n.ReadAt == nil {
+			r.db.tables.notifications[i].ReadAt = &at
+		}
+		return nil
+	}
+	return storage.ErrNotificationNotFound
+}
+
+func (r *notificationRepository) MarkAllNotificationsRead(ctx context.Context, userID int64, at time.Time) (int, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	count := 0
+	for i, n := range r.db.tables.notifications {
+		if n.UserID == userID && n.ReadAt == nil {
+			r.db.tables.notifications[i].ReadAt = &at
+			count++
+		}
+	}
+	return count, nil
+}
+
+// copyNotification возвращает копию уведомления, не разделяющую Data и ReadAt с таблицей.
+func copyNotification(n models.Notification) *models.Notification {
+	n.Data = maps.Clone(n.Data)
+	if n.ReadAt != nil {
+		t := *n.ReadAt
+		n.ReadAt = &t
+	}
+	return &n
+}
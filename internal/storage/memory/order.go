@@ -0,0 +1,187 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/storage"
+)
+
+// orderRepository — реализация storage.OrderStorage в памяти.
+type orderRepository struct {
+	db *DB
+}
+
+// NewOrderRepository создаёт репозиторий заказов в памяти.
+func NewOrderRepository(db *DB) storage.OrderStorage {
+	return &orderRepository{db: db}
+}
+
+func (r *orderRepository) CreateOrder(ctx context.Context, order *models.Order) error {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	if order.Status == "" {
+		order.Status = models.OrderPlaced
+	}
+	order.ID = r.db.tables.nextID()
+	order.CreatedAt = time.Now()
+	order.UpdatedAt = order.CreatedAt
+	stored := *order
+	if order.GiftedBy != nil {
+		giftedBy := *order.GiftedBy
+		stored.GiftedBy = &giftedBy
+	}
+	if order.PromotionID != nil {
+		promotionID := *order.PromotionID
+		stored.PromotionID = &promotionID
+	}
+	if order.VariantID != nil {
+		variantID := *order.VariantID
+		stored.VariantID = &variantID
+	}
+	r.db.tables.orders = append(r.db.tables.orders, stored)
+	return nil
+}
+
+// GetOrdersByUserID возвращает заказы пользователя от новых к старым, подставляя имена товара и акции.
+func (r *orderRepository) GetOrdersByUserID(ctx context.Context, userID int64) ([]*models.Order, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	var orders []*models.Order
+	for i := len(r.db.tables.orders) - 1; i >= 0; i-- {
+		order := r.db.tables.orders[i]
+		if order.UserID != userID {
+			continue
+		}
+		orders = append(orders, r.db.tables.joinOrder(order))
+	}
+	return orders, nil
+}
+
+func (r *orderRepository) CountUserOrders(ctx context.Context, userID, merchID int64, since time.Time) (int, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	count := 0
+	for _, order := range r.db.tables.orders {
+		if order.UserID == userID && order.MerchID == merchID && !order.CreatedAt.Before(since) &&
+			order.Status != models.OrderCancelled {
+			count += order.Quantity
+		}
+	}
+	return count, nil
+}
+
+func (r *orderRepository) GetOrderByID(ctx context.Context, id int64) (*models.Order, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	for _, order := range r.db.tables.orders {
+		if order.ID == id {
+			return r.db.tables.joinOrder(order), nil
+		}
+	}
+	return nil, storage.ErrOrderNotFound
+}
+
+// LockOrder в памяти равносилен чтению: транзакция и так держит мьютекс всего хранилища.
+func (r *orderRepository) LockOrder(ctx context.Context, id int64) (*models.Order, error) {
+	return r.GetOrderByID(ctx, id)
+}
+
+func (r *orderRepository) ListOrders(ctx context.Context, userID int64, status string, limit int) ([]*models.Order, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	var orders []*models.Order
+	for i := len(r.db.tables.orders) - 1; i >= 0 && len(orders) < limit; i-- {
+		order := r.db.tables.orders[i]
+		if (userID != 0 && order.UserID != userID) || (status != "" && order.Status != status) {
+			continue
+		}
+		orders = append(orders, r.db.tables.joinOrder(order))
+	}
+	return orders, nil
+}
+
+func (r *orderRepository) UpdateOrderStatus(ctx context.Context, id int64, status string, updatedAt time.Time) error {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	for i, order := range r.db.tables.orders {
+		if order.ID == id {
+			r.db.tables.orders[i].Status = status
+			r.db.tables.orders[i].UpdatedAt = updatedAt
+			return nil
+		}
+	}
+	return storage.ErrOrderNotFound
+}
+
+func (r *orderRepository) AddOrderStatusChange(ctx context.Context, change *models.OrderStatusChange) error {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	change.ID = r.db.tables.nextID()
+	change.CreatedAt = time.Now()
+	r.db.tables.orderHistory = append(r.db.tables.orderHistory, *change)
+	return nil
+}
+
+func (r *orderRepository) GetOrderStatusHistory(ctx context.Context, orderID int64) ([]models.OrderStatusChange, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	var history []models.OrderStatusChange
+	for _, c := range r.db.tables.orderHistory {
+		if c.OrderID == orderID {
+			history = append(history, c)
+		}
+	}
+	return history, nil
+}
+
+func (r *orderRepository) CountPromotionOrders(ctx context.Context, promotionID, payerID int64) (int, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	count := 0
+	for _, order := range r.db.tables.orders {
+		if order.PromotionID == nil || *order.PromotionID != promotionID || order.Status == models.OrderCancelled {
+			continue
+		}
+		payer := order.UserID
+		if order.GiftedBy != nil {
+			payer = *order.GiftedBy
+		}
+		if payerID == 0 || payer == payerID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// joinOrder возвращает копию заказа с именами товара, акции и варианта, как JOIN в PostgreSQL.
+func (t *tables) joinOrder(order models.Order) *models.Order {
+	order.MerchName = t.merch[order.MerchID].Name
+	if order.PromotionID != nil {
+		for _, p := range t.promotions {
+			if p.ID == *order.PromotionID {
+				order.PromotionName = p.Name
+				break
+			}
+		}
+	}
+	if order.VariantID != nil {
+		for _, v := range t.variants {
+			if v.ID == *order.VariantID {
+				order.VariantSize, order.VariantColor = v.Size, v.Color
+				break
+			}
+		}
+	}
+	return &order
+}
@@ -0,0 +1,126 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/storage"
+)
+
+// promotionRepository — реализация storage.PromotionStorage в памяти.
+type promotionRepository struct {
+	db *DB
+}
+
+// NewPromotionRepository создаёт репозиторий акций в памяти.
+func NewPromotionRepository(db *DB) storage.PromotionStorage {
+	return &promotionRepository{db: db}
+}
+
+func (r *promotionRepository) CreatePromotion(ctx context.Context, promo *models.Promotion) error {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	if promo.Code != "" {
+		for _, p := range r.db.tables.promotions {
+			if p.Code == promo.Code {
+				return storage.ErrPromoCodeExists
+			}
+		}
+	}
+	promo.ID = r.db.tables.nextID()
+	promo.CreatedAt = time.Now()
+	r.db.tables.promotions = append(r.db.tables.promotions, *copyPromotion(*promo))
+	return nil
+}
+
+func (r *promotionRepository) GetPromotionByID(ctx context.Context, id int64) (*models.Promotion, error) {
+	return r.find(ctx, func(p models.Promotion) bool { return p.ID == id })
+}
+
+// LockPromotion совпадает с GetPromotionByID: транзакция и так держит блокировку всей базы.
+func (r *promotionRepository) LockPromotion(ctx context.Context, id int64) (*models.Promotion, error) {
+	return r.GetPromotionByID(ctx, id)
+}
+
+func (r *promotionRepository) LockPromotionByCode(ctx context.Context, code string) (*models.Promotion, error) {
+	return r.find(ctx, func(p models.Promotion) bool { return p.Code != "" && p.Code == code })
+}
+
+func (r *promotionRepository) find(ctx context.Context, match func(p models.Promotion) bool) (*models.Promotion, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	for _, p := range r.db.tables.promotions {
+		if match(p) {
+			return copyPromotion(p), nil
+		}
+	}
+	return nil, storage.ErrPromotionNotFound
+}
+
+func (r *promotionRepository) ListPromotions(ctx context.Context) ([]models.Promotion, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	promos := make([]models.Promotion, 0, len(r.db.tables.promotions))
+	for i := len(r.db.tables.promotions) - 1; i >= 0; i-- {
+		promos = append(promos, *copyPromotion(r.db.tables.promotions[i]))
+	}
+	return promos, nil
+}
+
+func (r *promotionRepository) ListAutoPromotions(ctx context.Context, merchID, userID int64, at time.Time) ([]models.Promotion, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	var promos []models.Promotion
+	for _, p := range r.db.tables.promotions {
+		if !p.Active || p.Code != "" ||
+			(p.MerchID != nil && *p.MerchID != merchID) || (p.UserID != nil && *p.UserID != userID) ||
+			(p.StartsAt != nil && p.StartsAt.After(at)) || (p.EndsAt != nil && !p.EndsAt.After(at)) {
+			continue
+		}
+		promos = append(promos, *copyPromotion(p))
+	}
+	return promos, nil
+}
+
+func (r *promotionRepository) SetPromotionActive(ctx context.Context, id int64, active bool) error {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	for i, p := range r.db.tables.promotions {
+		if p.ID == id {
+			r.db.tables.promotions[i].Active = active
+			return nil
+		}
+	}
+	return storage.ErrPromotionNotFound
+}
+
+// copyPromotion возвращает копию акции, не разделяющую указатели с таблицей.
+func copyPromotion(p models.Promotion) *models.Promotion {
+	if p.MerchID != nil {
+		v := *p.MerchID
+		p.MerchID = &v
+	}
+	if p.UserID != nil {
+		v := *p.UserID
+		p.UserID = &v
+	}
+	if p.StartsAt != nil {
+		v := *p.StartsAt
+		p.StartsAt = &v
+	}
+	if p.EndsAt != nil {
+		v := *p.EndsAt
+		p.EndsAt = &v
+	}
+	if p.CreatedBy != nil {
+		v := *p.CreatedBy
+		p.CreatedBy = &v
+	}
+	return &p
+}
@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/storage"
+)
+
+// coinRequestRepository — реализация storage.CoinRequestStorage в памяти.
+type coinRequestRepository struct {
+	db *DB
+}
+
+// NewCoinRequestRepository создаёт репозиторий запросов монет в памяти.
+func NewCoinRequestRepository(db *DB) storage.CoinRequestStorage {
+	return &coinRequestRepository{db: db}
+}
+
+func (r *coinRequestRepository) CreateCoinRequest(ctx context.Context, req *models.CoinRequest) error {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	req.ID = r.db.tables.nextID()
+	req.CreatedAt = time.Now()
+	r.db.tables.coinRequests = append(r.db.tables.coinRequests, *req)
+	return nil
+}
+
+// LockCoinRequest в памяти равносилен чтению: транзакция и так держит мьютекс всего хранилища.
+func (r *coinRequestRepository) LockCoinRequest(ctx context.Context, id int64) (*models.CoinRequest, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	for _, req := range r.db.tables.coinRequests {
+		if req.ID == id {
+			return &req, nil
+		}
+	}
+	return nil, storage.ErrCoinRequestNotFound
+}
+
+func (r *coinRequestRepository) ResolveCoinRequest(ctx context.Context, id int64, status string, resolvedAt time.Time) error {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	for i, req := range r.db.tables.coinRequests {
+		if req.ID == id {
+			r.db.tables.coinRequests[i].Status = status
+			r.db.tables.coinRequests[i].ResolvedAt = &resolvedAt
+			return nil
+		}
+	}
+	return storage.ErrCoinRequestNotFound
+}
+
+// ListCoinRequests: запросы хранятся в порядке создания, обход с конца даёт новые первыми.
+func (r *coinRequestRepository) ListCoinRequests(ctx context.Context, userID int64, incoming bool, limit int) ([]models.CoinRequest, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	var requests []models.CoinRequest
+	for i := len(r.db.tables.coinRequests) - 1; i >= 0 && len(requests) < limit; i-- {
+		req := r.db.tables.coinRequests[i]
+		if (incoming && req.PayerID == userID) || (!incoming && req.RequesterID == userID) {
+			requests = append(requests, req)
+		}
+	}
+	return requests, nil
+}
+
+func (r *coinRequestRepository) ExpireCoinRequests(ctx context.Context, now time.Time) (int, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	expired := 0
+	for i, req := range r.db.tables.coinRequests {
+		if req.Status == models.CoinRequestPending && !req.ExpiresAt.After(now) {
+			r.db.tables.coinRequests[i].Status = models.CoinRequestExpired
+			r.db.tables.coinRequests[i].ResolvedAt = &now
+			expired++
+		}
+	}
+	return expired, nil
+}
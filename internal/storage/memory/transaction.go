@@ -0,0 +1,119 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/storage"
+)
+
+// coinTransactionRepository — реализация storage.CoinTransactionStorage в памяти.
+type coinTransactionRepository struct {
+	db *DB
+}
+
+// NewCoinTransactionRepository создаёт репозиторий операций с монетами в памяти.
+func NewCoinTransactionRepository(db *DB) storage.CoinTransactionStorage {
+	return &coinTransactionRepository{db: db}
+}
+
+func (r *coinTransactionRepository) CreateTransaction(ctx context.Context, userID int64, amount int, txType string, relatedUserID *int64) error {
+	return r.InsertTransaction(ctx, &models.CoinTransaction{
+		UserID:        userID,
+		Amount:        amount,
+		Type:          txType,
+		RelatedUserID: relatedUserID,
+	})
+}
+
+func (r *coinTransactionRepository) InsertTransaction(ctx context.Context, tx *models.CoinTransaction) error {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	if tx.IdempotencyKey != nil && r.db.tables.hasCoinTxKey(*tx.IdempotencyKey) {
+		return storage.ErrDuplicateTransaction
+	}
+
+	stored := *tx
+	stored.ID = r.db.tables.nextID()
+	stored.CreatedAt = time.Now()
+	if tx.RelatedUserID != nil {
+		related := *tx.RelatedUserID
+		stored.RelatedUserID = &related
+	}
+	if tx.IdempotencyKey != nil {
+		key := *tx.IdempotencyKey
+		stored.IdempotencyKey = &key
+	}
+	r.db.tables.coinTxs = append(r.db.tables.coinTxs, stored)
+
+	tx.ID, tx.CreatedAt = stored.ID, stored.CreatedAt
+	return nil
+}
+
+func (r *coinTransactionRepository) TransactionKeyExists(ctx context.Context, key string) (bool, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	return r.db.tables.hasCoinTxKey(key), nil
+}
+
+func (t *tables) hasCoinTxKey(key string) bool {
+	for _, tx := range t.coinTxs {
+		if tx.IdempotencyKey != nil && *tx.IdempotencyKey == key {
+			return true
+		}
+	}
+	return false
+}
+
+// GetTransactionsByUserID возвращает операции пользователя от новых к старым.
+func (r *coinTransactionRepository) GetTransactionsByUserID(ctx context.Context, userID int64) ([]*models.CoinTransaction, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	var transactions []*models.CoinTransaction
+	for i := len(r.db.tables.coinTxs) - 1; i >= 0; i-- {
+		tx := r.db.tables.coinTxs[i]
+		if tx.UserID == userID {
+			transactions = append(transactions, &tx)
+		}
+	}
+	return transactions, nil
+}
+
+// SearchTransactions ищет подстроку в комментарии без учёта регистра, как ILIKE в PostgreSQL.
+func (r *coinTransactionRepository) SearchTransactions(ctx context.Context, userID int64, query string, limit int) ([]*models.CoinTransaction, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	query = strings.ToLower(query)
+	var transactions []*models.CoinTransaction
+	for i := len(r.db.tables.coinTxs) - 1; i >= 0 && len(transactions) < limit; i-- {
+		tx := r.db.tables.coinTxs[i]
+		if tx.UserID != userID {
+			continue
+		}
+		if query != "" && !strings.Contains(strings.ToLower(tx.Comment), query) {
+			continue
+		}
+		transactions = append(transactions, &tx)
+	}
+	return transactions, nil
+}
+
+func (r *coinTransactionRepository) TransactionStats(ctx context.Context, userID int64, txType string, since time.Time) (int, int, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	var count, sum int
+	for _, tx := range r.db.tables.coinTxs {
+		if tx.UserID == userID && tx.Type == txType && !tx.CreatedAt.Before(since) {
+			count++
+			sum += tx.Amount
+		}
+	}
+	return count, sum, nil
+}
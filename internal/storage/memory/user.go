@@ -0,0 +1,125 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/storage"
+)
+
+// userRepository — реализация storage.UserStorage в памяти.
+type userRepository struct {
+	db *DB
+}
+
+// NewUserRepository создаёт репозиторий пользователей в памяти.
+func NewUserRepository(db *DB) storage.UserStorage {
+	return &userRepository{db: db}
+}
+
+func (r *userRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	for _, u := range r.db.tables.users {
+		if u.Email == email {
+			return &u, nil
+		}
+	}
+	return nil, storage.ErrUserNotFound
+}
+
+func (r *userRepository) CreateUser(ctx context.Context, user *models.User) (*models.User, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	for _, u := range r.db.tables.users {
+		if u.Email == user.Email {
+			return nil, storage.ErrUserExists
+		}
+	}
+	user.ID = r.db.tables.nextID()
+	// дату регистрации можно задать заранее (фикстуры, тесты правил по возрасту аккаунта)
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = time.Now()
+	}
+	stored := *user
+	stored.PassHash = append([]byte(nil), user.PassHash...)
+	r.db.tables.users[user.ID] = stored
+	return user, nil
+}
+
+func (r *userRepository) GetUserByID(ctx context.Context, id int64) (*models.User, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	u, ok := r.db.tables.users[id]
+	if !ok {
+		return nil, storage.ErrUserNotFound
+	}
+	return &u, nil
+}
+
+// LockUserByID в памяти равносилен GetUserByID: транзакция и так держит мьютекс всего хранилища.
+func (r *userRepository) LockUserByID(ctx context.Context, id int64) (*models.User, error) {
+	return r.GetUserByID(ctx, id)
+}
+
+func (r *userRepository) UpdateUserBalance(ctx context.Context, id int64, newBalance int) error {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	u, ok := r.db.tables.users[id]
+	if !ok {
+		return storage.ErrUserNotFound
+	}
+	u.CoinBalance = newBalance
+	r.db.tables.users[id] = u
+	return nil
+}
+
+func (r *userRepository) UpdateUserPassword(ctx context.Context, id int64, passHash []byte) error {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	u, ok := r.db.tables.users[id]
+	if !ok {
+		return storage.ErrUserNotFound
+	}
+	u.PassHash = append([]byte(nil), passHash...)
+	r.db.tables.users[id] = u
+	return nil
+}
+
+func (r *userRepository) SetUserAdmin(ctx context.Context, id int64, isAdmin bool) error {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	u, ok := r.db.tables.users[id]
+	if !ok {
+		return storage.ErrUserNotFound
+	}
+	u.IsAdmin = isAdmin
+	r.db.tables.users[id] = u
+	return nil
+}
+
+// ListActiveUserIDs: деактивации сотрудников в памяти нет, активны все пользователи.
+func (r *userRepository) ListActiveUserIDs(ctx context.Context, afterID int64, limit int) ([]int64, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	var ids []int64
+	for id := range r.db.tables.users {
+		if id > afterID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return ids, nil
+}
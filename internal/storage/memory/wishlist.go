@@ -0,0 +1,139 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+	"github.com/linemk/avito-shop/internal/storage"
+)
+
+// wishlistRepository — реализация storage.WishlistStorage в памяти.
+type wishlistRepository struct {
+	db *DB
+}
+
+// NewWishlistRepository создаёт репозиторий списков желаний в памяти.
+func NewWishlistRepository(db *DB) storage.WishlistStorage {
+	return &wishlistRepository{db: db}
+}
+
+func (r *wishlistRepository) AddToWishlist(ctx context.Context, userID, merchID int64) (*models.WishlistItem, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	for _, item := range r.db.tables.wishlist {
+		if item.UserID == userID && item.MerchID == merchID {
+			return nil, storage.ErrWishlistItemExists
+		}
+	}
+	item := models.WishlistItem{UserID: userID, MerchID: merchID, CreatedAt: time.Now()}
+	r.db.tables.wishlist = append(r.db.tables.wishlist, item)
+	return &item, nil
+}
+
+func (r *wishlistRepository) RemoveFromWishlist(ctx context.Context, userID, merchID int64) error {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	for i, item := range r.db.tables.wishlist {
+		if item.UserID == userID && item.MerchID == merchID {
+			// Новый срез: снимок таблиц для отката разделяет старый
+			wishlist := append([]models.WishlistItem(nil), r.db.tables.wishlist[:i]...)
+			r.db.tables.wishlist = append(wishlist, r.db.tables.wishlist[i+1:]...)
+			return nil
+		}
+	}
+	return storage.ErrWishlistItemNotFound
+}
+
+func (r *wishlistRepository) ListWishlist(ctx context.Context, userID int64) ([]models.WishlistItem, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	var items []models.WishlistItem
+	for _, item := range r.db.tables.wishlist {
+		if item.UserID == userID {
+			items = append(items, item)
+		}
+	}
+	// Добавленные позже стоят в таблице дальше
+	for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+		items[i], items[j] = items[j], items[i]
+	}
+	return items, nil
+}
+
+func (r *wishlistRepository) ListWishlistPrices(ctx context.Context, userID int64) ([]models.WishlistPrice, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	now := time.Now()
+	var items []models.WishlistPrice
+	// Добавленные позже стоят в таблице дальше: идём с конца, чтобы новые были первыми
+	for i := len(r.db.tables.wishlist) - 1; i >= 0; i-- {
+		entry := r.db.tables.wishlist[i]
+		if entry.UserID != userID {
+			continue
+		}
+		m, ok := r.db.tables.merch[entry.MerchID]
+		if !ok {
+			continue
+		}
+		items = append(items, r.db.tables.wishlistPrice(r.db.tables.effectiveMerch(m, now)))
+	}
+	return items, nil
+}
+
+// wishlistPrice считает цену товара из списка желаний так же, как запрос PostgreSQL:
+// самое дешёвое предложение в продаже среди активных вариантов, а без вариантов — сам товар.
+func (t *tables) wishlistPrice(m *models.Merch) models.WishlistPrice {
+	type offer struct {
+		price int
+		stock *int
+	}
+	var offers []offer
+	for _, v := range t.variants {
+		if v.MerchID != m.ID || !v.Active {
+			continue
+		}
+		o := offer{price: m.Price, stock: m.Stock}
+		if v.Price != nil {
+			o.price = *v.Price
+		}
+		if v.Stock != nil {
+			o.stock = v.Stock
+		}
+		offers = append(offers, o)
+	}
+	if len(offers) == 0 {
+		offers = append(offers, offer{price: m.Price, stock: m.Stock})
+	}
+
+	item := models.WishlistPrice{MerchID: m.ID, Name: m.Name, Price: m.Price, SoldOut: true}
+	for _, o := range offers {
+		if o.stock != nil && *o.stock <= 0 {
+			continue
+		}
+		if item.SoldOut || o.price < item.Price {
+			item.Price = o.price
+		}
+		item.SoldOut = false
+	}
+	return item
+}
+
+func (r *wishlistRepository) ListWishlistUsers(ctx context.Context, merchID int64) ([]int64, error) {
+	unlock := r.db.lock(ctx)
+	defer unlock()
+
+	var ids []int64
+	for _, item := range r.db.tables.wishlist {
+		if item.MerchID == merchID {
+			ids = append(ids, item.UserID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
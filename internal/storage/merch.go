@@ -4,14 +4,56 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"time"
 
+	"github.com/lib/pq"
 	"github.com/linemk/avito-shop/internal/domain/models"
 )
 
 // MerchStorage описывает методы для работы с таблицей мерча.
 type MerchStorage interface {
-	// GetMerchByName получает мерч по его названию, используя транзакцию.
-	GetMerchByName(ctx context.Context, tx *sql.Tx, name string) (*models.Merch, error)
+	// GetMerchByName получает мерч по его названию (в текущей транзакции, если она открыта).
+	GetMerchByName(ctx context.Context, name string) (*models.Merch, error)
+	// LockMerchByName получает мерч по названию и блокирует строку до конца транзакции,
+	// чтобы параллельные покупки не продали больше остатка.
+	LockMerchByName(ctx context.Context, name string) (*models.Merch, error)
+	// LockMerchByID — то же по идентификатору, для заказов, в которых товар уже известен по ID.
+	LockMerchByID(ctx context.Context, id int64) (*models.Merch, error)
+	// UpsertMerch добавляет товар или обновляет цену существующего с тем же именем (и снова делает его активным).
+	UpsertMerch(ctx context.Context, name string, price int) (*models.Merch, error)
+	// UpdateMerchStock задаёт остаток товара; nil — количество не ограничено.
+	UpdateMerchStock(ctx context.Context, id int64, stock *int) error
+	// UpdateMerchDetails задаёт категорию, описание и теги товара.
+	UpdateMerchDetails(ctx context.Context, id int64, category, description string, tags []string) error
+	// UpdateMerchImage задаёт ключ изображения товара в хранилище blob; пусто — изображения нет.
+	UpdateMerchImage(ctx context.Context, id int64, key string) error
+	// UpdatePurchaseLimit задаёт лимит покупок товара одним пользователем за период; 0 — без ограничения.
+	UpdatePurchaseLimit(ctx context.Context, id int64, limit int, period string) error
+	// ListMerch возвращает активные товары каталога по названию.
+	ListMerch(ctx context.Context) ([]models.Merch, error)
+	// UpdateMerchPrice меняет цену товара в merch; действующую цену определяет история цен.
+	UpdateMerchPrice(ctx context.Context, id int64, price int) error
+	// AddPriceChange записывает изменение цены в историю и заполняет change.ID и change.CreatedAt.
+	// Нулевой EffectiveFrom — цена действует сразу: время берётся из часов базы и записывается в change.
+	AddPriceChange(ctx context.Context, change *models.MerchPriceChange) error
+	// GetPriceHistory возвращает историю цен товара, включая запланированные изменения,
+	// по возрастанию EffectiveFrom.
+	GetPriceHistory(ctx context.Context, merchID int64) ([]models.MerchPriceChange, error)
+	// DeletePriceChange удаляет запись истории цен.
+	DeletePriceChange(ctx context.Context, id int64) error
+	// CreateVariant добавляет вариант товара и заполняет variant.ID.
+	// Если артикул уже занят, возвращает ErrVariantExists.
+	CreateVariant(ctx context.Context, variant *models.MerchVariant) error
+	// ListVariants возвращает активные варианты товара по артикулу.
+	ListVariants(ctx context.Context, merchID int64) ([]models.MerchVariant, error)
+	// LockVariantBySKU возвращает активный вариант товара merchID по артикулу и блокирует его строку
+	// до конца транзакции, чтобы параллельные покупки не продали больше остатка варианта.
+	LockVariantBySKU(ctx context.Context, merchID int64, sku string) (*models.MerchVariant, error)
+	// LockVariant — то же по идентификатору, включая выключенные варианты (для возврата на склад).
+	LockVariant(ctx context.Context, id int64) (*models.MerchVariant, error)
+	// UpdateVariant сохраняет цену, остаток и активность варианта.
+	UpdateVariant(ctx context.Context, variant *models.MerchVariant) error
 }
 
 // merchRepository — конкретная реализация интерфейса MerchStorage.
@@ -24,14 +66,56 @@ func NewMerchRepository(db *sql.DB) MerchStorage {
 	return &merchRepository{db: db}
 }
 
-var ErrMerchNotFound = errors.New("merch not found")
+var (
+	ErrMerchNotFound = errors.New("merch not found")
+	// ErrPriceChangeNotFound — запись истории цен не найдена.
+	ErrPriceChangeNotFound = errors.New("price change not found")
+	// ErrVariantNotFound — у товара нет такого варианта.
+	ErrVariantNotFound = errors.New("merch variant not found")
+	// ErrVariantExists — вариант с таким артикулом уже есть.
+	ErrVariantExists = errors.New("merch variant already exists")
+)
 
-// GetMerchByName ищет мерч по имени в таблице merch.
-func (r *merchRepository) GetMerchByName(ctx context.Context, tx *sql.Tx, name string) (*models.Merch, error) {
+// merchColumns — столбцы merch в порядке полей, которые читает scanMerch.
+const merchColumns = "id, name, price, stock, purchase_limit, purchase_limit_period, category, description, image_key, tags"
+
+// merchPrice — действующая цена товара m: последняя вступившая в силу запись merch_price_history,
+// а без истории — merch.price.
+const merchPrice = `COALESCE((SELECT h.price FROM merch_price_history h
+		WHERE h.merch_id = m.id AND h.effective_from <= NOW()
+		ORDER BY h.effective_from DESC, h.id DESC LIMIT 1), m.price)`
+
+// merchSelect выбирает товары (таблица m) в порядке полей scanMerch с действующей ценой.
+const merchSelect = `SELECT m.id, m.name,
+	` + merchPrice + `,
+	m.stock, m.purchase_limit, m.purchase_limit_period, m.category, m.description, m.image_key, m.tags
+	FROM merch m`
+
+func scanMerch(row interface{ Scan(dest ...any) error }) (*models.Merch, error) {
 	merch := &models.Merch{}
-	query := "SELECT id, name, price FROM merch WHERE name = $1"
-	row := tx.QueryRowContext(ctx, query, name)
-	if err := row.Scan(&merch.ID, &merch.Name, &merch.Price); err != nil {
+	if err := row.Scan(&merch.ID, &merch.Name, &merch.Price, &merch.Stock, &merch.PurchaseLimit, &merch.PurchaseLimitPeriod,
+		&merch.Category, &merch.Description, &merch.ImageKey, pq.Array(&merch.Tags)); err != nil {
+		return nil, err
+	}
+	return merch, nil
+}
+
+// GetMerchByName ищет мерч по имени в таблице merch.
+func (r *merchRepository) GetMerchByName(ctx context.Context, name string) (*models.Merch, error) {
+	return r.getMerch(ctx, merchSelect+" WHERE m.name = $1", name)
+}
+
+func (r *merchRepository) LockMerchByName(ctx context.Context, name string) (*models.Merch, error) {
+	return r.getMerch(ctx, merchSelect+" WHERE m.name = $1 FOR UPDATE OF m", name)
+}
+
+func (r *merchRepository) LockMerchByID(ctx context.Context, id int64) (*models.Merch, error) {
+	return r.getMerch(ctx, merchSelect+" WHERE m.id = $1 FOR UPDATE OF m", id)
+}
+
+func (r *merchRepository) getMerch(ctx context.Context, query string, arg any) (*models.Merch, error) {
+	merch, err := scanMerch(conn(ctx, r.db).QueryRowContext(ctx, query, arg))
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrMerchNotFound
 		}
@@ -39,3 +123,252 @@ func (r *merchRepository) GetMerchByName(ctx context.Context, tx *sql.Tx, name s
 	}
 	return merch, nil
 }
+
+// UpsertMerch добавляет товар или обновляет его цену по уникальному имени.
+// Возвращает цену из merch: историю цен ведёт вызывающий.
+func (r *merchRepository) UpsertMerch(ctx context.Context, name string, price int) (*models.Merch, error) {
+	query := `INSERT INTO merch (name, price) VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET price = EXCLUDED.price, is_active = TRUE
+		RETURNING ` + merchColumns
+	return scanMerch(conn(ctx, r.db).QueryRowContext(ctx, query, name, price))
+}
+
+func (r *merchRepository) UpdateMerchStock(ctx context.Context, id int64, stock *int) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, "UPDATE merch SET stock = $1 WHERE id = $2", stock, id)
+	if err != nil {
+		return fmt.Errorf("failed to update merch stock: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update merch stock: %w", err)
+	}
+	if n == 0 {
+		return ErrMerchNotFound
+	}
+	return nil
+}
+
+func (r *merchRepository) UpdateMerchDetails(ctx context.Context, id int64, category, description string, tags []string) error {
+	if tags == nil {
+		tags = []string{}
+	}
+	res, err := conn(ctx, r.db).ExecContext(ctx,
+		"UPDATE merch SET category = $1, description = $2, tags = $3 WHERE id = $4", category, description, pq.Array(tags), id)
+	if err != nil {
+		return fmt.Errorf("failed to update merch details: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update merch details: %w", err)
+	}
+	if n == 0 {
+		return ErrMerchNotFound
+	}
+	return nil
+}
+
+func (r *merchRepository) UpdateMerchImage(ctx context.Context, id int64, key string) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, "UPDATE merch SET image_key = $1 WHERE id = $2", key, id)
+	if err != nil {
+		return fmt.Errorf("failed to update merch image: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update merch image: %w", err)
+	}
+	if n == 0 {
+		return ErrMerchNotFound
+	}
+	return nil
+}
+
+func (r *merchRepository) UpdatePurchaseLimit(ctx context.Context, id int64, limit int, period string) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx,
+		"UPDATE merch SET purchase_limit = $1, purchase_limit_period = $2 WHERE id = $3", limit, period, id)
+	if err != nil {
+		return fmt.Errorf("failed to update purchase limit: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update purchase limit: %w", err)
+	}
+	if n == 0 {
+		return ErrMerchNotFound
+	}
+	return nil
+}
+
+func (r *merchRepository) ListMerch(ctx context.Context) ([]models.Merch, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, merchSelect+" WHERE m.is_active ORDER BY m.name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list merch: %w", err)
+	}
+	defer rows.Close()
+
+	var items []models.Merch
+	for rows.Next() {
+		m, err := scanMerch(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan merch: %w", err)
+		}
+		items = append(items, *m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *merchRepository) UpdateMerchPrice(ctx context.Context, id int64, price int) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, "UPDATE merch SET price = $1 WHERE id = $2", price, id)
+	if err != nil {
+		return fmt.Errorf("failed to update merch price: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update merch price: %w", err)
+	}
+	if n == 0 {
+		return ErrMerchNotFound
+	}
+	return nil
+}
+
+func (r *merchRepository) AddPriceChange(ctx context.Context, change *models.MerchPriceChange) error {
+	var effectiveFrom *time.Time
+	if !change.EffectiveFrom.IsZero() {
+		effectiveFrom = &change.EffectiveFrom
+	}
+	query := `INSERT INTO merch_price_history (merch_id, price, effective_from, changed_by, comment, created_at)
+	          VALUES ($1, $2, COALESCE($3, NOW()), $4, NULLIF($5, ''), NOW()) RETURNING id, effective_from, created_at`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, change.MerchID, change.Price, effectiveFrom, change.ChangedBy, change.Comment).
+		Scan(&change.ID, &change.EffectiveFrom, &change.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record price change: %w", err)
+	}
+	return nil
+}
+
+func (r *merchRepository) GetPriceHistory(ctx context.Context, merchID int64) ([]models.MerchPriceChange, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT id, merch_id, price, effective_from, changed_by, COALESCE(comment, ''), created_at
+		FROM merch_price_history
+		WHERE merch_id = $1
+		ORDER BY effective_from, id`, merchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []models.MerchPriceChange
+	for rows.Next() {
+		var c models.MerchPriceChange
+		if err := rows.Scan(&c.ID, &c.MerchID, &c.Price, &c.EffectiveFrom, &c.ChangedBy, &c.Comment, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan price change: %w", err)
+		}
+		history = append(history, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+func (r *merchRepository) DeletePriceChange(ctx context.Context, id int64) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM merch_price_history WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete price change: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete price change: %w", err)
+	}
+	if n == 0 {
+		return ErrPriceChangeNotFound
+	}
+	return nil
+}
+
+// variantColumns — столбцы merch_variants в порядке полей, которые читает scanVariant.
+const variantColumns = "id, merch_id, sku, size, color, price, stock, is_active"
+
+func scanVariant(row interface{ Scan(dest ...any) error }) (*models.MerchVariant, error) {
+	v := &models.MerchVariant{}
+	if err := row.Scan(&v.ID, &v.MerchID, &v.SKU, &v.Size, &v.Color, &v.Price, &v.Stock, &v.Active); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (r *merchRepository) CreateVariant(ctx context.Context, variant *models.MerchVariant) error {
+	query := `INSERT INTO merch_variants (merch_id, sku, size, color, price, stock, is_active)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, variant.MerchID, variant.SKU, variant.Size, variant.Color,
+		variant.Price, variant.Stock, variant.Active).Scan(&variant.ID)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" { // unique_violation
+			return ErrVariantExists
+		}
+		return fmt.Errorf("failed to create merch variant: %w", err)
+	}
+	return nil
+}
+
+func (r *merchRepository) ListVariants(ctx context.Context, merchID int64) ([]models.MerchVariant, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx,
+		"SELECT "+variantColumns+" FROM merch_variants WHERE merch_id = $1 AND is_active ORDER BY sku", merchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list merch variants: %w", err)
+	}
+	defer rows.Close()
+
+	var variants []models.MerchVariant
+	for rows.Next() {
+		v, err := scanVariant(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan merch variant: %w", err)
+		}
+		variants = append(variants, *v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return variants, nil
+}
+
+func (r *merchRepository) LockVariantBySKU(ctx context.Context, merchID int64, sku string) (*models.MerchVariant, error) {
+	return r.getVariant(ctx, "SELECT "+variantColumns+" FROM merch_variants WHERE merch_id = $1 AND sku = $2 AND is_active FOR UPDATE",
+		merchID, sku)
+}
+
+func (r *merchRepository) LockVariant(ctx context.Context, id int64) (*models.MerchVariant, error) {
+	return r.getVariant(ctx, "SELECT "+variantColumns+" FROM merch_variants WHERE id = $1 FOR UPDATE", id)
+}
+
+func (r *merchRepository) getVariant(ctx context.Context, query string, args ...any) (*models.MerchVariant, error) {
+	v, err := scanVariant(conn(ctx, r.db).QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrVariantNotFound
+		}
+		return nil, fmt.Errorf("failed to get merch variant: %w", err)
+	}
+	return v, nil
+}
+
+func (r *merchRepository) UpdateVariant(ctx context.Context, variant *models.MerchVariant) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx,
+		"UPDATE merch_variants SET price = $1, stock = $2, is_active = $3 WHERE id = $4",
+		variant.Price, variant.Stock, variant.Active, variant.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update merch variant: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update merch variant: %w", err)
+	}
+	if n == 0 {
+		return ErrVariantNotFound
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+)
+
+// ErrNotificationNotFound — уведомление не найдено или принадлежит другому пользователю.
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// NotificationStorage описывает методы для работы с уведомлениями.
+type NotificationStorage interface {
+	// CreateNotification записывает уведомление и заполняет n.ID и n.CreatedAt.
+	CreateNotification(ctx context.Context, n *models.Notification) error
+	// ListNotifications возвращает не больше limit уведомлений пользователя от новых к старым
+	// (limit <= 0 — все). unreadOnly оставляет только непрочитанные.
+	ListNotifications(ctx context.Context, userID int64, unreadOnly bool, limit int) ([]models.Notification, error)
+	// CountUnreadNotifications возвращает число непрочитанных уведомлений пользователя.
+	CountUnreadNotifications(ctx context.Context, userID int64) (int, error)
+	// MarkNotificationRead отмечает уведомление id пользователя userID прочитанным в момент at.
+	// Повторная отметка не меняет время прочтения.
+	MarkNotificationRead(ctx context.Context, userID, id int64, at time.Time) error
+	// MarkAllNotificationsRead отмечает прочитанными все уведомления пользователя
+	// и возвращает, сколько из них были непрочитанными.
+	MarkAllNotificationsRead(ctx context.Context, userID int64, at time.Time) (int, error)
+}
+
+// notificationRepository — конкретная реализация NotificationStorage.
+type notificationRepository struct {
+	db *sql.DB
+}
+
+// NewNotificationRepository создаёт новый репозиторий уведомлений.
+func NewNotificationRepository(db *sql.DB) NotificationStorage {
+	return &notificationRepository{db: db}
+}
+
+// notificationColumns — столбцы notifications в порядке полей, которые читает scanNotification.
+const notificationColumns = "id, user_id, type, message, data, read_at, created_at"
+
+func scanNotification(row interface{ Scan(dest ...any) error }) (*models.Notification, error) {
+	n := &models.Notification{}
+	var data []byte
+	var readAt sql.NullTime
+	if err := row.Scan(&n.ID, &n.UserID, &n.Type, &n.Message, &data, &readAt, &n.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &n.Data); err != nil {
+		return nil, fmt.Errorf("failed to decode notification data: %w", err)
+	}
+	if readAt.Valid {
+		n.ReadAt = &readAt.Time
+	}
+	return n, nil
+}
+
+func (r *notificationRepository) CreateNotification(ctx context.Context, n *models.Notification) error {
+	data, err := json.Marshal(n.Data)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification data: %w", err)
+	}
+	if n.Data == nil {
+		data = []byte("{}")
+	}
+	err = conn(ctx, r.db).QueryRowContext(ctx,
+		`INSERT INTO notifications (user_id, type, message, data, created_at)
+		 VALUES ($1, $2, $3, $4, NOW()) RETURNING id, created_at`,
+		n.UserID, n.Type, n.Message, data).Scan(&n.ID, &n.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create notification: %w", err)
+	}
+	return nil
+}
+
+func (r *notificationRepository) ListNotifications(ctx context.Context, userID int64, unreadOnly bool, limit int) ([]models.Notification, error) {
+	query := "SELECT " + notificationColumns + " FROM notifications WHERE user_id = $1"
+	if unreadOnly {
+		query += " AND read_at IS NULL"
+	}
+	query += " ORDER BY id DESC"
+	args := []any{userID}
+	if limit > 0 {
+		query += " LIMIT $2"
+		args = append(args, limit)
+	}
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+	defer rows.Close()
+
+	var notifications []models.Notification
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		notifications = append(notifications, *n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+func (r *notificationRepository) CountUnreadNotifications(ctx context.Context, userID int64) (int, error) {
+	var count int
+	err := conn(ctx, r.db).QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL", userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+	return count, nil
+}
+
+func (r *notificationRepository) MarkNotificationRead(ctx context.Context, userID, id int64, at time.Time) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx,
+		"UPDATE notifications SET read_at = COALESCE(read_at, $3) WHERE id = $1 AND user_id = $2",
+		id, userID, at)
+	if err != nil {
+		return fmt.Errorf("failed to mark notification read: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to mark notification read: %w", err)
+	}
+	if n == 0 {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
+
+func (r *notificationRepository) MarkAllNotificationsRead(ctx context.Context, userID int64, at time.Time) (int, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx,
+		"UPDATE notifications SET read_at = $2 WHERE user_id = $1 AND read_at IS NULL", userID, at)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+	return int(n), nil
+}
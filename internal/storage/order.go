@@ -3,17 +3,42 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/linemk/avito-shop/internal/domain/models"
 )
 
+// ErrOrderNotFound — заказ не найден.
+var ErrOrderNotFound = errors.New("order not found")
+
 // OrderStorage описывает методы для работы с заказами.
 type OrderStorage interface {
-	// CreateOrder вставляет новый заказ в таблицу orders с использованием транзакции.
-	CreateOrder(ctx context.Context, tx *sql.Tx, userID int64, merchID int64, quantity int, totalPrice int) error
+	// CreateOrder вставляет новый заказ в таблицу orders (в текущей транзакции, если она открыта)
+	// и заполняет order.ID и order.CreatedAt. Пустой статус означает models.OrderPlaced.
+	CreateOrder(ctx context.Context, order *models.Order) error
 	// GetOrdersByUserID возвращает список заказов для указанного пользователя, с JOIN для получения имени товара.
 	GetOrdersByUserID(ctx context.Context, userID int64) ([]*models.Order, error)
+	// CountUserOrders возвращает, сколько единиц товара merchID пользователь получил начиная с since
+	// (покупки и подарки, кроме отменённых).
+	CountUserOrders(ctx context.Context, userID, merchID int64, since time.Time) (int, error)
+	// GetOrderByID возвращает заказ с именем товара.
+	GetOrderByID(ctx context.Context, id int64) (*models.Order, error)
+	// LockOrder возвращает заказ и блокирует его строку до конца транзакции.
+	LockOrder(ctx context.Context, id int64) (*models.Order, error)
+	// ListOrders возвращает до limit последних заказов пользователя userID (0 — всех пользователей)
+	// в статусе status (пусто — в любом).
+	ListOrders(ctx context.Context, userID int64, status string, limit int) ([]*models.Order, error)
+	// UpdateOrderStatus меняет статус заказа и время его последнего изменения.
+	UpdateOrderStatus(ctx context.Context, id int64, status string, updatedAt time.Time) error
+	// AddOrderStatusChange записывает смену статуса в историю заказа.
+	AddOrderStatusChange(ctx context.Context, change *models.OrderStatusChange) error
+	// GetOrderStatusHistory возвращает историю статусов заказа от старых записей к новым.
+	GetOrderStatusHistory(ctx context.Context, orderID int64) ([]models.OrderStatusChange, error)
+	// CountPromotionOrders возвращает, сколько неотменённых заказов оформлено по акции promotionID
+	// покупателем payerID (тем, кто платил: gifted_by или user_id); payerID = 0 — всеми.
+	CountPromotionOrders(ctx context.Context, promotionID, payerID int64) (int, error)
 }
 
 // orderRepository — конкретная реализация OrderStorage.
@@ -26,26 +51,95 @@ func NewOrderRepository(db *sql.DB) OrderStorage {
 	return &orderRepository{db: db}
 }
 
+// orderSelect выбирает заказы с именем товара, акции и вариантом в порядке полей, которые читает scanOrder.
+const orderSelect = `
+		SELECT o.id, o.user_id, o.merch_id, m.name, o.quantity, o.total_price, o.gifted_by, o.status, o.created_at, o.updated_at,
+		       o.discount, o.promotion_id, COALESCE(p.name, ''),
+		       o.variant_id, o.variant_sku, COALESCE(v.size, ''), COALESCE(v.color, '')
+		FROM orders o
+		JOIN merch m ON o.merch_id = m.id
+		LEFT JOIN promotions p ON o.promotion_id = p.id
+		LEFT JOIN merch_variants v ON o.variant_id = v.id`
+
+func scanOrder(row interface{ Scan(dest ...any) error }) (*models.Order, error) {
+	order := &models.Order{}
+	var updatedAt sql.NullTime
+	if err := row.Scan(&order.ID, &order.UserID, &order.MerchID, &order.MerchName, &order.Quantity, &order.TotalPrice,
+		&order.GiftedBy, &order.Status, &order.CreatedAt, &updatedAt,
+		&order.Discount, &order.PromotionID, &order.PromotionName,
+		&order.VariantID, &order.VariantSKU, &order.VariantSize, &order.VariantColor); err != nil {
+		return nil, err
+	}
+	order.UpdatedAt = order.CreatedAt
+	if updatedAt.Valid {
+		order.UpdatedAt = updatedAt.Time
+	}
+	return order, nil
+}
+
 // CreateOrder вставляет новый заказ в таблицу orders.
-func (r *orderRepository) CreateOrder(ctx context.Context, tx *sql.Tx, userID int64, merchID int64, quantity int, totalPrice int) error {
-	query := `INSERT INTO orders (user_id, merch_id, quantity, total_price, created_at) 
-	          VALUES ($1, $2, $3, $4, NOW())`
-	_, err := tx.ExecContext(ctx, query, userID, merchID, quantity, totalPrice)
+func (r *orderRepository) CreateOrder(ctx context.Context, order *models.Order) error {
+	if order.Status == "" {
+		order.Status = models.OrderPlaced
+	}
+	query := `INSERT INTO orders (user_id, merch_id, quantity, total_price, gifted_by, status, discount, promotion_id, variant_id,
+	              variant_sku, created_at, updated_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW()) RETURNING id, created_at`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, order.UserID, order.MerchID, order.Quantity, order.TotalPrice, order.GiftedBy, order.Status,
+		order.Discount, order.PromotionID, order.VariantID, order.VariantSKU).
+		Scan(&order.ID, &order.CreatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to create order: %w", err)
 	}
+	order.UpdatedAt = order.CreatedAt
 	return nil
 }
 
 // GetOrdersByUserID возвращает список заказов для пользователя с JOIN, чтобы получить имя товара.
 func (r *orderRepository) GetOrdersByUserID(ctx context.Context, userID int64) ([]*models.Order, error) {
-	query := `
-		SELECT o.id, o.user_id, o.merch_id, m.name, o.quantity, o.total_price, o.created_at
-		FROM orders o
-		JOIN merch m ON o.merch_id = m.id
+	return r.queryOrders(ctx, orderSelect+`
 		WHERE o.user_id = $1
-		ORDER BY o.created_at DESC`
-	rows, err := r.db.QueryContext(ctx, query, userID)
+		ORDER BY o.created_at DESC`, userID)
+}
+
+func (r *orderRepository) CountUserOrders(ctx context.Context, userID, merchID int64, since time.Time) (int, error) {
+	var count int
+	query := `SELECT COALESCE(SUM(quantity), 0) FROM orders
+	          WHERE user_id = $1 AND merch_id = $2 AND created_at >= $3 AND status <> $4`
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, userID, merchID, since, models.OrderCancelled).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count orders: %w", err)
+	}
+	return count, nil
+}
+
+func (r *orderRepository) GetOrderByID(ctx context.Context, id int64) (*models.Order, error) {
+	return r.getOrder(ctx, orderSelect+" WHERE o.id = $1", id)
+}
+
+func (r *orderRepository) LockOrder(ctx context.Context, id int64) (*models.Order, error) {
+	return r.getOrder(ctx, orderSelect+" WHERE o.id = $1 FOR UPDATE OF o", id)
+}
+
+func (r *orderRepository) getOrder(ctx context.Context, query string, id int64) (*models.Order, error) {
+	order, err := scanOrder(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+	return order, nil
+}
+
+func (r *orderRepository) ListOrders(ctx context.Context, userID int64, status string, limit int) ([]*models.Order, error) {
+	return r.queryOrders(ctx, orderSelect+`
+		WHERE ($1 = 0 OR o.user_id = $1) AND ($2 = '' OR o.status = $2)
+		ORDER BY o.created_at DESC, o.id DESC
+		LIMIT $3`, userID, status, limit)
+}
+
+func (r *orderRepository) queryOrders(ctx context.Context, query string, args ...any) ([]*models.Order, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -53,8 +147,8 @@ func (r *orderRepository) GetOrdersByUserID(ctx context.Context, userID int64) (
 
 	var orders []*models.Order
 	for rows.Next() {
-		order := &models.Order{}
-		if err := rows.Scan(&order.ID, &order.UserID, &order.MerchID, &order.MerchName, &order.Quantity, &order.TotalPrice, &order.CreatedAt); err != nil {
+		order, err := scanOrder(rows)
+		if err != nil {
 			return nil, err
 		}
 		orders = append(orders, order)
@@ -64,3 +158,65 @@ func (r *orderRepository) GetOrdersByUserID(ctx context.Context, userID int64) (
 	}
 	return orders, nil
 }
+
+func (r *orderRepository) UpdateOrderStatus(ctx context.Context, id int64, status string, updatedAt time.Time) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx,
+		"UPDATE orders SET status = $1, updated_at = $2 WHERE id = $3", status, updatedAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to update order status: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update order status: %w", err)
+	}
+	if n == 0 {
+		return ErrOrderNotFound
+	}
+	return nil
+}
+
+func (r *orderRepository) AddOrderStatusChange(ctx context.Context, change *models.OrderStatusChange) error {
+	query := `INSERT INTO order_status_history (order_id, from_status, to_status, changed_by, comment, created_at)
+	          VALUES ($1, NULLIF($2, ''), $3, $4, NULLIF($5, ''), NOW()) RETURNING id, created_at`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, change.OrderID, change.FromStatus, change.ToStatus, change.ChangedBy, change.Comment).
+		Scan(&change.ID, &change.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record order status change: %w", err)
+	}
+	return nil
+}
+
+func (r *orderRepository) GetOrderStatusHistory(ctx context.Context, orderID int64) ([]models.OrderStatusChange, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT id, order_id, COALESCE(from_status, ''), to_status, changed_by, COALESCE(comment, ''), created_at
+		FROM order_status_history
+		WHERE order_id = $1
+		ORDER BY id`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order status history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []models.OrderStatusChange
+	for rows.Next() {
+		var c models.OrderStatusChange
+		if err := rows.Scan(&c.ID, &c.OrderID, &c.FromStatus, &c.ToStatus, &c.ChangedBy, &c.Comment, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan order status change: %w", err)
+		}
+		history = append(history, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+func (r *orderRepository) CountPromotionOrders(ctx context.Context, promotionID, payerID int64) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM orders
+	          WHERE promotion_id = $1 AND ($2 = 0 OR COALESCE(gifted_by, user_id) = $2) AND status <> $3`
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, promotionID, payerID, models.OrderCancelled).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count promotion orders: %w", err)
+	}
+	return count, nil
+}
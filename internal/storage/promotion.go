@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/linemk/avito-shop/internal/domain/models"
+)
+
+var (
+	// ErrPromotionNotFound — акция не найдена.
+	ErrPromotionNotFound = errors.New("promotion not found")
+	// ErrPromoCodeExists — акция с таким кодом уже есть.
+	ErrPromoCodeExists = errors.New("promo code already exists")
+)
+
+// PromotionStorage описывает методы для работы с акциями.
+type PromotionStorage interface {
+	// CreatePromotion создаёт акцию и заполняет promo.ID и promo.CreatedAt.
+	// Если код уже занят, возвращает ErrPromoCodeExists.
+	CreatePromotion(ctx context.Context, promo *models.Promotion) error
+	// GetPromotionByID возвращает акцию по идентификатору.
+	GetPromotionByID(ctx context.Context, id int64) (*models.Promotion, error)
+	// LockPromotion возвращает акцию и блокирует её строку до конца транзакции,
+	// чтобы параллельные покупки не превысили лимит использований.
+	LockPromotion(ctx context.Context, id int64) (*models.Promotion, error)
+	// LockPromotionByCode — то же, что LockPromotion, но по коду акции.
+	LockPromotionByCode(ctx context.Context, code string) (*models.Promotion, error)
+	// ListPromotions возвращает все акции от новых к старым.
+	ListPromotions(ctx context.Context) ([]models.Promotion, error)
+	// ListAutoPromotions возвращает активные акции без кода, которые действуют в момент at
+	// и подходят к товару merchID и покупателю userID.
+	ListAutoPromotions(ctx context.Context, merchID, userID int64, at time.Time) ([]models.Promotion, error)
+	// SetPromotionActive включает или выключает акцию.
+	SetPromotionActive(ctx context.Context, id int64, active bool) error
+}
+
+// promotionRepository — конкретная реализация PromotionStorage.
+type promotionRepository struct {
+	db *sql.DB
+}
+
+// NewPromotionRepository создаёт новый репозиторий акций.
+func NewPromotionRepository(db *sql.DB) PromotionStorage {
+	return &promotionRepository{db: db}
+}
+
+// promotionColumns — столбцы promotions в порядке полей, которые читает scanPromotion.
+const promotionColumns = `id, name, COALESCE(code, ''), discount_type, discount_value, merch_id, user_id,
+	starts_at, ends_at, max_uses, max_uses_per_user, is_active, created_by, created_at`
+
+func scanPromotion(row interface{ Scan(dest ...any) error }) (*models.Promotion, error) {
+	p := &models.Promotion{}
+	var startsAt, endsAt sql.NullTime
+	if err := row.Scan(&p.ID, &p.Name, &p.Code, &p.DiscountType, &p.DiscountValue, &p.MerchID, &p.UserID,
+		&startsAt, &endsAt, &p.MaxUses, &p.MaxUsesPerUser, &p.Active, &p.CreatedBy, &p.CreatedAt); err != nil {
+		return nil, err
+	}
+	if startsAt.Valid {
+		p.StartsAt = &startsAt.Time
+	}
+	if endsAt.Valid {
+		p.EndsAt = &endsAt.Time
+	}
+	return p, nil
+}
+
+func (r *promotionRepository) CreatePromotion(ctx context.Context, promo *models.Promotion) error {
+	query := `INSERT INTO promotions (name, code, discount_type, discount_value, merch_id, user_id,
+	              starts_at, ends_at, max_uses, max_uses_per_user, is_active, created_by, created_at)
+	          VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW())
+	          RETURNING id, created_at`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, promo.Name, promo.Code, promo.DiscountType, promo.DiscountValue,
+		promo.MerchID, promo.UserID, promo.StartsAt, promo.EndsAt, promo.MaxUses, promo.MaxUsesPerUser, promo.Active, promo.CreatedBy).
+		Scan(&promo.ID, &promo.CreatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" { // unique_violation
+			return ErrPromoCodeExists
+		}
+		return fmt.Errorf("failed to create promotion: %w", err)
+	}
+	return nil
+}
+
+func (r *promotionRepository) GetPromotionByID(ctx context.Context, id int64) (*models.Promotion, error) {
+	return r.getPromotion(ctx, "SELECT "+promotionColumns+" FROM promotions WHERE id = $1", id)
+}
+
+func (r *promotionRepository) LockPromotion(ctx context.Context, id int64) (*models.Promotion, error) {
+	return r.getPromotion(ctx, "SELECT "+promotionColumns+" FROM promotions WHERE id = $1 FOR UPDATE", id)
+}
+
+func (r *promotionRepository) LockPromotionByCode(ctx context.Context, code string) (*models.Promotion, error) {
+	return r.getPromotion(ctx, "SELECT "+promotionColumns+" FROM promotions WHERE code = $1 FOR UPDATE", code)
+}
+
+func (r *promotionRepository) getPromotion(ctx context.Context, query string, arg any) (*models.Promotion, error) {
+	promo, err := scanPromotion(conn(ctx, r.db).QueryRowContext(ctx, query, arg))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrPromotionNotFound
+		}
+		return nil, fmt.Errorf("failed to get promotion: %w", err)
+	}
+	return promo, nil
+}
+
+func (r *promotionRepository) ListPromotions(ctx context.Context) ([]models.Promotion, error) {
+	return r.queryPromotions(ctx, "SELECT "+promotionColumns+" FROM promotions ORDER BY id DESC")
+}
+
+func (r *promotionRepository) ListAutoPromotions(ctx context.Context, merchID, userID int64, at time.Time) ([]models.Promotion, error) {
+	return r.queryPromotions(ctx, `SELECT `+promotionColumns+` FROM promotions
+		WHERE is_active AND code IS NULL
+		  AND (merch_id IS NULL OR merch_id = $1) AND (user_id IS NULL OR user_id = $2)
+		  AND (starts_at IS NULL OR starts_at <= $3) AND (ends_at IS NULL OR ends_at > $3)
+		ORDER BY id`, merchID, userID, at)
+}
+
+func (r *promotionRepository) queryPromotions(ctx context.Context, query string, args ...any) ([]models.Promotion, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query promotions: %w", err)
+	}
+	defer rows.Close()
+
+	var promos []models.Promotion
+	for rows.Next() {
+		p, err := scanPromotion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan promotion: %w", err)
+		}
+		promos = append(promos, *p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return promos, nil
+}
+
+func (r *promotionRepository) SetPromotionActive(ctx context.Context, id int64, active bool) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, "UPDATE promotions SET is_active = $1 WHERE id = $2", active, id)
+	if err != nil {
+		return fmt.Errorf("failed to update promotion: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update promotion: %w", err)
+	}
+	if n == 0 {
+		return ErrPromotionNotFound
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/linemk/avito-shop/internal/domain/models"
+)
+
+// ErrCoinRequestNotFound — запрос монет не найден.
+var ErrCoinRequestNotFound = errors.New("coin request not found")
+
+// CoinRequestStorage — запросы монет у коллег (см. models.CoinRequest).
+type CoinRequestStorage interface {
+	// CreateCoinRequest записывает запрос и заполняет ID и CreatedAt.
+	CreateCoinRequest(ctx context.Context, req *models.CoinRequest) error
+	// LockCoinRequest возвращает запрос и блокирует его строку до конца текущей транзакции.
+	LockCoinRequest(ctx context.Context, id int64) (*models.CoinRequest, error)
+	// ResolveCoinRequest переводит запрос в статус status в момент resolvedAt.
+	ResolveCoinRequest(ctx context.Context, id int64, status string, resolvedAt time.Time) error
+	// ListCoinRequests возвращает до limit последних запросов, в которых пользователь —
+	// плательщик (incoming) или инициатор (!incoming), от новых к старым.
+	ListCoinRequests(ctx context.Context, userID int64, incoming bool, limit int) ([]models.CoinRequest, error)
+	// ExpireCoinRequests переводит в expired запросы pending со сроком не позже now
+	// и возвращает их число.
+	ExpireCoinRequests(ctx context.Context, now time.Time) (int, error)
+}
+
+type coinRequestRepository struct {
+	db *sql.DB
+}
+
+// NewCoinRequestRepository создаёт репозиторий запросов монет.
+func NewCoinRequestRepository(db *sql.DB) CoinRequestStorage {
+	return &coinRequestRepository{db: db}
+}
+
+const coinRequestColumns = "id, requester_id, payer_id, amount, comment, status, expires_at, created_at, resolved_at"
+
+func (r *coinRequestRepository) CreateCoinRequest(ctx context.Context, req *models.CoinRequest) error {
+	query := `INSERT INTO coin_requests (requester_id, payer_id, amount, comment, status, expires_at, created_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, NOW())
+	          RETURNING id, created_at`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query,
+		req.RequesterID, req.PayerID, req.Amount, req.Comment, req.Status, req.ExpiresAt,
+	).Scan(&req.ID, &req.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create coin request: %w", err)
+	}
+	return nil
+}
+
+func (r *coinRequestRepository) LockCoinRequest(ctx context.Context, id int64) (*models.CoinRequest, error) {
+	query := "SELECT " + coinRequestColumns + " FROM coin_requests WHERE id = $1 FOR UPDATE"
+	req, err := scanCoinRequest(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCoinRequestNotFound
+		}
+		return nil, fmt.Errorf("failed to get coin request: %w", err)
+	}
+	return req, nil
+}
+
+func (r *coinRequestRepository) ResolveCoinRequest(ctx context.Context, id int64, status string, resolvedAt time.Time) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx,
+		"UPDATE coin_requests SET status = $1, resolved_at = $2 WHERE id = $3", status, resolvedAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to update coin request: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrCoinRequestNotFound
+	}
+	return nil
+}
+
+func (r *coinRequestRepository) ListCoinRequests(ctx context.Context, userID int64, incoming bool, limit int) ([]models.CoinRequest, error) {
+	column := "requester_id"
+	if incoming {
+		column = "payer_id"
+	}
+	query := "SELECT " + coinRequestColumns + " FROM coin_requests WHERE " + column + ` = $1
+	          ORDER BY created_at DESC, id DESC
+	          LIMIT $2`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query coin requests: %w", err)
+	}
+	defer rows.Close()
+
+	var requests []models.CoinRequest
+	for rows.Next() {
+		req, err := scanCoinRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan coin request: %w", err)
+		}
+		requests = append(requests, *req)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read coin requests: %w", err)
+	}
+	return requests, nil
+}
+
+func (r *coinRequestRepository) ExpireCoinRequests(ctx context.Context, now time.Time) (int, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx,
+		"UPDATE coin_requests SET status = $1, resolved_at = $2 WHERE status = $3 AND expires_at <= $2",
+		models.CoinRequestExpired, now, models.CoinRequestPending)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire coin requests: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(affected), nil
+}
+
+// scanCoinRequest читает строку coin_requests из *sql.Row или *sql.Rows.
+func scanCoinRequest(row interface{ Scan(dest ...any) error }) (*models.CoinRequest, error) {
+	req := &models.CoinRequest{}
+	var resolvedAt sql.NullTime
+	err := row.Scan(&req.ID, &req.RequesterID, &req.PayerID, &req.Amount, &req.Comment,
+		&req.Status, &req.ExpiresAt, &req.CreatedAt, &resolvedAt)
+	if err != nil {
+		return nil, err
+	}
+	if resolvedAt.Valid {
+		req.ResolvedAt = &resolvedAt.Time
+	}
+	return req, nil
+}
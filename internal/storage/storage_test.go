@@ -2,6 +2,7 @@ package storage_test
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"github.com/linemk/avito-shop/internal/domain/models"
 	"regexp"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	"github.com/linemk/avito-shop/internal/storage"
 	"github.com/stretchr/testify/assert"
 )
@@ -25,11 +27,11 @@ func TestGetUserByID_Success(t *testing.T) {
 	userID := int64(1)
 
 	// Подготавливаем ожидаемые строки результата.
-	rows := sqlmock.NewRows([]string{"id", "username", "pass_hash", "coin_balance"}).
-		AddRow(userID, "test@example.com", []byte("hashed-password"), 1000)
+	rows := sqlmock.NewRows([]string{"id", "username", "pass_hash", "coin_balance", "is_admin", "created_at"}).
+		AddRow(userID, "test@example.com", []byte("hashed-password"), 1000, false, time.Now())
 
 	// Ожидаем выполнение запроса с аргументом userID.
-	mock.ExpectQuery("SELECT id, username, pass_hash, coin_balance FROM users WHERE id = \\$1").
+	mock.ExpectQuery("SELECT id, username, pass_hash, coin_balance, is_admin, created_at FROM users WHERE id = \\$1").
 		WithArgs(userID).WillReturnRows(rows)
 
 	// Вызываем тестируемую функцию.
@@ -55,8 +57,8 @@ func TestGetUserByID_NoRows(t *testing.T) {
 	userID := int64(2)
 
 	// Эмулируем ситуацию, когда запрос возвращает 0 строк.
-	rows := sqlmock.NewRows([]string{"id", "username", "pass_hash", "coin_balance"})
-	mock.ExpectQuery("SELECT id, username, pass_hash, coin_balance FROM users WHERE id = \\$1").
+	rows := sqlmock.NewRows([]string{"id", "username", "pass_hash", "coin_balance", "is_admin", "created_at"})
+	mock.ExpectQuery("SELECT id, username, pass_hash, coin_balance, is_admin, created_at FROM users WHERE id = \\$1").
 		WithArgs(userID).WillReturnRows(rows)
 
 	user, err := repo.GetUserByID(ctx, userID)
@@ -77,7 +79,7 @@ func TestGetUserByID_QueryError(t *testing.T) {
 	userID := int64(3)
 
 	// Эмулируем ошибку выполнения запроса.
-	mock.ExpectQuery("SELECT id, username, pass_hash, coin_balance FROM users WHERE id = \\$1").
+	mock.ExpectQuery("SELECT id, username, pass_hash, coin_balance, is_admin, created_at FROM users WHERE id = \\$1").
 		WithArgs(userID).WillReturnError(errors.New("db error"))
 
 	user, err := repo.GetUserByID(ctx, userID)
@@ -88,42 +90,45 @@ func TestGetUserByID_QueryError(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+// merchSelectQuery — выборка товаров с действующей ценой из истории цен.
+const merchSelectQuery = `SELECT m\.id, m\.name, COALESCE\(\(SELECT h\.price FROM merch_price_history h .+\), m\.price\), ` +
+	`m\.stock, m\.purchase_limit, m\.purchase_limit_period, m\.category, m\.description, m\.image_key, m\.tags FROM merch m`
+
+// merchRowColumns — столбцы строки товара в порядке scanMerch.
+var merchRowColumns = []string{"id", "name", "price", "stock", "purchase_limit", "purchase_limit_period", "category", "description", "image_key", "tags"}
+
 func TestGetMerchByName_Success(t *testing.T) {
 	// Создаем sqlmock для эмуляции БД.
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
 	defer db.Close()
 
-	// Ожидаем вызов Begin перед тем, как вызвать db.Begin().
-	mock.ExpectBegin()
 	repo := storage.NewMerchRepository(db)
+	txManager := storage.NewTxManager(db)
 	ctx := context.Background()
 	merchName := "t-shirt"
 
-	tx, err := db.Begin()
-	assert.NoError(t, err)
-
-	// Подготавливаем ожидаемые строки результата.
-	rows := sqlmock.NewRows([]string{"id", "name", "price"}).
-		AddRow(1, merchName, 80)
-
-	// Ожидаем запрос с аргументом merchName.
-	query := "SELECT id, name, price FROM merch WHERE name = \\$1"
+	// Ожидаем Begin, запрос с аргументом merchName и Commit.
+	rows := sqlmock.NewRows(merchRowColumns).
+		AddRow(1, merchName, 80, nil, 0, "", "", "", "", "{}")
+	query := merchSelectQuery + ` WHERE m\.name = \$1`
+	mock.ExpectBegin()
 	mock.ExpectQuery(query).WithArgs(merchName).WillReturnRows(rows)
+	mock.ExpectCommit()
 
-	// Вызываем GetMerchByName.
-	result, err := repo.GetMerchByName(ctx, tx, merchName)
+	// Вызываем GetMerchByName внутри транзакции.
+	var result *models.Merch
+	err = txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = repo.GetMerchByName(ctx, merchName)
+		return err
+	})
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
 	assert.Equal(t, int64(1), result.ID)
 	assert.Equal(t, merchName, result.Name)
 	assert.Equal(t, 80, result.Price)
 
-	// Ожидаем вызов Commit и коммитим транзакцию.
-	mock.ExpectCommit()
-	err = tx.Commit()
-	assert.NoError(t, err)
-
 	// Проверяем, что все ожидания выполнены.
 	err = mock.ExpectationsWereMet()
 	assert.NoError(t, err)
@@ -134,28 +139,28 @@ func TestGetMerchByName_NotFound(t *testing.T) {
 	assert.NoError(t, err)
 	defer db.Close()
 
-	mock.ExpectBegin()
 	repo := storage.NewMerchRepository(db)
+	txManager := storage.NewTxManager(db)
 	ctx := context.Background()
 	merchName := "non-existent"
 
-	tx, err := db.Begin()
-	assert.NoError(t, err)
-
-	// Эмулируем ситуацию, когда запрос возвращает 0 строк.
+	// Эмулируем ситуацию, когда запрос возвращает 0 строк: транзакция откатывается.
 	rows := sqlmock.NewRows([]string{"id", "name", "price"})
-	query := "SELECT id, name, price FROM merch WHERE name = \\$1"
+	query := merchSelectQuery + ` WHERE m\.name = \$1`
+	mock.ExpectBegin()
 	mock.ExpectQuery(query).WithArgs(merchName).WillReturnRows(rows)
+	mock.ExpectRollback()
 
-	result, err := repo.GetMerchByName(ctx, tx, merchName)
+	var result *models.Merch
+	err = txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = repo.GetMerchByName(ctx, merchName)
+		return err
+	})
 	assert.Error(t, err)
 	assert.True(t, errors.Is(err, storage.ErrMerchNotFound))
 	assert.Nil(t, result)
 
-	mock.ExpectRollback()
-	err = tx.Rollback()
-	assert.NoError(t, err)
-
 	err = mock.ExpectationsWereMet()
 	assert.NoError(t, err)
 }
@@ -165,27 +170,40 @@ func TestGetMerchByName_QueryError(t *testing.T) {
 	assert.NoError(t, err)
 	defer db.Close()
 
-	mock.ExpectBegin()
 	repo := storage.NewMerchRepository(db)
 	ctx := context.Background()
 	merchName := "t-shirt"
 
-	tx, err := db.Begin()
-	assert.NoError(t, err)
-
-	// Эмулируем ошибку выполнения запроса.
-	query := "SELECT id, name, price FROM merch WHERE name = \\$1"
+	// Эмулируем ошибку выполнения запроса (вне транзакции запрос идёт напрямую в БД).
+	query := merchSelectQuery + ` WHERE m\.name = \$1`
 	expectedError := errors.New("query error")
 	mock.ExpectQuery(query).WithArgs(merchName).WillReturnError(expectedError)
 
-	result, err := repo.GetMerchByName(ctx, tx, merchName)
+	result, err := repo.GetMerchByName(ctx, merchName)
 	assert.Error(t, err)
 	assert.Equal(t, expectedError, err)
 	assert.Nil(t, result)
 
-	mock.ExpectRollback()
-	err = tx.Rollback()
+	err = mock.ExpectationsWereMet()
 	assert.NoError(t, err)
+}
+
+func TestUpsertMerch_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := storage.NewMerchRepository(db)
+	ctx := context.Background()
+
+	// Вставка с ON CONFLICT по имени обновляет цену существующего товара.
+	query := regexp.QuoteMeta("INSERT INTO merch (name, price) VALUES ($1, $2)") + `\s+ON CONFLICT \(name\) DO UPDATE SET price = EXCLUDED.price`
+	mock.ExpectQuery(query).WithArgs("cup", 25).
+		WillReturnRows(sqlmock.NewRows(merchRowColumns).AddRow(2, "cup", 25, nil, 0, "", "accessories", "", "", "{}"))
+
+	merch, err := repo.UpsertMerch(ctx, "cup", 25)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.Merch{ID: 2, Name: "cup", Price: 25, Category: "accessories", Tags: []string{}}, merch)
 
 	err = mock.ExpectationsWereMet()
 	assert.NoError(t, err)
@@ -198,25 +216,25 @@ func TestCreateOrder_Success(t *testing.T) {
 	defer db.Close()
 
 	repo := storage.NewOrderRepository(db)
+	txManager := storage.NewTxManager(db)
 	ctx := context.Background()
 
-	// Ожидаем вызов BeginTx.
-	mock.ExpectBegin()
-	tx, err := db.Begin()
-	assert.NoError(t, err)
-
 	// Формируем ожидаемый SQL-запрос, используя regexp.QuoteMeta,
 	// чтобы экранировать специальные символы.
-	query := regexp.QuoteMeta("INSERT INTO orders (user_id, merch_id, quantity, total_price, created_at) VALUES ($1, $2, $3, $4, NOW())")
-	mock.ExpectExec(query).WithArgs(1, 2, 3, 150).WillReturnResult(sqlmock.NewResult(1, 1))
-
-	err = repo.CreateOrder(ctx, tx, 1, 2, 3, 150)
-	assert.NoError(t, err)
-
-	// Ожидаем Commit.
+	query := regexp.QuoteMeta("INSERT INTO orders (user_id, merch_id, quantity, total_price, gifted_by, status, discount, promotion_id, variant_id, variant_sku, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW()) RETURNING id, created_at")
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery(query).WithArgs(int64(1), int64(2), 3, 150, nil, models.OrderPlaced, 0, nil, nil, "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(7, now))
 	mock.ExpectCommit()
-	err = tx.Commit()
+
+	order := &models.Order{UserID: 1, MerchID: 2, Quantity: 3, TotalPrice: 150}
+	err = txManager.WithinTx(ctx, func(ctx context.Context) error {
+		return repo.CreateOrder(ctx, order)
+	})
 	assert.NoError(t, err)
+	assert.Equal(t, int64(7), order.ID)
+	assert.Equal(t, models.OrderPlaced, order.Status)
 
 	// Проверяем, что все ожидания sqlmock выполнены.
 	err = mock.ExpectationsWereMet()
@@ -232,25 +250,43 @@ func TestGetOrdersByUserID_Success(t *testing.T) {
 	ctx := context.Background()
 	userID := int64(1)
 
-	// Подготавливаем ожидаемые строки результата с полями: id, user_id, merch_id, m.name, quantity, total_price, created_at.
+	// Подготавливаем ожидаемые строки результата с полями: id, user_id, merch_id, m.name, quantity, total_price, gifted_by, status, created_at, updated_at,
+	// discount, promotion_id, p.name, variant_id, o.variant_sku, v.size, v.color.
 	now := time.Now()
-	rows := sqlmock.NewRows([]string{"id", "user_id", "merch_id", "name", "quantity", "total_price", "created_at"}).
-		AddRow(1, userID, 2, "t-shirt", 1, 80, now)
+	rows := sqlmock.NewRows([]string{"id", "user_id", "merch_id", "name", "quantity", "total_price", "gifted_by", "status", "created_at", "updated_at", "discount", "promotion_id", "promotion", "variant_id", "sku", "size", "color"}).
+		AddRow(1, userID, 2, "t-shirt", 1, 80, nil, models.OrderPlaced, now, now, 0, nil, "", nil, "", "", "").
+		AddRow(2, userID, 2, "t-shirt", 1, 60, 5, models.OrderDelivered, now, now.Add(time.Hour), 20, 9, "spring sale", 11, "TS-M-BLK", "M", "black")
 	query := `
-		SELECT o\.id, o\.user_id, o\.merch_id, m\.name, o\.quantity, o\.total_price, o\.created_at
+		SELECT o\.id, o\.user_id, o\.merch_id, m\.name, o\.quantity, o\.total_price, o\.gifted_by, o\.status, o\.created_at, o\.updated_at,
+		       o\.discount, o\.promotion_id, COALESCE\(p\.name, ''\),
+		       o\.variant_id, o\.variant_sku, COALESCE\(v\.size, ''\), COALESCE\(v\.color, ''\)
 		FROM orders o
 		JOIN merch m ON o\.merch_id = m\.id
+		LEFT JOIN promotions p ON o\.promotion_id = p\.id
+		LEFT JOIN merch_variants v ON o\.variant_id = v\.id
 		WHERE o\.user_id = \$1
 		ORDER BY o\.created_at DESC`
 	mock.ExpectQuery(query).WithArgs(userID).WillReturnRows(rows)
 
 	orders, err := repo.GetOrdersByUserID(ctx, userID)
 	assert.NoError(t, err)
-	assert.Len(t, orders, 1)
+	assert.Len(t, orders, 2)
 	assert.Equal(t, int64(1), orders[0].ID)
 	assert.Equal(t, "t-shirt", orders[0].MerchName)
 	assert.Equal(t, 1, orders[0].Quantity)
 	assert.Equal(t, 80, orders[0].TotalPrice)
+	assert.Nil(t, orders[0].GiftedBy)
+	assert.Equal(t, models.OrderDelivered, orders[1].Status)
+	assert.Equal(t, now.Add(time.Hour), orders[1].UpdatedAt)
+	if assert.NotNil(t, orders[1].GiftedBy) {
+		assert.Equal(t, int64(5), *orders[1].GiftedBy)
+	}
+	assert.Nil(t, orders[0].PromotionID)
+	assert.Equal(t, 20, orders[1].Discount)
+	assert.Equal(t, "spring sale", orders[1].PromotionName)
+	assert.Nil(t, orders[0].VariantID)
+	assert.Equal(t, "TS-M-BLK", orders[1].VariantSKU)
+	assert.Equal(t, "M", orders[1].VariantSize)
 
 	err = mock.ExpectationsWereMet()
 	assert.NoError(t, err)
@@ -266,9 +302,13 @@ func TestGetOrdersByUserID_QueryError(t *testing.T) {
 	userID := int64(1)
 
 	query := `
-		SELECT o\.id, o\.user_id, o\.merch_id, m\.name, o\.quantity, o\.total_price, o\.created_at
+		SELECT o\.id, o\.user_id, o\.merch_id, m\.name, o\.quantity, o\.total_price, o\.gifted_by, o\.status, o\.created_at, o\.updated_at,
+		       o\.discount, o\.promotion_id, COALESCE\(p\.name, ''\),
+		       o\.variant_id, o\.variant_sku, COALESCE\(v\.size, ''\), COALESCE\(v\.color, ''\)
 		FROM orders o
 		JOIN merch m ON o\.merch_id = m\.id
+		LEFT JOIN promotions p ON o\.promotion_id = p\.id
+		LEFT JOIN merch_variants v ON o\.variant_id = v\.id
 		WHERE o\.user_id = \$1
 		ORDER BY o\.created_at DESC`
 	expectedErr := errors.New("query error")
@@ -292,10 +332,10 @@ func TestGetUserByEmail_Success(t *testing.T) {
 	email := "test@example.com"
 
 	// Подготавливаем ожидаемые строки результата.
-	rows := sqlmock.NewRows([]string{"id", "username", "pass_hash", "coin_balance"}).
-		AddRow(1, email, []byte("hashed-password"), 1000)
+	rows := sqlmock.NewRows([]string{"id", "username", "pass_hash", "coin_balance", "is_admin", "created_at"}).
+		AddRow(1, email, []byte("hashed-password"), 1000, false, time.Now())
 	// Ожидаем запрос с аргументом email.
-	query := regexp.QuoteMeta("SELECT id, username, pass_hash, coin_balance FROM users WHERE username = $1")
+	query := regexp.QuoteMeta("SELECT id, username, pass_hash, coin_balance, is_admin, created_at FROM users WHERE username = $1")
 	mock.ExpectQuery(query).WithArgs(email).WillReturnRows(rows)
 
 	user, err := repo.GetUserByEmail(ctx, email)
@@ -319,8 +359,8 @@ func TestGetUserByEmail_NotFound(t *testing.T) {
 	email := "nonexistent@example.com"
 
 	// Эмулируем ситуацию, когда запрос возвращает 0 строк.
-	rows := sqlmock.NewRows([]string{"id", "username", "pass_hash", "coin_balance"})
-	query := regexp.QuoteMeta("SELECT id, username, pass_hash, coin_balance FROM users WHERE username = $1")
+	rows := sqlmock.NewRows([]string{"id", "username", "pass_hash", "coin_balance", "is_admin", "created_at"})
+	query := regexp.QuoteMeta("SELECT id, username, pass_hash, coin_balance, is_admin, created_at FROM users WHERE username = $1")
 	mock.ExpectQuery(query).WithArgs(email).WillReturnRows(rows)
 
 	user, err := repo.GetUserByEmail(ctx, email)
@@ -343,9 +383,9 @@ func TestCreateUser_Success(t *testing.T) {
 	coinBalance := 1000
 
 	// Подготавливаем ожидаемый запрос. Используем regexp.QuoteMeta.
-	query := regexp.QuoteMeta("INSERT INTO users (username, pass_hash, coin_balance) VALUES ($1, $2, $3) RETURNING id")
-	mock.ExpectQuery(query).WithArgs(email, passHash, coinBalance).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	query := regexp.QuoteMeta("INSERT INTO users (username, pass_hash, coin_balance, is_admin) VALUES ($1, $2, $3, $4) RETURNING id, created_at")
+	mock.ExpectQuery(query).WithArgs(email, passHash, coinBalance, false).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
 
 	user := &models.User{
 		Email:       email,
@@ -370,24 +410,18 @@ func TestUpdateUserBalance_Success(t *testing.T) {
 	userID := int64(1)
 	newBalance := 900
 
-	// Ожидаем вызов Begin.
-	mock.ExpectBegin()
+	txManager := storage.NewTxManager(db)
 
-	// Ожидаем вызов ExecContext с нужными параметрами.
+	// Ожидаем Begin, вызов ExecContext с нужными параметрами и Commit.
 	query := regexp.QuoteMeta("UPDATE users SET coin_balance = $1 WHERE id = $2")
+	mock.ExpectBegin()
 	mock.ExpectExec(query).WithArgs(newBalance, userID).
 		WillReturnResult(sqlmock.NewResult(0, 1)) // 1 строка затронута
-
-	// Создаем транзакцию.
-	tx, err := db.Begin()
-	assert.NoError(t, err)
-
-	err = repo.UpdateUserBalance(ctx, tx, userID, newBalance)
-	assert.NoError(t, err)
-
-	// Ожидаем вызов Commit.
 	mock.ExpectCommit()
-	err = tx.Commit()
+
+	err = txManager.WithinTx(ctx, func(ctx context.Context) error {
+		return repo.UpdateUserBalance(ctx, userID, newBalance)
+	})
 	assert.NoError(t, err)
 
 	// Проверяем, что все ожидания sqlmock выполнены.
@@ -405,29 +439,45 @@ func TestUpdateUserBalance_NoRows(t *testing.T) {
 	userID := int64(99)
 	newBalance := 900
 
-	// Ожидаем вызов Begin.
-	mock.ExpectBegin()
+	txManager := storage.NewTxManager(db)
 
+	// Ожидаем Begin, обновление без затронутых строк и Rollback.
 	query := regexp.QuoteMeta("UPDATE users SET coin_balance = $1 WHERE id = $2")
+	mock.ExpectBegin()
 	mock.ExpectExec(query).WithArgs(newBalance, userID).
 		WillReturnResult(sqlmock.NewResult(0, 0)) // 0 строк затронуто
+	mock.ExpectRollback()
 
-	tx, err := db.Begin()
-	assert.NoError(t, err)
-
-	err = repo.UpdateUserBalance(ctx, tx, userID, newBalance)
+	err = txManager.WithinTx(ctx, func(ctx context.Context) error {
+		return repo.UpdateUserBalance(ctx, userID, newBalance)
+	})
 	assert.Error(t, err)
 	assert.True(t, errors.Is(err, storage.ErrUserNotFound))
 
-	// Ожидаем вызов Rollback.
-	mock.ExpectRollback()
-	err = tx.Rollback()
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateUserPassword_NoRows(t *testing.T) {
+	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
+	defer db.Close()
 
-	assert.NoError(t, mock.ExpectationsWereMet())
+	repo := storage.NewUserRepository(db)
+	ctx := context.Background()
+	passHash := []byte("hash")
+
+	query := regexp.QuoteMeta("UPDATE users SET pass_hash = $1 WHERE id = $2")
+	mock.ExpectExec(query).WithArgs(passHash, int64(99)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = repo.UpdateUserPassword(ctx, 99, passHash)
+	assert.True(t, errors.Is(err, storage.ErrUserNotFound))
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
 }
 
-func TestGetUserByIDtx_Success(t *testing.T) {
+func TestLockUserByID_Success(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
 	defer db.Close()
@@ -437,30 +487,30 @@ func TestGetUserByIDtx_Success(t *testing.T) {
 	userID := int64(1)
 	email := "test@example.com"
 
-	// Начинаем транзакцию.
-	mock.ExpectBegin()
-	tx, err := db.Begin()
-	assert.NoError(t, err)
+	txManager := storage.NewTxManager(db)
 
 	// Подготавливаем ожидаемые строки результата.
-	rows := sqlmock.NewRows([]string{"id", "username", "pass_hash", "coin_balance"}).
-		AddRow(userID, email, []byte("hashed"), 1000)
-	query := regexp.QuoteMeta("SELECT id, username, pass_hash, coin_balance FROM users WHERE id = $1")
+	rows := sqlmock.NewRows([]string{"id", "username", "pass_hash", "coin_balance", "is_admin", "created_at"}).
+		AddRow(userID, email, []byte("hashed"), 1000, false, time.Now())
+	query := regexp.QuoteMeta("SELECT id, username, pass_hash, coin_balance, is_admin, created_at FROM users WHERE id = $1 FOR UPDATE NOWAIT")
+	mock.ExpectBegin()
 	mock.ExpectQuery(query).WithArgs(userID).WillReturnRows(rows)
+	mock.ExpectCommit()
 
-	user, err := repo.LockUserByIDTx(ctx, tx, userID)
+	var user *models.User
+	err = txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		user, err = repo.LockUserByID(ctx, userID)
+		return err
+	})
 	assert.NoError(t, err)
 	assert.Equal(t, userID, user.ID)
 	assert.Equal(t, email, user.Email)
 
-	mock.ExpectCommit()
-	err = tx.Commit()
-	assert.NoError(t, err)
-
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-func TestGetUserByIDtx_NotFound(t *testing.T) {
+func TestLockUserByID_NotFound(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
 	defer db.Close()
@@ -469,22 +519,635 @@ func TestGetUserByIDtx_NotFound(t *testing.T) {
 	ctx := context.Background()
 	userID := int64(99)
 
-	mock.ExpectBegin()
-	tx, err := db.Begin()
-	assert.NoError(t, err)
+	txManager := storage.NewTxManager(db)
 
-	rows := sqlmock.NewRows([]string{"id", "username", "pass_hash", "coin_balance"})
-	query := regexp.QuoteMeta("SELECT id, username, pass_hash, coin_balance FROM users WHERE id = $1")
+	rows := sqlmock.NewRows([]string{"id", "username", "pass_hash", "coin_balance", "is_admin", "created_at"})
+	query := regexp.QuoteMeta("SELECT id, username, pass_hash, coin_balance, is_admin, created_at FROM users WHERE id = $1 FOR UPDATE NOWAIT")
+	mock.ExpectBegin()
 	mock.ExpectQuery(query).WithArgs(userID).WillReturnRows(rows)
+	mock.ExpectRollback()
 
-	user, err := repo.LockUserByIDTx(ctx, tx, userID)
+	var user *models.User
+	err = txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		user, err = repo.LockUserByID(ctx, userID)
+		return err
+	})
 	assert.Error(t, err)
 	assert.Nil(t, user)
 	assert.True(t, errors.Is(err, storage.ErrUserNotFound))
 
-	mock.ExpectRollback()
-	err = tx.Rollback()
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTxManager_NestedJoinsOuterTx(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := storage.NewUserRepository(db)
+	txManager := storage.NewTxManager(db)
+	ctx := context.Background()
+
+	// Вложенный WithinTx не открывает новую транзакцию: ожидаем ровно один Begin и один Commit.
+	query := regexp.QuoteMeta("UPDATE users SET coin_balance = $1 WHERE id = $2")
+	mock.ExpectBegin()
+	mock.ExpectExec(query).WithArgs(900, int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query).WithArgs(1100, int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := repo.UpdateUserBalance(ctx, 1, 900); err != nil {
+			return err
+		}
+		return txManager.WithinTx(ctx, func(ctx context.Context) error {
+			return repo.UpdateUserBalance(ctx, 2, 1100)
+		})
+	})
 	assert.NoError(t, err)
 
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestInsertTransaction_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := storage.NewCoinTransactionRepository(db)
+	ctx := context.Background()
+	adminID := int64(7)
+	key := "grant:q3:alice@example.com"
+	createdAt := time.Now()
+
+	query := regexp.QuoteMeta("INSERT INTO coin_transactions (user_id, amount, type, related_user_id, comment, idempotency_key, created_at)")
+	mock.ExpectQuery(query).WithArgs(int64(1), 100, models.CoinTxGrant, &adminID, "Q3 bonus", &key).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(42, createdAt))
+
+	tx := &models.CoinTransaction{
+		UserID:         1,
+		Amount:         100,
+		Type:           models.CoinTxGrant,
+		RelatedUserID:  &adminID,
+		Comment:        "Q3 bonus",
+		IdempotencyKey: &key,
+	}
+	err = repo.InsertTransaction(ctx, tx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), tx.ID)
+	assert.Equal(t, createdAt, tx.CreatedAt)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestInsertTransaction_DuplicateKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := storage.NewCoinTransactionRepository(db)
+	key := "grant:q3:alice@example.com"
+
+	query := regexp.QuoteMeta("INSERT INTO coin_transactions")
+	mock.ExpectQuery(query).WillReturnError(&pq.Error{Code: "23505"})
+
+	err = repo.InsertTransaction(context.Background(), &models.CoinTransaction{UserID: 1, Amount: 1, Type: models.CoinTxGrant, IdempotencyKey: &key})
+	assert.True(t, errors.Is(err, storage.ErrDuplicateTransaction))
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestTransactionKeyExists(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := storage.NewCoinTransactionRepository(db)
+
+	query := regexp.QuoteMeta("SELECT EXISTS (SELECT 1 FROM coin_transactions WHERE idempotency_key = $1)")
+	mock.ExpectQuery(query).WithArgs("grant:q3:alice@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	exists, err := repo.TransactionKeyExists(context.Background(), "grant:q3:alice@example.com")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestCreateJobRun_Exists(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := storage.NewJobRunRepository(db)
+
+	query := regexp.QuoteMeta("INSERT INTO job_runs")
+	mock.ExpectQuery(query).WillReturnError(&pq.Error{Code: "23505"})
+
+	err = repo.CreateJobRun(context.Background(), &models.JobRun{Job: "allowance", ScheduledAt: time.Now(), Status: models.JobRunRunning})
+	assert.True(t, errors.Is(err, storage.ErrJobRunExists))
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestListJobRuns_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := storage.NewJobRunRepository(db)
+	scheduledAt := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	finishedAt := scheduledAt.Add(time.Minute)
+
+	query := regexp.QuoteMeta("SELECT id, job, scheduled_at, started_at, finished_at, status, error FROM job_runs")
+	rows := sqlmock.NewRows([]string{"id", "job", "scheduled_at", "started_at", "finished_at", "status", "error"}).
+		AddRow(2, "allowance", scheduledAt, scheduledAt, nil, models.JobRunRunning, "").
+		AddRow(1, "allowance", scheduledAt.AddDate(0, -1, 0), scheduledAt.AddDate(0, -1, 0), finishedAt, models.JobRunSucceeded, "")
+	mock.ExpectQuery(query).WithArgs("allowance", 10).WillReturnRows(rows)
+
+	runs, err := repo.ListJobRuns(context.Background(), "allowance", 10)
+	assert.NoError(t, err)
+	assert.Len(t, runs, 2)
+	assert.Nil(t, runs[0].FinishedAt)
+	assert.Equal(t, finishedAt, *runs[1].FinishedAt)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestListActiveUserIDs_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := storage.NewUserRepository(db)
+
+	query := regexp.QuoteMeta("SELECT id FROM users WHERE is_active AND id > $1 ORDER BY id LIMIT $2")
+	mock.ExpectQuery(query).WithArgs(int64(10), 2).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11).AddRow(12))
+
+	ids, err := repo.ListActiveUserIDs(context.Background(), 10, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{11, 12}, ids)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestListExpiredLots_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := storage.NewCoinLotRepository(db)
+	now := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	expiresAt := now.Add(-time.Hour)
+
+	query := regexp.QuoteMeta("SELECT id, user_id, amount, remaining, source, expires_at, created_at FROM coin_lots")
+	rows := sqlmock.NewRows([]string{"id", "user_id", "amount", "remaining", "source", "expires_at", "created_at"}).
+		AddRow(5, 1, 100, 40, models.CoinTxGrant, expiresAt, now.AddDate(0, -1, 0))
+	mock.ExpectQuery(query).WithArgs(now, int64(3), 10).WillReturnRows(rows)
+
+	lots, err := repo.ListExpiredLots(context.Background(), now, 3, 10)
+	assert.NoError(t, err)
+	assert.Len(t, lots, 1)
+	assert.Equal(t, 40, lots[0].Remaining)
+	assert.Equal(t, expiresAt, *lots[0].ExpiresAt)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestUpdateLotRemaining_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := storage.NewCoinLotRepository(db)
+
+	query := regexp.QuoteMeta("UPDATE coin_lots SET remaining = $1 WHERE id = $2")
+	mock.ExpectExec(query).WithArgs(0, int64(9)).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = repo.UpdateLotRemaining(context.Background(), 9, 0)
+	assert.True(t, errors.Is(err, storage.ErrLotNotFound))
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestSearchTransactions_EscapesPattern(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := storage.NewCoinTransactionRepository(db)
+	createdAt := time.Now()
+
+	query := regexp.QuoteMeta("WHERE user_id = $1 AND comment ILIKE $2")
+	rows := sqlmock.NewRows([]string{"id", "user_id", "amount", "type", "related_user_id", "comment", "created_at"}).
+		AddRow(3, 1, 50, models.CoinTxTransferReceived, 2, "100% thanks", createdAt)
+	mock.ExpectQuery(query).WithArgs(int64(1), `%100\%%`, 20).WillReturnRows(rows)
+
+	txs, err := repo.SearchTransactions(context.Background(), 1, "100%", 20)
+	assert.NoError(t, err)
+	assert.Len(t, txs, 1)
+	assert.Equal(t, "100% thanks", txs[0].Comment)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestTransactionStats_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := storage.NewCoinTransactionRepository(db)
+	since := time.Now().Add(-24 * time.Hour)
+
+	query := regexp.QuoteMeta("SELECT COUNT(*), COALESCE(SUM(amount), 0) FROM coin_transactions")
+	mock.ExpectQuery(query).WithArgs(int64(1), models.CoinTxTransferSent, since).
+		WillReturnRows(sqlmock.NewRows([]string{"count", "sum"}).AddRow(3, 250))
+
+	count, sum, err := repo.TransactionStats(context.Background(), 1, models.CoinTxTransferSent, since)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.Equal(t, 250, sum)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestExpireCoinRequests_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := storage.NewCoinRequestRepository(db)
+	now := time.Now()
+
+	query := regexp.QuoteMeta("UPDATE coin_requests SET status = $1, resolved_at = $2 WHERE status = $3 AND expires_at <= $2")
+	mock.ExpectExec(query).WithArgs(models.CoinRequestExpired, now, models.CoinRequestPending).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	expired, err := repo.ExpireCoinRequests(context.Background(), now)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, expired)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestLockCoinRequest_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := storage.NewCoinRequestRepository(db)
+
+	query := regexp.QuoteMeta("FROM coin_requests WHERE id = $1 FOR UPDATE")
+	mock.ExpectQuery(query).WithArgs(int64(5)).WillReturnError(sql.ErrNoRows)
+
+	_, err = repo.LockCoinRequest(context.Background(), 5)
+	assert.True(t, errors.Is(err, storage.ErrCoinRequestNotFound))
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestLockMerchByName_Stock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := storage.NewMerchRepository(db)
+
+	query := merchSelectQuery + regexp.QuoteMeta(" WHERE m.name = $1 FOR UPDATE OF m")
+	mock.ExpectQuery(query).WithArgs("pink-hoody").
+		WillReturnRows(sqlmock.NewRows(merchRowColumns).
+			AddRow(10, "pink-hoody", 500, 3, 1, "quarter", "clothing", "Limited edition", "merch/10/ab.png", "{pink,limited}"))
+
+	merch, err := repo.LockMerchByName(context.Background(), "pink-hoody")
+	assert.NoError(t, err)
+	if assert.NotNil(t, merch.Stock) {
+		assert.Equal(t, 3, *merch.Stock)
+	}
+	assert.Equal(t, 1, merch.PurchaseLimit)
+	assert.Equal(t, "quarter", merch.PurchaseLimitPeriod)
+	assert.Equal(t, "clothing", merch.Category)
+	assert.Equal(t, "merch/10/ab.png", merch.ImageKey)
+	assert.Equal(t, []string{"pink", "limited"}, merch.Tags)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestUpdateMerchStock_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := storage.NewMerchRepository(db)
+	stock := 5
+
+	query := regexp.QuoteMeta("UPDATE merch SET stock = $1 WHERE id = $2")
+	mock.ExpectExec(query).WithArgs(5, int64(42)).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = repo.UpdateMerchStock(context.Background(), 42, &stock)
+	assert.ErrorIs(t, err, storage.ErrMerchNotFound)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestUpdateMerchDetails_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := storage.NewMerchRepository(db)
+
+	// Теги передаются массивом PostgreSQL.
+	query := regexp.QuoteMeta("UPDATE merch SET category = $1, description = $2, tags = $3 WHERE id = $4")
+	mock.ExpectExec(query).WithArgs("clothing", "Warm hoody", `{"warm","winter"}`, int64(6)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = repo.UpdateMerchDetails(context.Background(), 6, "clothing", "Warm hoody", []string{"warm", "winter"})
+	assert.NoError(t, err)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestCountUserOrders_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := storage.NewOrderRepository(db)
+	since := time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC)
+
+	query := regexp.QuoteMeta("SELECT COALESCE(SUM(quantity), 0) FROM orders") + `\s+` +
+		regexp.QuoteMeta("WHERE user_id = $1 AND merch_id = $2 AND created_at >= $3 AND status <> $4")
+	mock.ExpectQuery(query).WithArgs(int64(1), int64(10), since, models.OrderCancelled).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(2))
+
+	count, err := repo.CountUserOrders(context.Background(), 1, 10, since)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestLockOrder_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := storage.NewOrderRepository(db)
+
+	mock.ExpectQuery(`FROM orders o\s+JOIN merch m ON o\.merch_id = m\.id\s+LEFT JOIN promotions p ON o\.promotion_id = p\.id\s+LEFT JOIN merch_variants v ON o\.variant_id = v\.id\s+WHERE o\.id = \$1 FOR UPDATE OF o`).
+		WithArgs(int64(42)).WillReturnError(sql.ErrNoRows)
+
+	order, err := repo.LockOrder(context.Background(), 42)
+	assert.ErrorIs(t, err, storage.ErrOrderNotFound)
+	assert.Nil(t, order)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestUpdateOrderStatus_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := storage.NewOrderRepository(db)
+	now := time.Now()
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE orders SET status = $1, updated_at = $2 WHERE id = $3")).
+		WithArgs(models.OrderDelivered, now, int64(42)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = repo.UpdateOrderStatus(context.Background(), 42, models.OrderDelivered, now)
+	assert.ErrorIs(t, err, storage.ErrOrderNotFound)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestGetPriceHistory_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := storage.NewMerchRepository(db)
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(24 * time.Hour)
+	adminID := int64(3)
+
+	query := `SELECT id, merch_id, price, effective_from, changed_by, COALESCE\(comment, ''\), created_at\s+` +
+		`FROM merch_price_history\s+WHERE merch_id = \$1\s+ORDER BY effective_from, id`
+	mock.ExpectQuery(query).WithArgs(int64(10)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "merch_id", "price", "effective_from", "changed_by", "comment", "created_at"}).
+			AddRow(1, 10, 500, past, nil, "initial price", past).
+			AddRow(2, 10, 400, future, adminID, "sale", past))
+
+	history, err := repo.GetPriceHistory(context.Background(), 10)
+	assert.NoError(t, err)
+	if assert.Len(t, history, 2) {
+		assert.Nil(t, history[0].ChangedBy)
+		assert.Equal(t, 400, history[1].Price)
+		assert.Equal(t, future, history[1].EffectiveFrom)
+		assert.Equal(t, &adminID, history[1].ChangedBy)
+		assert.Equal(t, "sale", history[1].Comment)
+	}
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestAddPriceChange_Immediate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := storage.NewMerchRepository(db)
+	dbNow := time.Now().Add(-time.Second)
+
+	// Без EffectiveFrom время изменения цены ставит база
+	query := regexp.QuoteMeta(`INSERT INTO merch_price_history (merch_id, price, effective_from, changed_by, comment, created_at)
+	          VALUES ($1, $2, COALESCE($3, NOW()), $4, NULLIF($5, ''), NOW()) RETURNING id, effective_from, created_at`)
+	mock.ExpectQuery(query).WithArgs(int64(10), 400, nil, nil, "fixtures").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "effective_from", "created_at"}).AddRow(5, dbNow, dbNow))
+
+	change := &models.MerchPriceChange{MerchID: 10, Price: 400, Comment: "fixtures"}
+	assert.NoError(t, repo.AddPriceChange(context.Background(), change))
+	assert.Equal(t, int64(5), change.ID)
+	assert.Equal(t, dbNow, change.EffectiveFrom)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestCreatePromotion_CodeExists(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := storage.NewPromotionRepository(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO promotions")).WillReturnError(&pq.Error{Code: "23505"})
+
+	err = repo.CreatePromotion(context.Background(), &models.Promotion{Name: "sale", Code: "SALE", DiscountType: models.DiscountFixed, DiscountValue: 10})
+	assert.ErrorIs(t, err, storage.ErrPromoCodeExists)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestCountPromotionOrders_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := storage.NewOrderRepository(db)
+
+	query := regexp.QuoteMeta("SELECT COUNT(*) FROM orders") + `\s+` +
+		regexp.QuoteMeta("WHERE promotion_id = $1 AND ($2 = 0 OR COALESCE(gifted_by, user_id) = $2) AND status <> $3")
+	mock.ExpectQuery(query).WithArgs(int64(4), int64(1), models.OrderCancelled).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+	count, err := repo.CountPromotionOrders(context.Background(), 4, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestLockMerchByID_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := storage.NewMerchRepository(db)
+
+	query := merchSelectQuery + regexp.QuoteMeta(" WHERE m.id = $1 FOR UPDATE OF m")
+	mock.ExpectQuery(query).WithArgs(int64(10)).WillReturnError(sql.ErrNoRows)
+
+	merch, err := repo.LockMerchByID(context.Background(), 10)
+	assert.ErrorIs(t, err, storage.ErrMerchNotFound)
+	assert.Nil(t, merch)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestLockVariantBySKU_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := storage.NewMerchRepository(db)
+
+	query := regexp.QuoteMeta("SELECT id, merch_id, sku, size, color, price, stock, is_active FROM merch_variants WHERE merch_id = $1 AND sku = $2 AND is_active FOR UPDATE")
+	mock.ExpectQuery(query).WithArgs(int64(2), "TS-M").WillReturnError(sql.ErrNoRows)
+
+	variant, err := repo.LockVariantBySKU(context.Background(), 2, "TS-M")
+	assert.ErrorIs(t, err, storage.ErrVariantNotFound)
+	assert.Nil(t, variant)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestAddToWishlist_Exists(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := storage.NewWishlistRepository(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO wishlist (user_id, merch_id, created_at)")).
+		WithArgs(int64(1), int64(2)).WillReturnError(&pq.Error{Code: "23505"})
+
+	_, err = repo.AddToWishlist(context.Background(), 1, 2)
+	assert.ErrorIs(t, err, storage.ErrWishlistItemExists)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestListWishlistPrices_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := storage.NewWishlistRepository(db)
+
+	// Цены считает один запрос по списку желаний пользователя, каталог целиком не читается
+	mock.ExpectQuery(`FROM wishlist w\s+JOIN merch m ON m\.id = w\.merch_id\s+WHERE w\.user_id = \$1 AND m\.is_active`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "sold_out"}).
+			AddRow(3, "t-shirt", 70, false).
+			AddRow(2, "hoody", 300, true))
+
+	prices, err := repo.ListWishlistPrices(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.WishlistPrice{
+		{MerchID: 3, Name: "t-shirt", Price: 70},
+		{MerchID: 2, Name: "hoody", Price: 300, SoldOut: true},
+	}, prices)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestCreateNotification_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := storage.NewNotificationRepository(db)
+	createdAt := time.Date(2025, time.May, 1, 12, 0, 0, 0, time.UTC)
+
+	// Data сохраняется в JSONB
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO notifications (user_id, type, message, data, created_at)")).
+		WithArgs(int64(3), models.NotificationBackInStock, "cup is back in stock", []byte(`{"item":"cup"}`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(7, createdAt))
+
+	n := &models.Notification{UserID: 3, Type: models.NotificationBackInStock, Message: "cup is back in stock", Data: map[string]any{"item": "cup"}}
+	err = repo.CreateNotification(context.Background(), n)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), n.ID)
+	assert.Equal(t, createdAt, n.CreatedAt)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
+
+func TestMarkNotificationRead_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := storage.NewNotificationRepository(db)
+	readAt := time.Date(2025, time.May, 1, 12, 0, 0, 0, time.UTC)
+
+	// Чужое или несуществующее уведомление не обновляется
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE notifications SET read_at = COALESCE(read_at, $3) WHERE id = $1 AND user_id = $2")).
+		WithArgs(int64(7), int64(3), readAt).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = repo.MarkNotificationRead(context.Background(), 3, 7, readAt)
+	assert.ErrorIs(t, err, storage.ErrNotificationNotFound)
+
+	err = mock.ExpectationsWereMet()
+	assert.NoError(t, err)
+}
@@ -3,16 +3,34 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
 	"github.com/linemk/avito-shop/internal/domain/models"
 )
 
+// ErrDuplicateTransaction — операция с таким ключом идемпотентности уже записана.
+var ErrDuplicateTransaction = errors.New("coin transaction with this idempotency key already exists")
+
 // CoinTransactionStorage описывает методы для работы с транзакциями.
 type CoinTransactionStorage interface {
-	// CreateTransaction создает запись о транзакции.
-	CreateTransaction(ctx context.Context, tx *sql.Tx, userID int64, amount int, txType string, relatedUserID *int64) error
+	// CreateTransaction создает запись о транзакции (в текущей транзакции БД, если она открыта).
+	CreateTransaction(ctx context.Context, userID int64, amount int, txType string, relatedUserID *int64) error
+	// InsertTransaction записывает операцию со всеми полями (комментарий, ключ идемпотентности)
+	// и заполняет ID и CreatedAt. При повторе ключа возвращает ErrDuplicateTransaction.
+	InsertTransaction(ctx context.Context, tx *models.CoinTransaction) error
+	// TransactionKeyExists проверяет, записана ли уже операция с ключом идемпотентности.
+	TransactionKeyExists(ctx context.Context, key string) (bool, error)
 	// GetTransactionsByUserID возвращает список транзакций для указанного пользователя.
 	GetTransactionsByUserID(ctx context.Context, userID int64) ([]*models.CoinTransaction, error)
+	// SearchTransactions возвращает до limit операций пользователя от новых к старым,
+	// в комментарии которых встречается query без учёта регистра (пустой query — все операции).
+	SearchTransactions(ctx context.Context, userID int64, query string, limit int) ([]*models.CoinTransaction, error)
+	// TransactionStats возвращает число и сумму операций типа txType пользователя не раньше since.
+	TransactionStats(ctx context.Context, userID int64, txType string, since time.Time) (count, sum int, err error)
 }
 
 type coinTransactionRepository struct {
@@ -23,23 +41,81 @@ func NewCoinTransactionRepository(db *sql.DB) CoinTransactionStorage {
 	return &coinTransactionRepository{db: db}
 }
 
-func (r *coinTransactionRepository) CreateTransaction(ctx context.Context, tx *sql.Tx, userID int64, amount int, txType string, relatedUserID *int64) error {
-	query := `INSERT INTO coin_transactions (user_id, amount, type, related_user_id, created_at)
-	          VALUES ($1, $2, $3, $4, NOW())`
-	_, err := tx.ExecContext(ctx, query, userID, amount, txType, relatedUserID)
+func (r *coinTransactionRepository) CreateTransaction(ctx context.Context, userID int64, amount int, txType string, relatedUserID *int64) error {
+	return r.InsertTransaction(ctx, &models.CoinTransaction{
+		UserID:        userID,
+		Amount:        amount,
+		Type:          txType,
+		RelatedUserID: relatedUserID,
+	})
+}
+
+func (r *coinTransactionRepository) InsertTransaction(ctx context.Context, tx *models.CoinTransaction) error {
+	query := `INSERT INTO coin_transactions (user_id, amount, type, related_user_id, comment, idempotency_key, created_at)
+	          VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, NOW())
+	          RETURNING id, created_at`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query,
+		tx.UserID, tx.Amount, tx.Type, tx.RelatedUserID, tx.Comment, tx.IdempotencyKey,
+	).Scan(&tx.ID, &tx.CreatedAt)
 	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" { // unique_violation
+			return ErrDuplicateTransaction
+		}
 		return fmt.Errorf("failed to create coin transaction: %w", err)
 	}
 	return nil
 }
 
+func (r *coinTransactionRepository) TransactionKeyExists(ctx context.Context, key string) (bool, error) {
+	var exists bool
+	query := "SELECT EXISTS (SELECT 1 FROM coin_transactions WHERE idempotency_key = $1)"
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, key).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check idempotency key: %w", err)
+	}
+	return exists, nil
+}
+
 func (r *coinTransactionRepository) GetTransactionsByUserID(ctx context.Context, userID int64) ([]*models.CoinTransaction, error) {
 	query := `
-		SELECT id, user_id, amount, type, related_user_id, created_at
+		SELECT id, user_id, amount, type, related_user_id, COALESCE(comment, ''), created_at
 		FROM coin_transactions
 		WHERE user_id = $1
 		ORDER BY created_at DESC`
-	rows, err := r.db.QueryContext(ctx, query, userID)
+	return r.queryTransactions(ctx, query, userID)
+}
+
+func (r *coinTransactionRepository) SearchTransactions(ctx context.Context, userID int64, query string, limit int) ([]*models.CoinTransaction, error) {
+	if query == "" {
+		return r.queryTransactions(ctx, `
+			SELECT id, user_id, amount, type, related_user_id, COALESCE(comment, ''), created_at
+			FROM coin_transactions
+			WHERE user_id = $1
+			ORDER BY created_at DESC, id DESC
+			LIMIT $2`, userID, limit)
+	}
+	return r.queryTransactions(ctx, `
+		SELECT id, user_id, amount, type, related_user_id, COALESCE(comment, ''), created_at
+		FROM coin_transactions
+		WHERE user_id = $1 AND comment ILIKE $2 ESCAPE '\'
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3`, userID, "%"+likeEscaper.Replace(query)+"%", limit)
+}
+
+func (r *coinTransactionRepository) TransactionStats(ctx context.Context, userID int64, txType string, since time.Time) (int, int, error) {
+	var count, sum int
+	query := `SELECT COUNT(*), COALESCE(SUM(amount), 0) FROM coin_transactions
+	          WHERE user_id = $1 AND type = $2 AND created_at >= $3`
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, userID, txType, since).Scan(&count, &sum); err != nil {
+		return 0, 0, fmt.Errorf("failed to get coin transaction stats: %w", err)
+	}
+	return count, sum, nil
+}
+
+// likeEscaper экранирует спецсимволы шаблона LIKE, чтобы query искался как обычная строка.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func (r *coinTransactionRepository) queryTransactions(ctx context.Context, query string, args ...any) ([]*models.CoinTransaction, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query coin transactions: %w", err)
 	}
@@ -48,7 +124,7 @@ func (r *coinTransactionRepository) GetTransactionsByUserID(ctx context.Context,
 	var transactions []*models.CoinTransaction
 	for rows.Next() {
 		tx := &models.CoinTransaction{}
-		if err := rows.Scan(&tx.ID, &tx.UserID, &tx.Amount, &tx.Type, &tx.RelatedUserID, &tx.CreatedAt); err != nil {
+		if err := rows.Scan(&tx.ID, &tx.UserID, &tx.Amount, &tx.Type, &tx.RelatedUserID, &tx.Comment, &tx.CreatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan coin transaction: %w", err)
 		}
 		transactions = append(transactions, tx)
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// TxManager описывает единицу работы: все вызовы репозиториев внутри fn
+// выполняются в одной транзакции, которая передаётся через контекст.
+// Если fn возвращает ошибку, транзакция откатывается, иначе — коммитится.
+// Вложенный вызов WithinTx присоединяется к уже открытой транзакции.
+type TxManager interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// txKey — ключ контекста, под которым хранится открытая транзакция.
+type txKey struct{}
+
+// executor — общий набор методов *sql.DB и *sql.Tx, которым пользуются репозитории.
+type executor interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// conn возвращает транзакцию из контекста, если она открыта, иначе — само подключение к БД.
+func conn(ctx context.Context, db *sql.DB) executor {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// txManager — реализация TxManager поверх *sql.DB.
+type txManager struct {
+	db *sql.DB
+}
+
+// NewTxManager создаёт менеджер транзакций для PostgreSQL.
+func NewTxManager(db *sql.DB) TxManager {
+	return &txManager{db: db}
+}
+
+// WithinTx открывает транзакцию, кладёт её в контекст и выполняет fn.
+func (m *txManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
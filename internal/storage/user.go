@@ -10,16 +10,30 @@ import (
 	"github.com/linemk/avito-shop/internal/domain/models"
 )
 
-var ErrUserNotFound = errors.New("user not found")
+var (
+	ErrUserNotFound = errors.New("user not found")
+	ErrUserExists   = errors.New("user already exists")
+	// ErrUserLocked — строку пользователя держит параллельная транзакция (LockUserByID не ждёт).
+	ErrUserLocked = errors.New("resource is locked, please try again")
+)
 
 type UserStorage interface {
 	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
 	CreateUser(ctx context.Context, user *models.User) (*models.User, error)
 	GetUserByID(ctx context.Context, id int64) (*models.User, error)
-	LockUserByIDTx(ctx context.Context, tx *sql.Tx, id int64) (*models.User, error)
-	UpdateUserBalance(ctx context.Context, tx *sql.Tx, id int64, newBalance int) error
+	// LockUserByID блокирует строку пользователя до конца текущей транзакции (см. TxManager).
+	LockUserByID(ctx context.Context, id int64) (*models.User, error)
+	UpdateUserBalance(ctx context.Context, id int64, newBalance int) error
+	UpdateUserPassword(ctx context.Context, id int64, passHash []byte) error
+	// SetUserAdmin выдаёт (isAdmin = true) или отзывает права администратора.
+	SetUserAdmin(ctx context.Context, id int64, isAdmin bool) error
+	// ListActiveUserIDs возвращает до limit идентификаторов активных пользователей больше afterID
+	// по возрастанию (постраничный обход всех сотрудников).
+	ListActiveUserIDs(ctx context.Context, afterID int64, limit int) ([]int64, error)
 }
 
+const userColumns = "id, username, pass_hash, coin_balance, is_admin, created_at"
+
 type userRepository struct {
 	db *sql.DB
 }
@@ -30,9 +44,9 @@ func NewUserRepository(db *sql.DB) *userRepository {
 
 // получение уже существующего пользователя
 func (r *userRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
-	user := &models.User{}
-	row := r.db.QueryRowContext(ctx, "SELECT id, username, pass_hash, coin_balance FROM users WHERE username = $1", email)
-	if err := row.Scan(&user.ID, &user.Email, &user.PassHash, &user.CoinBalance); err != nil {
+	row := conn(ctx, r.db).QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE username = $1", email)
+	user, err := scanUser(row)
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrUserNotFound
 		}
@@ -43,20 +57,25 @@ func (r *userRepository) GetUserByEmail(ctx context.Context, email string) (*mod
 
 func (r *userRepository) CreateUser(ctx context.Context, user *models.User) (*models.User, error) {
 	var id int64
-	err := r.db.QueryRowContext(ctx,
-		"INSERT INTO users (username, pass_hash, coin_balance) VALUES ($1, $2, $3) RETURNING id",
-		user.Email, user.PassHash, user.CoinBalance,
-	).Scan(&id)
+	var createdAt sql.NullTime
+	err := conn(ctx, r.db).QueryRowContext(ctx,
+		"INSERT INTO users (username, pass_hash, coin_balance, is_admin) VALUES ($1, $2, $3, $4) RETURNING id, created_at",
+		user.Email, user.PassHash, user.CoinBalance, user.IsAdmin,
+	).Scan(&id, &createdAt)
 	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" { // unique_violation
+			return nil, ErrUserExists
+		}
 		return nil, err
 	}
 	user.ID = id
+	user.CreatedAt = createdAt.Time
 	return user, nil
 }
 
 // TODO - можно сделать вычисление на стороне БД
-func (r *userRepository) UpdateUserBalance(ctx context.Context, tx *sql.Tx, id int64, newBalance int) error {
-	res, err := tx.ExecContext(ctx, "UPDATE users SET coin_balance = $1 WHERE id = $2", newBalance, id)
+func (r *userRepository) UpdateUserBalance(ctx context.Context, id int64, newBalance int) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, "UPDATE users SET coin_balance = $1 WHERE id = $2", newBalance, id)
 	if err != nil {
 		return err
 	}
@@ -70,14 +89,43 @@ func (r *userRepository) UpdateUserBalance(ctx context.Context, tx *sql.Tx, id i
 	return nil
 }
 
-func (r *userRepository) LockUserByIDTx(ctx context.Context, tx *sql.Tx, id int64) (*models.User, error) {
-	user := &models.User{}
+func (r *userRepository) UpdateUserPassword(ctx context.Context, id int64, passHash []byte) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, "UPDATE users SET pass_hash = $1 WHERE id = $2", passHash, id)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) SetUserAdmin(ctx context.Context, id int64, isAdmin bool) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, "UPDATE users SET is_admin = $1 WHERE id = $2", isAdmin, id)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
 
-	row := tx.QueryRowContext(ctx, "SELECT id, username, pass_hash, coin_balance FROM users WHERE id = $1 FOR UPDATE NOWAIT", id)
-	if err := row.Scan(&user.ID, &user.Email, &user.PassHash, &user.CoinBalance); err != nil {
+func (r *userRepository) LockUserByID(ctx context.Context, id int64) (*models.User, error) {
+	row := conn(ctx, r.db).QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = $1 FOR UPDATE NOWAIT", id)
+	user, err := scanUser(row)
+	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok {
 			if pqErr.Code == "55P03" { // lock
-				return nil, fmt.Errorf("resource is locked, please try again: %w", err)
+				return nil, fmt.Errorf("%w: %w", ErrUserLocked, err)
 			}
 		}
 		if errors.Is(err, sql.ErrNoRows) {
@@ -87,3 +135,34 @@ func (r *userRepository) LockUserByIDTx(ctx context.Context, tx *sql.Tx, id int6
 	}
 	return user, nil
 }
+
+func (r *userRepository) ListActiveUserIDs(ctx context.Context, afterID int64, limit int) ([]int64, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx,
+		"SELECT id FROM users WHERE is_active AND id > $1 ORDER BY id LIMIT $2", afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// scanUser читает строку с колонками userColumns. У пользователей, созданных до появления
+// created_at, дата регистрации нулевая.
+func scanUser(row *sql.Row) (*models.User, error) {
+	user := &models.User{}
+	var createdAt sql.NullTime
+	if err := row.Scan(&user.ID, &user.Email, &user.PassHash, &user.CoinBalance, &user.IsAdmin, &createdAt); err != nil {
+		return nil, err
+	}
+	user.CreatedAt = createdAt.Time
+	return user, nil
+}
@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/linemk/avito-shop/internal/domain/models"
+)
+
+var (
+	// ErrWishlistItemExists — товар уже в списке желаний.
+	ErrWishlistItemExists = errors.New("item already in wishlist")
+	// ErrWishlistItemNotFound — товара нет в списке желаний.
+	ErrWishlistItemNotFound = errors.New("item not in wishlist")
+)
+
+// WishlistStorage описывает методы для работы со списками желаний.
+type WishlistStorage interface {
+	// AddToWishlist добавляет товар в список желаний пользователя.
+	// Если товар уже там, возвращает ErrWishlistItemExists.
+	AddToWishlist(ctx context.Context, userID, merchID int64) (*models.WishlistItem, error)
+	// RemoveFromWishlist убирает товар из списка желаний пользователя.
+	RemoveFromWishlist(ctx context.Context, userID, merchID int64) error
+	// ListWishlist возвращает список желаний пользователя от новых к старым.
+	ListWishlist(ctx context.Context, userID int64) ([]models.WishlistItem, error)
+	// ListWishlistPrices возвращает активные товары из списка желаний пользователя с ценой,
+	// с которой товар становится доступен, от новых к старым — без загрузки всего каталога.
+	ListWishlistPrices(ctx context.Context, userID int64) ([]models.WishlistPrice, error)
+	// ListWishlistUsers возвращает пользователей, у которых товар в списке желаний, по возрастанию ID.
+	ListWishlistUsers(ctx context.Context, merchID int64) ([]int64, error)
+}
+
+// wishlistRepository — конкретная реализация WishlistStorage.
+type wishlistRepository struct {
+	db *sql.DB
+}
+
+// NewWishlistRepository создаёт новый репозиторий списков желаний.
+func NewWishlistRepository(db *sql.DB) WishlistStorage {
+	return &wishlistRepository{db: db}
+}
+
+func (r *wishlistRepository) AddToWishlist(ctx context.Context, userID, merchID int64) (*models.WishlistItem, error) {
+	item := &models.WishlistItem{UserID: userID, MerchID: merchID}
+	err := conn(ctx, r.db).QueryRowContext(ctx,
+		"INSERT INTO wishlist (user_id, merch_id, created_at) VALUES ($1, $2, NOW()) RETURNING created_at",
+		userID, merchID).Scan(&item.CreatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" { // unique_violation
+			return nil, ErrWishlistItemExists
+		}
+		return nil, fmt.Errorf("failed to add wishlist item: %w", err)
+	}
+	return item, nil
+}
+
+func (r *wishlistRepository) RemoveFromWishlist(ctx context.Context, userID, merchID int64) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM wishlist WHERE user_id = $1 AND merch_id = $2", userID, merchID)
+	if err != nil {
+		return fmt.Errorf("failed to remove wishlist item: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to remove wishlist item: %w", err)
+	}
+	if n == 0 {
+		return ErrWishlistItemNotFound
+	}
+	return nil
+}
+
+func (r *wishlistRepository) ListWishlist(ctx context.Context, userID int64) ([]models.WishlistItem, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx,
+		"SELECT user_id, merch_id, created_at FROM wishlist WHERE user_id = $1 ORDER BY created_at DESC, merch_id", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list wishlist: %w", err)
+	}
+	defer rows.Close()
+
+	var items []models.WishlistItem
+	for rows.Next() {
+		var item models.WishlistItem
+		if err := rows.Scan(&item.UserID, &item.MerchID, &item.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan wishlist item: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// wishlistPricesQuery считает по каждому товару из списка желаний его предложения: активные
+// варианты с собственными или унаследованными ценой и остатком, а без вариантов — сам товар.
+// Цена — самое дешёвое предложение в продаже; товар распродан, если в продаже нет ни одного.
+const wishlistPricesQuery = `
+	WITH wanted AS (
+		SELECT m.id, m.name, m.stock, ` + merchPrice + ` AS price, w.created_at
+		FROM wishlist w
+		JOIN merch m ON m.id = w.merch_id
+		WHERE w.user_id = $1 AND m.is_active
+	)
+	SELECT m.id, m.name,
+	       COALESCE(MIN(o.price) FILTER (WHERE o.stock IS NULL OR o.stock > 0), m.price),
+	       NOT BOOL_OR(o.stock IS NULL OR o.stock > 0)
+	FROM wanted m
+	CROSS JOIN LATERAL (
+		SELECT COALESCE(v.price, m.price) AS price, COALESCE(v.stock, m.stock) AS stock
+		FROM merch_variants v
+		WHERE v.merch_id = m.id AND v.is_active
+		UNION ALL
+		SELECT m.price, m.stock
+		WHERE NOT EXISTS (SELECT 1 FROM merch_variants v WHERE v.merch_id = m.id AND v.is_active)
+	) o
+	GROUP BY m.id, m.name, m.price, m.created_at
+	ORDER BY m.created_at DESC, m.id`
+
+func (r *wishlistRepository) ListWishlistPrices(ctx context.Context, userID int64) ([]models.WishlistPrice, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, wishlistPricesQuery, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list wishlist prices: %w", err)
+	}
+	defer rows.Close()
+
+	var items []models.WishlistPrice
+	for rows.Next() {
+		var item models.WishlistPrice
+		if err := rows.Scan(&item.MerchID, &item.Name, &item.Price, &item.SoldOut); err != nil {
+			return nil, fmt.Errorf("failed to scan wishlist price: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *wishlistRepository) ListWishlistUsers(ctx context.Context, merchID int64) ([]int64, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, "SELECT user_id FROM wishlist WHERE merch_id = $1 ORDER BY user_id", merchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list wishlist users: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan wishlist user: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
@@ -0,0 +1,2 @@
+-- остаток товара на складе; NULL — количество не ограничено
+ALTER TABLE merch ADD COLUMN IF NOT EXISTS stock INTEGER CHECK (stock >= 0);
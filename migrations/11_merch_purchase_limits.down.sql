@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_orders_user_merch_created;
+ALTER TABLE merch
+    DROP COLUMN IF EXISTS purchase_limit_period,
+    DROP COLUMN IF EXISTS purchase_limit;
@@ -0,0 +1,8 @@
+-- ограничение покупок товара одним сотрудником: не больше purchase_limit штук
+-- за период purchase_limit_period ('' — за всё время, month, quarter, year); 0 — без ограничения
+ALTER TABLE merch
+    ADD COLUMN IF NOT EXISTS purchase_limit INTEGER NOT NULL DEFAULT 0 CHECK (purchase_limit >= 0),
+    ADD COLUMN IF NOT EXISTS purchase_limit_period TEXT NOT NULL DEFAULT '';
+
+-- лимит считает заказы пользователя по товару за период
+CREATE INDEX IF NOT EXISTS idx_orders_user_merch_created ON orders (user_id, merch_id, created_at);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS order_status_history;
+DROP INDEX IF EXISTS idx_orders_status;
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS status;
@@ -0,0 +1,20 @@
+-- существующие заказы считаются оформленными, но ещё не выданными
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'placed',
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP;
+
+UPDATE orders SET updated_at = created_at;
+
+CREATE INDEX IF NOT EXISTS idx_orders_status ON orders (status, created_at);
+
+CREATE TABLE IF NOT EXISTS order_status_history (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    from_status TEXT,  -- NULL для создания заказа
+    to_status TEXT NOT NULL,
+    changed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    comment TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_status_history_order ON order_status_history (order_id, id);
@@ -0,0 +1,11 @@
+-- действующие цены переносим обратно в merch, запланированные изменения теряются
+UPDATE merch m SET price = h.price
+FROM (
+    SELECT DISTINCT ON (merch_id) merch_id, price
+    FROM merch_price_history
+    WHERE effective_from <= NOW()
+    ORDER BY merch_id, effective_from DESC, id DESC
+) h
+WHERE h.merch_id = m.id;
+
+DROP TABLE IF EXISTS merch_price_history;
@@ -0,0 +1,17 @@
+-- история цен мерча; запись с effective_from в будущем — запланированное изменение цены.
+-- Действующая цена товара — последняя вступившая в силу запись, без истории — merch.price
+CREATE TABLE IF NOT EXISTS merch_price_history (
+    id SERIAL PRIMARY KEY,
+    merch_id INTEGER NOT NULL REFERENCES merch(id) ON DELETE CASCADE,
+    price INTEGER NOT NULL CHECK (price >= 0),
+    effective_from TIMESTAMP WITH TIME ZONE NOT NULL,
+    changed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    comment TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_merch_price_history_merch ON merch_price_history (merch_id, effective_from);
+
+-- текущие цены становятся первой записью истории
+INSERT INTO merch_price_history (merch_id, price, effective_from, comment)
+SELECT id, price, CURRENT_TIMESTAMP, 'initial price' FROM merch;
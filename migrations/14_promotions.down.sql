@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_orders_promotion;
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS promotion_id,
+    DROP COLUMN IF EXISTS discount;
+DROP TABLE IF EXISTS promotions;
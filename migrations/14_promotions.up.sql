@@ -0,0 +1,26 @@
+-- акции: скидка в процентах или монетах на товар (merch_id) и/или покупателя (user_id);
+-- акция без кода применяется автоматически, с кодом — по промокоду
+CREATE TABLE IF NOT EXISTS promotions (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    code TEXT UNIQUE,  -- в верхнем регистре; NULL — акция без кода
+    discount_type TEXT NOT NULL CHECK (discount_type IN ('percent', 'fixed')),
+    discount_value INTEGER NOT NULL CHECK (discount_value > 0),
+    merch_id INTEGER REFERENCES merch(id) ON DELETE CASCADE,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    starts_at TIMESTAMP WITH TIME ZONE,
+    ends_at TIMESTAMP WITH TIME ZONE,
+    max_uses INTEGER NOT NULL DEFAULT 0 CHECK (max_uses >= 0),
+    max_uses_per_user INTEGER NOT NULL DEFAULT 0 CHECK (max_uses_per_user >= 0),
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- скидка, применённая к заказу; total_price — цена после скидки
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS discount INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS promotion_id INTEGER REFERENCES promotions(id) ON DELETE SET NULL;
+
+-- лимиты использования акции считают её заказы
+CREATE INDEX IF NOT EXISTS idx_orders_promotion ON orders (promotion_id) WHERE promotion_id IS NOT NULL;
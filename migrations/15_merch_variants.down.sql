@@ -0,0 +1,2 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS variant_id;
+DROP TABLE IF EXISTS merch_variants;
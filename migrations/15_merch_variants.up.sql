@@ -0,0 +1,18 @@
+-- варианты товара (размер, цвет) со своим артикулом; price и stock NULL — действуют цена
+-- и остаток самого товара
+CREATE TABLE IF NOT EXISTS merch_variants (
+    id SERIAL PRIMARY KEY,
+    merch_id INTEGER NOT NULL REFERENCES merch(id) ON DELETE CASCADE,
+    sku TEXT NOT NULL UNIQUE,
+    size TEXT NOT NULL DEFAULT '',
+    color TEXT NOT NULL DEFAULT '',
+    price INTEGER CHECK (price > 0),
+    stock INTEGER CHECK (stock >= 0),
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_merch_variants_merch ON merch_variants (merch_id);
+
+-- вариант, купленный в заказе; NULL — у товара нет вариантов
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS variant_id INTEGER REFERENCES merch_variants(id) ON DELETE SET NULL;
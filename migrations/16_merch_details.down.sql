@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_merch_category;
+ALTER TABLE merch
+    DROP COLUMN IF EXISTS tags,
+    DROP COLUMN IF EXISTS image_key,
+    DROP COLUMN IF EXISTS description,
+    DROP COLUMN IF EXISTS category;
@@ -0,0 +1,12 @@
+-- карточка товара для витрины: категория, описание, изображение (ключ в хранилище blob) и теги
+ALTER TABLE merch
+    ADD COLUMN IF NOT EXISTS category TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS image_key TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_merch_category ON merch (category);
+
+UPDATE merch SET category = 'clothing' WHERE name IN ('t-shirt', 'hoody', 'pink-hoody', 'socks') AND category = '';
+UPDATE merch SET category = 'accessories' WHERE name IN ('cup', 'umbrella', 'wallet', 'powerbank') AND category = '';
+UPDATE merch SET category = 'stationery' WHERE name IN ('book', 'pen') AND category = '';
@@ -0,0 +1,22 @@
+-- список желаний: товары, на которые сотрудник копит монеты или ждёт поступления
+CREATE TABLE IF NOT EXISTS wishlist (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    merch_id INTEGER NOT NULL REFERENCES merch(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, merch_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_wishlist_merch ON wishlist (merch_id);
+
+-- уведомления пользователям; пишутся в той же транзакции, что и изменение, которое их вызвало
+CREATE TABLE IF NOT EXISTS notifications (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type TEXT NOT NULL,
+    message TEXT NOT NULL,
+    data JSONB NOT NULL DEFAULT '{}',
+    read_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications (user_id, id DESC);
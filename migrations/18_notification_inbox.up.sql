@@ -0,0 +1,2 @@
+-- непрочитанные уведомления: счётчик во входящих считается по этому индексу
+CREATE INDEX IF NOT EXISTS idx_notifications_unread ON notifications (user_id) WHERE read_at IS NULL;
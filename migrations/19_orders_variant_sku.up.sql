@@ -0,0 +1,5 @@
+-- артикул купленного варианта хранится в самом заказе: если строку варианта удалят
+-- (variant_id станет NULL), по заказу всё равно видно, что покупался вариант
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS variant_sku TEXT NOT NULL DEFAULT '';
+
+UPDATE orders o SET variant_sku = v.sku FROM merch_variants v WHERE o.variant_id = v.id;
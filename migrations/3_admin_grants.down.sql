@@ -0,0 +1,6 @@
+ALTER TABLE users DROP COLUMN IF EXISTS is_admin;
+
+DROP INDEX IF EXISTS idx_coin_tx_idempotency_key;
+
+ALTER TABLE coin_transactions DROP COLUMN IF EXISTS idempotency_key;
+ALTER TABLE coin_transactions DROP COLUMN IF EXISTS comment;
@@ -0,0 +1,9 @@
+-- причина начисления/списания (для grant и adjustment) и ключ идемпотентности,
+-- по которому повторный запуск массового начисления пропускает уже выполненные строки
+ALTER TABLE coin_transactions ADD COLUMN IF NOT EXISTS comment TEXT;
+ALTER TABLE coin_transactions ADD COLUMN IF NOT EXISTS idempotency_key TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_coin_tx_idempotency_key ON coin_transactions (idempotency_key);
+
+-- права администратора выдаются явно (фикстурой с admin: true), а не по email из конфига
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_admin BOOLEAN NOT NULL DEFAULT FALSE;
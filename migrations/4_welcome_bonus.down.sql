@@ -0,0 +1 @@
+ALTER TABLE users ALTER COLUMN coin_balance SET DEFAULT 1000;
@@ -0,0 +1,3 @@
+-- стартовый бонус теперь начисляется сервисом отдельной записью в coin_transactions
+-- (сумма задаётся в конфиге), поэтому новый пользователь создаётся с нулевым балансом
+ALTER TABLE users ALTER COLUMN coin_balance SET DEFAULT 0;
@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS is_active;
+
+DROP TABLE IF EXISTS job_runs;
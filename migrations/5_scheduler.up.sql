@@ -0,0 +1,15 @@
+-- история запусков фоновых задач; уникальность (job, scheduled_at) не даёт двум репликам
+-- выполнить один и тот же запуск по расписанию
+CREATE TABLE IF NOT EXISTS job_runs (
+    id SERIAL PRIMARY KEY,
+    job TEXT NOT NULL,
+    scheduled_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    status TEXT NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    UNIQUE (job, scheduled_at)
+);
+
+-- soft deletion сотрудников (как is_active у merch): регулярные начисления получают только активные
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_active BOOLEAN NOT NULL DEFAULT TRUE;
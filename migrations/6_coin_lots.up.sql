@@ -0,0 +1,15 @@
+-- партии начисленных монет: списания расходуют их от старых к новым, остаток партии
+-- с истёкшим expires_at сгорает. Баланс, не покрытый партиями (начисленный до этой миграции),
+-- считается самым старым и не сгорает.
+CREATE TABLE IF NOT EXISTS coin_lots (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount INTEGER NOT NULL,
+    remaining INTEGER NOT NULL CHECK (remaining >= 0),
+    source TEXT NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_coin_lots_user_active ON coin_lots (user_id, created_at, id) WHERE remaining > 0;
+CREATE INDEX IF NOT EXISTS idx_coin_lots_expires_at ON coin_lots (expires_at) WHERE remaining > 0 AND expires_at IS NOT NULL;
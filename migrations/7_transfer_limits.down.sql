@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_coin_tx_user_type_created;
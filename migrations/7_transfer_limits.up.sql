@@ -0,0 +1,2 @@
+-- лимиты переводов считают операции пользователя заданного типа за последние сутки
+CREATE INDEX IF NOT EXISTS idx_coin_tx_user_type_created ON coin_transactions (user_id, type, created_at);
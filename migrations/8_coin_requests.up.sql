@@ -0,0 +1,17 @@
+-- запросы монет у коллег: плательщик принимает (выполняется перевод) или отклоняет запрос,
+-- не рассмотренные до expires_at запросы переводит в expired фоновая задача
+CREATE TABLE IF NOT EXISTS coin_requests (
+    id SERIAL PRIMARY KEY,
+    requester_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    payer_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount INTEGER NOT NULL CHECK (amount > 0),
+    comment TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'pending',
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    resolved_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_coin_requests_payer ON coin_requests (payer_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_coin_requests_requester ON coin_requests (requester_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_coin_requests_pending ON coin_requests (expires_at) WHERE status = 'pending';
@@ -0,0 +1,2 @@
+-- заказ, купленный в подарок: user_id — получатель, gifted_by — кто оплатил
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS gifted_by INTEGER REFERENCES users(id) ON DELETE SET NULL;
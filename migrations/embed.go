@@ -0,0 +1,9 @@
+// Package migrations встраивает SQL-миграции в бинарники сервера и мигратора.
+package migrations
+
+import "embed"
+
+// FS содержит все файлы миграций N_name.up.sql / N_name.down.sql.
+//
+//go:embed *.sql
+var FS embed.FS
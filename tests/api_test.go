@@ -9,8 +9,6 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-const baseURL = "http://localhost:8080"
-
 // AuthResponse структура ответа при аутентификации
 type AuthResponse struct {
 	Token string `json:"token"`
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fullInfoResponse — ответ /api/info вместе с инвентарём.
+type fullInfoResponse struct {
+	Coins     int `json:"coins"`
+	Inventory []struct {
+		Type     string `json:"type"`
+		Quantity int    `json:"quantity"`
+	} `json:"inventory"`
+	CoinHistory struct {
+		Received []struct {
+			FromUser string `json:"fromUser"`
+			Amount   int    `json:"amount"`
+		} `json:"received"`
+		Sent []struct {
+			ToUser string `json:"toUser"`
+			Amount int    `json:"amount"`
+		} `json:"sent"`
+	} `json:"coinHistory"`
+}
+
+// doRequest выполняет авторизованный запрос и возвращает код ответа (0 при сетевой ошибке).
+// Использует assert, а не require, так как вызывается и из горутин.
+func doRequest(t *testing.T, method, path, token string, body any) int {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil && !assert.NoError(t, json.NewEncoder(&buf).Encode(body)) {
+		return 0
+	}
+	req, err := http.NewRequest(method, baseURL+path, &buf)
+	if !assert.NoError(t, err) {
+		return 0
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		return 0
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode
+}
+
+func getInfo(t *testing.T, token string) fullInfoResponse {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, baseURL+"/api/info", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var info fullInfoResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&info))
+	return info
+}
+
+// сценарий: покупка списывает монеты и появляется в инвентаре
+func TestBuyReflectedInInfo(t *testing.T) {
+	token := authenticateUser(t, "inventory@test.com", "testpass")
+
+	assert.Equal(t, http.StatusOK, doRequest(t, http.MethodGet, "/api/buy/cup", token, nil))
+	assert.Equal(t, http.StatusOK, doRequest(t, http.MethodGet, "/api/buy/cup", token, nil))
+	assert.Equal(t, http.StatusOK, doRequest(t, http.MethodGet, "/api/buy/pen", token, nil))
+
+	info := getInfo(t, token)
+	assert.Equal(t, 1000-20-20-10, info.Coins)
+	quantities := make(map[string]int)
+	for _, item := range info.Inventory {
+		quantities[item.Type] = item.Quantity
+	}
+	assert.Equal(t, map[string]int{"cup": 2, "pen": 1}, quantities)
+}
+
+// сценарий: покупка дороже баланса отклоняется и ничего не меняет
+func TestBuyInsufficientFunds(t *testing.T) {
+	token := authenticateUser(t, "poor@test.com", "testpass")
+
+	// 1000 монет: две розовые толстовки по 500, третья не по карману
+	assert.Equal(t, http.StatusOK, doRequest(t, http.MethodGet, "/api/buy/pink-hoody", token, nil))
+	assert.Equal(t, http.StatusOK, doRequest(t, http.MethodGet, "/api/buy/pink-hoody", token, nil))
+	assert.Equal(t, http.StatusBadRequest, doRequest(t, http.MethodGet, "/api/buy/pink-hoody", token, nil))
+
+	info := getInfo(t, token)
+	assert.Equal(t, 0, info.Coins)
+	require.Len(t, info.Inventory, 1)
+	assert.Equal(t, 2, info.Inventory[0].Quantity)
+}
+
+// сценарий: перевод без достаточного баланса отклоняется
+func TestSendCoinInsufficientFunds(t *testing.T) {
+	token := authenticateUser(t, "greedy@test.com", "testpass")
+	_ = authenticateUser(t, "lucky@test.com", "testpass")
+
+	status := doRequest(t, http.MethodPost, "/api/sendCoin", token, SendCoinRequest{ToUser: "lucky@test.com", Amount: 1001})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, 1000, getInfo(t, token).Coins)
+}
+
+// сценарий: параллельные покупки одного пользователя не уводят баланс в минус
+func TestConcurrentPurchases(t *testing.T) {
+	token := authenticateUser(t, "rush-buyer@test.com", "testpass")
+
+	const attempts = 20
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+	)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if doRequest(t, http.MethodGet, "/api/buy/hoody", token, nil) == http.StatusOK {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// hoody стоит 300: успешных покупок не больше трёх, баланс сходится с числом заказов
+	info := getInfo(t, token)
+	assert.LessOrEqual(t, succeeded, 3)
+	assert.Equal(t, 1000-300*succeeded, info.Coins)
+	assert.GreaterOrEqual(t, info.Coins, 0)
+
+	var orders int
+	err := testDB.QueryRow(`SELECT COUNT(*) FROM orders o JOIN users u ON u.id = o.user_id WHERE u.username = $1`, "rush-buyer@test.com").Scan(&orders)
+	require.NoError(t, err)
+	assert.Equal(t, succeeded, orders)
+}
+
+// сценарий: параллельные покупки товара с ограниченным остатком разными пользователями
+// не продают больше, чем есть на складе
+func TestConcurrentLimitedStock(t *testing.T) {
+	const (
+		item   = "limited-mug"
+		stock  = 3
+		buyers = 12
+	)
+	_, err := testDB.Exec(`INSERT INTO merch (name, price, stock) VALUES ($1, 10, $2)`, item, stock)
+	require.NoError(t, err)
+
+	tokens := make([]string, buyers)
+	for i := range tokens {
+		tokens[i] = authenticateUser(t, fmt.Sprintf("stock-rush-%d@test.com", i), "testpass")
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+		soldOut   int
+	)
+	for i := range tokens {
+		wg.Add(1)
+		go func(token string) {
+			defer wg.Done()
+			status := doRequest(t, http.MethodGet, "/api/buy/"+item, token, nil)
+			mu.Lock()
+			defer mu.Unlock()
+			switch status {
+			case http.StatusOK:
+				succeeded++
+			case http.StatusConflict:
+				soldOut++
+			default:
+				t.Errorf("unexpected status %d", status)
+			}
+		}(tokens[i])
+	}
+	wg.Wait()
+
+	// Строка товара блокируется на время покупки: продано ровно столько, сколько было,
+	// остальные получили отказ «товар закончился»
+	assert.Equal(t, stock, succeeded)
+	assert.Equal(t, buyers-stock, soldOut)
+
+	var left int
+	require.NoError(t, testDB.QueryRow(`SELECT stock FROM merch WHERE name = $1`, item).Scan(&left))
+	assert.Zero(t, left)
+
+	var orders int
+	err = testDB.QueryRow(`SELECT COUNT(*) FROM orders o JOIN merch m ON m.id = o.merch_id WHERE m.name = $1`, item).Scan(&orders)
+	require.NoError(t, err)
+	assert.Equal(t, stock, orders)
+}
+
+// сценарий: параллельные переводы между несколькими пользователями сохраняют общую сумму монет
+func TestConcurrentTransfers(t *testing.T) {
+	const users = 4
+	tokens := make([]string, users)
+	names := make([]string, users)
+	for i := range tokens {
+		names[i] = fmt.Sprintf("ring-%d@test.com", i)
+		tokens[i] = authenticateUser(t, names[i], "testpass")
+	}
+
+	var wg sync.WaitGroup
+	for round := 0; round < 10; round++ {
+		for i := 0; i < users; i++ {
+			wg.Add(1)
+			go func(from, to int) {
+				defer wg.Done()
+				// Часть запросов может получить отказ из-за блокировки строки — это допустимо
+				_ = doRequest(t, http.MethodPost, "/api/sendCoin", tokens[from], SendCoinRequest{ToUser: names[to], Amount: 70})
+			}(i, (i+1)%users)
+		}
+	}
+	wg.Wait()
+
+	var total int
+	for i := range tokens {
+		info := getInfo(t, tokens[i])
+		assert.GreaterOrEqual(t, info.Coins, 0)
+		total += info.Coins
+
+		// Баланс совпадает с историей: 1000 + полученное - отправленное
+		expected := 1000
+		for _, r := range info.CoinHistory.Received {
+			expected += r.Amount
+		}
+		for _, s := range info.CoinHistory.Sent {
+			expected -= s.Amount
+		}
+		assert.Equal(t, expected, info.Coins, "balance of %s must match its history", names[i])
+	}
+	assert.Equal(t, users*1000, total, "coins must not appear or disappear")
+}
+
+// сценарий: одновременная первая аутентификация одного пользователя не ломает вход
+func TestConcurrentFirstLogin(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token := authenticateUser(t, "simultaneous@test.com", "testpass")
+			assert.NotEmpty(t, token)
+		}()
+	}
+	wg.Wait()
+}
+
+// сценарий: начисление уведомляет о товаре из списка желаний, когда баланса хватает на самый
+// дешёвый вариант в продаже (цену считает запрос списка желаний в PostgreSQL)
+func TestWishlistAffordableAfterTransfer(t *testing.T) {
+	const item = "wish-jacket"
+	var merchID int64
+	err := testDB.QueryRow(`INSERT INTO merch (name, price) VALUES ($1, 1500) RETURNING id`, item).Scan(&merchID)
+	require.NoError(t, err)
+	// Самый дешёвый вариант распродан, поэтому товар доступен с 1200 монет
+	_, err = testDB.Exec(`INSERT INTO merch_variants (merch_id, sku, price, stock) VALUES
+		($1, 'WJ-S', 1100, 0), ($1, 'WJ-M', 1200, NULL), ($1, 'WJ-L', NULL, NULL)`, merchID)
+	require.NoError(t, err)
+
+	token := authenticateUser(t, "wisher@test.com", "testpass")
+	friend := authenticateUser(t, "wish-friend@test.com", "testpass")
+	require.Equal(t, http.StatusCreated, doRequest(t, http.MethodPost, "/api/wishlist", token, map[string]string{"item": item}))
+
+	prices := func() []int {
+		rows, err := testDB.Query(`SELECT (n.data->>'price')::int FROM notifications n
+			JOIN users u ON u.id = n.user_id
+			WHERE u.username = 'wisher@test.com' AND n.type = 'wishlist.affordable' ORDER BY n.id`)
+		require.NoError(t, err)
+		defer rows.Close()
+		var res []int
+		for rows.Next() {
+			var price int
+			require.NoError(t, rows.Scan(&price))
+			res = append(res, price)
+		}
+		require.NoError(t, rows.Err())
+		return res
+	}
+
+	require.Equal(t, http.StatusOK, doRequest(t, http.MethodPost, "/api/sendCoin", friend, SendCoinRequest{ToUser: "wisher@test.com", Amount: 150}))
+	assert.Empty(t, prices())
+	require.Equal(t, http.StatusOK, doRequest(t, http.MethodPost, "/api/sendCoin", friend, SendCoinRequest{ToUser: "wisher@test.com", Amount: 100}))
+	assert.Equal(t, []int{1200}, prices())
+}